@@ -1,24 +1,45 @@
 package main
 
 import (
+	auditHandler "chambeo-api-core/internal/audit/handler"
+	auditRepository "chambeo-api-core/internal/audit/repository"
+	auditService "chambeo-api-core/internal/audit/service"
 	authHandler "chambeo-api-core/internal/auth/handler"
+	"chambeo-api-core/internal/auth/keys"
+	authMiddleware "chambeo-api-core/internal/auth/middleware"
+	authModels "chambeo-api-core/internal/auth/models"
+	authRepository "chambeo-api-core/internal/auth/repository"
 	authService "chambeo-api-core/internal/auth/service"
+	"chambeo-api-core/internal/config"
 	userHandler "chambeo-api-core/internal/users/handler"
 	userRepository "chambeo-api-core/internal/users/repository"
 	userService "chambeo-api-core/internal/users/service"
+	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/mailer"
+	"chambeo-api-core/pkg/oidc"
+	"chambeo-api-core/pkg/password"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"chambeo-api-core/pkg/secretBox"
+	"chambeo-api-core/pkg/throttle"
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 func main() {
 
+	cfg := config.Load()
+
 	// DB
 
 	//db, err := gorm.Open(postgres.Open("jdbc:postgresql://127.0.0.1:5432/chambeo"), &gorm.Config{}) // TODO
 
 	dsn := "host=127.0.0.1 user=chambeo password=chambeo dbname=chambeo port=5432 sslmode=disable TimeZone=Asia/Shanghai"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError turns driver errors such as unique violations into the
+	// gorm errors the repositories check for.
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 
 	if err != nil {
 		panic("failed to connect database")
@@ -26,36 +47,164 @@ func main() {
 
 	// Repo
 	usrRepository := userRepository.NewUser(*db)
+	refreshTokenRepository := authRepository.NewRefreshToken(*db)
+	passwordResetTokenRepository := authRepository.NewPasswordResetToken(*db)
+	mfaRepository := authRepository.NewMFA(*db)
+	externalIdentityRepository := authRepository.NewExternalIdentity(*db)
+	apiKeyRepository := authRepository.NewAPIKey(*db)
+	oauthClientRepository := authRepository.NewOAuthClient(*db)
+	sessionRepository := authRepository.NewSession(*db)
+	impersonationRepository := authRepository.NewImpersonation(*db)
+	auditEventRepository := auditRepository.NewAuditEvent(*db)
+	denylistRepository := authRepository.NewDenylist(*db)
+	if cfg.Auth.DenylistStore == "memory" {
+		denylistRepository = authRepository.NewMemoryDenylist()
+	}
+	loginAttemptRepository := authRepository.NewLoginAttempt(*db)
+	if cfg.Auth.LoginAttemptStore == "memory" {
+		loginAttemptRepository = authRepository.NewMemoryLoginAttempt()
+	}
+	// Each email can ask for one message of every kind per minute.
+	passwordResetThrottle := authRepository.NewThrottle(*db, "password_reset", time.Minute)
+	emailVerificationThrottle := authRepository.NewThrottle(*db, "email_verification", time.Minute)
+	magicLinkThrottle := authRepository.NewThrottle(*db, "magic_link", time.Minute)
+	if cfg.Auth.ThrottleStore == "memory" {
+		passwordResetThrottle = throttle.NewMemoryThrottle(time.Minute)
+		emailVerificationThrottle = throttle.NewMemoryThrottle(time.Minute)
+		magicLinkThrottle = throttle.NewMemoryThrottle(time.Minute)
+	}
+	// Keys
+	keyRing, err := keys.LoadKeyRing(cfg.Auth)
+	if err != nil {
+		panic("failed to load signing keys: " + err.Error())
+	}
+	mfaEncryptionKey, err := secretBox.ParseKey(cfg.Auth.MFAEncryptionKey)
+	if err != nil {
+		panic("invalid AUTH_MFA_ENCRYPTION_KEY: " + err.Error())
+	}
+	mfaSecretBox, err := secretBox.NewBox(mfaEncryptionKey)
+	if err != nil {
+		panic("failed to load the mfa encryption key: " + err.Error())
+	}
+	// Password
+	passwordHasher, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		panic("invalid password hasher configuration: " + err.Error())
+	}
+	var breachedPasswords passwordPolicy.BreachedListInterface
+	if cfg.BreachedPasswordsPath != "" {
+		breachedPasswords, err = passwordPolicy.LoadBreachedList(cfg.BreachedPasswordsPath)
+		if err != nil {
+			panic("failed to load breached passwords: " + err.Error())
+		}
+	}
+	passwordRules := passwordPolicy.NewPolicy(cfg.PasswordPolicy, breachedPasswords)
+	// Mail
+	localMailer := mailer.NewLocalMailer(cfg.Mail.OutboxDir)
 	// Service
-	authenticationService := authService.NewJWTService()
-	usrService := userService.NewUser(usrRepository)
+	auditEventService := auditService.NewAuditService(auditEventRepository, time.Duration(cfg.Audit.RetentionDays)*24*time.Hour)
+	stopAuditRetention := auditEventService.StartRetention()
+	defer stopAuditRetention()
+	usrService := userService.NewUser(usrRepository, passwordHasher, passwordRules)
+	authenticationService := authService.NewJWTService(keyRing, refreshTokenRepository, denylistRepository, usrService,
+		sessionRepository, cfg.Auth.MaxSessions)
+	mfaService := authService.NewMFAService(keyRing, mfaRepository, denylistRepository, loginAttemptRepository, mfaSecretBox)
+	var oidcProviders []oidc.ProviderInterface
+	for _, providerConfig := range cfg.Auth.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(providerConfig, nil))
+	}
+	oidcService := authService.NewOIDCService(keyRing, oidcProviders, externalIdentityRepository, usrService)
+	apiKeyService := authService.NewAPIKeyService(apiKeyRepository, usrService)
+	oauthClientService := authService.NewOAuthClientService(oauthClientRepository, &authenticationService)
+	sessionService := authService.NewSessionService(sessionRepository, refreshTokenRepository)
+	impersonationService := authService.NewImpersonationService(impersonationRepository, usrService, &authenticationService)
+	loginGuard := authService.NewLoginGuard(loginAttemptRepository, authService.DefaultAccountLoginPolicy, authService.DefaultIPLoginPolicy)
+	clientAuthenticator := authService.NewClientAuthenticator(cfg.Auth.IntrospectionClients, oauthClientRepository)
+	introspectionService := authService.NewIntrospectionService(&authenticationService, oauthClientRepository)
+	passwordService := authService.NewPasswordService(passwordResetTokenRepository, usrService, &authenticationService,
+		localMailer, passwordResetThrottle, passwordHasher, passwordRules, strings.TrimSuffix(cfg.FrontendURL, "/")+"/reset-password")
+	emailVerificationService := authService.NewEmailVerificationService(keyRing, usrService, localMailer,
+		emailVerificationThrottle, strings.TrimSuffix(cfg.FrontendURL, "/")+"/verify-email")
+	magicLinkService := authService.NewMagicLinkService(keyRing, usrService, denylistRepository, localMailer,
+		magicLinkThrottle, strings.TrimSuffix(cfg.FrontendURL, "/")+"/magic-link")
 	// Handler
-	usrHandler := userHandler.NewUserHandler(usrService)
-	authenticationHandler := authHandler.NewAuthHandler(&authenticationService, usrService)
+	usrHandler := userHandler.NewUserHandler(usrService, emailVerificationService, auditEventService)
+	authenticationHandler := authHandler.NewAuthHandler(&authenticationService, usrService, mfaService, oidcService,
+		oauthClientService, magicLinkService, loginGuard, auditEventService, passwordHasher, cfg.Auth.RequireVerifiedEmail,
+		strings.HasPrefix(cfg.BaseURL, "https://"))
+	wellKnownHandler := authHandler.NewWellKnownHandler(keyRing, cfg.BaseURL)
+	introspectionHandler := authHandler.NewIntrospectionHandler(introspectionService, clientAuthenticator)
+	passwordHandler := authHandler.NewPasswordHandler(passwordService, loginGuard, auditEventService)
+	emailVerificationHandler := authHandler.NewEmailVerificationHandler(emailVerificationService)
+	mfaHandler := authHandler.NewMFAHandler(mfaService, loginGuard, auditEventService)
+	apiKeyHandler := authHandler.NewAPIKeyHandler(apiKeyService)
+	oauthClientHandler := authHandler.NewOAuthClientHandler(oauthClientService)
+	sessionHandler := authHandler.NewSessionHandler(sessionService)
+	impersonationHandler := authHandler.NewImpersonationHandler(impersonationService, auditEventService)
+	auditEventHandler := auditHandler.NewAuditHandler(auditEventService)
+	// Middleware
+	authenticationMiddleware := authMiddleware.NewAuthMiddleware(&authenticationService, apiKeyService)
 
 	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic("invalid trusted proxies: " + err.Error())
+	}
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "pong",
 		})
 	})
-	// TODO segurizar endpoints q apliquen
+
+	wellKnownRouting := r.Group("/.well-known")
+	{
+		wellKnownRouting.GET("/jwks.json", wellKnownHandler.JWKS)
+		wellKnownRouting.GET("/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+	}
+
 	v1 := r.Group("/api/v1")
 	{
-		usersRouting := v1.Group("/users")
-		{
-			usersRouting.POST("/", usrHandler.Create)
-			usersRouting.GET("/:id", usrHandler.Get)
-			usersRouting.GET("/email/:email", usrHandler.GetByEmail)
-			usersRouting.PUT("/", usrHandler.Update)
-			usersRouting.DELETE("/:id", usrHandler.Delete)
-		}
+		userHandler.RegisterRoutes(v1.Group("/users"), usrHandler, authenticationMiddleware.Authenticate())
 
 		authRouting := v1.Group("/auth")
 		{
 			authRouting.POST("/token", authenticationHandler.GenerateToken)
-			authRouting.GET("/token/validate", authenticationHandler.ValidateToken)
+			authRouting.POST("/introspect", introspectionHandler.Introspect)
+			authRouting.POST("/password/forgot", passwordHandler.Forgot)
+			authRouting.POST("/password/reset", passwordHandler.Reset)
+			authRouting.POST("/password/change", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), passwordHandler.Change)
+			authRouting.POST("/email/verify", emailVerificationHandler.Verify)
+			authRouting.POST("/email/verify/resend", emailVerificationHandler.Resend)
 			authRouting.POST("/token/refresh", authenticationHandler.RefreshToken)
+			authRouting.POST("/mfa/verify", authenticationHandler.VerifyMFA)
+			authRouting.POST("/magic-link", authenticationHandler.RequestMagicLink)
+			authRouting.POST("/magic-link/login", authenticationHandler.MagicLinkLogin)
+			authRouting.GET("/oidc/:provider/authorize", authenticationHandler.AuthorizeOIDC)
+			authRouting.GET("/oidc/:provider/callback", authenticationHandler.OIDCCallback)
+			authRouting.POST("/mfa/enroll", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), mfaHandler.Enroll)
+			authRouting.POST("/mfa/confirm", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), mfaHandler.Confirm)
+			authRouting.POST("/mfa/disable", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), mfaHandler.Disable)
+			authRouting.POST("/logout", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), authenticationHandler.Logout)
+			authRouting.POST("/logout/all", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), authenticationHandler.LogoutAll)
+			authRouting.POST("/api-keys", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), apiKeyHandler.Create)
+			authRouting.GET("/api-keys", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), apiKeyHandler.List)
+			authRouting.DELETE("/api-keys/:id", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), apiKeyHandler.Revoke)
+			authRouting.GET("/sessions", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), sessionHandler.List)
+			authRouting.DELETE("/sessions/:id", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(), sessionHandler.Revoke)
+			authRouting.POST("/impersonate", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(),
+				authMiddleware.RequirePermission(authModels.PermissionUsersImpersonate), impersonationHandler.Impersonate)
+			authRouting.POST("/clients", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(),
+				authMiddleware.RequirePermission(authModels.PermissionClientsManage), oauthClientHandler.Create)
+			authRouting.GET("/clients", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(),
+				authMiddleware.RequirePermission(authModels.PermissionClientsManage), oauthClientHandler.List)
+			authRouting.DELETE("/clients/:client_id", authenticationMiddleware.Authenticate(), authMiddleware.RequireSession(),
+				authMiddleware.RequirePermission(authModels.PermissionClientsManage), oauthClientHandler.Revoke)
+		}
+
+		auditRouting := v1.Group("/audit")
+		{
+			auditRouting.GET("/events", authenticationMiddleware.Authenticate(),
+				authMiddleware.RequirePermission(authModels.PermissionAuditRead), auditEventHandler.List)
 		}
 
 	}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AuditHandlerInterface interface {
+	List(c *gin.Context)
+}
+
+type AuditEventLister interface {
+	List(filter models.AuditEventFilter) (*models.AuditEventPage, error)
+}
+
+type AuditHandler struct {
+	auditService AuditEventLister
+}
+
+func NewAuditHandler(auditService AuditEventLister) AuditHandlerInterface {
+	return AuditHandler{auditService: auditService}
+}
+
+func (a AuditHandler) List(c *gin.Context) {
+	var filter models.AuditEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid query parameters", err))
+		return
+	}
+
+	page, err := a.auditService.List(filter)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve the audit events")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
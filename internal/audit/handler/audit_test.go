@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuditHandler_List(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                       string
+		query                      string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, auditMock *mock.Mock)
+	}{
+		{
+			name:                       "filtered events should be listed",
+			query:                      "?user_id=7&event_type=login&from=2024-05-01T00:00:00Z&cursor=12&limit=1",
+			expectedBodyResponse:       `{"events":[{"id":11,"event_type":"login","outcome":"failure","reason":"invalid_credentials","email":"meze@gmail.com","ip":"10.0.0.2","user_agent":"Safari","created_at":"2024-05-01T10:00:00Z"}],"next_cursor":"11"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, auditMock *mock.Mock) {
+				auditMock.On("List", models.AuditEventFilter{
+					UserID:    "7",
+					EventType: models.EventLogin,
+					From:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+					Cursor:    12,
+					Limit:     1,
+				}).Return(&models.AuditEventPage{
+					Events: []models.AuditEvent{{ID: 11, EventType: models.EventLogin, Outcome: models.OutcomeFailure,
+						Reason: models.ReasonInvalidCredentials, Email: "meze@gmail.com", IP: "10.0.0.2", UserAgent: "Safari", CreatedAt: createdAt}},
+					NextCursor: "11",
+				}, nil)
+			},
+		},
+		{
+			name:                       "limit above the maximum should be rejected",
+			query:                      "?limit=500",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid query parameters"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, auditMock *mock.Mock) {},
+		},
+		{
+			name:                       "malformed date should be rejected",
+			query:                      "?from=yesterday",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid query parameters"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, auditMock *mock.Mock) {},
+		},
+		{
+			name:                       "service error should return internal error",
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to retrieve the audit events"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, auditMock *mock.Mock) {
+				auditMock.On("List", models.AuditEventFilter{}).Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedAuditService := &MockAuditService{}
+			tt.mockedBehavior(t, &mockedAuditService.Mock)
+
+			router := gin.Default()
+			router.Use(customError.ErrorHandler())
+			router.GET("/api/v1/audit/events", NewAuditHandler(mockedAuditService).List)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/audit/events"+tt.query, nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) List(filter models.AuditEventFilter) (*models.AuditEventPage, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditEventPage), args.Error(1)
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Event types, whether the action succeeded is kept in the outcome.
+const (
+	EventLogin          = "login"
+	EventTokenRefresh   = "token.refresh"
+	EventLogout         = "logout"
+	EventLogoutAll      = "logout.all"
+	EventPasswordChange = "password.change"
+	EventPasswordReset  = "password.reset"
+	EventMFAEnable      = "mfa.enable"
+	EventMFADisable     = "mfa.disable"
+	EventRoleChange     = "role.change"
+	EventImpersonation  = "impersonation"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const (
+	ReasonInvalidCredentials     = "invalid_credentials"
+	ReasonTooManyAttempts        = "too_many_attempts"
+	ReasonEmailNotVerified       = "email_not_verified"
+	ReasonInvalidMFACode         = "invalid_mfa_code"
+	ReasonInvalidRefreshToken    = "invalid_refresh_token"
+	ReasonRefreshTokenReused     = "refresh_token_reused"
+	ReasonInvalidResetToken      = "invalid_reset_token"
+	ReasonInvalidCurrentPassword = "invalid_current_password"
+)
+
+// AuditEvent is an entry of the security audit log. ActorID is who acted
+// and TargetID the account acted upon, both are user ids except for client
+// tokens whose actor is the client id. Entries are never updated.
+type AuditEvent struct {
+	ID        uint64    `gorm:"primarykey" json:"id"`
+	EventType string    `json:"event_type"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	TargetID  string    `json:"target_id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
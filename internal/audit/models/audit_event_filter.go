@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// AuditEventFilter selects the entries of the audit log. UserID matches the
+// actor or the target, zero values are ignored. Entries are returned newest
+// first and Cursor continues after the last entry of the previous page.
+type AuditEventFilter struct {
+	UserID    string    `form:"user_id"`
+	EventType string    `form:"event_type"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor    uint64    `form:"cursor"`
+	Limit     int       `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+type AuditEventPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// AuditEventRepositoryInterface is append only, entries are only removed by
+// DeleteBefore once they are older than the retention period.
+type AuditEventRepositoryInterface interface {
+	Create(event *models.AuditEvent) (*models.AuditEvent, error)
+	List(filter models.AuditEventFilter) ([]models.AuditEvent, error)
+	DeleteBefore(createdBefore time.Time) (int64, error)
+}
+
+type AuditEventRepository struct {
+	DB gorm.DB
+}
+
+func NewAuditEvent(db gorm.DB) AuditEventRepositoryInterface {
+	return &AuditEventRepository{DB: db}
+}
+
+func (r *AuditEventRepository) Create(event *models.AuditEvent) (*models.AuditEvent, error) {
+	if tx := r.DB.Create(event); tx.Error != nil {
+		log.Println("error inserting audit event: ", tx.Error.Error())
+		return nil, errors.New("error inserting audit event in DB")
+	}
+	return event, nil
+}
+
+// List returns up to filter.Limit entries, newest first.
+func (r *AuditEventRepository) List(filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	query := r.DB.Model(&models.AuditEvent{})
+	if filter.UserID != "" {
+		query = query.Where("actor_id = ? OR target_id = ?", filter.UserID, filter.UserID)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.Cursor > 0 {
+		query = query.Where("id < ?", filter.Cursor)
+	}
+
+	var events []models.AuditEvent
+	if tx := query.Order("id DESC").Limit(filter.Limit).Find(&events); tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving audit events %s", tx.Error.Error()))
+		return nil, errors.New("error retrieving audit events from DB")
+	}
+	return events, nil
+}
+
+func (r *AuditEventRepository) DeleteBefore(createdBefore time.Time) (int64, error) {
+	tx := r.DB.Where("created_at < ?", createdBefore).Delete(&models.AuditEvent{})
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error purging audit events %s", tx.Error.Error()))
+		return 0, errors.New("error deleting audit events from DB")
+	}
+	return tx.RowsAffected, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAuditEventRepository_Create(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, event *models.AuditEvent)
+		asserts        func(t *testing.T, event *models.AuditEvent, err error)
+	}{
+		{
+			name: "create audit event should be successful",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, event *models.AuditEvent) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `audit_events` (`event_type`,`outcome`,`reason`,`actor_id`,`target_id`,`email`,`ip`,`user_agent`,`created_at`) VALUES (?,?,?,?,?,?,?,?,?)")).
+					WithArgs(event.EventType, event.Outcome, event.Reason, event.ActorID, event.TargetID, event.Email, event.IP, event.UserAgent, event.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, event *models.AuditEvent, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint64(1), event.ID)
+			},
+		},
+		{
+			name: "create audit event should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, event *models.AuditEvent) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `audit_events`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, event *models.AuditEvent, err error) {
+				assert.Nil(t, event)
+				assert.Equal(t, "error inserting audit event in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			event := &models.AuditEvent{EventType: models.EventLogin, Outcome: models.OutcomeFailure, Reason: models.ReasonInvalidCredentials,
+				Email: "meze@gmail.com", IP: "10.0.0.1", UserAgent: "Firefox", CreatedAt: createdAt}
+
+			tt.mockedBehavior(t, mock, event)
+
+			repository := NewAuditEvent(*gormDb)
+
+			result, err := repository.Create(event)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestAuditEventRepository_List(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		filter         models.AuditEventFilter
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, events []models.AuditEvent, err error)
+	}{
+		{
+			name:   "every filter should be applied",
+			filter: models.AuditEventFilter{UserID: "7", EventType: models.EventLogin, From: from, To: to, Cursor: 100, Limit: 2},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "event_type", "target_id"}).
+					AddRow(99, models.EventLogin, "7").
+					AddRow(98, models.EventLogin, "7")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_events` WHERE (actor_id = ? OR target_id = ?) AND event_type = ? AND created_at >= ? AND created_at < ? AND id < ? ORDER BY id DESC LIMIT 2")).
+					WithArgs("7", "7", models.EventLogin, from, to, 100).
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, events []models.AuditEvent, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, events, 2)
+				assert.Equal(t, uint64(99), events[0].ID)
+			},
+		},
+		{
+			name:   "empty filter should only limit the result",
+			filter: models.AuditEventFilter{Limit: 50},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_events` ORDER BY id DESC LIMIT 50")).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			asserts: func(t *testing.T, events []models.AuditEvent, err error) {
+				assert.NoError(t, err)
+				assert.Empty(t, events)
+			},
+		},
+		{
+			name:   "db error should be returned",
+			filter: models.AuditEventFilter{Limit: 50},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `audit_events`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, events []models.AuditEvent, err error) {
+				assert.Equal(t, "error retrieving audit events from DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewAuditEvent(*gormDb)
+
+			events, err := repository.List(tt.filter)
+
+			tt.asserts(t, events, err)
+		})
+	}
+}
+
+func TestAuditEventRepository_DeleteBefore(t *testing.T) {
+	createdBefore := time.Now().Add(-24 * time.Hour)
+
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `audit_events` WHERE created_at < ?")).
+		WithArgs(createdBefore).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	deleted, err := NewAuditEvent(*gormDb).DeleteBefore(createdBefore)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
+
+func setupMockedDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gormDb, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	return gormDb, mock
+}
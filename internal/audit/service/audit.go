@@ -0,0 +1,105 @@
+package service
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/internal/audit/repository"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// purgeInterval is how often the retention loop deletes the entries past
+	// the retention period.
+	purgeInterval      = time.Hour
+	userAgentMaxLength = 512
+)
+
+type AuditServiceInterface interface {
+	// Record stores the event. A failure is only logged, it never changes
+	// the outcome of the audited request.
+	Record(event *models.AuditEvent)
+	List(filter models.AuditEventFilter) (*models.AuditEventPage, error)
+	// StartRetention purges the entries past the retention period in the
+	// background until the returned function is called.
+	StartRetention() (stop func())
+}
+
+type AuditService struct {
+	auditEventRepository repository.AuditEventRepositoryInterface
+	// retention is how long entries are kept, zero keeps them forever.
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewAuditService(auditEventRepository repository.AuditEventRepositoryInterface, retention time.Duration) AuditServiceInterface {
+	return &AuditService{auditEventRepository: auditEventRepository, retention: retention, now: time.Now}
+}
+
+func (a *AuditService) Record(event *models.AuditEvent) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = a.now()
+	}
+	if len(event.UserAgent) > userAgentMaxLength {
+		event.UserAgent = event.UserAgent[:userAgentMaxLength]
+	}
+	if _, err := a.auditEventRepository.Create(event); err != nil {
+		log.Println(fmt.Sprintf("error trying to record audit event %s %s: %s", event.EventType, event.Outcome, err.Error()))
+	}
+}
+
+func (a *AuditService) List(filter models.AuditEventFilter) (*models.AuditEventPage, error) {
+	if filter.Limit <= 0 || filter.Limit > models.MaxPageSize {
+		filter.Limit = models.DefaultPageSize
+	}
+
+	events, err := a.auditEventRepository.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditEventPage{Events: append([]models.AuditEvent{}, events...)}
+	if len(events) == filter.Limit {
+		page.NextCursor = strconv.FormatUint(events[len(events)-1].ID, 10)
+	}
+	return page, nil
+}
+
+// StartRetention purges right away and then every purgeInterval, so
+// recording an event never waits for a delete. Without a retention period
+// nothing is started.
+func (a *AuditService) StartRetention() (stop func()) {
+	if a.retention <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			a.purge()
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (a *AuditService) purge() {
+	deleted, err := a.auditEventRepository.DeleteBefore(a.now().Add(-a.retention))
+	if err != nil {
+		log.Println("error trying to purge audit events: ", err.Error())
+		return
+	}
+	if deleted > 0 {
+		log.Println(fmt.Sprintf("purged %d audit events past the retention period", deleted))
+	}
+}
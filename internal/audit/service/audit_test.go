@@ -0,0 +1,172 @@
+package service
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditService_Record(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		retention      time.Duration
+		mockedBehavior func(t *testing.T, repositoryMock *mock.Mock)
+		asserts        func(t *testing.T, repositoryMock *mock.Mock)
+	}{
+		{
+			name:      "event should be stored without purging",
+			retention: 24 * time.Hour,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("Create", mock.Anything).Return(&models.AuditEvent{ID: 1}, nil)
+			},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock) {
+				event := repositoryMock.Calls[0].Arguments.Get(0).(*models.AuditEvent)
+				assert.Equal(t, now, event.CreatedAt)
+				assert.Len(t, event.UserAgent, 512)
+				repositoryMock.AssertNotCalled(t, "DeleteBefore", mock.Anything)
+			},
+		},
+		{
+			name:      "store error should only be logged",
+			retention: 0,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("Create", mock.Anything).Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.AssertNumberOfCalls(t, "Create", 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditEventRepository := &MockAuditEventRepository{}
+			tt.mockedBehavior(t, &auditEventRepository.Mock)
+
+			auditService := &AuditService{auditEventRepository: auditEventRepository, retention: tt.retention, now: func() time.Time { return now }}
+
+			auditService.Record(&models.AuditEvent{EventType: models.EventLogin, Outcome: models.OutcomeSuccess, UserAgent: strings.Repeat("a", 600)})
+
+			tt.asserts(t, &auditEventRepository.Mock)
+		})
+	}
+}
+
+func TestAuditService_StartRetention(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	purged := make(chan time.Time, 1)
+	auditEventRepository := &MockAuditEventRepository{}
+	auditEventRepository.On("DeleteBefore", mock.Anything).Return(int64(3), nil).Run(func(args mock.Arguments) {
+		purged <- args.Get(0).(time.Time)
+	})
+
+	auditService := &AuditService{auditEventRepository: auditEventRepository, retention: 24 * time.Hour, now: func() time.Time { return now }}
+
+	stop := auditService.StartRetention()
+	defer stop()
+
+	select {
+	case createdBefore := <-purged:
+		assert.Equal(t, now.Add(-24*time.Hour), createdBefore)
+	case <-time.After(time.Second):
+		t.Fatal("retention did not purge on start")
+	}
+}
+
+func TestAuditService_StartRetentionWithoutRetention(t *testing.T) {
+	auditEventRepository := &MockAuditEventRepository{}
+
+	auditService := &AuditService{auditEventRepository: auditEventRepository, now: time.Now}
+
+	auditService.StartRetention()()
+
+	auditEventRepository.AssertNotCalled(t, "DeleteBefore", mock.Anything)
+}
+
+func TestAuditService_List(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		filter         models.AuditEventFilter
+		mockedBehavior func(t *testing.T, repositoryMock *mock.Mock)
+		asserts        func(t *testing.T, page *models.AuditEventPage, err error)
+	}{
+		{
+			name:   "full page should return the next cursor",
+			filter: models.AuditEventFilter{UserID: "7", Limit: 2},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("List", models.AuditEventFilter{UserID: "7", Limit: 2}).Return([]models.AuditEvent{{ID: 9}, {ID: 4}}, nil)
+			},
+			asserts: func(t *testing.T, page *models.AuditEventPage, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, page.Events, 2)
+				assert.Equal(t, "4", page.NextCursor)
+			},
+		},
+		{
+			name:   "last page should not return a cursor",
+			filter: models.AuditEventFilter{},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("List", models.AuditEventFilter{Limit: models.DefaultPageSize}).Return(nil, nil)
+			},
+			asserts: func(t *testing.T, page *models.AuditEventPage, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, []models.AuditEvent{}, page.Events)
+				assert.Empty(t, page.NextCursor)
+			},
+		},
+		{
+			name:   "repository error should be returned",
+			filter: models.AuditEventFilter{},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("List", mock.Anything).Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, page *models.AuditEventPage, err error) {
+				assert.Error(t, err)
+				assert.Nil(t, page)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditEventRepository := &MockAuditEventRepository{}
+			tt.mockedBehavior(t, &auditEventRepository.Mock)
+
+			page, err := NewAuditService(auditEventRepository, 0).List(tt.filter)
+
+			tt.asserts(t, page, err)
+		})
+	}
+}
+
+type MockAuditEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditEventRepository) Create(event *models.AuditEvent) (*models.AuditEvent, error) {
+	args := m.Called(event)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return event, args.Error(1)
+}
+
+func (m *MockAuditEventRepository) List(filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditEvent), args.Error(1)
+}
+
+func (m *MockAuditEventRepository) DeleteBefore(createdBefore time.Time) (int64, error) {
+	args := m.Called(createdBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
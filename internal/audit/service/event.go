@@ -0,0 +1,35 @@
+package service
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/internal/auth/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// NewEvent builds an event with the request metadata. When the caller is
+// authenticated it is both the actor and the target, except for
+// impersonation tokens whose actor is the admin behind them.
+func NewEvent(c *gin.Context, eventType string, outcome string) *models.AuditEvent {
+	event := &models.AuditEvent{
+		EventType: eventType,
+		Outcome:   outcome,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		return event
+	}
+	event.TargetID = claims.UserID
+	event.Email = claims.Email
+	switch {
+	case claims.IsImpersonated():
+		event.ActorID = claims.Actor.Subject
+	case claims.IsClient():
+		event.ActorID = claims.ClientID
+	default:
+		event.ActorID = claims.UserID
+	}
+	return event
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/internal/auth/middleware"
+	authModels "chambeo-api-core/internal/auth/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewEvent(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		claims   *authModels.CustomClaims
+		expected *models.AuditEvent
+	}{
+		{
+			name:     "anonymous request should only carry the request metadata",
+			expected: &models.AuditEvent{EventType: models.EventLogin, Outcome: models.OutcomeSuccess, IP: "10.0.0.2", UserAgent: "Safari"},
+		},
+		{
+			name:   "user should be actor and target",
+			claims: &authModels.CustomClaims{UserID: "7", Email: "meze@gmail.com"},
+			expected: &models.AuditEvent{EventType: models.EventLogin, Outcome: models.OutcomeSuccess, ActorID: "7", TargetID: "7",
+				Email: "meze@gmail.com", IP: "10.0.0.2", UserAgent: "Safari"},
+		},
+		{
+			name:   "impersonation should record the admin as actor",
+			claims: &authModels.CustomClaims{UserID: "7", Email: "meze@gmail.com", Actor: &authModels.Actor{Subject: "1"}},
+			expected: &models.AuditEvent{EventType: models.EventLogin, Outcome: models.OutcomeSuccess, ActorID: "1", TargetID: "7",
+				Email: "meze@gmail.com", IP: "10.0.0.2", UserAgent: "Safari"},
+		},
+		{
+			name:   "client should be recorded as actor",
+			claims: &authModels.CustomClaims{ClientID: "billing"},
+			expected: &models.AuditEvent{EventType: models.EventLogin, Outcome: models.OutcomeSuccess, ActorID: "billing",
+				IP: "10.0.0.2", UserAgent: "Safari"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("POST", "/", nil)
+			c.Request.RemoteAddr = "10.0.0.2:1234"
+			c.Request.Header.Set("User-Agent", "Safari")
+			if tt.claims != nil {
+				c.Set(middleware.ClaimsKey, tt.claims)
+			}
+
+			assert.Equal(t, tt.expected, NewEvent(c, models.EventLogin, models.OutcomeSuccess))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type APIKeyHandlerInterface interface {
+	Create(c *gin.Context)
+	List(c *gin.Context)
+	Revoke(c *gin.Context)
+}
+
+type APIKeyManagementService interface {
+	Create(userID string, request *models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error)
+	List(userID string) ([]models.APIKeyResponse, error)
+	Revoke(userID string, keyID string) error
+}
+
+type APIKeyHandler struct {
+	apiKeyService APIKeyManagementService
+}
+
+func NewAPIKeyHandler(apiKeyService APIKeyManagementService) APIKeyHandlerInterface {
+	return APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+func (a APIKeyHandler) Create(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	var createRequest models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	apiKey, err := a.apiKeyService.Create(claims.UserID, &createRequest)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to create the API key")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+func (a APIKeyHandler) List(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	apiKeys, err := a.apiKeyService.List(claims.UserID)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve the API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+func (a APIKeyHandler) Revoke(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	err := a.apiKeyService.Revoke(claims.UserID, c.Param("id"))
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to revoke the API key")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
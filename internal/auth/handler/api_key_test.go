@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var apiKeyClaims = &models.CustomClaims{UserID: "7", Email: "meze@gmail.com"}
+
+func TestAPIKeyHandler_Create(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, apiKeyMock *mock.Mock)
+	}{
+		{
+			name:                       "valid request should return the key once",
+			requestBody:                `{"name":"ci","scopes":["users:read"]}`,
+			expectedBodyResponse:       `{"id":3,"name":"ci","prefix":"chk_abcdef","scopes":["users:read"],"created_at":"2024-05-01T10:00:00Z","key":"chk_abcdefghij"}`,
+			expectedHttpStatusResponse: http.StatusCreated,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Create", "7", &models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"users:read"}}).Return(&models.CreatedAPIKeyResponse{
+					APIKeyResponse: models.APIKeyResponse{ID: 3, Name: "ci", Prefix: "chk_abcdef", Scopes: []string{"users:read"}, CreatedAt: createdAt},
+					Key:            "chk_abcdefghij",
+				}, nil)
+			},
+		},
+		{
+			name:                       "missing scopes should return bad request",
+			requestBody:                `{"name":"ci","scopes":[]}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+			},
+		},
+		{
+			name:                       "scope not granted should return validation error",
+			requestBody:                `{"name":"ci","scopes":["users:manage"]}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"API key scopes must be granted to the user","fields":[{"field":"scopes","code":"FORBIDDEN","message":"Scopes must be permissions granted to the user"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Create", "7", mock.Anything).Return(nil, models.ErrInvalidAPIKeyScope)
+			},
+		},
+		{
+			name:                       "past expiration should return validation error",
+			requestBody:                `{"name":"ci","scopes":["users:read"],"expires_at":"2020-01-01T00:00:00Z"}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"API key expiration must be in the future","fields":[{"field":"expires_at","code":"INVALID_BODY","message":"Expiration must be in the future"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Create", "7", mock.Anything).Return(nil, models.ErrInvalidAPIKeyExpiry)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"name":"ci","scopes":["users:read"]}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to create the API key"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Create", "7", mock.Anything).Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedAPIKeyService := &MockAPIKeyService{}
+			tt.mockedBehavior(t, &mockedAPIKeyService.Mock)
+
+			router := setupMockedAPIKeyRouter(NewAPIKeyHandler(mockedAPIKeyService), apiKeyClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/api-keys", bytes.NewBufferString(tt.requestBody))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestAPIKeyHandler_List(t *testing.T) {
+	mockedAPIKeyService := &MockAPIKeyService{}
+	mockedAPIKeyService.On("List", "7").Return([]models.APIKeyResponse{
+		{ID: 3, Name: "ci", Prefix: "chk_abcdef", Scopes: []string{"users:read"}, CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}, nil)
+
+	router := setupMockedAPIKeyRouter(NewAPIKeyHandler(mockedAPIKeyService), apiKeyClaims)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/auth/api-keys", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"id":3,"name":"ci","prefix":"chk_abcdef","scopes":["users:read"],"created_at":"2024-05-01T10:00:00Z"}]`, w.Body.String())
+}
+
+func TestAPIKeyHandler_Revoke(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		claims                     *models.CustomClaims
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, apiKeyMock *mock.Mock)
+	}{
+		{
+			name:                       "own key should be revoked",
+			claims:                     apiKeyClaims,
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Revoke", "7", "3").Return(nil)
+			},
+		},
+		{
+			name:                       "unknown key should return not found",
+			claims:                     apiKeyClaims,
+			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"API key not found"}`,
+			expectedHttpStatusResponse: http.StatusNotFound,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Revoke", "7", "3").Return(models.ErrAPIKeyNotFound)
+			},
+		},
+		{
+			name:                       "missing claims should return unauthorized",
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, apiKeyMock *mock.Mock) {
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedAPIKeyService := &MockAPIKeyService{}
+			tt.mockedBehavior(t, &mockedAPIKeyService.Mock)
+
+			router := setupMockedAPIKeyRouter(NewAPIKeyHandler(mockedAPIKeyService), tt.claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/v1/auth/api-keys/3", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func setupMockedAPIKeyRouter(apiKeyHandler APIKeyHandlerInterface, claims *models.CustomClaims) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/api-keys", apiKeyHandler.Create)
+			auth.GET("/api-keys", apiKeyHandler.List)
+			auth.DELETE("/api-keys/:id", apiKeyHandler.Revoke)
+		}
+	}
+
+	return r
+}
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) Create(userID string, request *models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error) {
+	args := m.Called(userID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CreatedAPIKeyResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) List(userID string) ([]models.APIKeyResponse, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKeyResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Revoke(userID string, keyID string) error {
+	args := m.Called(userID, keyID)
+	return args.Error(0)
+}
@@ -1,171 +1,435 @@
 package handler
 
 import (
+	auditModels "chambeo-api-core/internal/audit/models"
+	auditService "chambeo-api-core/internal/audit/service"
+	"chambeo-api-core/internal/auth/middleware"
 	"chambeo-api-core/internal/auth/models"
+	userModels "chambeo-api-core/internal/users/models"
 	"chambeo-api-core/internal/users/service"
 	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/password"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type AuthHandlerInterface interface {
 	GenerateToken(c *gin.Context)
 	RefreshToken(c *gin.Context)
-	ValidateToken(c *gin.Context)
+	VerifyMFA(c *gin.Context)
+	AuthorizeOIDC(c *gin.Context)
+	OIDCCallback(c *gin.Context)
+	RequestMagicLink(c *gin.Context)
+	MagicLinkLogin(c *gin.Context)
+	Logout(c *gin.Context)
+	LogoutAll(c *gin.Context)
 }
 
 type AuthService interface {
-	GenerateToken(email string, userId string) (*string, error)
+	GenerateToken(subject models.TokenSubject) (*models.TokenResponse, error)
+	ConsumeRefreshToken(refreshToken string) (*models.RefreshToken, error)
 	ParseToken(tokenString string) (*jwt.Token, error)
+	RevokeToken(claims *models.CustomClaims) error
+	RevokeAllTokens(userID string) error
+}
+
+type LoginMFAService interface {
+	IsEnabled(userID string) (bool, error)
+	Verify(userID string, code string) error
+	IssuePendingToken(userID string) (*models.MFAPendingResponse, error)
+	ParsePendingToken(token string) (string, error)
+	ConsumePendingToken(token string) error
+}
+
+type LoginOIDCService interface {
+	BeginLogin(provider string) (*models.OIDCAuthorization, error)
+	CompleteLogin(provider string, code string, state string, stateToken string) (*userModels.UserRequest, error)
+}
+
+type ClientCredentialsService interface {
+	IssueToken(clientID string, clientSecret string, scope string, audience string) (*models.TokenResponse, error)
+}
+
+type LoginMagicLinkService interface {
+	Send(email string) error
+	Consume(token string) (*userModels.UserRequest, error)
+}
+
+type LoginGuard interface {
+	Check(email string, ip string) (time.Duration, error)
+	RegisterFailure(email string, ip string) error
+	RegisterSuccess(email string) error
+}
+
+type Auditor interface {
+	Record(event *auditModels.AuditEvent)
 }
 
 type AuthHandler struct {
-	authService AuthService
-	userService service.UserServiceInterface
+	authService          AuthService
+	userService          service.UserServiceInterface
+	mfaService           LoginMFAService
+	oidcService          LoginOIDCService
+	clientService        ClientCredentialsService
+	magicLinkService     LoginMagicLinkService
+	loginGuard           LoginGuard
+	auditor              Auditor
+	passwordHasher       password.HasherInterface
+	requireVerifiedEmail bool
+	// secureCookies marks the cookies Secure whatever the request says,
+	// X-Forwarded-Proto can be sent by the client itself.
+	secureCookies bool
+	// dummyPasswordHash is verified when the email is unknown, so both
+	// failures take the same time and get the same response.
+	dummyPasswordHash string
 }
 
-func NewAuthHandler(authService AuthService, userService service.UserServiceInterface) AuthHandlerInterface {
-	return AuthHandler{authService: authService, userService: userService}
+func NewAuthHandler(authService AuthService, userService service.UserServiceInterface, mfaService LoginMFAService,
+	oidcService LoginOIDCService, clientService ClientCredentialsService, magicLinkService LoginMagicLinkService,
+	loginGuard LoginGuard, auditor Auditor, passwordHasher password.HasherInterface, requireVerifiedEmail bool,
+	secureCookies bool) AuthHandlerInterface {
+	dummyPasswordHash, err := passwordHasher.Hash("chambeo-dummy-password")
+	if err != nil {
+		log.Println("error trying to generate dummy password hash: ", err.Error())
+	}
+	return AuthHandler{
+		authService:          authService,
+		userService:          userService,
+		mfaService:           mfaService,
+		oidcService:          oidcService,
+		clientService:        clientService,
+		magicLinkService:     magicLinkService,
+		loginGuard:           loginGuard,
+		auditor:              auditor,
+		passwordHasher:       passwordHasher,
+		requireVerifiedEmail: requireVerifiedEmail,
+		secureCookies:        secureCookies,
+		dummyPasswordHash:    dummyPasswordHash,
+	}
 }
 
+// GenerateToken accepts a JSON or form encoded body. The password grant is
+// used when grant_type is empty.
 func (a AuthHandler) GenerateToken(c *gin.Context) {
-	var userDto models.UserLogin
-	err := c.ShouldBindJSON(&userDto)
+	var tokenRequest models.TokenRequest
+	var err error
+	if c.ContentType() == binding.MIMEPOSTForm {
+		err = c.ShouldBindWith(&tokenRequest, binding.Form)
+	} else {
+		err = c.ShouldBindJSON(&tokenRequest)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.InvalidBody,
-			Message: "Invalid request body",
-		})
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	if tokenRequest.GrantType == models.GrantTypeClientCredentials {
+		a.clientCredentialsToken(c, &tokenRequest)
+		return
+	}
+	if tokenRequest.GrantType != "" && tokenRequest.GrantType != models.GrantTypePassword {
+		c.Error(customError.NewInvalidBody("Unsupported grant type", err))
+		return
+	}
+	if tokenRequest.Email == "" || tokenRequest.Password == "" {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+	userDto := models.UserLogin{Email: tokenRequest.Email, Password: tokenRequest.Password}
+
+	if !a.allowAttempt(c, "", userDto.Email) {
 		return
 	}
 
 	user, err := a.userService.GetByEmail(userDto.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error trying to retrieve user from DB",
-		})
+	if err != nil && !errors.Is(err, userModels.ErrUserNotFound) {
+		c.Error(err).SetMeta("Error trying to retrieve user from DB")
 		return
 	}
 
 	if user == nil {
-		c.JSON(http.StatusNotFound, customError.Error{
-			Code:    customError.NotFound,
-			Message: "User not found",
-		})
+		a.validPassword(userDto.Password, a.dummyPasswordHash)
+		a.rejectCredentials(c, "", userDto.Email)
 		return
 	}
 
 	if !a.validPassword(userDto.Password, user.Password) {
-		c.JSON(http.StatusUnauthorized, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Invalid credentials",
-		})
+		a.rejectCredentials(c, strconv.Itoa(user.Id), userDto.Email)
 		return
 	}
 
-	token, err := a.authService.GenerateToken(user.Email, strconv.Itoa(user.Id))
+	// Hashes made with an older algorithm or parameters are upgraded while
+	// the plain password is at hand, a failure only delays the upgrade.
+	if a.passwordHasher.NeedsRehash(user.Password) {
+		if err := a.userService.UpdatePassword(strconv.Itoa(user.Id), userDto.Password); err != nil {
+			log.Println("error trying to rehash password: ", err.Error())
+		}
+	}
+
+	a.finishLogin(c, user)
+}
+
+// finishLogin runs the steps shared by every first factor once the user is
+// known: the verified email check and the second factor.
+func (a AuthHandler) finishLogin(c *gin.Context, user *userModels.UserRequest) {
+	if a.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		a.recordLogin(c, auditModels.OutcomeFailure, auditModels.ReasonEmailNotVerified, strconv.Itoa(user.Id), user.Email)
+		c.Error(customError.NewForbidden("Email address has not been verified", nil).WithCode(customError.EmailNotVerified))
+		return
+	}
+
+	mfaEnabled, err := a.mfaService.IsEnabled(strconv.Itoa(user.Id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error trying to generate token",
-		})
+		c.Error(err).SetMeta("Error trying to retrieve two-factor authentication status")
 		return
 	}
 
-	c.JSON(http.StatusOK, models.TokenResponse{AccessToken: *token})
-	return
+	if mfaEnabled {
+		pending, err := a.mfaService.IssuePendingToken(strconv.Itoa(user.Id))
+		if err != nil {
+			c.Error(err).SetMeta("Error trying to generate token")
+			return
+		}
+		c.JSON(http.StatusOK, pending)
+		return
+	}
+
+	a.completeLogin(c, user)
 }
 
-func (a AuthHandler) RefreshToken(c *gin.Context) {
-	var tokenToValidate models.TokenRequest
-	err := c.ShouldBindJSON(&tokenToValidate)
+// VerifyMFA is the second step of the login for accounts with two-factor
+// authentication, it exchanges the mfa_pending token and a TOTP or recovery
+// code for the access and refresh tokens.
+func (a AuthHandler) VerifyMFA(c *gin.Context) {
+	var verifyRequest models.MFAVerifyRequest
+	err := c.ShouldBindJSON(&verifyRequest)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.InvalidBody,
-			Message: "Invalid request body",
-		})
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
 		return
 	}
 
-	jwtToken, err := a.authService.ParseToken(tokenToValidate.AccessToken)
+	userID, err := a.mfaService.ParsePendingToken(verifyRequest.MFAToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error trying to parse token",
-		})
+		c.Error(models.ErrInvalidMFAToken.Wrap(err))
 		return
 	}
 
-	if !jwtToken.Valid {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error refreshing token",
-		})
+	user, err := a.userService.Get(userID)
+	if err != nil || user == nil {
+		c.Error(models.ErrInvalidMFAToken.Wrap(err))
 		return
 	}
 
-	claims, ok := jwtToken.Claims.(*models.CustomClaims)
+	// Failed codes count against the account like failed passwords, so the
+	// limit of a single mfa_pending token can not be dodged by logging in
+	// again.
+	if !a.allowAttempt(c, userID, user.Email) {
+		return
+	}
 
-	if !ok {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error trying to parse token claims",
-		})
+	err = a.mfaService.Verify(userID, verifyRequest.Code)
+	if errors.Is(err, models.ErrInvalidMFACode) || errors.Is(err, models.ErrMFANotEnabled) {
+		a.recordLogin(c, auditModels.OutcomeFailure, auditModels.ReasonInvalidMFACode, userID, user.Email)
+		if err := a.loginGuard.RegisterFailure(user.Email, c.ClientIP()); err != nil {
+			log.Println("error trying to register failed mfa code: ", err.Error())
+		}
+		// The pending token proves the password, a wrong code fails the
+		// login like wrong credentials do.
+		c.Error(customError.NewUnauthorized(models.ErrInvalidMFACode.Message, err).WithCode(customError.InvalidMFACode))
+		return
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to verify two-factor authentication code")
 		return
 	}
 
-	refreshedToken, err := a.authService.GenerateToken(claims.UserID, claims.Email)
+	if err := a.mfaService.ConsumePendingToken(verifyRequest.MFAToken); err != nil {
+		c.Error(models.ErrInvalidMFAToken.Wrap(err))
+		return
+	}
+
+	a.completeLogin(c, user)
+}
+
+// completeLogin issues the tokens once every login step has succeeded.
+func (a AuthHandler) completeLogin(c *gin.Context, user *userModels.UserRequest) {
+	permissions, err := a.userService.GetPermissions(strconv.Itoa(user.Id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error trying to refresh token",
-		})
+		c.Error(err).SetMeta("Error trying to retrieve user permissions")
 		return
 	}
 
-	c.JSON(http.StatusOK, models.TokenResponse{AccessToken: *refreshedToken})
-	return
+	token, err := a.authService.GenerateToken(models.TokenSubject{
+		UserID:       strconv.Itoa(user.Id),
+		Email:        user.Email,
+		Role:         permissions.Role,
+		Permissions:  permissions.Permissions,
+		TokenVersion: user.TokenVersion,
+		UserAgent:    c.Request.UserAgent(),
+		IP:           c.ClientIP(),
+	})
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to generate token")
+		return
+	}
 
+	// The account counter is only cleared here, a correct password must not
+	// reset the failures of a second factor that is still being guessed.
+	if err := a.loginGuard.RegisterSuccess(user.Email); err != nil {
+		log.Println("error trying to reset login attempts: ", err.Error())
+	}
+	a.recordLogin(c, auditModels.OutcomeSuccess, "", strconv.Itoa(user.Id), user.Email)
+	c.JSON(http.StatusOK, token)
 }
 
-func (a AuthHandler) ValidateToken(c *gin.Context) {
-	var tokenToValidate models.TokenRequest
-	err := c.ShouldBindJSON(&tokenToValidate)
+func (a AuthHandler) RefreshToken(c *gin.Context) {
+	var refreshRequest models.RefreshTokenRequest
+	err := c.ShouldBindJSON(&refreshRequest)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.InvalidBody,
-			Message: "Invalid request body",
-		})
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	storedToken, err := a.authService.ConsumeRefreshToken(refreshRequest.RefreshToken)
+	if errors.Is(err, models.ErrInvalidRefreshToken) || errors.Is(err, models.ErrRefreshTokenReused) {
+		event := auditService.NewEvent(c, auditModels.EventTokenRefresh, auditModels.OutcomeFailure)
+		event.Reason = auditModels.ReasonInvalidRefreshToken
+		if errors.Is(err, models.ErrRefreshTokenReused) {
+			event.Reason = auditModels.ReasonRefreshTokenReused
+		}
+		a.auditor.Record(event)
+		c.Error(models.ErrInvalidRefreshToken.Wrap(err))
+		return
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to validate refresh token")
+		return
+	}
+
+	user, err := a.userService.Get(strconv.Itoa(int(storedToken.UserID)))
+	if err != nil || user == nil {
+		c.Error(models.ErrInvalidRefreshToken.Wrap(err))
 		return
 	}
 
-	parsedToken, err := a.authService.ParseToken(tokenToValidate.AccessToken)
+	permissions, err := a.userService.GetPermissions(strconv.Itoa(user.Id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Error trying to parse token",
-		})
+		c.Error(err).SetMeta("Error trying to retrieve user permissions")
 		return
 	}
 
-	if !parsedToken.Valid {
-		c.JSON(http.StatusUnauthorized, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "Token is not valid",
-		})
+	refreshedToken, err := a.authService.GenerateToken(models.TokenSubject{
+		UserID:       strconv.Itoa(user.Id),
+		Email:        user.Email,
+		Role:         permissions.Role,
+		Permissions:  permissions.Permissions,
+		SessionID:    storedToken.SessionID,
+		TokenVersion: user.TokenVersion,
+		IP:           c.ClientIP(),
+	})
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to refresh token")
 		return
 	}
+
+	event := auditService.NewEvent(c, auditModels.EventTokenRefresh, auditModels.OutcomeSuccess)
+	event.ActorID = strconv.Itoa(user.Id)
+	event.TargetID = event.ActorID
+	event.Email = user.Email
+	a.auditor.Record(event)
+	c.JSON(http.StatusOK, refreshedToken)
 	return
+
 }
 
-func (a AuthHandler) validPassword(requestPassword, retrievedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(retrievedPassword), []byte(requestPassword))
+// Logout revokes the access token used to authenticate the request and the
+// refresh tokens of its session.
+func (a AuthHandler) Logout(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	if err := a.authService.RevokeToken(claims); err != nil {
+		c.Error(err).SetMeta("Error trying to revoke token")
+		return
+	}
+
+	a.auditor.Record(auditService.NewEvent(c, auditModels.EventLogout, auditModels.OutcomeSuccess))
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll revokes every token issued to the authenticated user.
+func (a AuthHandler) LogoutAll(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	if err := a.authService.RevokeAllTokens(claims.UserID); err != nil {
+		c.Error(err).SetMeta("Error trying to revoke tokens")
+		return
+	}
+
+	a.auditor.Record(auditService.NewEvent(c, auditModels.EventLogoutAll, auditModels.OutcomeSuccess))
+	c.Status(http.StatusNoContent)
+}
+
+// recordLogin stores the outcome of a login step, the caller is not
+// authenticated yet so the user is both the actor and the target.
+func (a AuthHandler) recordLogin(c *gin.Context, outcome string, reason string, userID string, email string) {
+	event := auditService.NewEvent(c, auditModels.EventLogin, outcome)
+	event.Reason = reason
+	event.ActorID = userID
+	event.TargetID = userID
+	event.Email = email
+	a.auditor.Record(event)
+}
+
+// allowAttempt answers 429 and returns false while the login guard holds
+// back the account or the address of the caller.
+func (a AuthHandler) allowAttempt(c *gin.Context, userID string, email string) bool {
+	retryAfter, err := a.loginGuard.Check(email, c.ClientIP())
 	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve login attempts")
+		return false
+	}
+	if retryAfter > 0 {
+		a.recordLogin(c, auditModels.OutcomeFailure, auditModels.ReasonTooManyAttempts, userID, email)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.Error(customError.NewTooManyRequests("Too many failed login attempts, try again later", nil))
 		return false
 	}
 	return true
 }
+
+func (a AuthHandler) rejectCredentials(c *gin.Context, userID string, email string) {
+	a.recordLogin(c, auditModels.OutcomeFailure, auditModels.ReasonInvalidCredentials, userID, email)
+	if err := a.loginGuard.RegisterFailure(email, c.ClientIP()); err != nil {
+		log.Println("error trying to register failed login: ", err.Error())
+	}
+	c.Error(customError.NewUnauthorized("Invalid credentials", nil).WithCode(customError.InvalidCredentials))
+}
+
+// validPassword verifies against the dummy hash when there is no stored one,
+// so accounts created with social login take as long to fail as the rest.
+func (a AuthHandler) validPassword(requestPassword, retrievedPassword string) bool {
+	if retrievedPassword == "" {
+		a.passwordHasher.Verify(requestPassword, a.dummyPasswordHash)
+		return false
+	}
+	valid, err := a.passwordHasher.Verify(requestPassword, retrievedPassword)
+	if err != nil {
+		return false
+	}
+	return valid
+}
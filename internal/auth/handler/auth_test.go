@@ -2,8 +2,11 @@ package handler
 
 import (
 	"bytes"
+	auditModels "chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/internal/auth/middleware"
 	authClaims "chambeo-api-core/internal/auth/models"
 	"chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/customError"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -23,9 +26,18 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 		"cCI6MTcwNTI3NjMyMiwibmJmIjoxNzA1MTg5OTIyLCJpYXQiOjE3MDUxODk5MjIsImp0aSI6IjEifQ.p2jndX8Bn8q3" +
 		"mrJp4vv9nsGugZOZRcukrOBuMSIO4SA"
 
+	mockedTokenResponse := &authClaims.TokenResponse{
+		AccessToken:  mockedToken,
+		RefreshToken: "mockedRefreshToken",
+		ExpiresIn:    900,
+		TokenType:    "Bearer",
+	}
+
 	createdAt := time.Now()
 	updatedAt := createdAt
 
+	userPermissions := &models.UserPermissions{Id: 1, Role: "user", Permissions: []string{"users:read"}}
+
 	tests := []struct {
 		name                       string
 		requestBody                string
@@ -50,11 +62,12 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 					UpdatedAt: updatedAt,
 					DeletedAt: nil,
 				}, nil)
-				authMock.On("GenerateToken", "meze@gmail.com", "1").Return(&mockedToken, nil)
+				userMock.On("GetPermissions", "1").Return(userPermissions, nil)
+				authMock.On("GenerateToken", authClaims.TokenSubject{UserID: "1", Email: "meze@gmail.com", Role: "user", Permissions: []string{"users:read"}}).Return(mockedTokenResponse, nil)
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
-				assert.Equal(t, fmt.Sprintf(`{"access_token":"%s"}`, mockedToken), response.Body.String())
+				assert.Equal(t, fmt.Sprintf(`{"access_token":"%s","refresh_token":"mockedRefreshToken","expires_in":900,"token_type":"Bearer"}`, mockedToken), response.Body.String())
 			},
 		},
 		{
@@ -82,12 +95,12 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 			},
 		},
 		{
-			name:                       "valid credentials should return user not found",
+			name:                       "unknown email should return the same response as a wrong password",
 			requestBody:                `{"email":"meze@gmail.com", "password":"password"}`,
-			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"User not found"}`,
-			expectedHttpStatusResponse: http.StatusNotFound,
+			expectedBodyResponse:       `{"code":"INVALID_CREDENTIALS","message":"Invalid credentials"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
 			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
-				userMock.On("GetByEmail", mock.Anything).Return(nil, nil)
+				userMock.On("GetByEmail", mock.Anything).Return(nil, models.ErrUserNotFound)
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -97,7 +110,7 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 		{
 			name:                       "invalid credentials should return unauthorized",
 			requestBody:                `{"email":"meze@gmail.com", "password":"invalidPassword"}`,
-			expectedBodyResponse:       `{"code":"ERROR","message":"Invalid credentials"}`,
+			expectedBodyResponse:       `{"code":"INVALID_CREDENTIALS","message":"Invalid credentials"}`,
 			expectedHttpStatusResponse: http.StatusUnauthorized,
 			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
 				userMock.On("GetByEmail", mock.Anything).Return(&models.UserRequest{
@@ -132,7 +145,8 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 					UpdatedAt: updatedAt,
 					DeletedAt: nil,
 				}, nil)
-				authMock.On("GenerateToken", "meze@gmail.com", "1").Return(nil, errors.New("error generating token"))
+				userMock.On("GetPermissions", "1").Return(userPermissions, nil)
+				authMock.On("GenerateToken", mock.Anything).Return(nil, errors.New("error generating token"))
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -149,9 +163,12 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 
 			tt.mockedBehavior(t, &mockedUserService.Mock, &mockedAuthService.Mock)
 
-			authHandler := NewAuthHandler(mockedAuthService, mockedUserService)
+			mockedMFAService := &MockMFAService{}
+			mockedMFAService.On("IsEnabled", "1").Return(false, nil)
+
+			authHandler := NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false)
 
-			router := setupMockedRouter(authHandler)
+			router := setupMockedRouter(authHandler, nil)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(tt.requestBody)))
@@ -164,67 +181,504 @@ func TestAuthHandler_GenerateToken(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_GenerateTokenRehashesPassword(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		needsRehash bool
+		rehashError error
+	}{
+		{name: "outdated hash should be rehashed", needsRehash: true, rehashError: nil},
+		{name: "rehash error should not fail the login", needsRehash: true, rehashError: errors.New("error from db")},
+		{name: "current hash should be kept", needsRehash: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
+			mockedAuthService := &MockAuthService{}
+			mockedMFAService := &MockMFAService{}
+			mockedHasher := &MockPasswordHasher{}
+
+			mockedUserService.On("GetByEmail", "meze@gmail.com").Return(&models.UserRequest{Id: 1, Email: "meze@gmail.com", Password: "stored-hash"}, nil)
+			mockedUserService.On("UpdatePassword", "1", "password").Return(tt.rehashError)
+			mockedUserService.On("GetPermissions", "1").Return(&models.UserPermissions{Id: 1, Role: "user"}, nil)
+			mockedMFAService.On("IsEnabled", "1").Return(false, nil)
+			mockedAuthService.On("GenerateToken", mock.Anything).Return(&authClaims.TokenResponse{AccessToken: "token"}, nil)
+			mockedHasher.On("Hash", mock.Anything).Return("dummy-hash", nil)
+			mockedHasher.On("Verify", "password", "stored-hash").Return(true, nil)
+			mockedHasher.On("NeedsRehash", "stored-hash").Return(tt.needsRehash)
+
+			router := setupMockedRouter(NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), mockedHasher, false, false), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(`{"email":"meze@gmail.com", "password":"password"}`)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			if tt.needsRehash {
+				mockedUserService.AssertCalled(t, "UpdatePassword", "1", "password")
+			} else {
+				mockedUserService.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_GenerateTokenUnknownEmailUsesDummyHash(t *testing.T) {
+	mockedUserService := &MockUserService{}
+	mockedHasher := &MockPasswordHasher{}
+
+	mockedUserService.On("GetByEmail", "meze@gmail.com").Return(nil, models.ErrUserNotFound)
+	mockedHasher.On("Hash", mock.Anything).Return("dummy-hash", nil)
+	mockedHasher.On("Verify", "password", "dummy-hash").Return(false, nil)
+
+	router := setupMockedRouter(NewAuthHandler(&MockAuthService{}, mockedUserService, &MockMFAService{}, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), mockedHasher, false, false), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(`{"email":"meze@gmail.com", "password":"password"}`)))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockedHasher.AssertCalled(t, "Verify", "password", "dummy-hash")
+}
+
+func TestAuthHandler_GenerateTokenAccountWithoutPasswordUsesDummyHash(t *testing.T) {
+	mockedUserService := &MockUserService{}
+	mockedHasher := &MockPasswordHasher{}
+
+	mockedUserService.On("GetByEmail", "meze@gmail.com").Return(&models.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+	mockedHasher.On("Hash", mock.Anything).Return("dummy-hash", nil)
+	mockedHasher.On("Verify", "password", "dummy-hash").Return(true, nil)
+
+	router := setupMockedRouter(NewAuthHandler(&MockAuthService{}, mockedUserService, &MockMFAService{}, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), mockedHasher, false, false), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(`{"email":"meze@gmail.com", "password":"password"}`)))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "an account without password should never log in with one")
+	mockedHasher.AssertCalled(t, "Verify", "password", "dummy-hash")
+}
+
+func TestAuthHandler_GenerateTokenRecordsAuditEvents(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		password string
+		expected *auditModels.AuditEvent
+	}{
+		{
+			name:     "successful login should be recorded",
+			password: "password",
+			expected: &auditModels.AuditEvent{EventType: auditModels.EventLogin, Outcome: auditModels.OutcomeSuccess,
+				ActorID: "1", TargetID: "1", Email: "meze@gmail.com", IP: "10.0.0.2", UserAgent: "Safari"},
+		},
+		{
+			name:     "wrong password should be recorded with its reason",
+			password: "invalidPassword",
+			expected: &auditModels.AuditEvent{EventType: auditModels.EventLogin, Outcome: auditModels.OutcomeFailure,
+				Reason: auditModels.ReasonInvalidCredentials, ActorID: "1", TargetID: "1", Email: "meze@gmail.com", IP: "10.0.0.2", UserAgent: "Safari"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
+			mockedAuthService := &MockAuthService{}
+			mockedMFAService := &MockMFAService{}
+			mockedHasher := &MockPasswordHasher{}
+			mockedAuditor := testAuditor()
+
+			mockedUserService.On("GetByEmail", "meze@gmail.com").Return(&models.UserRequest{Id: 1, Email: "meze@gmail.com", Password: "stored-hash"}, nil)
+			mockedUserService.On("GetPermissions", "1").Return(&models.UserPermissions{Id: 1, Role: "user"}, nil)
+			mockedMFAService.On("IsEnabled", "1").Return(false, nil)
+			mockedAuthService.On("GenerateToken", mock.Anything).Return(&authClaims.TokenResponse{AccessToken: "token"}, nil)
+			mockedHasher.On("Hash", mock.Anything).Return("dummy-hash", nil)
+			mockedHasher.On("Verify", "password", "stored-hash").Return(true, nil)
+			mockedHasher.On("Verify", "invalidPassword", "stored-hash").Return(false, nil)
+			mockedHasher.On("NeedsRehash", "stored-hash").Return(false)
+
+			router := setupMockedRouter(NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), mockedAuditor, mockedHasher, false, false), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(fmt.Sprintf(`{"email":"meze@gmail.com", "password":"%s"}`, tt.password))))
+			req.RemoteAddr = "10.0.0.2:1234"
+			req.Header.Set("User-Agent", "Safari")
+
+			router.ServeHTTP(w, req)
+
+			mockedAuditor.AssertNumberOfCalls(t, "Record", 1)
+			assert.Equal(t, tt.expected, mockedAuditor.Calls[0].Arguments.Get(0))
+		})
+	}
+}
+
+func TestAuthHandler_GenerateTokenLoginGuard(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		expectedRetryAfter         string
+		mockedBehavior             func(t *testing.T, userMock, guardMock *mock.Mock)
+		asserts                    func(t *testing.T, userMock, guardMock *mock.Mock)
+	}{
+		{
+			name:                       "blocked login should return too many requests",
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Too many failed login attempts, try again later"}`,
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			expectedRetryAfter:         "3",
+			mockedBehavior: func(t *testing.T, userMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(2500*time.Millisecond, nil)
+			},
+			asserts: func(t *testing.T, userMock, guardMock *mock.Mock) {
+				userMock.AssertNotCalled(t, "GetByEmail", mock.Anything)
+			},
+		},
+		{
+			name:                       "unknown email should register a failure",
+			expectedBodyResponse:       `{"code":"INVALID_CREDENTIALS","message":"Invalid credentials"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, userMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), nil)
+				guardMock.On("RegisterFailure", "meze@gmail.com", "10.0.0.1").Return(nil)
+				userMock.On("GetByEmail", "meze@gmail.com").Return(nil, models.ErrUserNotFound)
+			},
+			asserts: func(t *testing.T, userMock, guardMock *mock.Mock) {
+				guardMock.AssertCalled(t, "RegisterFailure", "meze@gmail.com", "10.0.0.1")
+			},
+		},
+		{
+			name:                       "guard error should return internal error",
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to retrieve login attempts"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, userMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, userMock, guardMock *mock.Mock) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
+			mockedGuard := &MockLoginGuard{}
+			tt.mockedBehavior(t, &mockedUserService.Mock, &mockedGuard.Mock)
+
+			router := setupMockedRouter(NewAuthHandler(&MockAuthService{}, mockedUserService, &MockMFAService{}, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, mockedGuard, testAuditor(), testPasswordHasher(), false, false), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(`{"email":"meze@gmail.com", "password":"password"}`)))
+			req.RemoteAddr = "10.0.0.1:4321"
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			assert.Equal(t, tt.expectedRetryAfter, w.Header().Get("Retry-After"))
+			tt.asserts(t, &mockedUserService.Mock, &mockedGuard.Mock)
+		})
+	}
+}
+
+func TestAuthHandler_GenerateTokenRequiresVerifiedEmail(t *testing.T) {
+
+	verifiedAt := time.Now()
+	userPermissions := &models.UserPermissions{Id: 1, Role: "user", Permissions: []string{"users:read"}}
+
+	tests := []struct {
+		name                       string
+		emailVerifiedAt            *time.Time
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+	}{
+		{
+			name:                       "unverified email should be rejected",
+			emailVerifiedAt:            nil,
+			expectedBodyResponse:       `{"code":"EMAIL_NOT_VERIFIED","message":"Email address has not been verified"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "verified email should return token",
+			emailVerifiedAt:            &verifiedAt,
+			expectedBodyResponse:       `{"access_token":"token","refresh_token":"refresh","expires_in":900,"token_type":"Bearer"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockedUserService := &MockUserService{}
+			mockedAuthService := &MockAuthService{}
+
+			mockedUserService.On("GetByEmail", mock.Anything).Return(&models.UserRequest{
+				Id:              1,
+				Email:           "meze@gmail.com",
+				Password:        "$2a$16$m.fWPulWk20mcpq5lZnkMeB7sOu2w10o/3EGwjLURZ3A7AcI9O4lC",
+				EmailVerifiedAt: tt.emailVerifiedAt,
+			}, nil)
+			mockedUserService.On("GetPermissions", "1").Return(userPermissions, nil)
+			mockedAuthService.On("GenerateToken", mock.Anything).Return(&authClaims.TokenResponse{
+				AccessToken:  "token",
+				RefreshToken: "refresh",
+				ExpiresIn:    900,
+				TokenType:    "Bearer",
+			}, nil)
+
+			mockedMFAService := &MockMFAService{}
+			mockedMFAService.On("IsEnabled", "1").Return(false, nil)
+
+			router := setupMockedRouter(NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), testPasswordHasher(), true, false), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(`{"email":"meze@gmail.com", "password":"password"}`)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestAuthHandler_GenerateTokenWithMFA(t *testing.T) {
+	mockedUserService := &MockUserService{}
+	mockedAuthService := &MockAuthService{}
+	mockedMFAService := &MockMFAService{}
+
+	mockedUserService.On("GetByEmail", mock.Anything).Return(&models.UserRequest{
+		Id:       1,
+		Email:    "meze@gmail.com",
+		Password: "$2a$16$m.fWPulWk20mcpq5lZnkMeB7sOu2w10o/3EGwjLURZ3A7AcI9O4lC",
+	}, nil)
+	mockedMFAService.On("IsEnabled", "1").Return(true, nil)
+	mockedMFAService.On("IssuePendingToken", "1").Return(&authClaims.MFAPendingResponse{
+		MFAToken:  "pending",
+		ExpiresIn: 300,
+		TokenType: authClaims.MFAPendingTokenType,
+	}, nil)
+
+	router := setupMockedRouter(NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false), nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewReader([]byte(`{"email":"meze@gmail.com", "password":"password"}`)))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"mfa_token":"pending","expires_in":300,"token_type":"mfa_pending"}`, w.Body.String())
+	mockedAuthService.AssertNotCalled(t, "GenerateToken", mock.Anything)
+}
+
+func TestAuthHandler_VerifyMFA(t *testing.T) {
+
+	userPermissions := &models.UserPermissions{Id: 1, Role: "employer", Permissions: []string{"jobs:publish"}}
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, userMock, authMock, mfaMock *mock.Mock)
+	}{
+		{
+			name:                       "valid code should return token",
+			requestBody:                `{"mfa_token":"pending","code":"123456"}`,
+			expectedBodyResponse:       `{"access_token":"token","refresh_token":"refresh","expires_in":900,"token_type":"Bearer"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, userMock, authMock, mfaMock *mock.Mock) {
+				mfaMock.On("ParsePendingToken", "pending").Return("1", nil)
+				mfaMock.On("Verify", "1", "123456").Return(nil)
+				mfaMock.On("ConsumePendingToken", "pending").Return(nil)
+				userMock.On("Get", "1").Return(&models.UserRequest{Id: 1, Email: "meze@gmail.com", TokenVersion: 2}, nil)
+				userMock.On("GetPermissions", "1").Return(userPermissions, nil)
+				authMock.On("GenerateToken", authClaims.TokenSubject{
+					UserID:       "1",
+					Email:        "meze@gmail.com",
+					Role:         "employer",
+					Permissions:  []string{"jobs:publish"},
+					TokenVersion: 2,
+				}).Return(&authClaims.TokenResponse{AccessToken: "token", RefreshToken: "refresh", ExpiresIn: 900, TokenType: "Bearer"}, nil)
+			},
+		},
+		{
+			name:                       "missing code should return bad request",
+			requestBody:                `{"mfa_token":"pending"}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, userMock, authMock, mfaMock *mock.Mock) {},
+		},
+		{
+			name:                       "invalid mfa token should return unauthorized",
+			requestBody:                `{"mfa_token":"pending","code":"123456"}`,
+			expectedBodyResponse:       `{"code":"INVALID_TOKEN","message":"Invalid or expired mfa token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, userMock, authMock, mfaMock *mock.Mock) {
+				mfaMock.On("ParsePendingToken", "pending").Return("", authClaims.ErrInvalidMFAToken)
+			},
+		},
+		{
+			name:                       "invalid code should return unauthorized",
+			requestBody:                `{"mfa_token":"pending","code":"000000"}`,
+			expectedBodyResponse:       `{"code":"INVALID_MFA_CODE","message":"Invalid two-factor authentication code"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, userMock, authMock, mfaMock *mock.Mock) {
+				mfaMock.On("ParsePendingToken", "pending").Return("1", nil)
+				userMock.On("Get", "1").Return(&models.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mfaMock.On("Verify", "1", "000000").Return(authClaims.ErrInvalidMFACode)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"mfa_token":"pending","code":"123456"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to verify two-factor authentication code"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, userMock, authMock, mfaMock *mock.Mock) {
+				mfaMock.On("ParsePendingToken", "pending").Return("1", nil)
+				userMock.On("Get", "1").Return(&models.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mfaMock.On("Verify", "1", "123456").Return(errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
+			mockedAuthService := &MockAuthService{}
+			mockedMFAService := &MockMFAService{}
+
+			tt.mockedBehavior(t, &mockedUserService.Mock, &mockedAuthService.Mock, &mockedMFAService.Mock)
+
+			router := setupMockedRouter(NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/mfa/verify", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestAuthHandler_VerifyMFALoginGuard(t *testing.T) {
+
+	storedUser := &models.UserRequest{Id: 1, Email: "meze@gmail.com"}
+
+	tests := []struct {
+		name                       string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mfaMock, guardMock *mock.Mock)
+		asserts                    func(t *testing.T, mfaMock, guardMock *mock.Mock)
+	}{
+		{
+			name:                       "blocked account should return too many requests",
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Too many failed login attempts, try again later"}`,
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			mockedBehavior: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Minute, nil)
+			},
+			asserts: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				mfaMock.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:                       "invalid code should register a failure",
+			expectedBodyResponse:       `{"code":"INVALID_MFA_CODE","message":"Invalid two-factor authentication code"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), nil)
+				guardMock.On("RegisterFailure", "meze@gmail.com", "10.0.0.1").Return(nil)
+				mfaMock.On("Verify", "1", "123456").Return(authClaims.ErrInvalidMFACode)
+			},
+			asserts: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.AssertCalled(t, "RegisterFailure", "meze@gmail.com", "10.0.0.1")
+				mfaMock.AssertNotCalled(t, "ConsumePendingToken", mock.Anything)
+			},
+		},
+		{
+			name:                       "used mfa token should return unauthorized",
+			expectedBodyResponse:       `{"code":"INVALID_TOKEN","message":"Invalid or expired mfa token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), nil)
+				mfaMock.On("Verify", "1", "123456").Return(nil)
+				mfaMock.On("ConsumePendingToken", "pending").Return(authClaims.ErrInvalidMFAToken)
+			},
+			asserts: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.AssertNotCalled(t, "RegisterSuccess", mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
+			mockedUserService.On("Get", "1").Return(storedUser, nil)
+			mockedMFAService := &MockMFAService{}
+			mockedMFAService.On("ParsePendingToken", "pending").Return("1", nil)
+			mockedGuard := &MockLoginGuard{}
+			tt.mockedBehavior(t, &mockedMFAService.Mock, &mockedGuard.Mock)
+
+			router := setupMockedRouter(NewAuthHandler(&MockAuthService{}, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, mockedGuard, testAuditor(), testPasswordHasher(), false, false), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/mfa/verify", bytes.NewReader([]byte(`{"mfa_token":"pending","code":"123456"}`)))
+			req.RemoteAddr = "10.0.0.1:4321"
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			tt.asserts(t, &mockedMFAService.Mock, &mockedGuard.Mock)
+		})
+	}
+}
+
 func TestAuthHandler_RefreshToken(t *testing.T) {
 
-	validTokenResponse := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoiMSIsImVtYWlsIjoibWV6ZUBnbWFpbC5jb20iLCJpc3MiOiJjaG" +
-		"FtYmVvLWNvIiwic3ViIjoiY2hhbWJlby1iZSIsImF1ZCI6WyJjaGFtYmVvLWZlIl0sImV4cCI6MTcwNTI3NjMyMiwibmJmIjoxNzA1MTg5OTI" +
-		"yLCJpYXQiOjE3MDUxODk5MjIsImp0aSI6IjEifQ.p2jndX8Bn8q3mrJp4vv9nsGugZOZRcukrOBuMSIO4SA"
-
-	validJwtParse := &jwt.Token{
-		Raw:    validTokenResponse,
-		Method: nil,
-		Header: nil,
-		Claims: &authClaims.CustomClaims{
-			UserID: "1",
-			Email:  "meze@gmail.com",
-			RegisteredClaims: jwt.RegisteredClaims{
-				Issuer:    "",
-				Subject:   "",
-				Audience:  nil,
-				ExpiresAt: nil,
-				NotBefore: nil,
-				IssuedAt:  nil,
-				ID:        "",
-			},
-		},
-		Signature: nil,
-		Valid:     true,
-	}
-
-	validJwtParseWithInvalidClaims := &jwt.Token{
-		Raw:       validTokenResponse,
-		Method:    nil,
-		Header:    nil,
-		Claims:    jwt.RegisteredClaims{},
-		Signature: nil,
-		Valid:     true,
-	}
-
-	invalidJwtParseWithInvalidClaims := &jwt.Token{
-		Raw:       validTokenResponse,
-		Method:    nil,
-		Header:    nil,
-		Claims:    jwt.RegisteredClaims{},
-		Signature: nil,
-		Valid:     false,
+	refreshedTokenResponse := &authClaims.TokenResponse{
+		AccessToken:  "newAccessToken",
+		RefreshToken: "newRefreshToken",
+		ExpiresIn:    900,
+		TokenType:    "Bearer",
+	}
+
+	storedRefreshToken := &authClaims.RefreshToken{ID: 1, UserID: 1, SessionID: "session"}
+
+	storedUser := &models.UserRequest{
+		Id:    1,
+		Email: "meze@gmail.com",
+		Role:  "admin",
 	}
 
+	adminPermissions := &models.UserPermissions{Id: 1, Role: "admin", Permissions: []string{"users:manage"}}
+
 	tests := []struct {
 		name                       string
 		requestBody                string
 		expectedBodyResponse       string
 		expectedHttpStatusResponse int
-		mockedBehavior             func(t *testing.T, authMock *mock.Mock)
+		mockedBehavior             func(t *testing.T, userMock, authMock *mock.Mock)
 		asserts                    func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string)
 	}{
 		{
-			name:                       "valid token should be refreshed",
-			requestBody:                fmt.Sprintf(`{"access_token":"%s"}`, validTokenResponse),
-			expectedBodyResponse:       fmt.Sprintf(`{"access_token":"%s"}`, validTokenResponse),
+			name:                       "valid refresh token should be rotated",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
+			expectedBodyResponse:       `{"access_token":"newAccessToken","refresh_token":"newRefreshToken","expires_in":900,"token_type":"Bearer"}`,
 			expectedHttpStatusResponse: http.StatusOK,
-			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(validJwtParse, nil)
-				authMock.On("GenerateToken", mock.Anything, mock.Anything).Return(&validTokenResponse, nil)
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(storedRefreshToken, nil)
+				userMock.On("Get", "1").Return(storedUser, nil)
+				userMock.On("GetPermissions", "1").Return(adminPermissions, nil)
+				authMock.On("GenerateToken", authClaims.TokenSubject{UserID: "1", Email: "meze@gmail.com", Role: "admin", Permissions: []string{"users:manage"}, SessionID: "session"}).Return(refreshedTokenResponse, nil)
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -232,13 +686,15 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 			},
 		},
 		{
-			name:                       "valid token return error when refreshing token",
-			requestBody:                fmt.Sprintf(`{"access_token":"%s"}`, validTokenResponse),
+			name:                       "valid refresh token return error when generating tokens",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
 			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to refresh token"}`,
 			expectedHttpStatusResponse: http.StatusInternalServerError,
-			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(validJwtParse, nil)
-				authMock.On("GenerateToken", mock.Anything, mock.Anything).Return(nil, errors.New("error refreshing token"))
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(storedRefreshToken, nil)
+				userMock.On("Get", "1").Return(storedUser, nil)
+				userMock.On("GetPermissions", "1").Return(adminPermissions, nil)
+				authMock.On("GenerateToken", mock.Anything).Return(nil, errors.New("error refreshing token"))
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -246,12 +702,14 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 			},
 		},
 		{
-			name:                       "valid token return error due invalid claims",
-			requestBody:                fmt.Sprintf(`{"access_token":"%s"}`, validTokenResponse),
-			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to parse token claims"}`,
+			name:                       "valid refresh token return error retrieving permissions",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to retrieve user permissions"}`,
 			expectedHttpStatusResponse: http.StatusInternalServerError,
-			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(validJwtParseWithInvalidClaims, nil)
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(storedRefreshToken, nil)
+				userMock.On("Get", "1").Return(storedUser, nil)
+				userMock.On("GetPermissions", "1").Return(nil, errors.New("error from repository"))
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -259,12 +717,12 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 			},
 		},
 		{
-			name:                       "valid token return error due invalid jwt refresh",
-			requestBody:                fmt.Sprintf(`{"access_token":"%s"}`, validTokenResponse),
-			expectedBodyResponse:       `{"code":"ERROR","message":"Error refreshing token"}`,
-			expectedHttpStatusResponse: http.StatusInternalServerError,
-			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(invalidJwtParseWithInvalidClaims, nil)
+			name:                       "invalid refresh token return unauthorized",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid refresh token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(nil, authClaims.ErrInvalidRefreshToken)
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -272,12 +730,39 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 			},
 		},
 		{
-			name:                       "invalid token return error due error parsing",
-			requestBody:                fmt.Sprintf(`{"access_token":"%s"}`, validTokenResponse),
-			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to parse token"}`,
+			name:                       "reused refresh token return unauthorized",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid refresh token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(nil, authClaims.ErrRefreshTokenReused)
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
+		{
+			name:                       "refresh token return error due repository failure",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to validate refresh token"}`,
 			expectedHttpStatusResponse: http.StatusInternalServerError,
-			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(nil, errors.New("error parsing token"))
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(nil, errors.New("error from repository"))
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
+		{
+			name:                       "refresh token of a deleted user return unauthorized",
+			requestBody:                `{"refresh_token":"refreshToken"}`,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid refresh token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, userMock, authMock *mock.Mock) {
+				authMock.On("ConsumeRefreshToken", "refreshToken").Return(storedRefreshToken, nil)
+				userMock.On("Get", "1").Return(nil, errors.New("error from repository"))
 			},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
@@ -286,10 +771,10 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 		},
 		{
 			name:                       "invalid request token return bad request",
-			requestBody:                `{"name":"meze"}`,
+			requestBody:                `{"access_token":"accessToken"}`,
 			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
 			expectedHttpStatusResponse: http.StatusBadRequest,
-			mockedBehavior:             func(t *testing.T, authMock *mock.Mock) {},
+			mockedBehavior:             func(t *testing.T, userMock, authMock *mock.Mock) {},
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
 				assert.Equal(t, expectedBody, response.Body.String())
@@ -299,12 +784,13 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
 			mockedAuthService := &MockAuthService{}
-			tt.mockedBehavior(t, &mockedAuthService.Mock)
+			tt.mockedBehavior(t, &mockedUserService.Mock, &mockedAuthService.Mock)
 
-			authHandler := NewAuthHandler(mockedAuthService, nil)
+			authHandler := NewAuthHandler(mockedAuthService, mockedUserService, &MockMFAService{}, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, &MockLoginGuard{}, testAuditor(), testPasswordHasher(), false, false)
 
-			router := setupMockedRouter(authHandler)
+			router := setupMockedRouter(authHandler, nil)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("POST", "/api/v1/auth/token/refresh", bytes.NewReader([]byte(tt.requestBody)))
@@ -316,141 +802,111 @@ func TestAuthHandler_RefreshToken(t *testing.T) {
 	}
 }
 
-func TestAuthHandler_ValidateToken(t *testing.T) {
+func TestAuthHandler_Logout(t *testing.T) {
 
-	validJwtParse := &jwt.Token{
-		Raw:    "validToken",
-		Method: nil,
-		Header: nil,
-		Claims: &authClaims.CustomClaims{
-			UserID: "1",
-			Email:  "meze@gmail.com",
-			RegisteredClaims: jwt.RegisteredClaims{
-				Issuer:    "",
-				Subject:   "",
-				Audience:  nil,
-				ExpiresAt: nil,
-				NotBefore: nil,
-				IssuedAt:  nil,
-				ID:        "",
-			},
-		},
-		Signature: nil,
-		Valid:     true,
-	}
-
-	invalidJwtParse := &jwt.Token{
-		Raw:    "validToken",
-		Method: nil,
-		Header: nil,
-		Claims: &authClaims.CustomClaims{
-			UserID: "1",
-			Email:  "meze@gmail.com",
-			RegisteredClaims: jwt.RegisteredClaims{
-				Issuer:    "",
-				Subject:   "",
-				Audience:  nil,
-				ExpiresAt: nil,
-				NotBefore: nil,
-				IssuedAt:  nil,
-				ID:        "",
-			},
-		},
-		Signature: nil,
-		Valid:     false,
-	}
+	claims := &authClaims.CustomClaims{UserID: "1", SessionID: "session", RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}}
 
 	tests := []struct {
 		name                       string
-		requestBody                string
+		path                       string
+		claims                     *authClaims.CustomClaims
 		expectedBodyResponse       string
 		expectedHttpStatusResponse int
 		mockedBehavior             func(t *testing.T, authMock *mock.Mock)
-		asserts                    func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string)
 	}{
 		{
-			name:                       "invalid body should return bad request",
-			requestBody:                `{"email":"meze@mail.com"}`,
-			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
-			expectedHttpStatusResponse: http.StatusBadRequest,
-			mockedBehavior:             func(t *testing.T, authMock *mock.Mock) {},
-			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
-				assert.Equal(t, expectedBody, response.Body.String())
-				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+			name:                       "logout should revoke the current token",
+			path:                       "/api/v1/auth/logout",
+			claims:                     claims,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
+				authMock.On("RevokeToken", claims).Return(nil)
 			},
 		},
 		{
-			name:                       "valid body should return error when parsing",
-			requestBody:                `{"access_token":"exampleMockedToken"}`,
-			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to parse token"}`,
+			name:                       "logout should return error revoking token",
+			path:                       "/api/v1/auth/logout",
+			claims:                     claims,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to revoke token"}`,
 			expectedHttpStatusResponse: http.StatusInternalServerError,
 			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(nil, errors.New("error parsing token"))
-			},
-			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
-				assert.Equal(t, expectedBody, response.Body.String())
-				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				authMock.On("RevokeToken", claims).Return(errors.New("error from denylist"))
 			},
 		},
 		{
-			name:                       "refreshed token is not valid",
-			requestBody:                `{"access_token":"exampleMockedToken"}`,
-			expectedBodyResponse:       `{"code":"ERROR","message":"Token is not valid"}`,
+			name:                       "logout without claims should return unauthorized",
+			path:                       "/api/v1/auth/logout",
+			claims:                     nil,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`,
 			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, authMock *mock.Mock) {},
+		},
+		{
+			name:                       "logout all should revoke every token of the user",
+			path:                       "/api/v1/auth/logout/all",
+			claims:                     claims,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
 			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(invalidJwtParse, nil)
-			},
-			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
-				assert.Equal(t, expectedBody, response.Body.String())
-				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				authMock.On("RevokeAllTokens", "1").Return(nil)
 			},
 		},
 		{
-			name:                       "refreshed token is valid",
-			requestBody:                `{"access_token":"exampleMockedToken"}`,
-			expectedBodyResponse:       `{"access_token":"validToken"}`,
-			expectedHttpStatusResponse: http.StatusOK,
+			name:                       "logout all should return error revoking tokens",
+			path:                       "/api/v1/auth/logout/all",
+			claims:                     claims,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to revoke tokens"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
 			mockedBehavior: func(t *testing.T, authMock *mock.Mock) {
-				authMock.On("ParseToken", mock.Anything).Return(validJwtParse, nil)
-			},
-			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
-				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				authMock.On("RevokeAllTokens", "1").Return(errors.New("error from db"))
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockedUserService := &MockUserService{}
 			mockedAuthService := &MockAuthService{}
 			tt.mockedBehavior(t, &mockedAuthService.Mock)
 
-			authHandler := NewAuthHandler(mockedAuthService, nil)
+			authHandler := NewAuthHandler(mockedAuthService, mockedUserService, &MockMFAService{}, &MockOIDCService{}, &MockClientCredentialsService{}, &MockMagicLinkService{}, &MockLoginGuard{}, testAuditor(), testPasswordHasher(), false, false)
 
-			router := setupMockedRouter(authHandler)
+			router := setupMockedRouter(authHandler, tt.claims)
 
 			w := httptest.NewRecorder()
-			req, _ := http.NewRequest("GET", "/api/v1/auth/token/validate", bytes.NewReader([]byte(tt.requestBody)))
+			req, _ := http.NewRequest("POST", tt.path, nil)
 
 			router.ServeHTTP(w, req)
 
-			tt.asserts(t, w, tt.expectedHttpStatusResponse, tt.expectedBodyResponse)
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			mockedAuthService.AssertExpectations(t)
 		})
 	}
 }
 
-func setupMockedRouter(authHandler AuthHandlerInterface) *gin.Engine {
+func setupMockedRouter(authHandler AuthHandlerInterface, claims *authClaims.CustomClaims) *gin.Engine {
 	r := gin.Default()
+	r.Use(customError.ErrorHandler())
 	r.GET("/ping", func(c *gin.Context) {
 		c.String(200, "pong")
 	})
 
 	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
 	{
 		users := v1.Group("/auth")
 		{
 			users.POST("/token", authHandler.GenerateToken)
-			users.GET("/token/validate", authHandler.ValidateToken)
 			users.POST("/token/refresh", authHandler.RefreshToken)
+			users.POST("/mfa/verify", authHandler.VerifyMFA)
+			users.POST("/logout", authHandler.Logout)
+			users.POST("/logout/all", authHandler.LogoutAll)
 		}
 
 	}
@@ -470,7 +926,7 @@ func (m *MockUserService) Get(id string) (*models.UserRequest, error) {
 	return args.Get(0).(*models.UserRequest), args.Error(1)
 }
 
-func (m *MockUserService) Update(user *models.UserRequest) (*models.UserRequest, error) {
+func (m *MockUserService) Update(user *models.UpdateUserRequest) (*models.UserRequest, error) {
 	args := m.Called(user)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
@@ -486,7 +942,7 @@ func (m *MockUserService) Delete(id string) (*models.UserRequest, error) {
 	return args.Get(0).(*models.UserRequest), args.Error(1)
 }
 
-func (m *MockUserService) Create(user *models.UserRequest) (*models.UserRequest, error) {
+func (m *MockUserService) Create(user *models.CreateUserRequest) (*models.UserRequest, error) {
 	args := m.Called(user)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
@@ -507,12 +963,20 @@ type MockAuthService struct {
 	mock.Mock
 }
 
-func (m *MockAuthService) GenerateToken(email, userId string) (*string, error) {
-	args := m.Called(email, userId)
+func (m *MockAuthService) GenerateToken(subject authClaims.TokenSubject) (*authClaims.TokenResponse, error) {
+	args := m.Called(subject)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*authClaims.TokenResponse), args.Error(1)
+}
+
+func (m *MockAuthService) ConsumeRefreshToken(refreshToken string) (*authClaims.RefreshToken, error) {
+	args := m.Called(refreshToken)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*string), args.Error(1)
+	return args.Get(0).(*authClaims.RefreshToken), args.Error(1)
 }
 
 func (m *MockAuthService) ParseToken(tokenString string) (*jwt.Token, error) {
@@ -522,3 +986,151 @@ func (m *MockAuthService) ParseToken(tokenString string) (*jwt.Token, error) {
 	}
 	return args.Get(0).(*jwt.Token), args.Error(1)
 }
+
+func (m *MockUserService) GetTokenVersion(id string) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) IncrementTokenVersion(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeToken(claims *authClaims.CustomClaims) error {
+	args := m.Called(claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeAllTokens(userID string) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetPermissions(id string) (*models.UserPermissions, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPermissions), args.Error(1)
+}
+
+func (m *MockUserService) UpdatePermissions(id string, permissions *models.UserPermissions, caller *authClaims.CustomClaims) (*models.UserPermissions, error) {
+	args := m.Called(id, permissions, caller)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPermissions), args.Error(1)
+}
+
+func (m *MockUserService) UpdatePassword(id string, password string) error {
+	args := m.Called(id, password)
+	return args.Error(0)
+}
+
+func (m *MockUserService) MarkEmailVerified(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) CreateExternal(user *models.UserRequest) (*models.UserRequest, error) {
+	args := m.Called(user)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserRequest), args.Error(1)
+}
+
+func (m *MockUserService) List(filter models.UserFilter) (*models.UserPage, error) {
+	args := m.Called(filter)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPage), args.Error(1)
+}
+
+func (m *MockUserService) Search(search models.UserSearch) (*models.UserPage, error) {
+	args := m.Called(search)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPage), args.Error(1)
+}
+
+func (m *MockUserService) Patch(id string, changes map[string]interface{}) (*models.UserRequest, error) {
+	args := m.Called(id, changes)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserRequest), args.Error(1)
+}
+
+type MockLoginGuard struct {
+	mock.Mock
+}
+
+func (m *MockLoginGuard) Check(email string, ip string) (time.Duration, error) {
+	args := m.Called(email, ip)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockLoginGuard) RegisterFailure(email string, ip string) error {
+	args := m.Called(email, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) RegisterSuccess(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func allowingLoginGuard() *MockLoginGuard {
+	guard := &MockLoginGuard{}
+	guard.On("Check", mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	guard.On("RegisterFailure", mock.Anything, mock.Anything).Return(nil)
+	guard.On("RegisterSuccess", mock.Anything).Return(nil)
+	return guard
+}
+
+type MockAuditor struct {
+	mock.Mock
+}
+
+func (m *MockAuditor) Record(event *auditModels.AuditEvent) {
+	m.Called(event)
+}
+
+func testAuditor() *MockAuditor {
+	auditor := &MockAuditor{}
+	auditor.On("Record", mock.Anything)
+	return auditor
+}
+
+type MockPasswordHasher struct {
+	mock.Mock
+}
+
+func (m *MockPasswordHasher) Hash(password string) (string, error) {
+	args := m.Called(password)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPasswordHasher) Verify(password string, encoded string) (bool, error) {
+	args := m.Called(password, encoded)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPasswordHasher) NeedsRehash(encoded string) bool {
+	args := m.Called(encoded)
+	return args.Bool(0)
+}
+
+// testPasswordHasher accepts "password" for any stored hash.
+func testPasswordHasher() *MockPasswordHasher {
+	hasher := &MockPasswordHasher{}
+	hasher.On("Hash", mock.Anything).Return("dummy-hash", nil)
+	hasher.On("Verify", "password", mock.Anything).Return(true, nil)
+	hasher.On("Verify", mock.Anything, mock.Anything).Return(false, nil)
+	hasher.On("NeedsRehash", mock.Anything).Return(false)
+	return hasher
+}
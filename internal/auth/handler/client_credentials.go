@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// clientCredentialsToken implements the client_credentials grant of RFC
+// 6749. The client authenticates with HTTP Basic or with client_id and
+// client_secret in the body.
+func (a AuthHandler) clientCredentialsToken(c *gin.Context, tokenRequest *models.TokenRequest) {
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = tokenRequest.ClientID, tokenRequest.ClientSecret
+	}
+
+	token, err := a.clientService.IssueToken(clientID, clientSecret, tokenRequest.Scope, tokenRequest.Audience)
+	if errors.Is(err, models.ErrInvalidClient) {
+		c.Header("WWW-Authenticate", `Basic realm="token"`)
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to generate token")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, token)
+}
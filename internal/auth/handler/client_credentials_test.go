@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthHandler_GenerateTokenClientCredentials(t *testing.T) {
+
+	tokenResponse := &models.TokenResponse{AccessToken: "token", ExpiresIn: 900, TokenType: "Bearer", Scope: "users:read"}
+
+	tests := []struct {
+		name                       string
+		contentType                string
+		requestBody                string
+		basicAuth                  bool
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, clientMock *mock.Mock)
+	}{
+		{
+			name:                       "form with basic auth should return token",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "grant_type=client_credentials&scope=users%3Aread",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"access_token":"token","expires_in":900,"token_type":"Bearer","scope":"users:read"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+				clientMock.On("IssueToken", "worker", "secret", "users:read", "").Return(tokenResponse, nil)
+			},
+		},
+		{
+			name:                       "json with credentials in the body should return token",
+			contentType:                "application/json",
+			requestBody:                `{"grant_type":"client_credentials","client_id":"worker","client_secret":"secret","audience":"chambeo-fe"}`,
+			expectedBodyResponse:       `{"access_token":"token","expires_in":900,"token_type":"Bearer","scope":"users:read"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+				clientMock.On("IssueToken", "worker", "secret", "", "chambeo-fe").Return(tokenResponse, nil)
+			},
+		},
+		{
+			name:                       "invalid client should return unauthorized",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "grant_type=client_credentials",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid client credentials"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+				clientMock.On("IssueToken", "worker", "secret", "", "").Return(nil, models.ErrInvalidClient)
+			},
+		},
+		{
+			name:                       "scope not allowed should return validation error",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "grant_type=client_credentials&scope=users%3Amanage",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Scope is not allowed for the client","fields":[{"field":"scope","code":"FORBIDDEN","message":"Scope is not allowed for the client"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+				clientMock.On("IssueToken", "worker", "secret", "users:manage", "").Return(nil, models.ErrInvalidClientScope)
+			},
+		},
+		{
+			name:                       "audience not allowed should return validation error",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "grant_type=client_credentials&audience=payments",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Audience is not allowed for the client","fields":[{"field":"audience","code":"FORBIDDEN","message":"Audience is not allowed for the client"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+				clientMock.On("IssueToken", "worker", "secret", "", "payments").Return(nil, models.ErrInvalidClientAudience)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "grant_type=client_credentials",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to generate token"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+				clientMock.On("IssueToken", "worker", "secret", "", "").Return(nil, errors.New("error signing token"))
+			},
+		},
+		{
+			name:                       "unsupported grant type should return bad request",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "grant_type=authorization_code",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Unsupported grant type"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, clientMock *mock.Mock) {
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedClientService := &MockClientCredentialsService{}
+			tt.mockedBehavior(t, &mockedClientService.Mock)
+
+			authHandler := NewAuthHandler(&MockAuthService{}, &MockUserService{}, &MockMFAService{}, &MockOIDCService{}, mockedClientService, &MockMagicLinkService{},
+				&MockLoginGuard{}, testAuditor(), testPasswordHasher(), false, false)
+			router := setupMockedRouter(authHandler, nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/token", bytes.NewBufferString(tt.requestBody))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.basicAuth {
+				req.SetBasicAuth("worker", "secret")
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+type MockClientCredentialsService struct {
+	mock.Mock
+}
+
+func (m *MockClientCredentialsService) IssueToken(clientID string, clientSecret string, scope string, audience string) (*models.TokenResponse, error) {
+	args := m.Called(clientID, clientSecret, scope, audience)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TokenResponse), args.Error(1)
+}
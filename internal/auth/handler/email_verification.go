@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type EmailVerificationHandlerInterface interface {
+	Verify(c *gin.Context)
+	Resend(c *gin.Context)
+}
+
+type EmailVerificationService interface {
+	Verify(token string) error
+	Resend(email string) error
+}
+
+type EmailVerificationHandler struct {
+	verificationService EmailVerificationService
+}
+
+func NewEmailVerificationHandler(verificationService EmailVerificationService) EmailVerificationHandlerInterface {
+	return EmailVerificationHandler{verificationService: verificationService}
+}
+
+func (e EmailVerificationHandler) Verify(c *gin.Context) {
+	var verifyRequest models.VerifyEmailRequest
+	err := c.ShouldBindJSON(&verifyRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	err = e.verificationService.Verify(verifyRequest.Token)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to verify email")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Resend answers 202 for unknown and already verified emails too, so it
+// cannot be used to find out which addresses have an account.
+func (e EmailVerificationHandler) Resend(c *gin.Context) {
+	var resendRequest models.ResendVerificationRequest
+	err := c.ShouldBindJSON(&resendRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	err = e.verificationService.Resend(resendRequest.Email)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to resend verification email")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
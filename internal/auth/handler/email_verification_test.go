@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmailVerificationHandler_Verify(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, verificationMock *mock.Mock)
+	}{
+		{
+			name:                       "valid token should verify the email",
+			requestBody:                `{"token":"verify"}`,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, verificationMock *mock.Mock) {
+				verificationMock.On("Verify", "verify").Return(nil)
+			},
+		},
+		{
+			name:                       "missing token should return bad request",
+			requestBody:                `{}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, verificationMock *mock.Mock) {},
+		},
+		{
+			name:                       "invalid token should return bad request",
+			requestBody:                `{"token":"verify"}`,
+			expectedBodyResponse:       `{"code":"INVALID_TOKEN","message":"Invalid or expired verification token"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, verificationMock *mock.Mock) {
+				verificationMock.On("Verify", "verify").Return(models.ErrInvalidVerificationToken)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"token":"verify"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to verify email"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, verificationMock *mock.Mock) {
+				verificationMock.On("Verify", "verify").Return(errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedVerificationService := &MockEmailVerificationService{}
+			tt.mockedBehavior(t, &mockedVerificationService.Mock)
+
+			router := setupMockedEmailVerificationRouter(NewEmailVerificationHandler(mockedVerificationService))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/email/verify", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestEmailVerificationHandler_Resend(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, verificationMock *mock.Mock)
+	}{
+		{
+			name:                       "valid email should be accepted",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusAccepted,
+			mockedBehavior: func(t *testing.T, verificationMock *mock.Mock) {
+				verificationMock.On("Resend", "meze@gmail.com").Return(nil)
+			},
+		},
+		{
+			name:                       "invalid email should return bad request",
+			requestBody:                `{"email":"meze"}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, verificationMock *mock.Mock) {},
+		},
+		{
+			name:                       "throttled email should return too many requests",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Verification email requested too often, try again later"}`,
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			mockedBehavior: func(t *testing.T, verificationMock *mock.Mock) {
+				verificationMock.On("Resend", "meze@gmail.com").Return(models.ErrVerificationThrottled)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to resend verification email"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, verificationMock *mock.Mock) {
+				verificationMock.On("Resend", "meze@gmail.com").Return(errors.New("error from mailer"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedVerificationService := &MockEmailVerificationService{}
+			tt.mockedBehavior(t, &mockedVerificationService.Mock)
+
+			router := setupMockedEmailVerificationRouter(NewEmailVerificationHandler(mockedVerificationService))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/email/verify/resend", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func setupMockedEmailVerificationRouter(verificationHandler EmailVerificationHandlerInterface) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/email/verify", verificationHandler.Verify)
+			auth.POST("/email/verify/resend", verificationHandler.Resend)
+		}
+	}
+
+	return r
+}
+
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) Verify(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) Resend(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
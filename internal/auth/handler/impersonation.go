@@ -0,0 +1,58 @@
+package handler
+
+import (
+	auditModels "chambeo-api-core/internal/audit/models"
+	auditService "chambeo-api-core/internal/audit/service"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type ImpersonationHandlerInterface interface {
+	Impersonate(c *gin.Context)
+}
+
+type UserImpersonationService interface {
+	Impersonate(admin *models.CustomClaims, request *models.ImpersonationRequest, ip string, userAgent string) (*models.TokenResponse, error)
+}
+
+type ImpersonationHandler struct {
+	impersonationService UserImpersonationService
+	auditor              Auditor
+}
+
+func NewImpersonationHandler(impersonationService UserImpersonationService, auditor Auditor) ImpersonationHandlerInterface {
+	return ImpersonationHandler{impersonationService: impersonationService, auditor: auditor}
+}
+
+// Impersonate issues a short lived token to act as another user, the reason
+// is kept in the audit trail.
+func (i ImpersonationHandler) Impersonate(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	var impersonationRequest models.ImpersonationRequest
+	if err := c.ShouldBindJSON(&impersonationRequest); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	token, err := i.impersonationService.Impersonate(claims, &impersonationRequest, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to impersonate the user")
+		return
+	}
+
+	event := auditService.NewEvent(c, auditModels.EventImpersonation, auditModels.OutcomeSuccess)
+	event.TargetID = strconv.Itoa(impersonationRequest.UserID)
+	event.Email = ""
+	i.auditor.Record(event)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, token)
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var impersonationClaims = &models.CustomClaims{UserID: "1", Email: "admin@chambeo.co", Role: models.RoleAdmin}
+
+func TestImpersonationHandler_Impersonate(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		claims                     *models.CustomClaims
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, impersonationMock *mock.Mock)
+	}{
+		{
+			name:                       "valid request should return the token",
+			claims:                     impersonationClaims,
+			requestBody:                `{"user_id":7,"reason":"ticket 42"}`,
+			expectedBodyResponse:       `{"access_token":"token","expires_in":600,"token_type":"Bearer"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, impersonationMock *mock.Mock) {
+				impersonationMock.On("Impersonate", impersonationClaims, &models.ImpersonationRequest{UserID: 7, Reason: "ticket 42"}, "192.0.2.1", "Firefox").
+					Return(&models.TokenResponse{AccessToken: "token", ExpiresIn: 600, TokenType: "Bearer"}, nil)
+			},
+		},
+		{
+			name:                       "missing reason should return bad request",
+			claims:                     impersonationClaims,
+			requestBody:                `{"user_id":7}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, impersonationMock *mock.Mock) {},
+		},
+		{
+			name:                       "unknown user should return not found",
+			claims:                     impersonationClaims,
+			requestBody:                `{"user_id":7,"reason":"ticket 42"}`,
+			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"User to impersonate not found"}`,
+			expectedHttpStatusResponse: http.StatusNotFound,
+			mockedBehavior: func(t *testing.T, impersonationMock *mock.Mock) {
+				impersonationMock.On("Impersonate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrImpersonationNotFound)
+			},
+		},
+		{
+			name:                       "privileged user should return forbidden",
+			claims:                     impersonationClaims,
+			requestBody:                `{"user_id":7,"reason":"ticket 42"}`,
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"User can not be impersonated"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+			mockedBehavior: func(t *testing.T, impersonationMock *mock.Mock) {
+				impersonationMock.On("Impersonate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, models.ErrImpersonationNotAllowed)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			claims:                     impersonationClaims,
+			requestBody:                `{"user_id":7,"reason":"ticket 42"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to impersonate the user"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, impersonationMock *mock.Mock) {
+				impersonationMock.On("Impersonate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("error from db"))
+			},
+		},
+		{
+			name:                       "missing claims should return unauthorized",
+			requestBody:                `{"user_id":7,"reason":"ticket 42"}`,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, impersonationMock *mock.Mock) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedImpersonationService := &MockImpersonationService{}
+			tt.mockedBehavior(t, &mockedImpersonationService.Mock)
+
+			router := setupMockedImpersonationRouter(NewImpersonationHandler(mockedImpersonationService, testAuditor()), tt.claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/impersonate", bytes.NewBufferString(tt.requestBody))
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("User-Agent", "Firefox")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func setupMockedImpersonationRouter(impersonationHandler ImpersonationHandlerInterface, claims *models.CustomClaims) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/impersonate", impersonationHandler.Impersonate)
+		}
+	}
+
+	return r
+}
+
+type MockImpersonationService struct {
+	mock.Mock
+}
+
+func (m *MockImpersonationService) Impersonate(admin *models.CustomClaims, request *models.ImpersonationRequest, ip string,
+	userAgent string) (*models.TokenResponse, error) {
+	args := m.Called(admin, request, ip, userAgent)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TokenResponse), args.Error(1)
+}
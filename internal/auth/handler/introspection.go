@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"strings"
+)
+
+const tokenType = "Bearer"
+
+type IntrospectionHandlerInterface interface {
+	Introspect(c *gin.Context)
+}
+
+type TokenIntrospector interface {
+	Introspect(tokenString string) (*jwt.Token, error)
+}
+
+type ClientAuthenticator interface {
+	Authenticate(clientID, clientSecret string) bool
+}
+
+type IntrospectionHandler struct {
+	tokenIntrospector   TokenIntrospector
+	clientAuthenticator ClientAuthenticator
+}
+
+func NewIntrospectionHandler(tokenIntrospector TokenIntrospector, clientAuthenticator ClientAuthenticator) IntrospectionHandlerInterface {
+	return IntrospectionHandler{tokenIntrospector: tokenIntrospector, clientAuthenticator: clientAuthenticator}
+}
+
+// Introspect implements RFC 7662. The calling client authenticates with HTTP
+// Basic or with client_id and client_secret in the body, and any token that
+// can not be validated is reported as inactive.
+func (i IntrospectionHandler) Introspect(c *gin.Context) {
+	var request models.IntrospectionRequest
+	err := c.ShouldBind(&request)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	clientID, clientSecret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, clientSecret = request.ClientID, request.ClientSecret
+	}
+	if !i.clientAuthenticator.Authenticate(clientID, clientSecret) {
+		c.Header("WWW-Authenticate", `Basic realm="introspect"`)
+		c.Error(models.ErrInvalidClient)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	token, err := i.tokenIntrospector.Introspect(request.Token)
+	if err != nil || token == nil || !token.Valid {
+		c.JSON(http.StatusOK, models.IntrospectionResponse{Active: false})
+		return
+	}
+
+	claims, ok := token.Claims.(*models.CustomClaims)
+	if !ok {
+		c.JSON(http.StatusOK, models.IntrospectionResponse{Active: false})
+		return
+	}
+
+	// User tokens carry their permissions instead of a scope.
+	scope := claims.Scope
+	if !claims.IsClient() {
+		scope = strings.Join(claims.Permissions, " ")
+	}
+	response := models.IntrospectionResponse{
+		Active:    true,
+		Sub:       claims.Subject,
+		Scope:     scope,
+		ClientID:  claims.ClientID,
+		TokenType: tokenType,
+		Act:       claims.Actor,
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntrospectionHandler_Introspect(t *testing.T) {
+
+	issuedAt := time.Unix(1705189922, 0)
+	expiresAt := issuedAt.Add(15 * time.Minute)
+
+	activeToken := &jwt.Token{
+		Valid: true,
+		Claims: &models.CustomClaims{
+			UserID:      "1",
+			Permissions: []string{"users:read", "users:write"},
+			ClientID:    "chambeo-fe",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "1",
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		},
+	}
+
+	clientToken := &jwt.Token{
+		Valid: true,
+		Claims: &models.CustomClaims{
+			Scope:    "users:read",
+			ClientID: "billing",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "billing",
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		},
+	}
+
+	impersonationToken := &jwt.Token{
+		Valid: true,
+		Claims: &models.CustomClaims{
+			UserID:   "7",
+			ClientID: "chambeo-fe",
+			Actor:    &models.Actor{Subject: "1", Email: "admin@chambeo.co"},
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "7",
+				IssuedAt:  jwt.NewNumericDate(issuedAt),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		},
+	}
+
+	tests := []struct {
+		name                       string
+		contentType                string
+		requestBody                string
+		basicAuth                  bool
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock)
+	}{
+		{
+			name:                       "active token with form body and basic auth should return claims",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token=validToken&token_type_hint=access_token",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"active":true,"sub":"1","exp":1705190822,"iat":1705189922,"scope":"users:read users:write","client_id":"chambeo-fe","token_type":"Bearer"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(true)
+				introspectorMock.On("Introspect", "validToken").Return(activeToken, nil)
+			},
+		},
+		{
+			name:                       "client token should return its scope",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token=clientToken",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"active":true,"sub":"billing","exp":1705190822,"iat":1705189922,"scope":"users:read","client_id":"billing","token_type":"Bearer"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(true)
+				introspectorMock.On("Introspect", "clientToken").Return(clientToken, nil)
+			},
+		},
+		{
+			name:                       "token of a revoked client should be reported as inactive",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token=revokedClientToken",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"active":false}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(true)
+				introspectorMock.On("Introspect", "revokedClientToken").Return(nil, models.ErrTokenRevoked)
+			},
+		},
+		{
+			name:                       "impersonation token should return the actor",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token=impersonationToken",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"active":true,"sub":"7","exp":1705190822,"iat":1705189922,"client_id":"chambeo-fe","token_type":"Bearer","act":{"sub":"1","email":"admin@chambeo.co"}}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(true)
+				introspectorMock.On("Introspect", "impersonationToken").Return(impersonationToken, nil)
+			},
+		},
+		{
+			name:                       "active token with json body and client credentials should return claims",
+			contentType:                "application/json",
+			requestBody:                `{"token":"validToken","client_id":"gateway","client_secret":"secret"}`,
+			expectedBodyResponse:       `{"active":true,"sub":"1","exp":1705190822,"iat":1705189922,"scope":"users:read users:write","client_id":"chambeo-fe","token_type":"Bearer"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(true)
+				introspectorMock.On("Introspect", "validToken").Return(activeToken, nil)
+			},
+		},
+		{
+			name:                       "invalid token should be reported as inactive",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token=invalidToken",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"active":false}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(true)
+				introspectorMock.On("Introspect", "invalidToken").Return(nil, errors.New("token is expired"))
+			},
+		},
+		{
+			name:                       "invalid client credentials should return unauthorized",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token=validToken",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid client credentials"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {
+				authenticatorMock.On("Authenticate", "gateway", "secret").Return(false)
+			},
+		},
+		{
+			name:                       "missing token should return bad request",
+			contentType:                "application/x-www-form-urlencoded",
+			requestBody:                "token_type_hint=access_token",
+			basicAuth:                  true,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, introspectorMock, authenticatorMock *mock.Mock) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedIntrospector := &MockTokenIntrospector{}
+			mockedAuthenticator := &MockClientAuthenticator{}
+			tt.mockedBehavior(t, &mockedIntrospector.Mock, &mockedAuthenticator.Mock)
+
+			router := setupMockedIntrospectionRouter(NewIntrospectionHandler(mockedIntrospector, mockedAuthenticator))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/introspect", strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.basicAuth {
+				req.SetBasicAuth("gateway", "secret")
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			mockedIntrospector.AssertExpectations(t)
+			mockedAuthenticator.AssertExpectations(t)
+		})
+	}
+}
+
+func setupMockedIntrospectionRouter(introspectionHandler IntrospectionHandlerInterface) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/introspect", introspectionHandler.Introspect)
+		}
+	}
+
+	return r
+}
+
+type MockTokenIntrospector struct {
+	mock.Mock
+}
+
+func (m *MockTokenIntrospector) Introspect(tokenString string) (*jwt.Token, error) {
+	args := m.Called(tokenString)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*jwt.Token), args.Error(1)
+}
+
+type MockClientAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockClientAuthenticator) Authenticate(clientID, clientSecret string) bool {
+	args := m.Called(clientID, clientSecret)
+	return args.Bool(0)
+}
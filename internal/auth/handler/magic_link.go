@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RequestMagicLink mails a passwordless login link. It answers 202 for
+// unknown emails too, so it cannot be used to find out which addresses have
+// an account.
+func (a AuthHandler) RequestMagicLink(c *gin.Context) {
+	var magicLinkRequest models.MagicLinkRequest
+	err := c.ShouldBindJSON(&magicLinkRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	err = a.magicLinkService.Send(magicLinkRequest.Email)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to send login link")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// MagicLinkLogin exchanges the token of a login link for the same response
+// as GenerateToken.
+func (a AuthHandler) MagicLinkLogin(c *gin.Context) {
+	var loginRequest models.MagicLinkLoginRequest
+	err := c.ShouldBindJSON(&loginRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	user, err := a.magicLinkService.Consume(loginRequest.Token)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to verify login link")
+		return
+	}
+
+	a.finishLogin(c, user)
+}
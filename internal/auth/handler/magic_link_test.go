@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/models"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthHandler_RequestMagicLink(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, magicLinkMock *mock.Mock)
+	}{
+		{
+			name:                       "valid email should be accepted",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedHttpStatusResponse: http.StatusAccepted,
+			mockedBehavior: func(t *testing.T, magicLinkMock *mock.Mock) {
+				magicLinkMock.On("Send", "meze@gmail.com").Return(nil)
+			},
+		},
+		{
+			name:                       "invalid email should return bad request",
+			requestBody:                `{"email":"meze"}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, magicLinkMock *mock.Mock) {},
+		},
+		{
+			name:                       "throttled email should return too many requests",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Login link requested too often, try again later"}`,
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			mockedBehavior: func(t *testing.T, magicLinkMock *mock.Mock) {
+				magicLinkMock.On("Send", "meze@gmail.com").Return(models.ErrMagicLinkThrottled)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to send login link"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, magicLinkMock *mock.Mock) {
+				magicLinkMock.On("Send", "meze@gmail.com").Return(errors.New("smtp down"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedMagicLinkService := &MockMagicLinkService{}
+			tt.mockedBehavior(t, &mockedMagicLinkService.Mock)
+
+			authHandler := NewAuthHandler(&MockAuthService{}, &MockUserService{}, &MockMFAService{}, &MockOIDCService{}, &MockClientCredentialsService{}, mockedMagicLinkService,
+				allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false)
+			router := setupMockedMagicLinkRouter(authHandler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/magic-link", bytes.NewBufferString(tt.requestBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestAuthHandler_MagicLinkLogin(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, magicLinkMock, userMock, mfaMock, authMock *mock.Mock)
+	}{
+		{
+			name:                       "valid link should issue tokens",
+			requestBody:                `{"token":"link"}`,
+			expectedBodyResponse:       `{"access_token":"token","expires_in":0,"token_type":""}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, magicLinkMock, userMock, mfaMock, authMock *mock.Mock) {
+				magicLinkMock.On("Consume", "link").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mfaMock.On("IsEnabled", "1").Return(false, nil)
+				userMock.On("GetPermissions", "1").Return(&userModels.UserPermissions{Id: 1, Role: "user"}, nil)
+				authMock.On("GenerateToken", mock.Anything).Return(&models.TokenResponse{AccessToken: "token"}, nil)
+			},
+		},
+		{
+			name:                       "user with mfa should receive a pending token",
+			requestBody:                `{"token":"link"}`,
+			expectedBodyResponse:       `{"mfa_token":"pending","expires_in":300,"token_type":"mfa_pending"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, magicLinkMock, userMock, mfaMock, authMock *mock.Mock) {
+				magicLinkMock.On("Consume", "link").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mfaMock.On("IsEnabled", "1").Return(true, nil)
+				mfaMock.On("IssuePendingToken", "1").Return(&models.MFAPendingResponse{MFAToken: "pending", ExpiresIn: 300, TokenType: models.MFAPendingTokenType}, nil)
+			},
+		},
+		{
+			name:                       "used link should return unauthorized",
+			requestBody:                `{"token":"link"}`,
+			expectedBodyResponse:       `{"code":"INVALID_TOKEN","message":"Invalid, expired or already used login link"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, magicLinkMock, userMock, mfaMock, authMock *mock.Mock) {
+				magicLinkMock.On("Consume", "link").Return(nil, models.ErrInvalidMagicLink)
+			},
+		},
+		{
+			name:                       "missing token should return bad request",
+			requestBody:                `{}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, magicLinkMock, userMock, mfaMock, authMock *mock.Mock) {},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"token":"link"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to verify login link"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, magicLinkMock, userMock, mfaMock, authMock *mock.Mock) {
+				magicLinkMock.On("Consume", "link").Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedMagicLinkService := &MockMagicLinkService{}
+			mockedUserService := &MockUserService{}
+			mockedMFAService := &MockMFAService{}
+			mockedAuthService := &MockAuthService{}
+			tt.mockedBehavior(t, &mockedMagicLinkService.Mock, &mockedUserService.Mock, &mockedMFAService.Mock, &mockedAuthService.Mock)
+
+			authHandler := NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, &MockOIDCService{}, &MockClientCredentialsService{}, mockedMagicLinkService,
+				allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false)
+			router := setupMockedMagicLinkRouter(authHandler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/magic-link/login", bytes.NewBufferString(tt.requestBody))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func setupMockedMagicLinkRouter(authHandler AuthHandlerInterface) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/magic-link", authHandler.RequestMagicLink)
+			auth.POST("/magic-link/login", authHandler.MagicLinkLogin)
+		}
+	}
+
+	return r
+}
+
+type MockMagicLinkService struct {
+	mock.Mock
+}
+
+func (m *MockMagicLinkService) Send(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockMagicLinkService) Consume(token string) (*userModels.UserRequest, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
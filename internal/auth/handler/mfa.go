@@ -0,0 +1,119 @@
+package handler
+
+import (
+	auditModels "chambeo-api-core/internal/audit/models"
+	auditService "chambeo-api-core/internal/audit/service"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type MFAHandlerInterface interface {
+	Enroll(c *gin.Context)
+	Confirm(c *gin.Context)
+	Disable(c *gin.Context)
+}
+
+type MFAEnrollmentService interface {
+	Enroll(userID string, account string) (*models.MFAEnrollmentResponse, error)
+	Confirm(userID string, code string) ([]string, error)
+	Disable(userID string, code string) error
+}
+
+type MFAHandler struct {
+	mfaService MFAEnrollmentService
+	loginGuard LoginGuard
+	auditor    Auditor
+}
+
+func NewMFAHandler(mfaService MFAEnrollmentService, loginGuard LoginGuard, auditor Auditor) MFAHandlerInterface {
+	return MFAHandler{mfaService: mfaService, loginGuard: loginGuard, auditor: auditor}
+}
+
+func (m MFAHandler) Enroll(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	enrollment, err := m.mfaService.Enroll(claims.UserID, claims.Email)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to enroll two-factor authentication")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (m MFAHandler) Confirm(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	var codeRequest models.MFACodeRequest
+	if err := c.ShouldBindJSON(&codeRequest); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	recoveryCodes, err := m.mfaService.Confirm(claims.UserID, codeRequest.Code)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to confirm two-factor authentication")
+		return
+	}
+
+	m.auditor.Record(auditService.NewEvent(c, auditModels.EventMFAEnable, auditModels.OutcomeSuccess))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.MFARecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+}
+
+func (m MFAHandler) Disable(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	var codeRequest models.MFACodeRequest
+	if err := c.ShouldBindJSON(&codeRequest); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	// Disable accepts the same codes as the login, so its failures share
+	// the counters of the login guard.
+	retryAfter, err := m.loginGuard.Check(claims.Email, c.ClientIP())
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve login attempts")
+		return
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.Error(customError.NewTooManyRequests("Too many failed attempts, try again later", nil))
+		return
+	}
+
+	err = m.mfaService.Disable(claims.UserID, codeRequest.Code)
+	if errors.Is(err, models.ErrInvalidMFACode) {
+		if err := m.loginGuard.RegisterFailure(claims.Email, c.ClientIP()); err != nil {
+			log.Println("error trying to register failed mfa code: ", err.Error())
+		}
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to disable two-factor authentication")
+		return
+	}
+
+	m.auditor.Record(auditService.NewEvent(c, auditModels.EventMFADisable, auditModels.OutcomeSuccess))
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var mfaClaims = &models.CustomClaims{UserID: "1", Email: "meze@gmail.com"}
+
+func TestMFAHandler_Enroll(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mfaMock *mock.Mock)
+	}{
+		{
+			name:                       "enroll should return secret and uri",
+			expectedBodyResponse:       `{"secret":"SECRET","otpauth_uri":"otpauth://totp/Chambeo:meze@gmail.com?secret=SECRET"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Enroll", "1", "meze@gmail.com").Return(&models.MFAEnrollmentResponse{
+					Secret:     "SECRET",
+					OtpauthURI: "otpauth://totp/Chambeo:meze@gmail.com?secret=SECRET",
+				}, nil)
+			},
+		},
+		{
+			name:                       "enabled mfa should return conflict",
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"Two-factor authentication is already enabled"}`,
+			expectedHttpStatusResponse: http.StatusConflict,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Enroll", "1", "meze@gmail.com").Return(nil, models.ErrMFAAlreadyEnabled)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to enroll two-factor authentication"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Enroll", "1", "meze@gmail.com").Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedMFAService := &MockMFAService{}
+			tt.mockedBehavior(t, &mockedMFAService.Mock)
+
+			router := setupMockedMFARouter(NewMFAHandler(mockedMFAService, allowingLoginGuard(), testAuditor()), mfaClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/mfa/enroll", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestMFAHandler_Confirm(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mfaMock *mock.Mock)
+	}{
+		{
+			name:                       "valid code should return recovery codes",
+			requestBody:                `{"code":"123456"}`,
+			expectedBodyResponse:       `{"recovery_codes":["abcd-efgh","ijkl-mnop"]}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Confirm", "1", "123456").Return([]string{"abcd-efgh", "ijkl-mnop"}, nil)
+			},
+		},
+		{
+			name:                       "missing code should return bad request",
+			requestBody:                `{}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mfaMock *mock.Mock) {},
+		},
+		{
+			name:                       "invalid code should return bad request",
+			requestBody:                `{"code":"000000"}`,
+			expectedBodyResponse:       `{"code":"INVALID_MFA_CODE","message":"Invalid two-factor authentication code"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Confirm", "1", "000000").Return(nil, models.ErrInvalidMFACode)
+			},
+		},
+		{
+			name:                       "missing enrollment should return conflict",
+			requestBody:                `{"code":"123456"}`,
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"No pending two-factor authentication enrollment"}`,
+			expectedHttpStatusResponse: http.StatusConflict,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Confirm", "1", "123456").Return(nil, models.ErrMFANotEnrolled)
+			},
+		},
+		{
+			name:                       "enabled mfa should return conflict",
+			requestBody:                `{"code":"123456"}`,
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"Two-factor authentication is already enabled"}`,
+			expectedHttpStatusResponse: http.StatusConflict,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Confirm", "1", "123456").Return(nil, fmt.Errorf("error al confirmar: %w", models.ErrMFAAlreadyEnabled))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedMFAService := &MockMFAService{}
+			tt.mockedBehavior(t, &mockedMFAService.Mock)
+
+			router := setupMockedMFARouter(NewMFAHandler(mockedMFAService, allowingLoginGuard(), testAuditor()), mfaClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/mfa/confirm", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestMFAHandler_Disable(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		claims                     *models.CustomClaims
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mfaMock *mock.Mock)
+	}{
+		{
+			name:                       "valid code should disable mfa",
+			requestBody:                `{"code":"123456"}`,
+			claims:                     mfaClaims,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Disable", "1", "123456").Return(nil)
+			},
+		},
+		{
+			name:                       "invalid code should return bad request",
+			requestBody:                `{"code":"000000"}`,
+			claims:                     mfaClaims,
+			expectedBodyResponse:       `{"code":"INVALID_MFA_CODE","message":"Invalid two-factor authentication code"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Disable", "1", "000000").Return(models.ErrInvalidMFACode)
+			},
+		},
+		{
+			name:                       "disabled mfa should return conflict",
+			requestBody:                `{"code":"123456"}`,
+			claims:                     mfaClaims,
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"Two-factor authentication is not enabled"}`,
+			expectedHttpStatusResponse: http.StatusConflict,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Disable", "1", "123456").Return(models.ErrMFANotEnabled)
+			},
+		},
+		{
+			name:                       "missing claims should return unauthorized",
+			requestBody:                `{"code":"123456"}`,
+			claims:                     nil,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, mfaMock *mock.Mock) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedMFAService := &MockMFAService{}
+			tt.mockedBehavior(t, &mockedMFAService.Mock)
+
+			router := setupMockedMFARouter(NewMFAHandler(mockedMFAService, allowingLoginGuard(), testAuditor()), tt.claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/mfa/disable", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestMFAHandler_DisableLoginGuard(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mfaMock, guardMock *mock.Mock)
+		asserts                    func(t *testing.T, mfaMock, guardMock *mock.Mock)
+	}{
+		{
+			name:                       "blocked account should return too many requests",
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Too many failed attempts, try again later"}`,
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			mockedBehavior: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Minute, nil)
+			},
+			asserts: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				mfaMock.AssertNotCalled(t, "Disable", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:                       "invalid code should register a failure",
+			expectedBodyResponse:       `{"code":"INVALID_MFA_CODE","message":"Invalid two-factor authentication code"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), nil)
+				guardMock.On("RegisterFailure", "meze@gmail.com", "10.0.0.1").Return(nil)
+				mfaMock.On("Disable", "1", "123456").Return(models.ErrInvalidMFACode)
+			},
+			asserts: func(t *testing.T, mfaMock, guardMock *mock.Mock) {
+				guardMock.AssertCalled(t, "RegisterFailure", "meze@gmail.com", "10.0.0.1")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedMFAService := &MockMFAService{}
+			mockedGuard := &MockLoginGuard{}
+			tt.mockedBehavior(t, &mockedMFAService.Mock, &mockedGuard.Mock)
+
+			router := setupMockedMFARouter(NewMFAHandler(mockedMFAService, mockedGuard, testAuditor()), mfaClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/mfa/disable", bytes.NewReader([]byte(`{"code":"123456"}`)))
+			req.RemoteAddr = "10.0.0.1:4321"
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			tt.asserts(t, &mockedMFAService.Mock, &mockedGuard.Mock)
+		})
+	}
+}
+
+func setupMockedMFARouter(mfaHandler MFAHandlerInterface, claims *models.CustomClaims) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/mfa/enroll", mfaHandler.Enroll)
+			auth.POST("/mfa/confirm", mfaHandler.Confirm)
+			auth.POST("/mfa/disable", mfaHandler.Disable)
+		}
+	}
+
+	return r
+}
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) Enroll(userID string, account string) (*models.MFAEnrollmentResponse, error) {
+	args := m.Called(userID, account)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAEnrollmentResponse), args.Error(1)
+}
+
+func (m *MockMFAService) Confirm(userID string, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAService) Disable(userID string, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) IsEnabled(userID string) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) Verify(userID string, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) IssuePendingToken(userID string) (*models.MFAPendingResponse, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAPendingResponse), args.Error(1)
+}
+
+func (m *MockMFAService) ParsePendingToken(token string) (string, error) {
+	args := m.Called(token)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMFAService) ConsumePendingToken(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type OAuthClientHandlerInterface interface {
+	Create(c *gin.Context)
+	List(c *gin.Context)
+	Revoke(c *gin.Context)
+}
+
+type OAuthClientRegistry interface {
+	Register(request *models.CreateOAuthClientRequest) (*models.CreatedOAuthClientResponse, error)
+	List() ([]models.OAuthClientResponse, error)
+	Revoke(clientID string) error
+}
+
+type OAuthClientHandler struct {
+	clientRegistry OAuthClientRegistry
+}
+
+func NewOAuthClientHandler(clientRegistry OAuthClientRegistry) OAuthClientHandlerInterface {
+	return OAuthClientHandler{clientRegistry: clientRegistry}
+}
+
+func (o OAuthClientHandler) Create(c *gin.Context) {
+	var createRequest models.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	client, err := o.clientRegistry.Register(&createRequest)
+	if errors.Is(err, models.ErrInvalidClientScope) || errors.Is(err, models.ErrInvalidClientAudience) {
+		field, message := "scopes", "Values must be permissions a client can be granted"
+		if errors.Is(err, models.ErrInvalidClientAudience) {
+			field, message = "audiences", "Values must not be empty or contain spaces"
+		}
+		c.Error(customError.NewValidation("Client can not be registered", []customError.FieldError{{
+			Field:   field,
+			Code:    customError.InvalidBody,
+			Message: message,
+		}}, err))
+		return
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to register the client")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, client)
+}
+
+func (o OAuthClientHandler) List(c *gin.Context) {
+	clients, err := o.clientRegistry.List()
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve the clients")
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+func (o OAuthClientHandler) Revoke(c *gin.Context) {
+	err := o.clientRegistry.Revoke(c.Param("client_id"))
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to revoke the client")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOAuthClientHandler_Create(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, registryMock *mock.Mock)
+	}{
+		{
+			name:                       "valid request should return the secret once",
+			requestBody:                `{"name":"Worker","scopes":["users:read"]}`,
+			expectedBodyResponse:       `{"client_id":"worker","name":"Worker","scopes":["users:read"],"audiences":["chambeo-fe"],"created_at":"2024-05-01T10:00:00Z","client_secret":"secret"}`,
+			expectedHttpStatusResponse: http.StatusCreated,
+			mockedBehavior: func(t *testing.T, registryMock *mock.Mock) {
+				registryMock.On("Register", &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read"}}).Return(&models.CreatedOAuthClientResponse{
+					OAuthClientResponse: models.OAuthClientResponse{ClientID: "worker", Name: "Worker", Scopes: []string{"users:read"},
+						Audiences: []string{"chambeo-fe"}, CreatedAt: createdAt},
+					ClientSecret: "secret",
+				}, nil)
+			},
+		},
+		{
+			name:                       "missing name should return bad request",
+			requestBody:                `{"scopes":["users:read"]}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, registryMock *mock.Mock) {
+			},
+		},
+		{
+			name:                       "privileged scope should return validation error",
+			requestBody:                `{"name":"Worker","scopes":["users:manage"]}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Client can not be registered","fields":[{"field":"scopes","code":"INVALID_BODY","message":"Values must be permissions a client can be granted"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, registryMock *mock.Mock) {
+				registryMock.On("Register", mock.Anything).Return(nil, models.ErrInvalidClientScope)
+			},
+		},
+		{
+			name:                       "invalid audience should return validation error",
+			requestBody:                `{"name":"Worker","scopes":["users:read"],"audiences":[""]}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Client can not be registered","fields":[{"field":"audiences","code":"INVALID_BODY","message":"Values must not be empty or contain spaces"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, registryMock *mock.Mock) {
+				registryMock.On("Register", mock.Anything).Return(nil, models.ErrInvalidClientAudience)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"name":"Worker","scopes":["users:read"]}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to register the client"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, registryMock *mock.Mock) {
+				registryMock.On("Register", mock.Anything).Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedRegistry := &MockOAuthClientRegistry{}
+			tt.mockedBehavior(t, &mockedRegistry.Mock)
+
+			router := setupMockedOAuthClientRouter(NewOAuthClientHandler(mockedRegistry))
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/clients", bytes.NewBufferString(tt.requestBody))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestOAuthClientHandler_ListAndRevoke(t *testing.T) {
+	mockedRegistry := &MockOAuthClientRegistry{}
+	mockedRegistry.On("List").Return([]models.OAuthClientResponse{}, nil)
+	mockedRegistry.On("Revoke", "worker").Return(nil)
+	mockedRegistry.On("Revoke", "unknown").Return(models.ErrOAuthClientNotFound)
+
+	router := setupMockedOAuthClientRouter(NewOAuthClientHandler(mockedRegistry))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/auth/clients", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/auth/clients/worker", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/v1/auth/clients/unknown", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, `{"code":"NOT_FOUND","message":"Client not found"}`, w.Body.String())
+}
+
+func setupMockedOAuthClientRouter(clientHandler OAuthClientHandlerInterface) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/clients", clientHandler.Create)
+			auth.GET("/clients", clientHandler.List)
+			auth.DELETE("/clients/:client_id", clientHandler.Revoke)
+		}
+	}
+
+	return r
+}
+
+type MockOAuthClientRegistry struct {
+	mock.Mock
+}
+
+func (m *MockOAuthClientRegistry) Register(request *models.CreateOAuthClientRequest) (*models.CreatedOAuthClientResponse, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CreatedOAuthClientResponse), args.Error(1)
+}
+
+func (m *MockOAuthClientRegistry) List() ([]models.OAuthClientResponse, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.OAuthClientResponse), args.Error(1)
+}
+
+func (m *MockOAuthClientRegistry) Revoke(clientID string) error {
+	args := m.Called(clientID)
+	return args.Error(0)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	oidcStateCookie     = "chambeo_oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc/"
+)
+
+// AuthorizeOIDC starts a social login, it redirects the browser to the
+// provider and keeps the state of the flow in an HttpOnly cookie.
+func (a AuthHandler) AuthorizeOIDC(c *gin.Context) {
+	authorization, err := a.oidcService.BeginLogin(c.Param("provider"))
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to start social login")
+		return
+	}
+
+	// Lax keeps the cookie on the top level redirect back from the provider.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, authorization.StateToken, authorization.ExpiresIn, oidcStateCookiePath, "", a.secureCookie(c), true)
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, authorization.URL)
+}
+
+// OIDCCallback receives the authorization code from the provider and logs
+// the linked user in like GenerateToken does.
+func (a AuthHandler) OIDCCallback(c *gin.Context) {
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", a.secureCookie(c), true)
+
+	if c.Query("error") != "" || c.Query("code") == "" {
+		c.Error(customError.NewUnauthorized("Social login was cancelled or denied", nil))
+		return
+	}
+
+	user, err := a.oidcService.CompleteLogin(c.Param("provider"), c.Query("code"), c.Query("state"), stateToken)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to complete social login")
+		return
+	}
+
+	a.finishLogin(c, user)
+}
+
+// secureCookie only trusts the connection itself, behind a proxy the
+// cookies are Secure because the API is published over https.
+func (a AuthHandler) secureCookie(c *gin.Context) bool {
+	return a.secureCookies || c.Request.TLS != nil
+}
@@ -0,0 +1,346 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/keys"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/service"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/oidc"
+	"chambeo-api-core/pkg/oidc/oidctest"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthHandler_AuthorizeOIDC(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, oidcMock *mock.Mock)
+		asserts                    func(t *testing.T, response *httptest.ResponseRecorder)
+	}{
+		{
+			name:                       "configured provider should redirect and set the state cookie",
+			expectedHttpStatusResponse: http.StatusFound,
+			mockedBehavior: func(t *testing.T, oidcMock *mock.Mock) {
+				oidcMock.On("BeginLogin", "google").Return(&models.OIDCAuthorization{
+					URL: "https://accounts.google.com/o/oauth2/v2/auth?state=abc", StateToken: "state-token", ExpiresIn: 600,
+				}, nil)
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder) {
+				assert.Equal(t, "https://accounts.google.com/o/oauth2/v2/auth?state=abc", response.Header().Get("Location"))
+				cookie := response.Header().Get("Set-Cookie")
+				assert.Contains(t, cookie, "chambeo_oidc_state=state-token")
+				assert.Contains(t, cookie, "HttpOnly")
+				assert.Contains(t, cookie, "SameSite=Lax")
+			},
+		},
+		{
+			name:                       "unknown provider should return not found",
+			expectedHttpStatusResponse: http.StatusNotFound,
+			mockedBehavior: func(t *testing.T, oidcMock *mock.Mock) {
+				oidcMock.On("BeginLogin", "google").Return(nil, models.ErrUnknownOIDCProvider)
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder) {
+				assert.Equal(t, `{"code":"NOT_FOUND","message":"Identity provider not found"}`, response.Body.String())
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, oidcMock *mock.Mock) {
+				oidcMock.On("BeginLogin", "google").Return(nil, errors.New("discovery failed"))
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedOIDCService := &MockOIDCService{}
+			tt.mockedBehavior(t, &mockedOIDCService.Mock)
+
+			authHandler := NewAuthHandler(&MockAuthService{}, &MockUserService{}, &MockMFAService{}, mockedOIDCService, &MockClientCredentialsService{}, &MockMagicLinkService{},
+				allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false)
+			router := setupMockedOIDCRouter(authHandler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/auth/oidc/google/authorize", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			tt.asserts(t, w)
+		})
+	}
+}
+
+func TestAuthHandler_AuthorizeOIDCSecureCookie(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		secureCookies bool
+		expected      bool
+	}{
+		{name: "forwarded proto should not make the cookie secure", secureCookies: false, expected: false},
+		{name: "api published over https should always set a secure cookie", secureCookies: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedOIDCService := &MockOIDCService{}
+			mockedOIDCService.On("BeginLogin", "google").Return(&models.OIDCAuthorization{
+				URL: "https://accounts.google.com/o/oauth2/v2/auth?state=abc", StateToken: "state-token", ExpiresIn: 600,
+			}, nil)
+
+			authHandler := NewAuthHandler(&MockAuthService{}, &MockUserService{}, &MockMFAService{}, mockedOIDCService, &MockClientCredentialsService{}, &MockMagicLinkService{},
+				allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, tt.secureCookies)
+			router := setupMockedOIDCRouter(authHandler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/auth/oidc/google/authorize", nil)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.expected, strings.Contains(w.Header().Get("Set-Cookie"), "Secure"))
+		})
+	}
+}
+
+func TestAuthHandler_OIDCCallback(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		query                      string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock)
+	}{
+		{
+			name:                       "valid callback should issue tokens",
+			query:                      "?code=code&state=state",
+			expectedBodyResponse:       `{"access_token":"token","expires_in":0,"token_type":""}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {
+				oidcMock.On("CompleteLogin", "google", "code", "state", "state-token").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mfaMock.On("IsEnabled", "1").Return(false, nil)
+				userMock.On("GetPermissions", "1").Return(&userModels.UserPermissions{Id: 1, Role: "user"}, nil)
+				authMock.On("GenerateToken", mock.Anything).Return(&models.TokenResponse{AccessToken: "token"}, nil)
+			},
+		},
+		{
+			name:                       "user with mfa should receive a pending token",
+			query:                      "?code=code&state=state",
+			expectedBodyResponse:       `{"mfa_token":"pending","expires_in":300,"token_type":"mfa_pending"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {
+				oidcMock.On("CompleteLogin", "google", "code", "state", "state-token").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mfaMock.On("IsEnabled", "1").Return(true, nil)
+				mfaMock.On("IssuePendingToken", "1").Return(&models.MFAPendingResponse{MFAToken: "pending", ExpiresIn: 300, TokenType: models.MFAPendingTokenType}, nil)
+			},
+		},
+		{
+			name:                       "denied authorization should return unauthorized",
+			query:                      "?error=access_denied&state=state",
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Social login was cancelled or denied"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {},
+		},
+		{
+			name:                       "invalid state should return unauthorized",
+			query:                      "?code=code&state=state",
+			expectedBodyResponse:       `{"code":"INVALID_TOKEN","message":"Invalid or expired social login"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {
+				oidcMock.On("CompleteLogin", "google", "code", "state", "state-token").Return(nil, models.ErrInvalidOIDCState)
+			},
+		},
+		{
+			name:                       "unverified provider email should return forbidden",
+			query:                      "?code=code&state=state",
+			expectedBodyResponse:       `{"code":"EMAIL_NOT_VERIFIED","message":"Email address has not been verified by the identity provider"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+			mockedBehavior: func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {
+				oidcMock.On("CompleteLogin", "google", "code", "state", "state-token").Return(nil, models.ErrOIDCEmailNotVerified)
+			},
+		},
+		{
+			name:                       "unverified local account should return conflict",
+			query:                      "?code=code&state=state",
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"An account with this email exists, verify it before using social login"}`,
+			expectedHttpStatusResponse: http.StatusConflict,
+			mockedBehavior: func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {
+				oidcMock.On("CompleteLogin", "google", "code", "state", "state-token").Return(nil, models.ErrOIDCAccountNotLinkable)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			query:                      "?code=code&state=state",
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to complete social login"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, oidcMock, userMock, mfaMock, authMock *mock.Mock) {
+				oidcMock.On("CompleteLogin", "google", "code", "state", "state-token").Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedOIDCService := &MockOIDCService{}
+			mockedUserService := &MockUserService{}
+			mockedMFAService := &MockMFAService{}
+			mockedAuthService := &MockAuthService{}
+			tt.mockedBehavior(t, &mockedOIDCService.Mock, &mockedUserService.Mock, &mockedMFAService.Mock, &mockedAuthService.Mock)
+
+			authHandler := NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, mockedOIDCService, &MockClientCredentialsService{}, &MockMagicLinkService{},
+				allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false)
+			router := setupMockedOIDCRouter(authHandler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/auth/oidc/google/callback"+tt.query, nil)
+			req.AddCookie(&http.Cookie{Name: "chambeo_oidc_state", Value: "state-token"})
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			assert.Contains(t, w.Header().Get("Set-Cookie"), "chambeo_oidc_state=;")
+		})
+	}
+}
+
+// TestAuthHandler_OIDCEndToEnd runs the whole social login against an
+// in-process identity provider, only the storage is mocked.
+func TestAuthHandler_OIDCEndToEnd(t *testing.T) {
+	idp := oidctest.NewIdP("chambeo", "secret")
+	defer idp.Close()
+
+	key, _ := keys.NewHMACKey("test", []byte("a-test-secret-that-is-long-enough"))
+	keyRing, _ := keys.NewKeyRing("test", key)
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "test",
+		Issuer:       idp.Issuer(),
+		ClientID:     "chambeo",
+		ClientSecret: "secret",
+		RedirectURL:  "https://api.chambeo.co/api/v1/auth/oidc/test/callback",
+	}, nil)
+
+	identityRepository := &MockExternalIdentityRepository{}
+	identityRepository.On("Get", "test", "1234567890").Return(nil, nil)
+	identityRepository.On("Create", mock.MatchedBy(func(identity *models.ExternalIdentity) bool {
+		return identity.Provider == "test" && identity.Subject == "1234567890" && identity.UserID == 8
+	})).Return(&models.ExternalIdentity{ID: 1}, nil)
+
+	mockedUserService := &MockUserService{}
+	mockedUserService.On("GetByEmail", "meze@gmail.com").Return(nil, userModels.ErrUserNotFound)
+	mockedUserService.On("CreateExternal", mock.Anything).Return(&userModels.UserRequest{Id: 8, Email: "meze@gmail.com"}, nil)
+	mockedUserService.On("GetPermissions", "8").Return(&userModels.UserPermissions{Id: 8, Role: "user"}, nil)
+	mockedMFAService := &MockMFAService{}
+	mockedMFAService.On("IsEnabled", "8").Return(false, nil)
+	mockedAuthService := &MockAuthService{}
+	mockedAuthService.On("GenerateToken", mock.MatchedBy(func(subject models.TokenSubject) bool {
+		return subject.UserID == "8" && subject.Email == "meze@gmail.com"
+	})).Return(&models.TokenResponse{AccessToken: "token", RefreshToken: "refresh"}, nil)
+
+	oidcService := service.NewOIDCService(keyRing, []oidc.ProviderInterface{provider}, identityRepository, mockedUserService)
+	router := setupMockedOIDCRouter(NewAuthHandler(mockedAuthService, mockedUserService, mockedMFAService, oidcService, &MockClientCredentialsService{}, &MockMagicLinkService{},
+		allowingLoginGuard(), testAuditor(), testPasswordHasher(), false, false))
+
+	// The API redirects the browser to the provider.
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/auth/oidc/test/authorize", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	stateCookie := w.Result().Cookies()[0]
+
+	// The provider logs the user in and redirects back with the code.
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	idpResponse, err := client.Get(w.Header().Get("Location"))
+	assert.NoError(t, err)
+	idpResponse.Body.Close()
+	callback, _ := url.Parse(idpResponse.Header.Get("Location"))
+	assert.True(t, strings.HasPrefix(callback.String(), "https://api.chambeo.co/api/v1/auth/oidc/test/callback?"))
+
+	// The callback exchanges the code and issues the tokens.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"access_token":"token","refresh_token":"refresh","expires_in":0,"token_type":""}`, w.Body.String())
+	identityRepository.AssertExpectations(t)
+	mockedUserService.AssertExpectations(t)
+
+	// The code can not be replayed.
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func setupMockedOIDCRouter(authHandler AuthHandlerInterface) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.GET("/oidc/:provider/authorize", authHandler.AuthorizeOIDC)
+			auth.GET("/oidc/:provider/callback", authHandler.OIDCCallback)
+		}
+	}
+
+	return r
+}
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) BeginLogin(provider string) (*models.OIDCAuthorization, error) {
+	args := m.Called(provider)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OIDCAuthorization), args.Error(1)
+}
+
+func (m *MockOIDCService) CompleteLogin(provider string, code string, state string, stateToken string) (*userModels.UserRequest, error) {
+	args := m.Called(provider, code, state, stateToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+type MockExternalIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockExternalIdentityRepository) Get(provider string, subject string) (*models.ExternalIdentity, error) {
+	args := m.Called(provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExternalIdentity), args.Error(1)
+}
+
+func (m *MockExternalIdentityRepository) Create(identity *models.ExternalIdentity) (*models.ExternalIdentity, error) {
+	args := m.Called(identity)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExternalIdentity), args.Error(1)
+}
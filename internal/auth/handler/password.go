@@ -0,0 +1,146 @@
+package handler
+
+import (
+	auditModels "chambeo-api-core/internal/audit/models"
+	auditService "chambeo-api-core/internal/audit/service"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type PasswordHandlerInterface interface {
+	Forgot(c *gin.Context)
+	Reset(c *gin.Context)
+	Change(c *gin.Context)
+}
+
+type PasswordService interface {
+	RequestReset(email string) error
+	ResetPassword(token string, password string) error
+	ChangePassword(userID string, currentPassword string, newPassword string) error
+}
+
+type PasswordHandler struct {
+	passwordService PasswordService
+	loginGuard      LoginGuard
+	auditor         Auditor
+}
+
+func NewPasswordHandler(passwordService PasswordService, loginGuard LoginGuard, auditor Auditor) PasswordHandlerInterface {
+	return PasswordHandler{passwordService: passwordService, loginGuard: loginGuard, auditor: auditor}
+}
+
+// Forgot always answers 202 for a well formed request, whether the email
+// belongs to an account or not.
+func (p PasswordHandler) Forgot(c *gin.Context) {
+	var forgotRequest models.ForgotPasswordRequest
+	err := c.ShouldBindJSON(&forgotRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	if err := p.passwordService.RequestReset(forgotRequest.Email); err != nil {
+		c.Error(err).SetMeta("Error trying to request password reset")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func (p PasswordHandler) Reset(c *gin.Context) {
+	var resetRequest models.ResetPasswordRequest
+	err := c.ShouldBindJSON(&resetRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	err = p.passwordService.ResetPassword(resetRequest.Token, resetRequest.Password)
+	var violationError *passwordPolicy.ViolationError
+	if errors.As(err, &violationError) {
+		c.Error(passwordValidationError(violationError, "password"))
+		return
+	}
+	if errors.Is(err, models.ErrInvalidResetToken) {
+		event := auditService.NewEvent(c, auditModels.EventPasswordReset, auditModels.OutcomeFailure)
+		event.Reason = auditModels.ReasonInvalidResetToken
+		p.auditor.Record(event)
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to reset password")
+		return
+	}
+
+	p.auditor.Record(auditService.NewEvent(c, auditModels.EventPasswordReset, auditModels.OutcomeSuccess))
+	c.Status(http.StatusNoContent)
+}
+
+// Change requires the current password and closes every session of the
+// user, including the one used to call it.
+func (p PasswordHandler) Change(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	var changeRequest models.ChangePasswordRequest
+	err := c.ShouldBindJSON(&changeRequest)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	// A stolen access token must not be a way around the login guard to
+	// guess the password, so wrong current passwords count as failed logins.
+	retryAfter, err := p.loginGuard.Check(claims.Email, c.ClientIP())
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve login attempts")
+		return
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.Error(customError.NewTooManyRequests("Too many failed attempts, try again later", nil))
+		return
+	}
+
+	err = p.passwordService.ChangePassword(claims.UserID, changeRequest.CurrentPassword, changeRequest.NewPassword)
+	var violationError *passwordPolicy.ViolationError
+	if errors.As(err, &violationError) {
+		c.Error(passwordValidationError(violationError, "new_password"))
+		return
+	}
+	if errors.Is(err, models.ErrInvalidCurrentPassword) {
+		event := auditService.NewEvent(c, auditModels.EventPasswordChange, auditModels.OutcomeFailure)
+		event.Reason = auditModels.ReasonInvalidCurrentPassword
+		p.auditor.Record(event)
+		if err := p.loginGuard.RegisterFailure(claims.Email, c.ClientIP()); err != nil {
+			log.Println("error trying to register failed password change: ", err.Error())
+		}
+		c.Error(customError.NewValidation("Current password is invalid", []customError.FieldError{{
+			Field:   "current_password",
+			Code:    customError.InvalidCredentials,
+			Message: "Does not match the current password",
+		}}, err))
+		return
+	}
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to change password")
+		return
+	}
+
+	p.auditor.Record(auditService.NewEvent(c, auditModels.EventPasswordChange, auditModels.OutcomeSuccess))
+	c.Status(http.StatusNoContent)
+}
+
+func passwordValidationError(violationError *passwordPolicy.ViolationError, field string) *customError.DomainError {
+	return customError.NewValidation("Password does not satisfy the password policy", violationError.FieldErrors(field), violationError)
+}
@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPasswordHandler_Forgot(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, passwordMock *mock.Mock)
+	}{
+		{
+			name:                       "valid email should be accepted",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusAccepted,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("RequestReset", "meze@gmail.com").Return(nil)
+			},
+		},
+		{
+			name:                       "invalid email should return bad request",
+			requestBody:                `{"email":"meze"}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, passwordMock *mock.Mock) {},
+		},
+		{
+			name:                       "throttled email should return too many requests",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Password reset requested too often, try again later"}`,
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("RequestReset", "meze@gmail.com").Return(models.ErrPasswordResetThrottled)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"email":"meze@gmail.com"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to request password reset"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("RequestReset", "meze@gmail.com").Return(errors.New("error from mailer"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedPasswordService := &MockPasswordService{}
+			tt.mockedBehavior(t, &mockedPasswordService.Mock)
+
+			router := setupMockedPasswordRouter(NewPasswordHandler(mockedPasswordService, allowingLoginGuard(), testAuditor()), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/password/forgot", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestPasswordHandler_Reset(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, passwordMock *mock.Mock)
+	}{
+		{
+			name:                       "valid token should reset the password",
+			requestBody:                `{"token":"reset","password":"new-password"}`,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ResetPassword", "reset", "new-password").Return(nil)
+			},
+		},
+		{
+			name:                       "missing password should return bad request",
+			requestBody:                `{"token":"reset"}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, passwordMock *mock.Mock) {},
+		},
+		{
+			name:                       "short password should return field errors",
+			requestBody:                `{"token":"reset","password":"short"}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Password does not satisfy the password policy","fields":[{"field":"password","code":"TOO_SHORT","message":"Must be at least 8 characters long"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ResetPassword", "reset", "short").Return(tooShortViolation)
+			},
+		},
+		{
+			name:                       "invalid token should return bad request",
+			requestBody:                `{"token":"reset","password":"new-password"}`,
+			expectedBodyResponse:       `{"code":"INVALID_TOKEN","message":"Invalid or expired reset token"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ResetPassword", "reset", "new-password").Return(models.ErrInvalidResetToken)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"token":"reset","password":"new-password"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to reset password"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ResetPassword", "reset", "new-password").Return(errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedPasswordService := &MockPasswordService{}
+			tt.mockedBehavior(t, &mockedPasswordService.Mock)
+
+			router := setupMockedPasswordRouter(NewPasswordHandler(mockedPasswordService, allowingLoginGuard(), testAuditor()), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/password/reset", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestPasswordHandler_Change(t *testing.T) {
+
+	claims := &models.CustomClaims{UserID: "1", Email: "meze@gmail.com"}
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		claims                     *models.CustomClaims
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, passwordMock *mock.Mock)
+	}{
+		{
+			name:                       "valid passwords should change the password",
+			requestBody:                `{"current_password":"current-password","new_password":"new-password"}`,
+			claims:                     claims,
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ChangePassword", "1", "current-password", "new-password").Return(nil)
+			},
+		},
+		{
+			name:                       "missing claims should return unauthorized",
+			requestBody:                `{"current_password":"current-password","new_password":"new-password"}`,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, passwordMock *mock.Mock) {},
+		},
+		{
+			name:                       "missing new password should return bad request",
+			requestBody:                `{"current_password":"current-password"}`,
+			claims:                     claims,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, passwordMock *mock.Mock) {},
+		},
+		{
+			name:                       "weak new password should return field errors",
+			requestBody:                `{"current_password":"current-password","new_password":"short"}`,
+			claims:                     claims,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Password does not satisfy the password policy","fields":[{"field":"new_password","code":"TOO_SHORT","message":"Must be at least 8 characters long"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ChangePassword", "1", "current-password", "short").Return(tooShortViolation)
+			},
+		},
+		{
+			name:                       "wrong current password should return field error",
+			requestBody:                `{"current_password":"wrong-password","new_password":"new-password"}`,
+			claims:                     claims,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Current password is invalid","fields":[{"field":"current_password","code":"INVALID_CREDENTIALS","message":"Does not match the current password"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ChangePassword", "1", "wrong-password", "new-password").Return(models.ErrInvalidCurrentPassword)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			requestBody:                `{"current_password":"current-password","new_password":"new-password"}`,
+			claims:                     claims,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to change password"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, passwordMock *mock.Mock) {
+				passwordMock.On("ChangePassword", "1", "current-password", "new-password").Return(errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedPasswordService := &MockPasswordService{}
+			tt.mockedBehavior(t, &mockedPasswordService.Mock)
+
+			router := setupMockedPasswordRouter(NewPasswordHandler(mockedPasswordService, allowingLoginGuard(), testAuditor()), tt.claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/password/change", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestPasswordHandler_ChangeLoginGuard(t *testing.T) {
+
+	claims := &models.CustomClaims{UserID: "1", Email: "meze@gmail.com"}
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedRetryAfter         string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, passwordMock, guardMock *mock.Mock)
+		asserts                    func(t *testing.T, passwordMock, guardMock *mock.Mock)
+	}{
+		{
+			name:                       "blocked account should return too many requests",
+			requestBody:                `{"current_password":"current-password","new_password":"new-password"}`,
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Too many failed attempts, try again later"}`,
+			expectedRetryAfter:         "60",
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			mockedBehavior: func(t *testing.T, passwordMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Minute, nil)
+			},
+			asserts: func(t *testing.T, passwordMock, guardMock *mock.Mock) {
+				passwordMock.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:                       "wrong current password should register a failure",
+			requestBody:                `{"current_password":"wrong-password","new_password":"new-password"}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Current password is invalid","fields":[{"field":"current_password","code":"INVALID_CREDENTIALS","message":"Does not match the current password"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, passwordMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), nil)
+				guardMock.On("RegisterFailure", "meze@gmail.com", "10.0.0.1").Return(nil)
+				passwordMock.On("ChangePassword", "1", "wrong-password", "new-password").Return(models.ErrInvalidCurrentPassword)
+			},
+			asserts: func(t *testing.T, passwordMock, guardMock *mock.Mock) {
+				guardMock.AssertCalled(t, "RegisterFailure", "meze@gmail.com", "10.0.0.1")
+			},
+		},
+		{
+			name:                       "guard error should return internal error",
+			requestBody:                `{"current_password":"current-password","new_password":"new-password"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to retrieve login attempts"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, passwordMock, guardMock *mock.Mock) {
+				guardMock.On("Check", "meze@gmail.com", "10.0.0.1").Return(time.Duration(0), errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, passwordMock, guardMock *mock.Mock) {
+				passwordMock.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedPasswordService := &MockPasswordService{}
+			mockedGuard := &MockLoginGuard{}
+			tt.mockedBehavior(t, &mockedPasswordService.Mock, &mockedGuard.Mock)
+
+			router := setupMockedPasswordRouter(NewPasswordHandler(mockedPasswordService, mockedGuard, testAuditor()), claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/auth/password/change", bytes.NewReader([]byte(tt.requestBody)))
+			req.RemoteAddr = "10.0.0.1:4321"
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			assert.Equal(t, tt.expectedRetryAfter, w.Header().Get("Retry-After"))
+			tt.asserts(t, &mockedPasswordService.Mock, &mockedGuard.Mock)
+		})
+	}
+}
+
+var tooShortViolation = &passwordPolicy.ViolationError{
+	Violations: []passwordPolicy.Violation{{Code: passwordPolicy.TooShort, Message: "Must be at least 8 characters long"}},
+}
+
+func setupMockedPasswordRouter(passwordHandler PasswordHandlerInterface, claims *models.CustomClaims) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/password/forgot", passwordHandler.Forgot)
+			auth.POST("/password/reset", passwordHandler.Reset)
+			auth.POST("/password/change", passwordHandler.Change)
+		}
+	}
+
+	return r
+}
+
+type MockPasswordService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordService) RequestReset(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockPasswordService) ResetPassword(token string, password string) error {
+	args := m.Called(token, password)
+	return args.Error(0)
+}
+
+func (m *MockPasswordService) ChangePassword(userID string, currentPassword string, newPassword string) error {
+	args := m.Called(userID, currentPassword, newPassword)
+	return args.Error(0)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SessionHandlerInterface interface {
+	List(c *gin.Context)
+	Revoke(c *gin.Context)
+}
+
+type SessionManagementService interface {
+	List(userID string, currentSessionID string) ([]models.SessionResponse, error)
+	Revoke(userID string, sessionID string) error
+}
+
+type SessionHandler struct {
+	sessionService SessionManagementService
+}
+
+func NewSessionHandler(sessionService SessionManagementService) SessionHandlerInterface {
+	return SessionHandler{sessionService: sessionService}
+}
+
+func (s SessionHandler) List(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	sessions, err := s.sessionService.List(claims.UserID, claims.SessionID)
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to retrieve the sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (s SessionHandler) Revoke(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	err := s.sessionService.Revoke(claims.UserID, c.Param("id"))
+	if err != nil {
+		c.Error(err).SetMeta("Error trying to revoke the session")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
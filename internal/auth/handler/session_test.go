@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/middleware"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var sessionClaims = &models.CustomClaims{UserID: "7", Email: "meze@gmail.com", SessionID: "phone"}
+
+func TestSessionHandler_List(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                       string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, sessionMock *mock.Mock)
+	}{
+		{
+			name:                       "sessions should be listed with the current one flagged",
+			expectedBodyResponse:       `[{"id":"phone","user_agent":"Safari","ip":"10.0.0.2","created_at":"2024-05-01T10:00:00Z","last_seen_at":"2024-05-01T10:00:00Z","current":true}]`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, sessionMock *mock.Mock) {
+				sessionMock.On("List", "7", "phone").Return([]models.SessionResponse{
+					{ID: "phone", UserAgent: "Safari", IP: "10.0.0.2", CreatedAt: createdAt, LastSeenAt: createdAt, Current: true},
+				}, nil)
+			},
+		},
+		{
+			name:                       "service error should return internal error",
+			expectedBodyResponse:       `{"code":"ERROR","message":"Error trying to retrieve the sessions"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, sessionMock *mock.Mock) {
+				sessionMock.On("List", "7", "phone").Return(nil, errors.New("error from db"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedSessionService := &MockSessionService{}
+			tt.mockedBehavior(t, &mockedSessionService.Mock)
+
+			router := setupMockedSessionRouter(NewSessionHandler(mockedSessionService), sessionClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/auth/sessions", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestSessionHandler_Revoke(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		claims                     *models.CustomClaims
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, sessionMock *mock.Mock)
+	}{
+		{
+			name:                       "own session should be revoked",
+			claims:                     sessionClaims,
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, sessionMock *mock.Mock) {
+				sessionMock.On("Revoke", "7", "laptop").Return(nil)
+			},
+		},
+		{
+			name:                       "unknown session should return not found",
+			claims:                     sessionClaims,
+			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"Session not found"}`,
+			expectedHttpStatusResponse: http.StatusNotFound,
+			mockedBehavior: func(t *testing.T, sessionMock *mock.Mock) {
+				sessionMock.On("Revoke", "7", "laptop").Return(models.ErrSessionNotFound)
+			},
+		},
+		{
+			name:                       "missing claims should return unauthorized",
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, sessionMock *mock.Mock) {
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedSessionService := &MockSessionService{}
+			tt.mockedBehavior(t, &mockedSessionService.Mock)
+
+			router := setupMockedSessionRouter(NewSessionHandler(mockedSessionService), tt.claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/api/v1/auth/sessions/laptop", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func setupMockedSessionRouter(sessionHandler SessionHandlerInterface, claims *models.CustomClaims) *gin.Engine {
+	r := gin.Default()
+	r.Use(customError.ErrorHandler())
+
+	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
+	{
+		auth := v1.Group("/auth")
+		{
+			auth.GET("/sessions", sessionHandler.List)
+			auth.DELETE("/sessions/:id", sessionHandler.Revoke)
+		}
+	}
+
+	return r
+}
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) List(userID string, currentSessionID string) ([]models.SessionResponse, error) {
+	args := m.Called(userID, currentSessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SessionResponse), args.Error(1)
+}
+
+func (m *MockSessionService) Revoke(userID string, sessionID string) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type WellKnownHandlerInterface interface {
+	JWKS(c *gin.Context)
+	OpenIDConfiguration(c *gin.Context)
+}
+
+type KeySet interface {
+	PublicJWKSet() models.JWKSet
+}
+
+type WellKnownHandler struct {
+	keySet  KeySet
+	baseURL string
+}
+
+func NewWellKnownHandler(keySet KeySet, baseURL string) WellKnownHandlerInterface {
+	return WellKnownHandler{keySet: keySet, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (w WellKnownHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, w.keySet.PublicJWKSet())
+}
+
+func (w WellKnownHandler) OpenIDConfiguration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, models.OpenIDConfiguration{
+		Issuer:                           models.Issuer,
+		JwksURI:                          w.baseURL + "/.well-known/jwks.json",
+		TokenEndpoint:                    w.baseURL + "/api/v1/auth/token",
+		RefreshEndpoint:                  w.baseURL + "/api/v1/auth/token/refresh",
+		GrantTypesSupported:              []string{models.GrantTypePassword, "refresh_token", models.GrantTypeClientCredentials},
+		ResponseTypesSupported:           []string{"token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: publishedAlgorithms(w.keySet.PublicJWKSet()),
+		// none is kept for the password grant of the public front end.
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nbf", "jti", "user_id", "email", "role", "permissions", "sid", "scope", "client_id"},
+		IntrospectionEndpoint:             w.baseURL + "/api/v1/auth/introspect",
+		IntrospectionEndpointAuthMethods:  []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+// publishedAlgorithms only lists the algorithms of the keys in the JWKS, a
+// shared HMAC secret is never published so relying parties could not verify
+// its tokens.
+func publishedAlgorithms(jwkSet models.JWKSet) []string {
+	seen := map[string]bool{}
+	algorithms := []string{}
+	for _, jwk := range jwkSet.Keys {
+		if !seen[jwk.Alg] {
+			seen[jwk.Alg] = true
+			algorithms = append(algorithms, jwk.Alg)
+		}
+	}
+	return algorithms
+}
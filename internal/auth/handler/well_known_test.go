@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWellKnownHandler_JWKS(t *testing.T) {
+
+	mockedKeySet := &MockKeySet{}
+	mockedKeySet.On("PublicJWKSet").Return(models.JWKSet{Keys: []models.JWK{
+		{Kty: "OKP", Use: "sig", Kid: "ed-2024", Alg: "EdDSA", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+	}})
+
+	router := setupMockedWellKnownRouter(NewWellKnownHandler(mockedKeySet, "https://api.chambeo.co/"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.Equal(t, `{"keys":[{"kty":"OKP","use":"sig","kid":"ed-2024","alg":"EdDSA","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`, w.Body.String())
+}
+
+func TestWellKnownHandler_OpenIDConfiguration(t *testing.T) {
+
+	mockedKeySet := &MockKeySet{}
+	mockedKeySet.On("PublicJWKSet").Return(models.JWKSet{Keys: []models.JWK{
+		{Kty: "OKP", Use: "sig", Kid: "ed-2024", Alg: "EdDSA", Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},
+		{Kty: "RSA", Use: "sig", Kid: "rsa-2023", Alg: "RS256", N: "n", E: "AQAB"},
+		{Kty: "RSA", Use: "sig", Kid: "rsa-2022", Alg: "RS256", N: "n", E: "AQAB"},
+	}})
+
+	router := setupMockedWellKnownRouter(NewWellKnownHandler(mockedKeySet, "https://api.chambeo.co/"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/.well-known/openid-configuration", nil)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"issuer": "chambeo-co",
+		"jwks_uri": "https://api.chambeo.co/.well-known/jwks.json",
+		"token_endpoint": "https://api.chambeo.co/api/v1/auth/token",
+		"refresh_endpoint": "https://api.chambeo.co/api/v1/auth/token/refresh",
+		"grant_types_supported": ["password", "refresh_token", "client_credentials"],
+		"response_types_supported": ["token"],
+		"subject_types_supported": ["public"],
+		"id_token_signing_alg_values_supported": ["EdDSA", "RS256"],
+		"token_endpoint_auth_methods_supported": ["client_secret_basic", "client_secret_post", "none"],
+		"claims_supported": ["iss", "sub", "aud", "exp", "iat", "nbf", "jti", "user_id", "email", "role", "permissions", "sid", "scope", "client_id"],
+		"introspection_endpoint": "https://api.chambeo.co/api/v1/auth/introspect",
+		"introspection_endpoint_auth_methods_supported": ["client_secret_basic", "client_secret_post"]
+	}`, w.Body.String())
+}
+
+func setupMockedWellKnownRouter(wellKnownHandler WellKnownHandlerInterface) *gin.Engine {
+	r := gin.Default()
+
+	wellKnown := r.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", wellKnownHandler.JWKS)
+		wellKnown.GET("/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+	}
+
+	return r
+}
+
+type MockKeySet struct {
+	mock.Mock
+}
+
+func (m *MockKeySet) PublicJWKSet() models.JWKSet {
+	args := m.Called()
+	return args.Get(0).(models.JWKSet)
+}
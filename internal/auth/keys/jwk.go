@@ -0,0 +1,53 @@
+package keys
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK returns the public JSON Web Key of k. HMAC keys have no public part
+// and are never exported.
+func (k *Key) JWK() (models.JWK, bool) {
+	jwk := models.JWK{Use: "sig", Kid: k.ID, Alg: k.Method.Alg()}
+
+	switch publicKey := k.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(publicKey.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = encode(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(publicKey)
+	default:
+		return models.JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// PublicJWKSet returns the public keys of the ring, including retired ones
+// so tokens they signed can still be verified by other services.
+func (k *KeyRing) PublicJWKSet() models.JWKSet {
+	set := models.JWKSet{Keys: []models.JWK{}}
+	for _, key := range k.Keys() {
+		if jwk, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func encode(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
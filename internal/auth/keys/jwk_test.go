@@ -0,0 +1,70 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+)
+
+func TestKey_JWK(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	hmacKey, _ := NewHMACKey("hs", []byte("a-test-secret-that-is-long-enough"))
+	es256Key, _ := NewECDSAKey("es", ecKey)
+
+	rsaJWK, ok := NewRSAKey("rs", rsaKey).JWK()
+	assert.True(t, ok)
+	assert.Equal(t, "RSA", rsaJWK.Kty)
+	assert.Equal(t, "RS256", rsaJWK.Alg)
+	assert.Equal(t, "sig", rsaJWK.Use)
+	assert.Equal(t, "AQAB", rsaJWK.E)
+	assert.Equal(t, rsaKey.N, new(big.Int).SetBytes(decode(t, rsaJWK.N)))
+
+	ecJWK, ok := es256Key.JWK()
+	assert.True(t, ok)
+	assert.Equal(t, "EC", ecJWK.Kty)
+	assert.Equal(t, "P-256", ecJWK.Crv)
+	assert.Len(t, decode(t, ecJWK.X), 32)
+	assert.Len(t, decode(t, ecJWK.Y), 32)
+	assert.Equal(t, ecKey.X, new(big.Int).SetBytes(decode(t, ecJWK.X)))
+
+	edJWK, ok := NewEd25519Key("ed", edKey).JWK()
+	assert.True(t, ok)
+	assert.Equal(t, "OKP", edJWK.Kty)
+	assert.Equal(t, "Ed25519", edJWK.Crv)
+	assert.Equal(t, []byte(edPublic), decode(t, edJWK.X))
+
+	_, ok = hmacKey.JWK()
+	assert.False(t, ok)
+}
+
+func TestKeyRing_PublicJWKSet(t *testing.T) {
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, retiredKey, _ := ed25519.GenerateKey(rand.Reader)
+	retired, _ := NewPublicKey("retired", retiredKey.Public())
+	hmacKey, _ := NewHMACKey("hs", []byte("a-test-secret-that-is-long-enough"))
+
+	ring, _ := NewKeyRing("active", NewEd25519Key("active", edKey), retired, hmacKey)
+
+	set := ring.PublicJWKSet()
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, "active", set.Keys[0].Kid)
+	assert.Equal(t, "retired", set.Keys[1].Kid)
+}
+
+func decode(t *testing.T, value string) []byte {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
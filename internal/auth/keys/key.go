@@ -0,0 +1,136 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a signing key identified by its kid. Keys loaded from a public key
+// only can verify tokens but never sign them.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("hmac secret for key %s must be at least 32 bytes", id)
+	}
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+func NewRSAKey(id string, privateKey *rsa.PrivateKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodRS256, signKey: privateKey, verifyKey: &privateKey.PublicKey}
+}
+
+func NewECDSAKey(id string, privateKey *ecdsa.PrivateKey) (*Key, error) {
+	if privateKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("ecdsa key %s must use the P-256 curve", id)
+	}
+	return &Key{ID: id, Method: jwt.SigningMethodES256, signKey: privateKey, verifyKey: &privateKey.PublicKey}, nil
+}
+
+func NewEd25519Key(id string, privateKey ed25519.PrivateKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signKey: privateKey, verifyKey: privateKey.Public()}
+}
+
+// NewPublicKey builds a verify only key, useful to keep accepting tokens
+// signed by a retired key whose private part was destroyed.
+func NewPublicKey(id string, publicKey crypto.PublicKey) (*Key, error) {
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verifyKey: pub}, nil
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ecdsa key %s must use the P-256 curve", id)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodES256, verifyKey: pub}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verifyKey: pub}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T for key %s", publicKey, id)
+	}
+}
+
+// ParsePEM builds a key from a PEM block. PKCS#1, PKCS#8, SEC 1 private keys
+// and PKIX public keys are supported.
+func ParsePEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s is not PEM encoded", id)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing rsa key %s: %w", id, err)
+		}
+		return NewRSAKey(id, privateKey), nil
+	case "EC PRIVATE KEY":
+		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing ecdsa key %s: %w", id, err)
+		}
+		return NewECDSAKey(id, privateKey)
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing private key %s: %w", id, err)
+		}
+		return newPrivateKey(id, privateKey)
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing public key %s: %w", id, err)
+		}
+		return NewPublicKey(id, publicKey)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %s for key %s", block.Type, id)
+	}
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// PublicKey returns the asymmetric public key, or nil for HMAC keys which
+// must never be published.
+func (k *Key) PublicKey() crypto.PublicKey {
+	if _, symmetric := k.verifyKey.([]byte); symmetric {
+		return nil
+	}
+	return k.verifyKey
+}
+
+func (k *Key) sign(claims jwt.Claims) (string, error) {
+	if !k.CanSign() {
+		return "", errors.New("key " + k.ID + " can only verify tokens")
+	}
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.signKey)
+}
+
+func newPrivateKey(id string, privateKey interface{}) (*Key, error) {
+	switch private := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return NewRSAKey(id, private), nil
+	case *ecdsa.PrivateKey:
+		return NewECDSAKey(id, private)
+	case ed25519.PrivateKey:
+		return NewEd25519Key(id, private), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T for key %s", privateKey, id)
+	}
+}
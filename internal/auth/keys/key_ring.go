@@ -0,0 +1,151 @@
+package keys
+
+import (
+	"chambeo-api-core/internal/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KeyRing signs new tokens with the active key and verifies tokens signed by
+// any key it holds, so retired keys keep working until their tokens expire.
+type KeyRing struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyRing(activeKeyID string, keys ...*Key) (*KeyRing, error) {
+	ring := &KeyRing{keys: map[string]*Key{}}
+	for _, key := range keys {
+		if _, duplicated := ring.keys[key.ID]; duplicated {
+			return nil, fmt.Errorf("duplicated key id %s", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	if activeKeyID == "" && len(keys) == 1 {
+		activeKeyID = keys[0].ID
+	}
+
+	active, ok := ring.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeKeyID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %s has no private key", activeKeyID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// LoadKeyRing builds the key ring from configuration. When no key is
+// configured an ephemeral Ed25519 key is generated, which is only suitable
+// for local development since tokens will not survive a restart.
+func LoadKeyRing(cfg config.AuthConfig) (*KeyRing, error) {
+	var keys []*Key
+
+	if cfg.HMACSecret != "" {
+		key, err := NewHMACKey(cfg.HMACKeyID, []byte(cfg.HMACSecret))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if cfg.KeysDir != "" {
+		dirKeys, err := loadDir(cfg.KeysDir)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, dirKeys...)
+	}
+
+	if len(keys) == 0 {
+		log.Println("no signing keys configured, generating an ephemeral key")
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyRing("", NewEd25519Key("ephemeral", privateKey))
+	}
+
+	return NewKeyRing(cfg.ActiveKeyID, keys...)
+}
+
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	return k.active.sign(claims)
+}
+
+// Keyfunc resolves the verification key from the kid header and refuses
+// tokens whose alg does not match the algorithm of that key.
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("token algorithm does not match key " + kid)
+	}
+	return key.verifyKey, nil
+}
+
+func (k *KeyRing) Algorithms() []string {
+	seen := map[string]bool{}
+	var algorithms []string
+	for _, key := range k.Keys() {
+		if !seen[key.Method.Alg()] {
+			seen[key.Method.Alg()] = true
+			algorithms = append(algorithms, key.Method.Alg())
+		}
+	}
+	return algorithms
+}
+
+// Keys returns every key ordered by kid, the active one first.
+func (k *KeyRing) Keys() []*Key {
+	keys := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == k.active || keys[j] == k.active {
+			return keys[i] == k.active
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (k *KeyRing) ActiveKey() *Key {
+	return k.active
+}
+
+func loadDir(dir string) ([]*Key, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading key %s: %w", path, err)
+		}
+		key, err := ParsePEM(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
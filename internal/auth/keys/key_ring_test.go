@@ -0,0 +1,180 @@
+package keys
+
+import (
+	"chambeo-api-core/internal/config"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyRing_SignAndVerify(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	hmacKey, _ := NewHMACKey("hs", []byte("a-test-secret-that-is-long-enough"))
+	es256Key, _ := NewECDSAKey("es", ecKey)
+
+	tests := []struct {
+		name        string
+		key         *Key
+		expectedAlg string
+	}{
+		{name: "HS256", key: hmacKey, expectedAlg: "HS256"},
+		{name: "RS256", key: NewRSAKey("rs", rsaKey), expectedAlg: "RS256"},
+		{name: "ES256", key: es256Key, expectedAlg: "ES256"},
+		{name: "EdDSA", key: NewEd25519Key("ed", edKey), expectedAlg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := NewKeyRing(tt.key.ID, tt.key)
+			assert.NoError(t, err)
+
+			signed, err := ring.Sign(testClaims())
+			assert.NoError(t, err)
+
+			token, err := jwt.ParseWithClaims(signed, &jwt.RegisteredClaims{}, ring.Keyfunc, jwt.WithValidMethods(ring.Algorithms()))
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, tt.expectedAlg, token.Method.Alg())
+			assert.Equal(t, tt.key.ID, token.Header["kid"])
+		})
+	}
+}
+
+func TestKeyRing_Keyfunc(t *testing.T) {
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	hmacKey, _ := NewHMACKey("hs", []byte("a-test-secret-that-is-long-enough"))
+	ring, _ := NewKeyRing("ed", NewEd25519Key("ed", edKey), hmacKey)
+
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	unknownKid.Header["kid"] = "unknown"
+	unknownSigned, _ := unknownKid.SignedString(edKey)
+
+	missingKid := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	missingSigned, _ := missingKid.SignedString(edKey)
+
+	algorithmMismatch := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	algorithmMismatch.Header["kid"] = "ed"
+	mismatchSigned, _ := algorithmMismatch.SignedString([]byte("a-test-secret-that-is-long-enough"))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "unknown kid should be rejected", token: unknownSigned},
+		{name: "missing kid should be rejected", token: missingSigned},
+		{name: "algorithm not matching the key should be rejected", token: mismatchSigned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.ParseWithClaims(tt.token, &jwt.RegisteredClaims{}, ring.Keyfunc, jwt.WithValidMethods(ring.Algorithms()))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewKeyRing(t *testing.T) {
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	signingKey := NewEd25519Key("ed", edKey)
+	verifyOnlyKey, _ := NewPublicKey("public", edKey.Public())
+
+	_, err := NewKeyRing("missing", signingKey)
+	assert.Error(t, err)
+
+	_, err = NewKeyRing("public", signingKey, verifyOnlyKey)
+	assert.Error(t, err)
+
+	_, err = NewKeyRing("ed", signingKey, signingKey)
+	assert.Error(t, err)
+
+	_, err = NewHMACKey("short", []byte("short"))
+	assert.Error(t, err)
+
+	ring, err := NewKeyRing("", signingKey)
+	assert.NoError(t, err)
+	assert.Equal(t, signingKey, ring.ActiveKey())
+
+	ring, err = NewKeyRing("ed", verifyOnlyKey, signingKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Key{signingKey, verifyOnlyKey}, ring.Keys())
+}
+
+func TestLoadKeyRing(t *testing.T) {
+
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writePEM(t, dir, "rsa-2023.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecBytes, _ := x509.MarshalECPrivateKey(ecKey)
+	writePEM(t, dir, "ec-2024.pem", "EC PRIVATE KEY", ecBytes)
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edBytes, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	writePEM(t, dir, "ed-2024.pem", "PRIVATE KEY", edBytes)
+
+	retiredKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	retiredBytes, _ := x509.MarshalPKIXPublicKey(&retiredKey.PublicKey)
+	writePEM(t, dir, "retired.pem", "PUBLIC KEY", retiredBytes)
+
+	ring, err := LoadKeyRing(config.AuthConfig{
+		KeysDir:     dir,
+		ActiveKeyID: "ed-2024",
+		HMACSecret:  "a-test-secret-that-is-long-enough",
+		HMACKeyID:   "hs",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ed-2024", ring.ActiveKey().ID)
+	assert.Len(t, ring.Keys(), 5)
+	assert.ElementsMatch(t, []string{"EdDSA", "ES256", "HS256", "RS256"}, ring.Algorithms())
+
+	retiredToken := jwt.NewWithClaims(jwt.SigningMethodRS256, testClaims())
+	retiredToken.Header["kid"] = "retired"
+	retiredSigned, _ := retiredToken.SignedString(retiredKey)
+	_, err = jwt.ParseWithClaims(retiredSigned, &jwt.RegisteredClaims{}, ring.Keyfunc)
+	assert.NoError(t, err)
+}
+
+func TestLoadKeyRingWithoutKeys(t *testing.T) {
+	ring, err := LoadKeyRing(config.AuthConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, "EdDSA", ring.ActiveKey().Method.Alg())
+}
+
+func TestLoadKeyRingWithInvalidPEM(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a pem"), 0600))
+
+	_, err := LoadKeyRing(config.AuthConfig{KeysDir: dir})
+	assert.Error(t, err)
+}
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/customError"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"strings"
+)
+
+const (
+	ClaimsKey           = "claims"
+	authorizationHeader = "Authorization"
+	apiKeyHeader        = "X-API-Key"
+	bearerScheme        = "Bearer"
+)
+
+type AuthMiddlewareInterface interface {
+	Authenticate() gin.HandlerFunc
+}
+
+type TokenParser interface {
+	ParseToken(tokenString string) (*jwt.Token, error)
+}
+
+type APIKeyAuthenticator interface {
+	Authenticate(key string) (*models.CustomClaims, error)
+}
+
+type AuthMiddleware struct {
+	tokenParser         TokenParser
+	apiKeyAuthenticator APIKeyAuthenticator
+}
+
+func NewAuthMiddleware(tokenParser TokenParser, apiKeyAuthenticator APIKeyAuthenticator) AuthMiddlewareInterface {
+	return AuthMiddleware{tokenParser: tokenParser, apiKeyAuthenticator: apiKeyAuthenticator}
+}
+
+// Authenticate requires a valid "Authorization: Bearer <token>" header, or
+// an "X-API-Key" header when there is none, and stores the claims of the
+// caller in the context under ClaimsKey.
+func (a AuthMiddleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(authorizationHeader) == "" && c.GetHeader(apiKeyHeader) != "" {
+			a.authenticateAPIKey(c)
+			return
+		}
+
+		tokenString, ok := bearerToken(c.GetHeader(authorizationHeader))
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+
+		token, err := a.tokenParser.ParseToken(tokenString)
+		if err != nil || token == nil || !token.Valid {
+			abortUnauthorized(c)
+			return
+		}
+
+		claims, ok := token.Claims.(*models.CustomClaims)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Next()
+	}
+}
+
+func (a AuthMiddleware) authenticateAPIKey(c *gin.Context) {
+	if a.apiKeyAuthenticator == nil {
+		abortUnauthorized(c)
+		return
+	}
+
+	claims, err := a.apiKeyAuthenticator.Authenticate(strings.TrimSpace(c.GetHeader(apiKeyHeader)))
+	if err != nil || claims == nil {
+		abortUnauthorized(c)
+		return
+	}
+
+	c.Set(ClaimsKey, claims)
+	c.Next()
+}
+
+func GetClaims(c *gin.Context) (*models.CustomClaims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*models.CustomClaims)
+	return claims, ok
+}
+
+// CanAccessUser reports whether the authenticated caller owns the given
+// account or holds an elevated role.
+func CanAccessUser(c *gin.Context, userId string) bool {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return false
+	}
+	return (claims.UserID != "" && claims.UserID == userId) || claims.IsElevated()
+}
+
+// RequireRole must run after Authenticate and only lets through callers
+// holding one of the given roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if !claims.HasRole(roles...) {
+			abortForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission must run after Authenticate and only lets through
+// callers holding every given permission.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		for _, permission := range permissions {
+			if !claims.HasPermission(permission) {
+				abortForbidden(c)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession must run after Authenticate and rejects callers using an
+// API key, a client token or an impersonation token, for the endpoints that
+// manage the account credentials.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if claims.APIKeyID != "" || claims.IsClient() || claims.IsImpersonated() {
+			abortForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// DenyImpersonation must run after Authenticate and rejects impersonation
+// tokens, for sensitive operations an admin may not perform on behalf of
+// the user.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			abortUnauthorized(c)
+			return
+		}
+		if claims.IsImpersonated() {
+			abortForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, bearerScheme) {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func abortUnauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", bearerScheme)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, customError.Error{
+		Code:    customError.Unauthorized,
+		Message: "Invalid or expired token",
+	})
+}
+
+func abortForbidden(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, customError.Error{
+		Code:    customError.Forbidden,
+		Message: "Insufficient permissions",
+	})
+}
@@ -0,0 +1,366 @@
+package middleware
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthMiddleware_Authenticate(t *testing.T) {
+
+	validClaims := &models.CustomClaims{UserID: "1", Email: "meze@gmail.com", Role: models.RoleUser}
+
+	validToken := &jwt.Token{Claims: validClaims, Valid: true}
+	invalidToken := &jwt.Token{Claims: validClaims, Valid: false}
+	tokenWithInvalidClaims := &jwt.Token{Claims: jwt.RegisteredClaims{}, Valid: true}
+
+	unauthorizedBody := `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`
+
+	tests := []struct {
+		name                       string
+		authorizationHeader        string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, parserMock *mock.Mock)
+	}{
+		{
+			name:                       "valid bearer token should reach the handler with claims",
+			authorizationHeader:        "Bearer validToken",
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock) {
+				parserMock.On("ParseToken", "validToken").Return(validToken, nil)
+			},
+		},
+		{
+			name:                       "scheme should be case insensitive",
+			authorizationHeader:        "bearer validToken",
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock) {
+				parserMock.On("ParseToken", "validToken").Return(validToken, nil)
+			},
+		},
+		{
+			name:                       "missing header should return unauthorized",
+			authorizationHeader:        "",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, parserMock *mock.Mock) {},
+		},
+		{
+			name:                       "non bearer scheme should return unauthorized",
+			authorizationHeader:        "Basic dXNlcjpwYXNz",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior:             func(t *testing.T, parserMock *mock.Mock) {},
+		},
+		{
+			name:                       "expired token should return unauthorized",
+			authorizationHeader:        "Bearer expiredToken",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock) {
+				parserMock.On("ParseToken", "expiredToken").Return(nil, jwt.ErrTokenExpired)
+			},
+		},
+		{
+			name:                       "invalid token should return unauthorized",
+			authorizationHeader:        "Bearer invalidToken",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock) {
+				parserMock.On("ParseToken", "invalidToken").Return(invalidToken, nil)
+			},
+		},
+		{
+			name:                       "token with unexpected claims should return unauthorized",
+			authorizationHeader:        "Bearer weirdToken",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock) {
+				parserMock.On("ParseToken", "weirdToken").Return(tokenWithInvalidClaims, nil)
+			},
+		},
+		{
+			name:                       "parser error should return unauthorized",
+			authorizationHeader:        "Bearer brokenToken",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock) {
+				parserMock.On("ParseToken", "brokenToken").Return(nil, errors.New("signature is invalid"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedParser := &MockTokenParser{}
+			tt.mockedBehavior(t, &mockedParser.Mock)
+
+			router := gin.Default()
+			router.GET("/protected", NewAuthMiddleware(mockedParser, &MockAPIKeyAuthenticator{}).Authenticate(), func(c *gin.Context) {
+				claims, _ := GetClaims(c)
+				c.JSON(http.StatusOK, gin.H{"user_id": claims.UserID})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			if tt.authorizationHeader != "" {
+				req.Header.Set("Authorization", tt.authorizationHeader)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestAuthMiddleware_AuthenticateAPIKey(t *testing.T) {
+
+	apiKeyClaims := &models.CustomClaims{UserID: "2", Role: models.RoleUser, APIKeyID: "3"}
+	validToken := &jwt.Token{Claims: &models.CustomClaims{UserID: "1"}, Valid: true}
+
+	unauthorizedBody := `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`
+
+	tests := []struct {
+		name                       string
+		authorizationHeader        string
+		apiKeyHeader               string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, parserMock *mock.Mock, apiKeyMock *mock.Mock)
+	}{
+		{
+			name:                       "valid api key should reach the handler with claims",
+			apiKeyHeader:               "chk_valid",
+			expectedBodyResponse:       `{"user_id":"2"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Authenticate", "chk_valid").Return(apiKeyClaims, nil)
+			},
+		},
+		{
+			name:                       "invalid api key should return unauthorized",
+			apiKeyHeader:               "chk_revoked",
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock, apiKeyMock *mock.Mock) {
+				apiKeyMock.On("Authenticate", "chk_revoked").Return(nil, models.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name:                       "bearer token should take precedence over api key",
+			authorizationHeader:        "Bearer validToken",
+			apiKeyHeader:               "chk_valid",
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, parserMock *mock.Mock, apiKeyMock *mock.Mock) {
+				parserMock.On("ParseToken", "validToken").Return(validToken, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedParser := &MockTokenParser{}
+			mockedAPIKeyAuthenticator := &MockAPIKeyAuthenticator{}
+			tt.mockedBehavior(t, &mockedParser.Mock, &mockedAPIKeyAuthenticator.Mock)
+
+			router := gin.Default()
+			router.GET("/protected", NewAuthMiddleware(mockedParser, mockedAPIKeyAuthenticator).Authenticate(), func(c *gin.Context) {
+				claims, _ := GetClaims(c)
+				c.JSON(http.StatusOK, gin.H{"user_id": claims.UserID})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+			if tt.authorizationHeader != "" {
+				req.Header.Set("Authorization", tt.authorizationHeader)
+			}
+			req.Header.Set("X-API-Key", tt.apiKeyHeader)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			mockedAPIKeyAuthenticator.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCanAccessUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   *models.CustomClaims
+		userId   string
+		expected bool
+	}{
+		{name: "owner can access own account", claims: &models.CustomClaims{UserID: "1", Role: models.RoleUser}, userId: "1", expected: true},
+		{name: "user cannot access other account", claims: &models.CustomClaims{UserID: "1", Role: models.RoleUser}, userId: "2", expected: false},
+		{name: "admin can access other account", claims: &models.CustomClaims{UserID: "1", Role: models.RoleAdmin}, userId: "2", expected: true},
+		{name: "anonymous cannot access any account", claims: nil, userId: "1", expected: false},
+		{name: "admin api key without users:manage cannot access other account", claims: &models.CustomClaims{UserID: "1", Role: models.RoleAdmin, Permissions: []string{models.PermissionUsersRead}, APIKeyID: "3"}, userId: "2", expected: false},
+		{name: "client without users:manage cannot access any account", claims: &models.CustomClaims{ClientID: "worker", Permissions: []string{models.PermissionUsersRead}}, userId: "", expected: false},
+		{name: "client with users:manage can access any account", claims: &models.CustomClaims{ClientID: "worker", Permissions: []string{models.PermissionUsersManage}}, userId: "2", expected: true},
+		{name: "admin api key with users:manage can access other account", claims: &models.CustomClaims{UserID: "1", Role: models.RoleAdmin, Permissions: []string{models.PermissionUsersManage}, APIKeyID: "3"}, userId: "2", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.claims != nil {
+				c.Set(ClaimsKey, tt.claims)
+			}
+			assert.Equal(t, tt.expected, CanAccessUser(c, tt.userId))
+		})
+	}
+}
+
+func TestRequireRoleAndPermission(t *testing.T) {
+
+	workerClaims := &models.CustomClaims{UserID: "1", Role: models.RoleWorker, Permissions: []string{models.PermissionUsersRead, models.PermissionJobsApply}}
+
+	forbiddenBody := `{"code":"FORBIDDEN","message":"Insufficient permissions"}`
+	unauthorizedBody := `{"code":"UNAUTHORIZED","message":"Invalid or expired token"}`
+
+	tests := []struct {
+		name                       string
+		claims                     *models.CustomClaims
+		middleware                 gin.HandlerFunc
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+	}{
+		{
+			name:                       "matching role should reach the handler",
+			claims:                     workerClaims,
+			middleware:                 RequireRole(models.RoleEmployer, models.RoleWorker),
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+		},
+		{
+			name:                       "missing role should return forbidden",
+			claims:                     workerClaims,
+			middleware:                 RequireRole(models.RoleAdmin),
+			expectedBodyResponse:       forbiddenBody,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "granted permissions should reach the handler",
+			claims:                     workerClaims,
+			middleware:                 RequirePermission(models.PermissionUsersRead, models.PermissionJobsApply),
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+		},
+		{
+			name:                       "any missing permission should return forbidden",
+			claims:                     workerClaims,
+			middleware:                 RequirePermission(models.PermissionUsersRead, models.PermissionJobsPublish),
+			expectedBodyResponse:       forbiddenBody,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "missing claims should return unauthorized",
+			claims:                     nil,
+			middleware:                 RequirePermission(models.PermissionUsersRead),
+			expectedBodyResponse:       unauthorizedBody,
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+		},
+		{
+			name:                       "session should reach the handler",
+			claims:                     workerClaims,
+			middleware:                 RequireSession(),
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+		},
+		{
+			name:                       "client token should not reach session only handler",
+			claims:                     &models.CustomClaims{ClientID: "worker", Permissions: []string{models.PermissionUsersRead}},
+			middleware:                 RequireSession(),
+			expectedBodyResponse:       forbiddenBody,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "api key should not reach session only handler",
+			claims:                     &models.CustomClaims{UserID: "1", Role: models.RoleWorker, APIKeyID: "3"},
+			middleware:                 RequireSession(),
+			expectedBodyResponse:       forbiddenBody,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "impersonation token should not reach session only handler",
+			claims:                     &models.CustomClaims{UserID: "1", Role: models.RoleWorker, Actor: &models.Actor{Subject: "9"}},
+			middleware:                 RequireSession(),
+			expectedBodyResponse:       forbiddenBody,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "impersonation token should not reach sensitive handler",
+			claims:                     &models.CustomClaims{UserID: "1", Role: models.RoleWorker, Actor: &models.Actor{Subject: "9"}},
+			middleware:                 DenyImpersonation(),
+			expectedBodyResponse:       forbiddenBody,
+			expectedHttpStatusResponse: http.StatusForbidden,
+		},
+		{
+			name:                       "api key should reach sensitive handler",
+			claims:                     &models.CustomClaims{UserID: "1", Role: models.RoleWorker, APIKeyID: "3"},
+			middleware:                 DenyImpersonation(),
+			expectedBodyResponse:       `{"user_id":"1"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.Default()
+			router.GET("/protected", func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set(ClaimsKey, tt.claims)
+				}
+			}, tt.middleware, func(c *gin.Context) {
+				claims, _ := GetClaims(c)
+				c.JSON(http.StatusOK, gin.H{"user_id": claims.UserID})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/protected", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+type MockTokenParser struct {
+	mock.Mock
+}
+
+func (m *MockTokenParser) ParseToken(tokenString string) (*jwt.Token, error) {
+	args := m.Called(tokenString)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*jwt.Token), args.Error(1)
+}
+
+type MockAPIKeyAuthenticator struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyAuthenticator) Authenticate(key string) (*models.CustomClaims, error) {
+	args := m.Called(key)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CustomClaims), args.Error(1)
+}
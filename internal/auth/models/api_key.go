@@ -0,0 +1,26 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type APIKey struct {
+	ID     uint `gorm:"primarykey"`
+	UserID uint
+	Name   string
+	// Prefix is the start of the key, kept in clear so users can tell their
+	// keys apart.
+	Prefix  string
+	KeyHash string
+	// Scopes are the permissions granted to the key, separated by spaces.
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
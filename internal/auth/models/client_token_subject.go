@@ -0,0 +1,7 @@
+package models
+
+type ClientTokenSubject struct {
+	ClientID string
+	Scopes   []string
+	Audience string
+}
@@ -3,7 +3,66 @@ package models
 import "github.com/golang-jwt/jwt/v5"
 
 type CustomClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID       string   `json:"user_id"`
+	Email        string   `json:"email"`
+	Role         string   `json:"role,omitempty"`
+	Permissions  []string `json:"permissions,omitempty"`
+	SessionID    string   `json:"sid,omitempty"`
+	TokenVersion int      `json:"tv"`
+	Scope        string   `json:"scope,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	// APIKeyID is set when the caller authenticated with an API key instead
+	// of an access token, it is never part of a signed token.
+	APIKeyID string `json:"-"`
+	// Actor is the RFC 8693 act claim, it identifies the admin behind an
+	// impersonation token.
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
+
+type Actor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
+// IsImpersonated reports whether an admin is acting as the user.
+func (c *CustomClaims) IsImpersonated() bool {
+	return c.Actor != nil
+}
+
+// IsClient reports whether the token was issued to a client through the
+// client_credentials grant, such tokens carry no user.
+func (c *CustomClaims) IsClient() bool {
+	return c.UserID == "" && c.ClientID != ""
+}
+
+// IsElevated reports whether the caller acts with an elevated role. API
+// keys of elevated users only do so when they were granted users:manage,
+// and clients only when users:manage is one of their scopes.
+func (c *CustomClaims) IsElevated() bool {
+	if c.IsClient() {
+		return c.HasPermission(PermissionUsersManage)
+	}
+	if c.APIKeyID != "" && !c.HasPermission(PermissionUsersManage) {
+		return false
+	}
+	return elevatedRoles[c.Role]
+}
+
+func (c *CustomClaims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CustomClaims) HasPermission(permission string) bool {
+	for _, granted := range c.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"chambeo-api-core/pkg/customError"
+)
+
+// The messages are the ones answered by the API. Two errors of the same kind
+// match with errors.Is when their messages are equal, so they must differ.
+var (
+	ErrInvalidRefreshToken    = customError.NewUnauthorized("Invalid refresh token", nil)
+	ErrRefreshTokenReused     = customError.NewUnauthorized("Refresh token was already used", nil)
+	ErrTokenRevoked           = customError.NewUnauthorized("Token has been revoked", nil)
+	ErrInvalidResetToken      = customError.NewValidation("Invalid or expired reset token", nil, nil).WithCode(customError.InvalidToken)
+	ErrInvalidCurrentPassword = customError.NewValidation("Current password is invalid", []customError.FieldError{{
+		Field:   "current_password",
+		Code:    customError.InvalidCredentials,
+		Message: "Does not match the current password",
+	}}, nil)
+	ErrPasswordResetThrottled   = customError.NewTooManyRequests("Password reset requested too often, try again later", nil)
+	ErrInvalidVerificationToken = customError.NewValidation("Invalid or expired verification token", nil, nil).WithCode(customError.InvalidToken)
+	ErrVerificationThrottled    = customError.NewTooManyRequests("Verification email requested too often, try again later", nil)
+	ErrMFAAlreadyEnabled        = customError.NewConflict("Two-factor authentication is already enabled", nil)
+	ErrMFANotEnrolled           = customError.NewConflict("No pending two-factor authentication enrollment", nil)
+	ErrMFANotEnabled            = customError.NewConflict("Two-factor authentication is not enabled", nil)
+	ErrInvalidMFACode           = customError.NewValidation("Invalid two-factor authentication code", nil, nil).WithCode(customError.InvalidMFACode)
+	ErrInvalidMFAToken          = customError.NewUnauthorized("Invalid or expired mfa token", nil).WithCode(customError.InvalidToken)
+	ErrUnknownOIDCProvider      = customError.NewNotFound("Identity provider not found", nil)
+	ErrInvalidOIDCState         = customError.NewUnauthorized("Invalid or expired social login", nil).WithCode(customError.InvalidToken)
+	ErrInvalidOIDCLogin         = customError.NewUnauthorized("Social login could not be verified", nil).WithCode(customError.InvalidToken)
+	ErrOIDCEmailNotVerified     = customError.NewForbidden("Email address has not been verified by the identity provider", nil).
+					WithCode(customError.EmailNotVerified)
+	ErrOIDCAccountNotLinkable = customError.NewConflict("An account with this email exists, verify it before using social login", nil)
+	ErrInvalidAPIKey          = customError.NewUnauthorized("Invalid, revoked or expired API key", nil)
+	ErrInvalidAPIKeyScope     = customError.NewValidation("API key scopes must be granted to the user", []customError.FieldError{{
+		Field:   "scopes",
+		Code:    customError.Forbidden,
+		Message: "Scopes must be permissions granted to the user",
+	}}, nil)
+	ErrInvalidAPIKeyExpiry = customError.NewValidation("API key expiration must be in the future", []customError.FieldError{{
+		Field:   "expires_at",
+		Code:    customError.InvalidBody,
+		Message: "Expiration must be in the future",
+	}}, nil)
+	ErrAPIKeyNotFound     = customError.NewNotFound("API key not found", nil)
+	ErrInvalidClient      = customError.NewUnauthorized("Invalid client credentials", nil)
+	ErrInvalidClientScope = customError.NewValidation("Scope is not allowed for the client", []customError.FieldError{{
+		Field:   "scope",
+		Code:    customError.Forbidden,
+		Message: "Scope is not allowed for the client",
+	}}, nil)
+	ErrInvalidClientAudience = customError.NewValidation("Audience is not allowed for the client", []customError.FieldError{{
+		Field:   "audience",
+		Code:    customError.Forbidden,
+		Message: "Audience is not allowed for the client",
+	}}, nil)
+	ErrInvalidTokenAudience    = customError.NewUnauthorized("Token audience is not accepted", nil)
+	ErrOAuthClientNotFound     = customError.NewNotFound("Client not found", nil)
+	ErrSessionNotFound         = customError.NewNotFound("Session not found", nil)
+	ErrInvalidMagicLink        = customError.NewUnauthorized("Invalid, expired or already used login link", nil).WithCode(customError.InvalidToken)
+	ErrMagicLinkThrottled      = customError.NewTooManyRequests("Login link requested too often, try again later", nil)
+	ErrImpersonationNotAllowed = customError.NewForbidden("User can not be impersonated", nil)
+	ErrImpersonationNotFound   = customError.NewNotFound("User to impersonate not found", nil)
+)
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ExternalIdentity links an account of an OpenID Connect provider, given by
+// the issuer subject, to a user.
+type ExternalIdentity struct {
+	ID        uint `gorm:"primarykey"`
+	Provider  string
+	Subject   string
+	UserID    uint
+	Email     string
+	CreatedAt time.Time
+}
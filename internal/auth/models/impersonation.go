@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Impersonation is the audit record of a token issued to an admin to act as
+// a user.
+type Impersonation struct {
+	ID        uint `gorm:"primarykey"`
+	AdminID   uint
+	UserID    uint
+	Reason    string
+	IP        string
+	UserAgent string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
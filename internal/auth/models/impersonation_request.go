@@ -0,0 +1,6 @@
+package models
+
+type ImpersonationRequest struct {
+	UserID int    `json:"user_id" binding:"required,gt=0"`
+	Reason string `json:"reason" binding:"required,max=500"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// IntrospectionResponse follows RFC 7662, inactive tokens only carry Active.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Act       *Actor `json:"act,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+const (
+	Issuer         = "chambeo-co"
+	ClientAudience = "chambeo-fe"
+	// EmailVerificationAudience keeps verification links from being
+	// accepted as access tokens.
+	EmailVerificationAudience = "chambeo-email-verification"
+	// MFAAudience is used by the mfa_pending token handed out between the
+	// password and the second factor steps of the login.
+	MFAAudience = "chambeo-mfa"
+	// OIDCStateAudience is used by the state cookie of social logins.
+	OIDCStateAudience = "chambeo-oidc-state"
+	// MagicLinkAudience is used by the passwordless login links.
+	MagicLinkAudience = "chambeo-magic-link"
+)
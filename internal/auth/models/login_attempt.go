@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginAttempt counts the consecutive failed logins of an account or an IP,
+// Key is prefixed with the kind of subject, e.g. "account:" or "ip:".
+type LoginAttempt struct {
+	Key           string `gorm:"primarykey"`
+	Failures      int
+	LastFailureAt time.Time
+}
@@ -0,0 +1,9 @@
+package models
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type MFAEnrollment struct {
+	UserID uint `gorm:"primarykey;autoIncrement:false"`
+	Secret string
+	// ConfirmedAt is nil until the user proves the authenticator works by
+	// sending a first code, only confirmed enrollments are enforced.
+	ConfirmedAt *time.Time
+	// LastUsedStep is the TOTP time step of the last accepted code, a code
+	// can not be used twice.
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+type MFARecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+const MFAPendingTokenType = "mfa_pending"
+
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAPendingResponse is returned by the login instead of a TokenResponse
+// when the account has two-factor authentication enabled.
+type MFAPendingResponse struct {
+	MFAToken  string `json:"mfa_token"`
+	ExpiresIn int64  `json:"expires_in"`
+	TokenType string `json:"token_type"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient is a confidential client allowed to use the
+// client_credentials grant.
+type OAuthClient struct {
+	ID         uint `gorm:"primarykey"`
+	ClientID   string
+	Name       string
+	SecretHash string
+	// Scopes and Audiences are separated by spaces.
+	Scopes    string
+	Audiences string
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (o *OAuthClient) ScopeList() []string {
+	return strings.Fields(o.Scopes)
+}
+
+func (o *OAuthClient) AudienceList() []string {
+	return strings.Fields(o.Audiences)
+}
+
+// TableName overrides the gorm default, which would be o_auth_clients.
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
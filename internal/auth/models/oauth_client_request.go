@@ -0,0 +1,8 @@
+package models
+
+type CreateOAuthClientRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// Audiences defaults to this API when it is empty.
+	Audiences []string `json:"audiences"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type OAuthClientResponse struct {
+	ClientID  string    `json:"client_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Audiences []string  `json:"audiences"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatedOAuthClientResponse is the only response that includes the secret.
+type CreatedOAuthClientResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret"`
+}
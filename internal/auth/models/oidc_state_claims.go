@@ -0,0 +1,21 @@
+package models
+
+import "github.com/golang-jwt/jwt/v5"
+
+// OIDCStateClaims keep what the callback of a social login needs to finish
+// the flow. They travel in an HttpOnly cookie, the jti is the state sent to
+// the provider.
+type OIDCStateClaims struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.RegisteredClaims
+}
+
+// OIDCAuthorization is the start of a social login, the client is sent to
+// URL and StateToken is kept for the callback.
+type OIDCAuthorization struct {
+	URL        string
+	StateToken string
+	ExpiresIn  int
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type PasswordResetToken struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,62 @@
+package models
+
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionUsersManage = "users:manage"
+	PermissionJobsPublish = "jobs:publish"
+	PermissionJobsApply   = "jobs:apply"
+	// PermissionClientsManage allows registering the clients of the
+	// client_credentials grant.
+	PermissionClientsManage = "clients:manage"
+	// PermissionUsersImpersonate allows issuing tokens to act as another
+	// user.
+	PermissionUsersImpersonate = "users:impersonate"
+	// PermissionAuditRead allows querying the security audit log.
+	PermissionAuditRead = "audit:read"
+)
+
+var permissions = map[string]bool{
+	PermissionUsersRead:        true,
+	PermissionUsersWrite:       true,
+	PermissionUsersManage:      true,
+	PermissionJobsPublish:      true,
+	PermissionJobsApply:        true,
+	PermissionClientsManage:    true,
+	PermissionUsersImpersonate: true,
+	PermissionAuditRead:        true,
+}
+
+// rolePermissions are granted to every user with the role, on top of the
+// permissions stored for the user.
+var rolePermissions = map[string][]string{
+	RoleUser:     {PermissionUsersRead, PermissionUsersWrite},
+	RoleEmployer: {PermissionUsersRead, PermissionUsersWrite, PermissionJobsPublish},
+	RoleWorker:   {PermissionUsersRead, PermissionUsersWrite, PermissionJobsApply},
+	RoleAdmin: {PermissionUsersRead, PermissionUsersWrite, PermissionUsersManage, PermissionJobsPublish, PermissionJobsApply,
+		PermissionClientsManage, PermissionUsersImpersonate, PermissionAuditRead},
+}
+
+// privilegedPermissions administer accounts, clients or the audit log, they
+// are only reached through a user login and never granted to clients of the
+// client_credentials grant.
+var privilegedPermissions = map[string]bool{
+	PermissionUsersManage:      true,
+	PermissionClientsManage:    true,
+	PermissionUsersImpersonate: true,
+	PermissionAuditRead:        true,
+}
+
+func IsValidPermission(permission string) bool {
+	return permissions[permission]
+}
+
+// IsClientScope reports whether the permission can be registered as a scope
+// of an oauth client.
+func IsClientScope(permission string) bool {
+	return IsValidPermission(permission) && !privilegedPermissions[permission]
+}
+
+func DefaultPermissions(role string) []string {
+	return append([]string{}, rolePermissions[role]...)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RefreshToken is persisted hashed. Every token issued from the same login
+// shares the SessionID, which identifies the token family.
+type RefreshToken struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint
+	SessionID string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
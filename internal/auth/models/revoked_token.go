@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RevokedToken is a denylist entry, kept until the token would have expired
+// on its own.
+type RevokedToken struct {
+	JTI       string `gorm:"primaryKey;column:jti"`
+	ExpiresAt time.Time
+}
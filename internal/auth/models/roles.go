@@ -0,0 +1,32 @@
+package models
+
+const (
+	RoleUser     = "user"
+	RoleAdmin    = "admin"
+	RoleEmployer = "employer"
+	RoleWorker   = "worker"
+)
+
+// elevatedRoles can act on accounts other than their own.
+var elevatedRoles = map[string]bool{
+	RoleAdmin: true,
+}
+
+// selfAssignableRoles can be chosen by the user at sign up.
+var selfAssignableRoles = map[string]bool{
+	RoleUser:     true,
+	RoleEmployer: true,
+	RoleWorker:   true,
+}
+
+func IsElevatedRole(role string) bool {
+	return elevatedRoles[role]
+}
+
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || selfAssignableRoles[role]
+}
+
+func IsSelfAssignableRole(role string) bool {
+	return selfAssignableRoles[role]
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is a login, its ID is the SessionID shared by the refresh tokens
+// of the same family and carried in the sid claim of the access tokens.
+type Session struct {
+	ID         string `gorm:"primarykey"`
+	UserID     uint
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  *time.Time
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// ThrottledAction is the last time an action ran for a key, Key is prefixed
+// with the scope of the action, e.g. "password_reset:".
+type ThrottledAction struct {
+	Key          string `gorm:"primarykey"`
+	LastActionAt time.Time
+}
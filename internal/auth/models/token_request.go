@@ -1,5 +1,19 @@
 package models
 
+const (
+	GrantTypePassword          = "password"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// TokenRequest is the body of the token endpoint. Email and Password are
+// used by the password grant, the default one, and the client fields by the
+// client_credentials grant.
 type TokenRequest struct {
-	AccessToken string `json:"access_token" binding:"required"`
+	GrantType    string `json:"grant_type" form:"grant_type"`
+	Email        string `json:"email" form:"email"`
+	Password     string `json:"password" form:"password"`
+	ClientID     string `json:"client_id" form:"client_id"`
+	ClientSecret string `json:"client_secret" form:"client_secret"`
+	Scope        string `json:"scope" form:"scope"`
+	Audience     string `json:"audience" form:"audience"`
 }
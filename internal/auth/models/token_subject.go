@@ -0,0 +1,19 @@
+package models
+
+type TokenSubject struct {
+	UserID       string
+	Email        string
+	Role         string
+	Permissions  []string
+	SessionID    string
+	TokenVersion int
+	Scope        string
+	ClientID     string
+	// UserAgent and IP describe the device, they are stored with the
+	// session when it starts and the IP is refreshed on every refresh.
+	UserAgent string
+	IP        string
+	// ActorID and ActorEmail identify the admin of an impersonation token.
+	ActorID    string
+	ActorEmail string
+}
@@ -0,0 +1,8 @@
+package models
+
+import "github.com/golang-jwt/jwt/v5"
+
+type VerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
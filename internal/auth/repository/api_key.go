@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type APIKeyRepositoryInterface interface {
+	Create(key *models.APIKey) (*models.APIKey, error)
+	GetByHash(keyHash string) (*models.APIKey, error)
+	ListByUser(userID uint) ([]models.APIKey, error)
+	Revoke(id uint, userID uint, revokedAt time.Time) (bool, error)
+	MarkUsed(id uint, usedAt time.Time) error
+}
+
+type APIKeyRepository struct {
+	DB gorm.DB
+}
+
+func NewAPIKey(db gorm.DB) APIKeyRepositoryInterface {
+	return &APIKeyRepository{DB: db}
+}
+
+func (r *APIKeyRepository) Create(key *models.APIKey) (*models.APIKey, error) {
+	if tx := r.DB.Create(key); tx.Error != nil {
+		log.Println("error inserting api key: ", tx.Error.Error())
+		return nil, errors.New("error inserting api key in DB")
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	var key *models.APIKey
+	if tx := r.DB.Where("key_hash = ?", keyHash).First(&key); tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving api key %s", tx.Error.Error()))
+		return nil, errors.New("error retrieving api key from DB")
+	}
+	return key, nil
+}
+
+// ListByUser returns the keys of the user that have not been revoked,
+// expired keys are included so the user can see and revoke them.
+func (r *APIKeyRepository) ListByUser(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if tx := r.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("id").Find(&keys); tx.Error != nil {
+		log.Println(fmt.Sprintf("error listing api keys of user %d %s", userID, tx.Error.Error()))
+		return nil, errors.New("error retrieving api keys from DB")
+	}
+	return keys, nil
+}
+
+// Revoke returns false when the key does not belong to the user or was
+// already revoked.
+func (r *APIKeyRepository) Revoke(id uint, userID uint, revokedAt time.Time) (bool, error) {
+	tx := r.DB.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", revokedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking api key %d %s", id, tx.Error.Error()))
+		return false, errors.New("error updating api key in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *APIKeyRepository) MarkUsed(id uint, usedAt time.Time) error {
+	tx := r.DB.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error updating last use of api key %d %s", id, tx.Error.Error()))
+		return errors.New("error updating api key in DB")
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyRepository_Create(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, key *models.APIKey)
+		asserts        func(t *testing.T, key *models.APIKey, err error)
+	}{
+		{
+			name: "create api key should be successful",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, key *models.APIKey) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `api_keys` (`user_id`,`name`,`prefix`,`key_hash`,`scopes`,`expires_at`,`last_used_at`,`revoked_at`,`created_at`) VALUES (?,?,?,?,?,?,?,?,?)")).
+					WithArgs(key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, nil, nil, nil, key.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, key *models.APIKey, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), key.ID)
+			},
+		},
+		{
+			name: "create api key should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, key *models.APIKey) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `api_keys`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, key *models.APIKey, err error) {
+				assert.Nil(t, key)
+				assert.Equal(t, "error inserting api key in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			key := &models.APIKey{UserID: 1, Name: "ci", Prefix: "chk_abcdef", KeyHash: "hash", Scopes: "users:read", CreatedAt: createdAt}
+
+			tt.mockedBehavior(t, mock, key)
+
+			repository := NewAPIKey(*gormDb)
+
+			result, err := repository.Create(key)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestAPIKeyRepository_GetByHash(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, key *models.APIKey, err error)
+	}{
+		{
+			name: "existing hash should return key",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "key_hash", "scopes"}).AddRow(1, 7, "hash", "users:read")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE key_hash = ? ORDER BY `api_keys`.`id` LIMIT 1")).
+					WithArgs("hash").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, key *models.APIKey, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(7), key.UserID)
+				assert.Equal(t, []string{"users:read"}, key.ScopeList())
+			},
+		},
+		{
+			name: "unknown hash should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys`")).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, key *models.APIKey, err error) {
+				assert.Nil(t, key)
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewAPIKey(*gormDb)
+
+			result, err := repository.GetByHash("hash")
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestAPIKeyRepository_ListByUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, keys []models.APIKey, err error)
+	}{
+		{
+			name: "active keys of the user should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(1, 7, "ci").AddRow(2, 7, "backup")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE user_id = ? AND revoked_at IS NULL ORDER BY id")).
+					WithArgs(7).
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, keys []models.APIKey, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, keys, 2)
+				assert.Equal(t, "backup", keys[1].Name)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, keys []models.APIKey, err error) {
+				assert.Nil(t, keys)
+				assert.Equal(t, "error retrieving api keys from DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewAPIKey(*gormDb)
+
+			keys, err := repository.ListByUser(7)
+
+			tt.asserts(t, keys, err)
+		})
+	}
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, revoked bool, err error)
+	}{
+		{
+			name: "active key of the user should be revoked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `api_keys` SET `revoked_at`=? WHERE id = ? AND user_id = ? AND revoked_at IS NULL")).
+					WithArgs(revokedAt, 1, 7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+			},
+		},
+		{
+			name: "key of another user should not be revoked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `api_keys` SET `revoked_at`=? WHERE id = ? AND user_id = ? AND revoked_at IS NULL")).
+					WithArgs(revokedAt, 1, 7).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, revoked)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `api_keys` SET `revoked_at`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.Error(t, err)
+				assert.False(t, revoked)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewAPIKey(*gormDb)
+
+			revoked, err := repository.Revoke(1, 7, revokedAt)
+
+			tt.asserts(t, revoked, err)
+		})
+	}
+}
+
+func TestAPIKeyRepository_MarkUsed(t *testing.T) {
+	usedAt := time.Now()
+
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `api_keys` SET `last_used_at`=? WHERE id = ?")).
+		WithArgs(usedAt, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := NewAPIKey(*gormDb).MarkUsed(1, usedAt)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// DenylistRepositoryInterface stores the jti of revoked access tokens until
+// their original expiration.
+type DenylistRepositoryInterface interface {
+	Add(jti string, expiresAt time.Time) error
+	Contains(jti string) (bool, error)
+	// Consume adds the jti and reports false when it was already there, so
+	// single use tokens can be spent only once.
+	Consume(jti string, expiresAt time.Time) (bool, error)
+}
+
+type DenylistRepository struct {
+	DB gorm.DB
+}
+
+func NewDenylist(db gorm.DB) DenylistRepositoryInterface {
+	return &DenylistRepository{DB: db}
+}
+
+func (d *DenylistRepository) Add(jti string, expiresAt time.Time) error {
+	tx := d.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt})
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking token %s %s", jti, tx.Error.Error()))
+		return errors.New("error inserting revoked token in DB")
+	}
+
+	if tx := d.DB.Where("expires_at <= ?", time.Now()).Delete(&models.RevokedToken{}); tx.Error != nil {
+		log.Println("error purging expired revoked tokens: ", tx.Error.Error())
+	}
+	return nil
+}
+
+func (d *DenylistRepository) Contains(jti string) (bool, error) {
+	var count int64
+	tx := d.DB.Model(&models.RevokedToken{}).
+		Where("jti = ? AND expires_at > ?", jti, time.Now()).
+		Count(&count)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error checking revoked token %s %s", jti, tx.Error.Error()))
+		return false, errors.New("error retrieving revoked token from DB")
+	}
+	return count > 0, nil
+}
+
+func (d *DenylistRepository) Consume(jti string, expiresAt time.Time) (bool, error) {
+	tx := d.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt})
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error consuming token %s %s", jti, tx.Error.Error()))
+		return false, errors.New("error inserting revoked token in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryDenylistRepository keeps revoked tokens in process memory. It is
+// meant for tests and single instance deployments.
+type MemoryDenylistRepository struct {
+	mutex   sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+func NewMemoryDenylist() DenylistRepositoryInterface {
+	return &MemoryDenylistRepository{entries: map[string]time.Time{}, now: time.Now}
+}
+
+func (m *MemoryDenylistRepository) Add(jti string, expiresAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	for key, expiration := range m.entries {
+		if !expiration.After(now) {
+			delete(m.entries, key)
+		}
+	}
+	if expiresAt.After(now) {
+		m.entries[jti] = expiresAt
+	}
+	return nil
+}
+
+func (m *MemoryDenylistRepository) Contains(jti string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	expiresAt, ok := m.entries[jti]
+	if !ok {
+		return false, nil
+	}
+	if !expiresAt.After(m.now()) {
+		delete(m.entries, jti)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *MemoryDenylistRepository) Consume(jti string, expiresAt time.Time) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if stored, ok := m.entries[jti]; ok && stored.After(m.now()) {
+		return false, nil
+	}
+	m.entries[jti] = expiresAt
+	return true, nil
+}
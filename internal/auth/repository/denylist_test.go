@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestDenylistRepository_Add(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "add should insert the jti and purge expired entries",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens` (`jti`,`expires_at`) VALUES (?,?) ON DUPLICATE KEY UPDATE `jti`=`jti`")).
+					WithArgs("jti", expiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `revoked_tokens` WHERE expires_at <= ?")).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "add should return db error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error inserting revoked token in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewDenylist(*gormDb)
+
+			err := repository.Add("jti", expiresAt)
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func TestDenylistRepository_Contains(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, revoked bool, err error)
+	}{
+		{
+			name: "revoked jti should be found",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `revoked_tokens` WHERE jti = ? AND expires_at > ?")).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+			},
+		},
+		{
+			name: "unknown jti should not be found",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `revoked_tokens` WHERE jti = ? AND expires_at > ?")).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, revoked)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `revoked_tokens`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.Error(t, err)
+				assert.False(t, revoked)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewDenylist(*gormDb)
+
+			revoked, err := repository.Contains("jti")
+
+			tt.asserts(t, revoked, err)
+		})
+	}
+}
+
+func TestDenylistRepository_Consume(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, consumed bool, err error)
+	}{
+		{
+			name: "unused jti should be consumed",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens` (`jti`,`expires_at`) VALUES (?,?) ON DUPLICATE KEY UPDATE `jti`=`jti`")).
+					WithArgs("jti", expiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, consumed bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, consumed)
+			},
+		},
+		{
+			name: "used jti should not be consumed again",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens`")).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, consumed bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, consumed)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, consumed bool, err error) {
+				assert.Error(t, err)
+				assert.False(t, consumed)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewDenylist(*gormDb)
+
+			consumed, err := repository.Consume("jti", expiresAt)
+
+			tt.asserts(t, consumed, err)
+		})
+	}
+}
+
+func TestMemoryDenylistRepository(t *testing.T) {
+	now := time.Now()
+	repository := &MemoryDenylistRepository{entries: map[string]time.Time{}, now: func() time.Time { return now }}
+
+	assert.NoError(t, repository.Add("active", now.Add(time.Minute)))
+	assert.NoError(t, repository.Add("alreadyExpired", now.Add(-time.Minute)))
+
+	revoked, err := repository.Contains("active")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, _ = repository.Contains("alreadyExpired")
+	assert.False(t, revoked)
+
+	revoked, _ = repository.Contains("unknown")
+	assert.False(t, revoked)
+
+	now = now.Add(2 * time.Minute)
+
+	revoked, _ = repository.Contains("active")
+	assert.False(t, revoked)
+	assert.Empty(t, repository.entries)
+}
+
+func TestMemoryDenylistRepository_Consume(t *testing.T) {
+	now := time.Now()
+	repository := &MemoryDenylistRepository{entries: map[string]time.Time{}, now: func() time.Time { return now }}
+
+	consumed, err := repository.Consume("link", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+
+	consumed, _ = repository.Consume("link", now.Add(time.Minute))
+	assert.False(t, consumed)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+)
+
+type ExternalIdentityRepositoryInterface interface {
+	Get(provider string, subject string) (*models.ExternalIdentity, error)
+	Create(identity *models.ExternalIdentity) (*models.ExternalIdentity, error)
+}
+
+type ExternalIdentityRepository struct {
+	DB gorm.DB
+}
+
+func NewExternalIdentity(db gorm.DB) ExternalIdentityRepositoryInterface {
+	return &ExternalIdentityRepository{DB: db}
+}
+
+// Get returns nil without error when the identity was never linked.
+func (r *ExternalIdentityRepository) Get(provider string, subject string) (*models.ExternalIdentity, error) {
+	var identity *models.ExternalIdentity
+	tx := r.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving %s identity %s %s", provider, subject, tx.Error.Error()))
+		return nil, errors.New("error retrieving external identity from DB")
+	}
+	return identity, nil
+}
+
+func (r *ExternalIdentityRepository) Create(identity *models.ExternalIdentity) (*models.ExternalIdentity, error) {
+	if tx := r.DB.Create(identity); tx.Error != nil {
+		log.Println(fmt.Sprintf("error inserting %s identity of user %d %s", identity.Provider, identity.UserID, tx.Error.Error()))
+		return nil, errors.New("error inserting external identity in DB")
+	}
+	return identity, nil
+}
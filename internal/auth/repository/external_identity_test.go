@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestExternalIdentityRepository_Get(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, identity *models.ExternalIdentity, err error)
+	}{
+		{
+			name: "linked identity should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "provider", "subject", "user_id", "email"}).
+					AddRow(1, "google", "1234567890", 7, "meze@gmail.com")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `external_identities` WHERE provider = ? AND subject = ? ORDER BY `external_identities`.`id` LIMIT 1")).
+					WithArgs("google", "1234567890").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, identity *models.ExternalIdentity, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(7), identity.UserID)
+			},
+		},
+		{
+			name: "unknown identity should return nil without error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `external_identities`")).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, identity *models.ExternalIdentity, err error) {
+				assert.NoError(t, err)
+				assert.Nil(t, identity)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `external_identities`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, identity *models.ExternalIdentity, err error) {
+				assert.Nil(t, identity)
+				assert.Equal(t, "error retrieving external identity from DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewExternalIdentity(*gormDb)
+
+			identity, err := repository.Get("google", "1234567890")
+
+			tt.asserts(t, identity, err)
+		})
+	}
+}
+
+func TestExternalIdentityRepository_Create(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, identity *models.ExternalIdentity, err error)
+	}{
+		{
+			name: "identity should be inserted",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `external_identities` (`provider`,`subject`,`user_id`,`email`,`created_at`) VALUES (?,?,?,?,?)")).
+					WithArgs("google", "1234567890", 7, "meze@gmail.com", createdAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, identity *models.ExternalIdentity, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), identity.ID)
+			},
+		},
+		{
+			name: "duplicated identity should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `external_identities`")).
+					WillReturnError(errors.New("duplicate key"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, identity *models.ExternalIdentity, err error) {
+				assert.Nil(t, identity)
+				assert.Equal(t, "error inserting external identity in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewExternalIdentity(*gormDb)
+
+			identity, err := repository.Create(&models.ExternalIdentity{
+				Provider: "google", Subject: "1234567890", UserID: 7, Email: "meze@gmail.com", CreatedAt: createdAt,
+			})
+
+			tt.asserts(t, identity, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
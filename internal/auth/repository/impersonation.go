@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+)
+
+// ImpersonationRepositoryInterface is the audit trail of impersonations, its
+// records are never updated nor deleted.
+type ImpersonationRepositoryInterface interface {
+	Create(impersonation *models.Impersonation) (*models.Impersonation, error)
+}
+
+type ImpersonationRepository struct {
+	DB gorm.DB
+}
+
+func NewImpersonation(db gorm.DB) ImpersonationRepositoryInterface {
+	return &ImpersonationRepository{DB: db}
+}
+
+func (r *ImpersonationRepository) Create(impersonation *models.Impersonation) (*models.Impersonation, error) {
+	if tx := r.DB.Create(impersonation); tx.Error != nil {
+		log.Println("error inserting impersonation: ", tx.Error.Error())
+		return nil, errors.New("error inserting impersonation in DB")
+	}
+	return impersonation, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestImpersonationRepository_Create(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, impersonation *models.Impersonation)
+		asserts        func(t *testing.T, impersonation *models.Impersonation, err error)
+	}{
+		{
+			name: "create impersonation should be successful",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, impersonation *models.Impersonation) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `impersonations` (`admin_id`,`user_id`,`reason`,`ip`,`user_agent`,`expires_at`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+					WithArgs(impersonation.AdminID, impersonation.UserID, impersonation.Reason, impersonation.IP, impersonation.UserAgent,
+						impersonation.ExpiresAt, impersonation.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, impersonation *models.Impersonation, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), impersonation.ID)
+			},
+		},
+		{
+			name: "create impersonation should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, impersonation *models.Impersonation) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `impersonations`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, impersonation *models.Impersonation, err error) {
+				assert.Nil(t, impersonation)
+				assert.Equal(t, "error inserting impersonation in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			impersonation := &models.Impersonation{AdminID: 1, UserID: 7, Reason: "ticket 42", IP: "10.0.0.1", UserAgent: "Firefox",
+				ExpiresAt: now.Add(10 * time.Minute), CreatedAt: now}
+
+			tt.mockedBehavior(t, mock, impersonation)
+
+			repository := NewImpersonation(*gormDb)
+
+			result, err := repository.Create(impersonation)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// LoginAttemptRepositoryInterface keeps the failed login counters. Failures
+// older than resetBefore are forgotten, the counter starts again from one.
+type LoginAttemptRepositoryInterface interface {
+	Get(key string) (*models.LoginAttempt, error)
+	RegisterFailure(key string, failedAt time.Time, resetBefore time.Time) (*models.LoginAttempt, error)
+	Reset(key string) error
+}
+
+type LoginAttemptRepository struct {
+	DB gorm.DB
+}
+
+func NewLoginAttempt(db gorm.DB) LoginAttemptRepositoryInterface {
+	return &LoginAttemptRepository{DB: db}
+}
+
+// Get returns nil without error when the key has no failures.
+func (r *LoginAttemptRepository) Get(key string) (*models.LoginAttempt, error) {
+	var attempt *models.LoginAttempt
+	tx := r.DB.Where("key = ?", key).First(&attempt)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving login attempts of %s %s", key, tx.Error.Error()))
+		return nil, errors.New("error retrieving login attempts from DB")
+	}
+	return attempt, nil
+}
+
+// RegisterFailure increments the counter in a single upsert so concurrent
+// attempts are all counted.
+func (r *LoginAttemptRepository) RegisterFailure(key string, failedAt time.Time, resetBefore time.Time) (*models.LoginAttempt, error) {
+	tx := r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", resetBefore),
+			"last_failure_at": failedAt,
+		}),
+	}).Create(&models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: failedAt})
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error registering failed login of %s %s", key, tx.Error.Error()))
+		return nil, errors.New("error inserting login attempt in DB")
+	}
+
+	if tx := r.DB.Where("last_failure_at < ?", resetBefore).Delete(&models.LoginAttempt{}); tx.Error != nil {
+		log.Println("error purging expired login attempts: ", tx.Error.Error())
+	}
+	return r.Get(key)
+}
+
+func (r *LoginAttemptRepository) Reset(key string) error {
+	if tx := r.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}); tx.Error != nil {
+		log.Println(fmt.Sprintf("error resetting login attempts of %s %s", key, tx.Error.Error()))
+		return errors.New("error deleting login attempts from DB")
+	}
+	return nil
+}
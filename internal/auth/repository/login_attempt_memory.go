@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"sync"
+	"time"
+)
+
+// MemoryLoginAttemptRepository keeps the counters in process memory. Each
+// instance counts on its own, so it is only accurate with a single instance.
+type MemoryLoginAttemptRepository struct {
+	mutex   sync.Mutex
+	entries map[string]models.LoginAttempt
+}
+
+func NewMemoryLoginAttempt() LoginAttemptRepositoryInterface {
+	return &MemoryLoginAttemptRepository{entries: map[string]models.LoginAttempt{}}
+}
+
+func (m *MemoryLoginAttemptRepository) Get(key string) (*models.LoginAttempt, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	attempt, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (m *MemoryLoginAttemptRepository) RegisterFailure(key string, failedAt time.Time, resetBefore time.Time) (*models.LoginAttempt, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for entryKey, entry := range m.entries {
+		if entry.LastFailureAt.Before(resetBefore) {
+			delete(m.entries, entryKey)
+		}
+	}
+
+	attempt := m.entries[key]
+	attempt.Key = key
+	attempt.Failures++
+	attempt.LastFailureAt = failedAt
+	m.entries[key] = attempt
+	return &attempt, nil
+}
+
+func (m *MemoryLoginAttemptRepository) Reset(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
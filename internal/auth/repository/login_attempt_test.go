@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLoginAttemptRepository_RegisterFailure(t *testing.T) {
+	failedAt := time.Now()
+	resetBefore := failedAt.Add(-time.Hour)
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, failures int, err error)
+	}{
+		{
+			name: "failure should be counted and returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `login_attempts` (`key`,`failures`,`last_failure_at`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `failures`=CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,`last_failure_at`=?")).
+					WithArgs("account:meze@gmail.com", 1, failedAt, resetBefore, failedAt).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE last_failure_at < ?")).
+					WithArgs(resetBefore).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				rows := sqlmock.NewRows([]string{"key", "failures", "last_failure_at"}).AddRow("account:meze@gmail.com", 4, failedAt)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `login_attempts` WHERE key = ? ORDER BY `login_attempts`.`key` LIMIT 1")).
+					WithArgs("account:meze@gmail.com").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, failures int, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 4, failures)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `login_attempts`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, failures int, err error) {
+				assert.Equal(t, "error inserting login attempt in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewLoginAttempt(*gormDb)
+
+			attempt, err := repository.RegisterFailure("account:meze@gmail.com", failedAt, resetBefore)
+
+			failures := 0
+			if attempt != nil {
+				failures = attempt.Failures
+			}
+			tt.asserts(t, failures, err)
+		})
+	}
+}
+
+func TestLoginAttemptRepository_Get(t *testing.T) {
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `login_attempts` WHERE key = ?")).
+		WithArgs("ip:10.0.0.1").
+		WillReturnRows(&sqlmock.Rows{})
+
+	attempt, err := NewLoginAttempt(*gormDb).Get("ip:10.0.0.1")
+
+	assert.NoError(t, err)
+	assert.Nil(t, attempt)
+}
+
+func TestLoginAttemptRepository_Reset(t *testing.T) {
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE key = ?")).
+		WithArgs("account:meze@gmail.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := NewLoginAttempt(*gormDb).Reset("account:meze@gmail.com")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
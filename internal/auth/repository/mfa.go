@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type MFARepositoryInterface interface {
+	Get(userID uint) (*models.MFAEnrollment, error)
+	Enroll(enrollment *models.MFAEnrollment) error
+	Confirm(userID uint, confirmedAt time.Time, codeHashes []string) error
+	UseStep(userID uint, step int64) (bool, error)
+	UpdateSecret(userID uint, secret string) error
+	UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) (bool, error)
+	Delete(userID uint) error
+}
+
+type MFARepository struct {
+	DB gorm.DB
+}
+
+func NewMFA(db gorm.DB) MFARepositoryInterface {
+	return &MFARepository{DB: db}
+}
+
+// Get returns nil without error when the user never started an enrollment.
+func (r *MFARepository) Get(userID uint) (*models.MFAEnrollment, error) {
+	var enrollment *models.MFAEnrollment
+	tx := r.DB.Where("user_id = ?", userID).First(&enrollment)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving mfa enrollment of user %d %s", userID, tx.Error.Error()))
+		return nil, errors.New("error retrieving mfa enrollment from DB")
+	}
+	return enrollment, nil
+}
+
+// Enroll replaces any previous enrollment of the user with a new pending one.
+func (r *MFARepository) Enroll(enrollment *models.MFAEnrollment) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", enrollment.UserID).Delete(&models.MFAEnrollment{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(enrollment).Error
+	})
+	if err != nil {
+		log.Println(fmt.Sprintf("error enrolling mfa of user %d %s", enrollment.UserID, err.Error()))
+		return errors.New("error inserting mfa enrollment in DB")
+	}
+	return nil
+}
+
+// Confirm enables the enrollment and replaces the recovery codes of the user.
+func (r *MFARepository) Confirm(userID uint, confirmedAt time.Time, codeHashes []string) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.MFAEnrollment{}).Where("user_id = ?", userID).Update("confirmed_at", confirmedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]models.MFARecoveryCode, 0, len(codeHashes))
+		for _, codeHash := range codeHashes {
+			codes = append(codes, models.MFARecoveryCode{UserID: userID, CodeHash: codeHash})
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		log.Println(fmt.Sprintf("error confirming mfa of user %d %s", userID, err.Error()))
+		return errors.New("error confirming mfa enrollment in DB")
+	}
+	return nil
+}
+
+// UseStep records step as the last accepted code. It returns false when a
+// code of the same or a later step was already accepted.
+func (r *MFARepository) UseStep(userID uint, step int64) (bool, error) {
+	tx := r.DB.Model(&models.MFAEnrollment{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error updating mfa step of user %d %s", userID, tx.Error.Error()))
+		return false, errors.New("error updating mfa enrollment in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *MFARepository) UpdateSecret(userID uint, secret string) error {
+	tx := r.DB.Model(&models.MFAEnrollment{}).Where("user_id = ?", userID).Update("secret", secret)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error updating mfa secret of user %d %s", userID, tx.Error.Error()))
+		return errors.New("error updating mfa enrollment in DB")
+	}
+	return nil
+}
+
+func (r *MFARepository) UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	tx := r.DB.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", usedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error using mfa recovery code of user %d %s", userID, tx.Error.Error()))
+		return false, errors.New("error updating mfa recovery code in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *MFARepository) Delete(userID uint) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.MFAEnrollment{}).Error
+	})
+	if err != nil {
+		log.Println(fmt.Sprintf("error deleting mfa of user %d %s", userID, err.Error()))
+		return errors.New("error deleting mfa enrollment from DB")
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestMFARepository_Get(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, enrollment *models.MFAEnrollment, err error)
+	}{
+		{
+			name: "existing enrollment should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"user_id", "secret", "last_used_step"}).AddRow(1, "SECRET", 10)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `mfa_enrollments` WHERE user_id = ? ORDER BY `mfa_enrollments`.`user_id` LIMIT 1")).
+					WithArgs(1).
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, enrollment *models.MFAEnrollment, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "SECRET", enrollment.Secret)
+				assert.Equal(t, int64(10), enrollment.LastUsedStep)
+			},
+		},
+		{
+			name: "missing enrollment should return nil without error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `mfa_enrollments`")).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, enrollment *models.MFAEnrollment, err error) {
+				assert.NoError(t, err)
+				assert.Nil(t, enrollment)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `mfa_enrollments`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, enrollment *models.MFAEnrollment, err error) {
+				assert.Nil(t, enrollment)
+				assert.Equal(t, "error retrieving mfa enrollment from DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewMFA(*gormDb)
+
+			enrollment, err := repository.Get(1)
+
+			tt.asserts(t, enrollment, err)
+		})
+	}
+}
+
+func TestMFARepository_Enroll(t *testing.T) {
+	createdAt := time.Now()
+
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `mfa_enrollments` WHERE user_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `mfa_enrollments` (`user_id`,`secret`,`confirmed_at`,`last_used_step`,`created_at`) VALUES (?,?,?,?,?)")).
+		WithArgs(1, "SECRET", nil, 0, createdAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := NewMFA(*gormDb).Enroll(&models.MFAEnrollment{UserID: 1, Secret: "SECRET", CreatedAt: createdAt})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMFARepository_Confirm(t *testing.T) {
+	confirmedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, mock sqlmock.Sqlmock, err error)
+	}{
+		{
+			name: "confirm should enable the enrollment and replace recovery codes",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `mfa_enrollments` SET `confirmed_at`=? WHERE user_id = ?")).
+					WithArgs(confirmedAt, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `mfa_recovery_codes` WHERE user_id = ?")).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `mfa_recovery_codes` (`user_id`,`code_hash`,`used_at`,`created_at`) VALUES (?,?,?,?),(?,?,?,?)")).
+					WillReturnResult(sqlmock.NewResult(1, 2))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, mock sqlmock.Sqlmock, err error) {
+				assert.NoError(t, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			name: "db error should rollback",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `mfa_enrollments`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, mock sqlmock.Sqlmock, err error) {
+				assert.Equal(t, "error confirming mfa enrollment in DB", err.Error())
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewMFA(*gormDb)
+
+			err := repository.Confirm(1, confirmedAt, []string{"hash-1", "hash-2"})
+
+			tt.asserts(t, mock, err)
+		})
+	}
+}
+
+func TestMFARepository_UseStep(t *testing.T) {
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expected     bool
+	}{
+		{name: "newer step should be accepted", rowsAffected: 1, expected: true},
+		{name: "replayed step should be rejected", rowsAffected: 0, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `mfa_enrollments` SET `last_used_step`=? WHERE user_id = ? AND last_used_step < ?")).
+				WithArgs(42, 1, 42).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+			mock.ExpectCommit()
+
+			used, err := NewMFA(*gormDb).UseStep(1, 42)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, used)
+		})
+	}
+}
+
+func TestMFARepository_UpdateSecret(t *testing.T) {
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `mfa_enrollments` SET `secret`=? WHERE user_id = ?")).
+		WithArgs("v1:sealed", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := NewMFA(*gormDb).UpdateSecret(1, "v1:sealed")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMFARepository_UseRecoveryCode(t *testing.T) {
+	usedAt := time.Now()
+
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expected     bool
+	}{
+		{name: "unused code should be accepted", rowsAffected: 1, expected: true},
+		{name: "used or unknown code should be rejected", rowsAffected: 0, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `mfa_recovery_codes` SET `used_at`=? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL")).
+				WithArgs(usedAt, 1, "hash").
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+			mock.ExpectCommit()
+
+			used, err := NewMFA(*gormDb).UseRecoveryCode(1, "hash", usedAt)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, used)
+		})
+	}
+}
+
+func TestMFARepository_Delete(t *testing.T) {
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `mfa_recovery_codes` WHERE user_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `mfa_enrollments` WHERE user_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := NewMFA(*gormDb).Delete(1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
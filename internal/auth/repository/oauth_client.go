@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type OAuthClientRepositoryInterface interface {
+	Create(client *models.OAuthClient) (*models.OAuthClient, error)
+	GetByClientID(clientID string) (*models.OAuthClient, error)
+	List() ([]models.OAuthClient, error)
+	Revoke(clientID string, revokedAt time.Time) (bool, error)
+}
+
+type OAuthClientRepository struct {
+	DB gorm.DB
+}
+
+func NewOAuthClient(db gorm.DB) OAuthClientRepositoryInterface {
+	return &OAuthClientRepository{DB: db}
+}
+
+func (r *OAuthClientRepository) Create(client *models.OAuthClient) (*models.OAuthClient, error) {
+	if tx := r.DB.Create(client); tx.Error != nil {
+		log.Println("error inserting oauth client: ", tx.Error.Error())
+		return nil, errors.New("error inserting oauth client in DB")
+	}
+	return client, nil
+}
+
+func (r *OAuthClientRepository) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	var client *models.OAuthClient
+	if tx := r.DB.Where("client_id = ?", clientID).First(&client); tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving oauth client %s", tx.Error.Error()))
+		return nil, errors.New("error retrieving oauth client from DB")
+	}
+	return client, nil
+}
+
+func (r *OAuthClientRepository) List() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	if tx := r.DB.Where("revoked_at IS NULL").Order("id").Find(&clients); tx.Error != nil {
+		log.Println(fmt.Sprintf("error listing oauth clients %s", tx.Error.Error()))
+		return nil, errors.New("error retrieving oauth clients from DB")
+	}
+	return clients, nil
+}
+
+// Revoke returns false when the client does not exist or was already
+// revoked.
+func (r *OAuthClientRepository) Revoke(clientID string, revokedAt time.Time) (bool, error) {
+	tx := r.DB.Model(&models.OAuthClient{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", revokedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking oauth client %s %s", clientID, tx.Error.Error()))
+		return false, errors.New("error updating oauth client in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
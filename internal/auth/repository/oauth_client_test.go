@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestOAuthClientRepository_Create(t *testing.T) {
+	createdAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, client *models.OAuthClient)
+		asserts        func(t *testing.T, client *models.OAuthClient, err error)
+	}{
+		{
+			name: "create oauth client should be successful",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, client *models.OAuthClient) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `oauth_clients` (`client_id`,`name`,`secret_hash`,`scopes`,`audiences`,`revoked_at`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+					WithArgs(client.ClientID, client.Name, client.SecretHash, client.Scopes, client.Audiences, nil, client.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, client *models.OAuthClient, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), client.ID)
+			},
+		},
+		{
+			name: "create oauth client should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, client *models.OAuthClient) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `oauth_clients`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, client *models.OAuthClient, err error) {
+				assert.Nil(t, client)
+				assert.Equal(t, "error inserting oauth client in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			client := &models.OAuthClient{ClientID: "worker", Name: "Worker", SecretHash: "hash", Scopes: "users:read",
+				Audiences: "chambeo-fe", CreatedAt: createdAt}
+
+			tt.mockedBehavior(t, mock, client)
+
+			repository := NewOAuthClient(*gormDb)
+
+			result, err := repository.Create(client)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestOAuthClientRepository_GetByClientID(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, client *models.OAuthClient, err error)
+	}{
+		{
+			name: "existing client should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "client_id", "scopes", "audiences"}).AddRow(1, "worker", "users:read", "chambeo-fe notifications")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `oauth_clients` WHERE client_id = ? ORDER BY `oauth_clients`.`id` LIMIT 1")).
+					WithArgs("worker").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, client *models.OAuthClient, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"chambeo-fe", "notifications"}, client.AudienceList())
+			},
+		},
+		{
+			name: "unknown client should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `oauth_clients`")).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, client *models.OAuthClient, err error) {
+				assert.Nil(t, client)
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewOAuthClient(*gormDb)
+
+			result, err := repository.GetByClientID("worker")
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestOAuthClientRepository_List(t *testing.T) {
+	gormDb, mock := setupMockedDB(t)
+	rows := sqlmock.NewRows([]string{"id", "client_id"}).AddRow(1, "worker").AddRow(2, "notifications")
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `oauth_clients` WHERE revoked_at IS NULL ORDER BY id")).
+		WillReturnRows(rows)
+
+	clients, err := NewOAuthClient(*gormDb).List()
+
+	assert.NoError(t, err)
+	assert.Len(t, clients, 2)
+	assert.Equal(t, "notifications", clients[1].ClientID)
+}
+
+func TestOAuthClientRepository_Revoke(t *testing.T) {
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, revoked bool, err error)
+	}{
+		{
+			name: "active client should be revoked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `oauth_clients` SET `revoked_at`=? WHERE client_id = ? AND revoked_at IS NULL")).
+					WithArgs(revokedAt, "worker").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+			},
+		},
+		{
+			name: "unknown client should not be revoked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `oauth_clients` SET `revoked_at`=? WHERE client_id = ? AND revoked_at IS NULL")).
+					WithArgs(revokedAt, "worker").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, revoked bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, revoked)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewOAuthClient(*gormDb)
+
+			revoked, err := repository.Revoke("worker", revokedAt)
+
+			tt.asserts(t, revoked, err)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type PasswordResetTokenRepositoryInterface interface {
+	Create(token *models.PasswordResetToken) (*models.PasswordResetToken, error)
+	GetByHash(tokenHash string) (*models.PasswordResetToken, error)
+	MarkUsed(id uint, usedAt time.Time) (bool, error)
+}
+
+type PasswordResetTokenRepository struct {
+	DB gorm.DB
+}
+
+func NewPasswordResetToken(db gorm.DB) PasswordResetTokenRepositoryInterface {
+	return &PasswordResetTokenRepository{DB: db}
+}
+
+func (r *PasswordResetTokenRepository) Create(token *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	if tx := r.DB.Create(token); tx.Error != nil {
+		log.Println("error inserting password reset token: ", tx.Error.Error())
+		return nil, errors.New("error inserting password reset token in DB")
+	}
+	return token, nil
+}
+
+func (r *PasswordResetTokenRepository) GetByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	var token *models.PasswordResetToken
+	if tx := r.DB.Where("token_hash = ?", tokenHash).First(&token); tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving password reset token %s", tx.Error.Error()))
+		return nil, errors.New("error retrieving password reset token from DB")
+	}
+	return token, nil
+}
+
+// MarkUsed returns false when the token had already been used, so the same
+// link can not reset the password twice.
+func (r *PasswordResetTokenRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	tx := r.DB.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error marking password reset token %d as used %s", id, tx.Error.Error()))
+		return false, errors.New("error updating password reset token in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
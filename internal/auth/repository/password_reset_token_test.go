@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetTokenRepository_Create(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	createdAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, token *models.PasswordResetToken)
+		asserts        func(t *testing.T, token *models.PasswordResetToken, err error)
+	}{
+		{
+			name: "create password reset token should be successful",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, token *models.PasswordResetToken) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `password_reset_tokens` (`user_id`,`token_hash`,`expires_at`,`used_at`,`created_at`) VALUES (?,?,?,?,?)")).
+					WithArgs(token.UserID, token.TokenHash, token.ExpiresAt, nil, token.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, token *models.PasswordResetToken, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), token.ID)
+			},
+		},
+		{
+			name: "create password reset token should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, token *models.PasswordResetToken) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `password_reset_tokens`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, token *models.PasswordResetToken, err error) {
+				assert.Nil(t, token)
+				assert.Equal(t, "error inserting password reset token in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			token := &models.PasswordResetToken{UserID: 1, TokenHash: "hash", ExpiresAt: expiresAt, CreatedAt: createdAt}
+
+			tt.mockedBehavior(t, mock, token)
+
+			repository := NewPasswordResetToken(*gormDb)
+
+			result, err := repository.Create(token)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestPasswordResetTokenRepository_GetByHash(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, token *models.PasswordResetToken, err error)
+	}{
+		{
+			name: "existing hash should return token",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "token_hash"}).AddRow(1, 7, "hash")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `password_reset_tokens` WHERE token_hash = ? ORDER BY `password_reset_tokens`.`id` LIMIT 1")).
+					WithArgs("hash").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, token *models.PasswordResetToken, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(7), token.UserID)
+			},
+		},
+		{
+			name: "unknown hash should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `password_reset_tokens`")).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, token *models.PasswordResetToken, err error) {
+				assert.Nil(t, token)
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewPasswordResetToken(*gormDb)
+
+			result, err := repository.GetByHash("hash")
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestPasswordResetTokenRepository_MarkUsed(t *testing.T) {
+	usedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, marked bool, err error)
+	}{
+		{
+			name: "unused token should be marked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `password_reset_tokens` SET `used_at`=? WHERE id = ? AND used_at IS NULL")).
+					WithArgs(usedAt, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, marked bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, marked)
+			},
+		},
+		{
+			name: "already used token should not be marked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `password_reset_tokens` SET `used_at`=? WHERE id = ? AND used_at IS NULL")).
+					WithArgs(usedAt, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, marked bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, marked)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `password_reset_tokens` SET `used_at`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, marked bool, err error) {
+				assert.Error(t, err)
+				assert.False(t, marked)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewPasswordResetToken(*gormDb)
+
+			marked, err := repository.MarkUsed(1, usedAt)
+
+			tt.asserts(t, marked, err)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type RefreshTokenRepositoryInterface interface {
+	Create(token *models.RefreshToken) (*models.RefreshToken, error)
+	GetByHash(tokenHash string) (*models.RefreshToken, error)
+	MarkUsed(id uint, usedAt time.Time) (bool, error)
+	RevokeSession(sessionID string) error
+	RevokeUser(userID uint) error
+}
+
+type RefreshTokenRepository struct {
+	DB gorm.DB
+}
+
+func NewRefreshToken(db gorm.DB) RefreshTokenRepositoryInterface {
+	return &RefreshTokenRepository{DB: db}
+}
+
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) (*models.RefreshToken, error) {
+	if tx := r.DB.Create(token); tx.Error != nil {
+		log.Println("error inserting refresh token: ", tx.Error.Error())
+		return nil, errors.New("error inserting refresh token in DB")
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token *models.RefreshToken
+	if tx := r.DB.Where("token_hash = ?", tokenHash).First(&token); tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving refresh token %s", tx.Error.Error()))
+		return nil, errors.New("error retrieving refresh token from DB")
+	}
+	return token, nil
+}
+
+// MarkUsed flags the token as consumed. It returns false when the token had
+// already been used, so concurrent refreshes with the same token are detected.
+func (r *RefreshTokenRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	tx := r.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error marking refresh token %d as used %s", id, tx.Error.Error()))
+		return false, errors.New("error updating refresh token in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeSession(sessionID string) error {
+	tx := r.DB.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking refresh tokens of session %s %s", sessionID, tx.Error.Error()))
+		return errors.New("error revoking refresh tokens in DB")
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeUser(userID uint) error {
+	tx := r.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking refresh tokens of user %d %s", userID, tx.Error.Error()))
+		return errors.New("error revoking refresh tokens in DB")
+	}
+	return nil
+}
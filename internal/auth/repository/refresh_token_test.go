@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenRepository_Create(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	createdAt := time.Now()
+
+	tests := []struct {
+		name           string
+		token          *models.RefreshToken
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, token *models.RefreshToken)
+		asserts        func(t *testing.T, token *models.RefreshToken, err error)
+	}{
+		{
+			name:  "create refresh token should be successful",
+			token: &models.RefreshToken{UserID: 1, SessionID: "session", TokenHash: "hash", ExpiresAt: expiresAt, CreatedAt: createdAt},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, token *models.RefreshToken) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens` (`user_id`,`session_id`,`token_hash`,`expires_at`,`used_at`,`revoked_at`,`created_at`) VALUES (?,?,?,?,?,?,?)")).
+					WithArgs(token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt, nil, nil, token.CreatedAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, token *models.RefreshToken, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(1), token.ID)
+			},
+		},
+		{
+			name:  "create refresh token should return error",
+			token: &models.RefreshToken{UserID: 1, SessionID: "session", TokenHash: "hash", ExpiresAt: expiresAt, CreatedAt: createdAt},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, token *models.RefreshToken) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `refresh_tokens`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, token *models.RefreshToken, err error) {
+				assert.Nil(t, token)
+				assert.Equal(t, "error inserting refresh token in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock, tt.token)
+
+			repository := NewRefreshToken(*gormDb)
+
+			result, err := repository.Create(tt.token)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestRefreshTokenRepository_GetByHash(t *testing.T) {
+	tests := []struct {
+		name           string
+		hash           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, hash string)
+		asserts        func(t *testing.T, token *models.RefreshToken, err error)
+	}{
+		{
+			name: "existing hash should return token",
+			hash: "hash",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, hash string) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "session_id", "token_hash"}).
+					AddRow(1, 1, "session", hash)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `refresh_tokens` WHERE token_hash = ? ORDER BY `refresh_tokens`.`id` LIMIT 1")).
+					WithArgs(hash).
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, token *models.RefreshToken, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "session", token.SessionID)
+			},
+		},
+		{
+			name: "unknown hash should return error",
+			hash: "hash",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, hash string) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `refresh_tokens` WHERE token_hash = ? ORDER BY `refresh_tokens`.`id` LIMIT 1")).
+					WithArgs(hash).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, token *models.RefreshToken, err error) {
+				assert.Nil(t, token)
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock, tt.hash)
+
+			repository := NewRefreshToken(*gormDb)
+
+			result, err := repository.GetByHash(tt.hash)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestRefreshTokenRepository_MarkUsed(t *testing.T) {
+	usedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, marked bool, err error)
+	}{
+		{
+			name: "unused token should be marked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `used_at`=? WHERE id = ? AND used_at IS NULL")).
+					WithArgs(usedAt, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, marked bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, marked)
+			},
+		},
+		{
+			name: "already used token should not be marked",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `used_at`=? WHERE id = ? AND used_at IS NULL")).
+					WithArgs(usedAt, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, marked bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, marked)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `used_at`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, marked bool, err error) {
+				assert.Error(t, err)
+				assert.False(t, marked)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewRefreshToken(*gormDb)
+
+			marked, err := repository.MarkUsed(1, usedAt)
+
+			tt.asserts(t, marked, err)
+		})
+	}
+}
+
+func TestRefreshTokenRepository_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "revoke session should update every token of the family",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at`=? WHERE session_id = ? AND revoked_at IS NULL")).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "revoke session should return db error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error revoking refresh tokens in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewRefreshToken(*gormDb)
+
+			err := repository.RevokeSession("session")
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func TestRefreshTokenRepository_RevokeUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "revoke user should update every token of the user",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at`=? WHERE user_id = ? AND revoked_at IS NULL")).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "revoke user should return db error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `refresh_tokens` SET `revoked_at`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error revoking refresh tokens in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewRefreshToken(*gormDb)
+
+			err := repository.RevokeUser(1)
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func setupMockedDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gormDb, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	return gormDb, mock
+}
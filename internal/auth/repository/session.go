@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type SessionRepositoryInterface interface {
+	Create(session *models.Session) (*models.Session, error)
+	Get(id string) (*models.Session, error)
+	ListActive(userID uint, seenAfter time.Time) ([]models.Session, error)
+	Touch(id string, seenAt time.Time, ip string) error
+	Revoke(id string, userID uint, revokedAt time.Time) (bool, error)
+	RevokeUser(userID uint, revokedAt time.Time) error
+}
+
+type SessionRepository struct {
+	DB gorm.DB
+}
+
+func NewSession(db gorm.DB) SessionRepositoryInterface {
+	return &SessionRepository{DB: db}
+}
+
+func (r *SessionRepository) Create(session *models.Session) (*models.Session, error) {
+	if tx := r.DB.Create(session); tx.Error != nil {
+		log.Println("error inserting session: ", tx.Error.Error())
+		return nil, errors.New("error inserting session in DB")
+	}
+	return session, nil
+}
+
+// Get returns nil without error when the session does not exist.
+func (r *SessionRepository) Get(id string) (*models.Session, error) {
+	var session *models.Session
+	tx := r.DB.Where("id = ?", id).First(&session)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving session %s %s", id, tx.Error.Error()))
+		return nil, errors.New("error retrieving session from DB")
+	}
+	return session, nil
+}
+
+// ListActive returns the sessions of the user that were not revoked and
+// were used after seenAfter, oldest first.
+func (r *SessionRepository) ListActive(userID uint, seenAfter time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	tx := r.DB.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", userID, seenAfter).
+		Order("created_at").
+		Find(&sessions)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error listing sessions of user %d %s", userID, tx.Error.Error()))
+		return nil, errors.New("error retrieving sessions from DB")
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) Touch(id string, seenAt time.Time, ip string) error {
+	updates := map[string]interface{}{"last_seen_at": seenAt}
+	if ip != "" {
+		updates["ip"] = ip
+	}
+	if tx := r.DB.Model(&models.Session{}).Where("id = ?", id).Updates(updates); tx.Error != nil {
+		log.Println(fmt.Sprintf("error updating session %s %s", id, tx.Error.Error()))
+		return errors.New("error updating session in DB")
+	}
+	return nil
+}
+
+// Revoke returns false when the session does not belong to the user or was
+// already revoked.
+func (r *SessionRepository) Revoke(id string, userID uint, revokedAt time.Time) (bool, error) {
+	tx := r.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", revokedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking session %s %s", id, tx.Error.Error()))
+		return false, errors.New("error updating session in DB")
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func (r *SessionRepository) RevokeUser(userID uint, revokedAt time.Time) error {
+	tx := r.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error revoking sessions of user %d %s", userID, tx.Error.Error()))
+		return errors.New("error updating sessions in DB")
+	}
+	return nil
+}
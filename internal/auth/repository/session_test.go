@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_Create(t *testing.T) {
+	now := time.Now()
+
+	gormDb, mock := setupMockedDB(t)
+	session := &models.Session{ID: "session", UserID: 7, UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: now, LastSeenAt: now}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `sessions` (`id`,`user_id`,`user_agent`,`ip`,`created_at`,`last_seen_at`,`revoked_at`) VALUES (?,?,?,?,?,?,?)")).
+		WithArgs("session", 7, "Firefox", "10.0.0.1", now, now, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := NewSession(*gormDb).Create(session)
+
+	assert.NoError(t, err)
+	assert.Equal(t, session, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepository_Get(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, session *models.Session, err error)
+	}{
+		{
+			name: "existing session should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id"}).AddRow("session", 7)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions` WHERE id = ? ORDER BY `sessions`.`id` LIMIT 1")).
+					WithArgs("session").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, session *models.Session, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(7), session.UserID)
+			},
+		},
+		{
+			name: "unknown session should return nil",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, session *models.Session, err error) {
+				assert.NoError(t, err)
+				assert.Nil(t, session)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, session *models.Session, err error) {
+				assert.Nil(t, session)
+				assert.Equal(t, "error retrieving session from DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewSession(*gormDb)
+
+			result, err := repository.Get("session")
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestSessionRepository_ListActive(t *testing.T) {
+	seenAfter := time.Now().Add(-time.Hour)
+
+	gormDb, mock := setupMockedDB(t)
+	rows := sqlmock.NewRows([]string{"id", "user_id"}).AddRow("first", 7).AddRow("second", 7)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `sessions` WHERE user_id = ? AND revoked_at IS NULL AND last_seen_at > ? ORDER BY created_at")).
+		WithArgs(7, seenAfter).
+		WillReturnRows(rows)
+
+	sessions, err := NewSession(*gormDb).ListActive(7, seenAfter)
+
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "first", sessions[0].ID)
+}
+
+func TestSessionRepository_Touch(t *testing.T) {
+	seenAt := time.Now()
+
+	tests := []struct {
+		name           string
+		ip             string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "touch should update activity and ip",
+			ip:   "10.0.0.2",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `ip`=?,`last_seen_at`=? WHERE id = ?")).
+					WithArgs("10.0.0.2", seenAt, "session").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "touch without ip should keep the stored one",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `last_seen_at`=? WHERE id = ?")).
+					WithArgs(seenAt, "session").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			err := NewSession(*gormDb).Touch("session", seenAt, tt.ip)
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSessionRepository_Revoke(t *testing.T) {
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		rowsAffected   int64
+		expectedResult bool
+	}{
+		{name: "active session of the user should be revoked", rowsAffected: 1, expectedResult: true},
+		{name: "session of another user should not be revoked", rowsAffected: 0, expectedResult: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `revoked_at`=? WHERE id = ? AND user_id = ? AND revoked_at IS NULL")).
+				WithArgs(revokedAt, "session", 7).
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+			mock.ExpectCommit()
+
+			revoked, err := NewSession(*gormDb).Revoke("session", 7, revokedAt)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, revoked)
+		})
+	}
+}
+
+func TestSessionRepository_RevokeUser(t *testing.T) {
+	revokedAt := time.Now()
+
+	gormDb, mock := setupMockedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `sessions` SET `revoked_at`=? WHERE user_id = ? AND revoked_at IS NULL")).
+		WithArgs(revokedAt, 7).
+		WillReturnError(errors.New("error from db"))
+	mock.ExpectRollback()
+
+	err := NewSession(*gormDb).RevokeUser(7, revokedAt)
+
+	assert.Equal(t, "error updating sessions in DB", err.Error())
+}
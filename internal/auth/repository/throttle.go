@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/throttle"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+// ThrottleRepository lets through at most one action per key every interval.
+// The last action is kept in the DB, so the limit is shared by every
+// instance. Scope separates the keys of each kind of action.
+type ThrottleRepository struct {
+	DB       gorm.DB
+	scope    string
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewThrottle(db gorm.DB, scope string, interval time.Duration) throttle.ThrottleInterface {
+	return &ThrottleRepository{DB: db, scope: scope, interval: interval, now: time.Now}
+}
+
+// Allow records the action in a single upsert that only replaces actions
+// older than the interval, so concurrent requests let through only one.
+func (r *ThrottleRepository) Allow(key string) (bool, error) {
+	now := r.now()
+	allowedBefore := now.Add(-r.interval)
+	tx := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"last_action_at": now}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("throttled_actions.last_action_at <= ?", allowedBefore),
+		}},
+	}).Create(&models.ThrottledAction{Key: r.scope + ":" + key, LastActionAt: now})
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error throttling %s action %s", r.scope, tx.Error.Error()))
+		return false, errors.New("error inserting throttled action in DB")
+	}
+	if tx.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if tx := r.DB.Where("key LIKE ? AND last_action_at <= ?", r.scope+":%", allowedBefore).
+		Delete(&models.ThrottledAction{}); tx.Error != nil {
+		log.Println("error purging expired throttled actions: ", tx.Error.Error())
+	}
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestThrottleRepository_Allow(t *testing.T) {
+	now := time.Now()
+	allowedBefore := now.Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, allowed bool, err error)
+	}{
+		{
+			name: "first action should be allowed and old actions purged",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `throttled_actions` (`key`,`last_action_at`) VALUES (?,?)")).
+					WithArgs("password_reset:meze@gmail.com", now, now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `throttled_actions` WHERE key LIKE ? AND last_action_at <= ?")).
+					WithArgs("password_reset:%", allowedBefore).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, allowed bool, err error) {
+				assert.NoError(t, err)
+				assert.True(t, allowed)
+			},
+		},
+		{
+			name: "action within the interval should be throttled",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `throttled_actions`")).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, allowed bool, err error) {
+				assert.NoError(t, err)
+				assert.False(t, allowed)
+			},
+		},
+		{
+			name: "db error should be returned",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `throttled_actions`")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, allowed bool, err error) {
+				assert.False(t, allowed)
+				assert.Equal(t, "error inserting throttled action in DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDb, mock := setupMockedDB(t)
+
+			tt.mockedBehavior(t, mock)
+
+			throttle := NewThrottle(*gormDb, "password_reset", time.Minute).(*ThrottleRepository)
+			throttle.now = func() time.Time { return now }
+
+			allowed, err := throttle.Allow("meze@gmail.com")
+
+			tt.asserts(t, allowed, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
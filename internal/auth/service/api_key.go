@@ -0,0 +1,219 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/secureToken"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiKeyPrefix     = "chk_"
+	apiKeySize       = 32
+	apiKeyShownChars = 10
+	// apiKeyUsageInterval limits how often the last use of a key is written,
+	// a busy integration would otherwise update the row on every request.
+	apiKeyUsageInterval = time.Minute
+)
+
+type APIKeyServiceInterface interface {
+	Create(userID string, request *models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error)
+	List(userID string) ([]models.APIKeyResponse, error)
+	Revoke(userID string, keyID string) error
+	// Authenticate returns the principal of the key, with the permissions
+	// limited to the scopes of the key that the user still holds.
+	Authenticate(key string) (*models.CustomClaims, error)
+}
+
+type APIKeyUserStore interface {
+	Get(id string) (*userModels.UserRequest, error)
+	GetPermissions(id string) (*userModels.UserPermissions, error)
+}
+
+type APIKeyService struct {
+	apiKeyRepository repository.APIKeyRepositoryInterface
+	userStore        APIKeyUserStore
+}
+
+func NewAPIKeyService(apiKeyRepository repository.APIKeyRepositoryInterface, userStore APIKeyUserStore) APIKeyServiceInterface {
+	return &APIKeyService{
+		apiKeyRepository: apiKeyRepository,
+		userStore:        userStore,
+	}
+}
+
+// Create stores a new key for the user. The scopes must be permissions the
+// user currently holds and the key itself is only returned here.
+func (a *APIKeyService) Create(userID string, request *models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("id de usuario invalido: %w", err)
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, models.ErrInvalidAPIKeyExpiry
+	}
+
+	permissions, err := a.userStore.GetPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := grantedScopes(request.Scopes, permissions.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := secureToken.Generate(apiKeySize)
+	if err != nil {
+		log.Println("error trying to generate api key")
+		return nil, fmt.Errorf("error al intentar generar la api key: %w", err)
+	}
+	key := apiKeyPrefix + token
+
+	stored, err := a.apiKeyRepository.Create(&models.APIKey{
+		UserID:    uint(id),
+		Name:      request.Name,
+		Prefix:    key[:apiKeyShownChars],
+		KeyHash:   secureToken.Hash(key),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: request.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedAPIKeyResponse{APIKeyResponse: apiKeyResponse(stored), Key: key}, nil
+}
+
+func (a *APIKeyService) List(userID string) ([]models.APIKeyResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("id de usuario invalido: %w", err)
+	}
+
+	keys, err := a.apiKeyRepository.ListByUser(uint(id))
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, apiKeyResponse(&keys[i]))
+	}
+	return responses, nil
+}
+
+func (a *APIKeyService) Revoke(userID string, keyID string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("id de usuario invalido: %w", err)
+	}
+	key, err := strconv.Atoi(keyID)
+	if err != nil {
+		return models.ErrAPIKeyNotFound
+	}
+
+	revoked, err := a.apiKeyRepository.Revoke(uint(key), uint(id), time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return models.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (a *APIKeyService) Authenticate(key string) (*models.CustomClaims, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	stored, err := a.apiKeyRepository.GetByHash(secureToken.Hash(key))
+	if err != nil {
+		return nil, models.ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if stored.RevokedAt != nil || (stored.ExpiresAt != nil && !stored.ExpiresAt.After(now)) {
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	userID := strconv.Itoa(int(stored.UserID))
+	user, err := a.userStore.Get(userID)
+	if err != nil {
+		return nil, models.ErrInvalidAPIKey
+	}
+	permissions, err := a.userStore.GetPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= apiKeyUsageInterval {
+		if err := a.apiKeyRepository.MarkUsed(stored.ID, now); err != nil {
+			log.Println("error trying to update last use of api key: ", err.Error())
+		}
+	}
+
+	scopes := stored.ScopeList()
+	return &models.CustomClaims{
+		UserID:      userID,
+		Email:       user.Email,
+		Role:        permissions.Role,
+		Permissions: intersectScopes(scopes, permissions.Permissions),
+		Scope:       strings.Join(scopes, " "),
+		APIKeyID:    strconv.Itoa(int(stored.ID)),
+	}, nil
+}
+
+// grantedScopes removes duplicated scopes and fails when one of them is not
+// held by the user.
+func grantedScopes(requested []string, permissions []string) ([]string, error) {
+	held := map[string]bool{}
+	for _, permission := range permissions {
+		held[permission] = true
+	}
+
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range requested {
+		if !held[scope] {
+			return nil, models.ErrInvalidAPIKeyScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func intersectScopes(scopes []string, permissions []string) []string {
+	held := map[string]bool{}
+	for _, permission := range permissions {
+		held[permission] = true
+	}
+
+	granted := []string{}
+	for _, scope := range scopes {
+		if held[scope] {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
+
+func apiKeyResponse(key *models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/secureToken"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyService_Create(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+	userPermissions := &userModels.UserPermissions{Id: 7, Role: "user", Permissions: []string{"users:read", "users:write", "jobs:apply"}}
+
+	tests := []struct {
+		name           string
+		request        *models.CreateAPIKeyRequest
+		mockedBehavior func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore)
+		asserts        func(t *testing.T, response *models.CreatedAPIKeyResponse, err error, repository *MockAPIKeyRepository)
+	}{
+		{
+			name:    "key with held scopes should be created",
+			request: &models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"users:read", "jobs:apply", "users:read"}, ExpiresAt: &future},
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				userStore.On("GetPermissions", "7").Return(userPermissions, nil)
+				repository.On("Create", mock.Anything).Return(func(key *models.APIKey) *models.APIKey {
+					key.ID = 3
+					return key
+				}, nil)
+			},
+			asserts: func(t *testing.T, response *models.CreatedAPIKeyResponse, err error, repository *MockAPIKeyRepository) {
+				assert.NoError(t, err)
+				assert.Equal(t, uint(3), response.ID)
+				assert.True(t, strings.HasPrefix(response.Key, "chk_"))
+				assert.Equal(t, response.Key[:10], response.Prefix)
+				assert.Equal(t, []string{"users:read", "jobs:apply"}, response.Scopes)
+
+				stored := repository.Calls[0].Arguments.Get(0).(*models.APIKey)
+				assert.Equal(t, uint(7), stored.UserID)
+				assert.Equal(t, secureToken.Hash(response.Key), stored.KeyHash)
+				assert.Equal(t, "users:read jobs:apply", stored.Scopes)
+				assert.Equal(t, &future, stored.ExpiresAt)
+			},
+		},
+		{
+			name:    "scope not held by the user should be rejected",
+			request: &models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"users:read", "users:manage"}},
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				userStore.On("GetPermissions", "7").Return(userPermissions, nil)
+			},
+			asserts: func(t *testing.T, response *models.CreatedAPIKeyResponse, err error, repository *MockAPIKeyRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidAPIKeyScope)
+				repository.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:    "expiration in the past should be rejected",
+			request: &models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"users:read"}, ExpiresAt: &past},
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedAPIKeyResponse, err error, repository *MockAPIKeyRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidAPIKeyExpiry)
+			},
+		},
+		{
+			name:    "repository error should be returned",
+			request: &models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"users:read"}},
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				userStore.On("GetPermissions", "7").Return(userPermissions, nil)
+				repository.On("Create", mock.Anything).Return(nil, errors.New("error inserting api key in DB"))
+			},
+			asserts: func(t *testing.T, response *models.CreatedAPIKeyResponse, err error, repository *MockAPIKeyRepository) {
+				assert.Nil(t, response)
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &MockAPIKeyRepository{}
+			userStore := &MockAPIKeyUserStore{}
+			tt.mockedBehavior(repository, userStore)
+
+			apiKeyService := NewAPIKeyService(repository, userStore)
+
+			response, err := apiKeyService.Create("7", tt.request)
+
+			tt.asserts(t, response, err, repository)
+		})
+	}
+}
+
+func TestAPIKeyService_ListAndRevoke(t *testing.T) {
+	repository := &MockAPIKeyRepository{}
+	repository.On("ListByUser", uint(7)).Return([]models.APIKey{{ID: 3, Name: "ci", Prefix: "chk_abcdef", Scopes: "users:read"}}, nil)
+	repository.On("Revoke", uint(3), uint(7), mock.Anything).Return(true, nil)
+	repository.On("Revoke", uint(4), uint(7), mock.Anything).Return(false, nil)
+	apiKeyService := NewAPIKeyService(repository, &MockAPIKeyUserStore{})
+
+	keys, err := apiKeyService.List("7")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.APIKeyResponse{{ID: 3, Name: "ci", Prefix: "chk_abcdef", Scopes: []string{"users:read"}}}, keys)
+
+	assert.NoError(t, apiKeyService.Revoke("7", "3"))
+	assert.ErrorIs(t, apiKeyService.Revoke("7", "4"), models.ErrAPIKeyNotFound)
+	assert.ErrorIs(t, apiKeyService.Revoke("7", "abc"), models.ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	key := "chk_" + strings.Repeat("a", 43)
+	keyHash := secureToken.Hash(key)
+	past := time.Now().Add(-time.Hour)
+	recently := time.Now().Add(-10 * time.Second)
+	user := &userModels.UserRequest{Id: 7, Email: "meze@gmail.com"}
+	userPermissions := &userModels.UserPermissions{Id: 7, Role: "user", Permissions: []string{"users:read", "jobs:apply"}}
+
+	tests := []struct {
+		name           string
+		key            string
+		mockedBehavior func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore)
+		asserts        func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository)
+	}{
+		{
+			name: "valid key should return claims limited to held scopes",
+			key:  key,
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				repository.On("GetByHash", keyHash).Return(&models.APIKey{ID: 3, UserID: 7, Scopes: "users:read users:write"}, nil)
+				repository.On("MarkUsed", uint(3), mock.Anything).Return(nil)
+				userStore.On("Get", "7").Return(user, nil)
+				userStore.On("GetPermissions", "7").Return(userPermissions, nil)
+			},
+			asserts: func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository) {
+				assert.NoError(t, err)
+				assert.Equal(t, "7", claims.UserID)
+				assert.Equal(t, "meze@gmail.com", claims.Email)
+				assert.Equal(t, "user", claims.Role)
+				assert.Equal(t, []string{"users:read"}, claims.Permissions)
+				assert.Equal(t, "users:read users:write", claims.Scope)
+				assert.Equal(t, "3", claims.APIKeyID)
+				repository.AssertCalled(t, "MarkUsed", uint(3), mock.Anything)
+			},
+		},
+		{
+			name: "recently used key should not be marked again",
+			key:  key,
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				repository.On("GetByHash", keyHash).Return(&models.APIKey{ID: 3, UserID: 7, Scopes: "users:read", LastUsedAt: &recently}, nil)
+				userStore.On("Get", "7").Return(user, nil)
+				userStore.On("GetPermissions", "7").Return(userPermissions, nil)
+			},
+			asserts: func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository) {
+				assert.NoError(t, err)
+				repository.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "revoked key should be rejected",
+			key:  key,
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				repository.On("GetByHash", keyHash).Return(&models.APIKey{ID: 3, UserID: 7, RevokedAt: &past}, nil)
+			},
+			asserts: func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository) {
+				assert.Nil(t, claims)
+				assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name: "expired key should be rejected",
+			key:  key,
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				repository.On("GetByHash", keyHash).Return(&models.APIKey{ID: 3, UserID: 7, ExpiresAt: &past}, nil)
+			},
+			asserts: func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository) {
+				assert.Nil(t, claims)
+				assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name: "unknown key should be rejected",
+			key:  key,
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+				repository.On("GetByHash", keyHash).Return(nil, errors.New("error retrieving api key from DB"))
+			},
+			asserts: func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository) {
+				assert.Nil(t, claims)
+				assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+			},
+		},
+		{
+			name: "key without prefix should be rejected without lookup",
+			key:  "not-a-key",
+			mockedBehavior: func(repository *MockAPIKeyRepository, userStore *MockAPIKeyUserStore) {
+			},
+			asserts: func(t *testing.T, claims *models.CustomClaims, err error, repository *MockAPIKeyRepository) {
+				assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+				repository.AssertNotCalled(t, "GetByHash", mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &MockAPIKeyRepository{}
+			userStore := &MockAPIKeyUserStore{}
+			tt.mockedBehavior(repository, userStore)
+
+			apiKeyService := NewAPIKeyService(repository, userStore)
+
+			claims, err := apiKeyService.Authenticate(tt.key)
+
+			tt.asserts(t, claims, err, repository)
+		})
+	}
+}
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(key *models.APIKey) (*models.APIKey, error) {
+	args := m.Called(key)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	if fn, ok := args.Get(0).(func(*models.APIKey) *models.APIKey); ok {
+		return fn(key), nil
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	args := m.Called(keyHash)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListByUser(userID uint) ([]models.APIKey, error) {
+	args := m.Called(userID)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(id uint, userID uint, revokedAt time.Time) (bool, error) {
+	args := m.Called(id, userID, revokedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) MarkUsed(id uint, usedAt time.Time) error {
+	args := m.Called(id, usedAt)
+	return args.Error(0)
+}
+
+type MockAPIKeyUserStore struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyUserStore) Get(id string) (*userModels.UserRequest, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockAPIKeyUserStore) GetPermissions(id string) (*userModels.UserPermissions, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserPermissions), args.Error(1)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/repository"
+	"chambeo-api-core/pkg/secureToken"
+	"crypto/subtle"
+)
+
+type ClientAuthenticatorInterface interface {
+	Authenticate(clientID, clientSecret string) bool
+}
+
+// ClientAuthenticator validates clients against the credentials loaded from
+// the configuration and, for any other client id, against the registered
+// oauth clients.
+type ClientAuthenticator struct {
+	secretHashes     map[string]string
+	clientRepository repository.OAuthClientRepositoryInterface
+}
+
+func NewClientAuthenticator(clients map[string]string, clientRepository repository.OAuthClientRepositoryInterface) ClientAuthenticatorInterface {
+	secretHashes := make(map[string]string, len(clients))
+	for clientID, secret := range clients {
+		secretHashes[clientID] = secureToken.Hash(secret)
+	}
+	return &ClientAuthenticator{secretHashes: secretHashes, clientRepository: clientRepository}
+}
+
+func (a *ClientAuthenticator) Authenticate(clientID, clientSecret string) bool {
+	if clientID == "" || clientSecret == "" {
+		return false
+	}
+	if expected, ok := a.secretHashes[clientID]; ok {
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(secureToken.Hash(clientSecret))) == 1
+	}
+
+	client, err := a.clientRepository.GetByClientID(clientID)
+	if err != nil {
+		return false
+	}
+	return validClientSecret(client, clientSecret)
+}
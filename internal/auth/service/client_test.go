@@ -0,0 +1,95 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/secureToken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestClientAuthenticator_Authenticate(t *testing.T) {
+
+	revokedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		clientID       string
+		clientSecret   string
+		expected       bool
+		mockedBehavior func(t *testing.T, repositoryMock *mock.Mock)
+	}{
+		{
+			name:           "valid configured credentials should be accepted",
+			clientID:       "gateway",
+			clientSecret:   "gateway-secret",
+			expected:       true,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {},
+		},
+		{
+			name:           "wrong configured secret should be rejected",
+			clientID:       "gateway",
+			clientSecret:   "other-secret",
+			expected:       false,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {},
+		},
+		{
+			name:           "empty secret should be rejected",
+			clientID:       "gateway",
+			clientSecret:   "",
+			expected:       false,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {},
+		},
+		{
+			name:         "valid registered client should be accepted",
+			clientID:     "billing",
+			clientSecret: "billing-secret",
+			expected:     true,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByClientID", "billing").Return(&models.OAuthClient{ClientID: "billing",
+					SecretHash: secureToken.Hash("billing-secret")}, nil)
+			},
+		},
+		{
+			name:         "wrong registered secret should be rejected",
+			clientID:     "billing",
+			clientSecret: "other-secret",
+			expected:     false,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByClientID", "billing").Return(&models.OAuthClient{ClientID: "billing",
+					SecretHash: secureToken.Hash("billing-secret")}, nil)
+			},
+		},
+		{
+			name:         "revoked registered client should be rejected",
+			clientID:     "billing",
+			clientSecret: "billing-secret",
+			expected:     false,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByClientID", "billing").Return(&models.OAuthClient{ClientID: "billing",
+					SecretHash: secureToken.Hash("billing-secret"), RevokedAt: &revokedAt}, nil)
+			},
+		},
+		{
+			name:         "unknown client should be rejected",
+			clientID:     "unknown",
+			clientSecret: "gateway-secret",
+			expected:     false,
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByClientID", "unknown").Return(nil, models.ErrOAuthClientNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientRepository := &MockOAuthClientRepository{}
+			tt.mockedBehavior(t, &clientRepository.Mock)
+
+			authenticator := NewClientAuthenticator(map[string]string{"gateway": "gateway-secret"}, clientRepository)
+
+			assert.Equal(t, tt.expected, authenticator.Authenticate(tt.clientID, tt.clientSecret))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/keys"
+	"chambeo-api-core/internal/auth/models"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/mailer"
+	"chambeo-api-core/pkg/throttle"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const verificationTokenDuration = 24 * time.Hour
+
+type EmailVerificationServiceInterface interface {
+	SendVerification(user *userModels.UserRequest) error
+	Verify(token string) error
+	Resend(email string) error
+}
+
+type VerificationUserStore interface {
+	Get(id string) (*userModels.UserRequest, error)
+	GetByEmail(email string) (*userModels.UserRequest, error)
+	MarkEmailVerified(id string) error
+}
+
+type EmailVerificationService struct {
+	keyRing   *keys.KeyRing
+	userStore VerificationUserStore
+	mailer    mailer.MailerInterface
+	throttle  throttle.ThrottleInterface
+	verifyURL string
+}
+
+func NewEmailVerificationService(keyRing *keys.KeyRing, userStore VerificationUserStore, mailer mailer.MailerInterface,
+	throttle throttle.ThrottleInterface, verifyURL string) EmailVerificationServiceInterface {
+	return &EmailVerificationService{
+		keyRing:   keyRing,
+		userStore: userStore,
+		mailer:    mailer,
+		throttle:  throttle,
+		verifyURL: verifyURL,
+	}
+}
+
+// SendVerification mails a signed link bound to the user id and its current
+// email, so the link stops working if the email changes.
+func (e *EmailVerificationService) SendVerification(user *userModels.UserRequest) error {
+	token, err := e.keyRing.Sign(models.VerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    models.Issuer,
+			Subject:   strconv.Itoa(user.Id),
+			Audience:  []string{models.EmailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(verificationTokenDuration)),
+		},
+	})
+	if err != nil {
+		log.Println("error trying to sign email verification token")
+		return fmt.Errorf("error al intentar generar el enlace de verificacion: %w", err)
+	}
+
+	return e.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Confirma tu email en Chambeo",
+		Body: fmt.Sprintf("Para confirmar tu email ingresa a %s?token=%s\r\n\r\nEl enlace vence en %d horas.",
+			e.verifyURL, url.QueryEscape(token), int(verificationTokenDuration.Hours())),
+	})
+}
+
+func (e *EmailVerificationService) Verify(token string) error {
+	claims := &models.VerificationClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, e.keyRing.Keyfunc,
+		jwt.WithValidMethods(e.keyRing.Algorithms()),
+		jwt.WithIssuer(models.Issuer),
+		jwt.WithAudience(models.EmailVerificationAudience))
+	if err != nil {
+		log.Println("invalid email verification token: ", err.Error())
+		return models.ErrInvalidVerificationToken
+	}
+
+	user, err := e.userStore.Get(claims.Subject)
+	if err != nil || user == nil || !strings.EqualFold(user.Email, claims.Email) {
+		return models.ErrInvalidVerificationToken
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return e.userStore.MarkEmailVerified(claims.Subject)
+}
+
+// Resend is throttled per email address. Unknown or already verified
+// addresses are ignored without error.
+func (e *EmailVerificationService) Resend(email string) error {
+	allowed, err := e.throttle.Allow(strings.ToLower(email))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return models.ErrVerificationThrottled
+	}
+
+	user, err := e.userStore.GetByEmail(email)
+	if err != nil || user == nil || user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return e.SendVerification(user)
+}
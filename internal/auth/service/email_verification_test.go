@@ -0,0 +1,244 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/mailer"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailVerificationService_SendAndVerify(t *testing.T) {
+
+	unverifiedUser := &userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, userStoreMock *mock.Mock)
+		asserts        func(t *testing.T, userStoreMock *mock.Mock, err error)
+	}{
+		{
+			name: "valid link should verify the email",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(unverifiedUser, nil)
+				userStoreMock.On("MarkEmailVerified", "1").Return(nil)
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				userStoreMock.AssertCalled(t, "MarkEmailVerified", "1")
+			},
+		},
+		{
+			name: "link for a previous email should be rejected",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(&userModels.UserRequest{Id: 1, Email: "other@gmail.com"}, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidVerificationToken)
+				userStoreMock.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+			},
+		},
+		{
+			name: "already verified email should not be verified again",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com", EmailVerifiedAt: &verifiedAt}, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				userStoreMock.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+			},
+		},
+		{
+			name: "unknown user should be rejected",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidVerificationToken)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &MockVerificationUserStore{}
+			mockedMailer := &MockMailer{}
+			mockedMailer.On("Send", mock.Anything).Return(nil)
+			tt.mockedBehavior(t, &userStore.Mock)
+
+			verificationService := NewEmailVerificationService(testKeyRing(t), userStore, mockedMailer, &MockThrottle{}, "https://chambeo.co/verify-email")
+
+			assert.NoError(t, verificationService.SendVerification(unverifiedUser))
+			message := mockedMailer.Calls[0].Arguments.Get(0).(mailer.Message)
+			assert.Equal(t, "meze@gmail.com", message.To)
+
+			err := verificationService.Verify(verificationToken(t, message))
+
+			tt.asserts(t, &userStore.Mock, err)
+		})
+	}
+}
+
+func TestEmailVerificationService_VerifyRejectsAccessTokens(t *testing.T) {
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+	ring := testKeyRing(t)
+
+	authService := NewJWTService(ring, refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+	accessToken, _ := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
+
+	verificationService := NewEmailVerificationService(ring, &MockVerificationUserStore{}, &MockMailer{}, &MockThrottle{}, "https://chambeo.co/verify-email")
+
+	assert.ErrorIs(t, verificationService.Verify(accessToken.AccessToken), models.ErrInvalidVerificationToken)
+	assert.ErrorIs(t, verificationService.Verify("not-a-token"), models.ErrInvalidVerificationToken)
+}
+
+func TestAuthServiceRejectsVerificationTokens(t *testing.T) {
+	ring := testKeyRing(t)
+	verificationToken, _ := ring.Sign(models.VerificationClaims{
+		Email: "meze@gmail.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    models.Issuer,
+			Subject:   "1",
+			Audience:  []string{models.EmailVerificationAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	authService := NewJWTService(ring, &MockRefreshTokenRepository{}, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	_, err := authService.ParseToken(verificationToken)
+	assert.Error(t, err)
+}
+
+func TestEmailVerificationService_Resend(t *testing.T) {
+
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock)
+		asserts        func(t *testing.T, mailerMock *mock.Mock, err error)
+	}{
+		{
+			name: "unverified user should receive a new link",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(true, nil)
+				userStoreMock.On("GetByEmail", "Meze@gmail.com").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mailerMock.On("Send", mock.Anything).Return(nil)
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				mailerMock.AssertNumberOfCalls(t, "Send", 1)
+			},
+		},
+		{
+			name: "throttled email should be rejected",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(false, nil)
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrVerificationThrottled)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "throttle error should be returned",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(false, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.EqualError(t, err, "error from db")
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "verified user should not receive anything",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(true, nil)
+				userStoreMock.On("GetByEmail", "Meze@gmail.com").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com", EmailVerifiedAt: &verifiedAt}, nil)
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "unknown email should not receive anything",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(true, nil)
+				userStoreMock.On("GetByEmail", "Meze@gmail.com").Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &MockVerificationUserStore{}
+			mockedThrottle := &MockThrottle{}
+			mockedMailer := &MockMailer{}
+			tt.mockedBehavior(t, &userStore.Mock, &mockedThrottle.Mock, &mockedMailer.Mock)
+
+			verificationService := NewEmailVerificationService(testKeyRing(t), userStore, mockedMailer, mockedThrottle, "https://chambeo.co/verify-email")
+
+			err := verificationService.Resend("Meze@gmail.com")
+
+			tt.asserts(t, &mockedMailer.Mock, err)
+		})
+	}
+}
+
+func verificationToken(t *testing.T, message mailer.Message) string {
+	link := message.Body[strings.Index(message.Body, "https://"):strings.Index(message.Body, "\r\n")]
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
+
+type MockVerificationUserStore struct {
+	mock.Mock
+}
+
+func (m *MockVerificationUserStore) Get(id string) (*userModels.UserRequest, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockVerificationUserStore) GetByEmail(email string) (*userModels.UserRequest, error) {
+	args := m.Called(email)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockVerificationUserStore) MarkEmailVerified(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockThrottle struct {
+	mock.Mock
+}
+
+func (m *MockThrottle) Allow(key string) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// privilegedPermissions can not be exercised through an impersonation, the
+// users holding any of them can not be impersonated.
+var privilegedPermissions = []string{
+	models.PermissionUsersManage,
+	models.PermissionClientsManage,
+	models.PermissionUsersImpersonate,
+	models.PermissionAuditRead,
+}
+
+type ImpersonationServiceInterface interface {
+	Impersonate(admin *models.CustomClaims, request *models.ImpersonationRequest, ip string, userAgent string) (*models.TokenResponse, error)
+}
+
+type ImpersonationUserStore interface {
+	Get(id string) (*userModels.UserRequest, error)
+	GetPermissions(id string) (*userModels.UserPermissions, error)
+}
+
+type ImpersonationTokenIssuer interface {
+	GenerateImpersonationToken(subject models.TokenSubject) (*models.TokenResponse, error)
+}
+
+type ImpersonationService struct {
+	impersonationRepository repository.ImpersonationRepositoryInterface
+	userStore               ImpersonationUserStore
+	tokenIssuer             ImpersonationTokenIssuer
+}
+
+func NewImpersonationService(impersonationRepository repository.ImpersonationRepositoryInterface, userStore ImpersonationUserStore,
+	tokenIssuer ImpersonationTokenIssuer) ImpersonationServiceInterface {
+	return &ImpersonationService{
+		impersonationRepository: impersonationRepository,
+		userStore:               userStore,
+		tokenIssuer:             tokenIssuer,
+	}
+}
+
+// Impersonate issues a token to act as the requested user and writes it to
+// the audit trail. No token is handed out when the record can not be
+// stored.
+func (i *ImpersonationService) Impersonate(admin *models.CustomClaims, request *models.ImpersonationRequest, ip string,
+	userAgent string) (*models.TokenResponse, error) {
+	adminID, err := strconv.ParseUint(admin.UserID, 10, 64)
+	if err != nil || admin.IsImpersonated() {
+		return nil, models.ErrImpersonationNotAllowed
+	}
+	targetID := strconv.Itoa(request.UserID)
+	if targetID == admin.UserID {
+		return nil, models.ErrImpersonationNotAllowed
+	}
+
+	user, err := i.userStore.Get(targetID)
+	if err != nil || user == nil {
+		return nil, models.ErrImpersonationNotFound
+	}
+	permissions, err := i.userStore.GetPermissions(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if models.IsElevatedRole(permissions.Role) || containsAny(permissions.Permissions, privilegedPermissions) {
+		log.Println(fmt.Sprintf("user %s tried to impersonate the privileged user %s", admin.UserID, targetID))
+		return nil, models.ErrImpersonationNotAllowed
+	}
+
+	token, err := i.tokenIssuer.GenerateImpersonationToken(models.TokenSubject{
+		UserID:       targetID,
+		Email:        user.Email,
+		Role:         permissions.Role,
+		Permissions:  permissions.Permissions,
+		TokenVersion: user.TokenVersion,
+		ActorID:      admin.UserID,
+		ActorEmail:   admin.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(userAgent) > userAgentMaxLength {
+		userAgent = userAgent[:userAgentMaxLength]
+	}
+	now := time.Now()
+	_, err = i.impersonationRepository.Create(&models.Impersonation{
+		AdminID:   uint(adminID),
+		UserID:    uint(request.UserID),
+		Reason:    request.Reason,
+		IP:        ip,
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(time.Duration(token.ExpiresIn) * time.Second),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al intentar registrar la suplantacion: %w", err)
+	}
+
+	log.Println(fmt.Sprintf("user %s is impersonating user %s", admin.UserID, targetID))
+	return token, nil
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsToken(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+var impersonationAdmin = &models.CustomClaims{UserID: "1", Email: "admin@chambeo.co", Role: models.RoleAdmin}
+
+func TestImpersonationService_Impersonate(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		admin          *models.CustomClaims
+		mockedBehavior func(t *testing.T, userStoreMock, impersonationMock *mock.Mock)
+		asserts        func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock)
+	}{
+		{
+			name:  "regular user should be impersonated and audited",
+			admin: impersonationAdmin,
+			mockedBehavior: func(t *testing.T, userStoreMock, impersonationMock *mock.Mock) {
+				userStoreMock.On("Get", "7").Return(&userModels.UserRequest{Id: 7, Email: "meze@gmail.com", TokenVersion: 2}, nil)
+				userStoreMock.On("GetPermissions", "7").Return(&userModels.UserPermissions{Id: 7, Role: models.RoleWorker,
+					Permissions: []string{models.PermissionUsersRead, models.PermissionJobsApply}}, nil)
+				impersonationMock.On("Create", mock.Anything).Return(&models.Impersonation{}, nil)
+			},
+			asserts: func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock) {
+				assert.NoError(t, err)
+				assert.Empty(t, token.RefreshToken)
+				assert.Equal(t, int64(600), token.ExpiresIn)
+
+				parsed, err := authService.ParseToken(token.AccessToken)
+				assert.NoError(t, err)
+				claims := parsed.Claims.(*models.CustomClaims)
+				assert.Equal(t, "7", claims.Subject)
+				assert.Equal(t, "7", claims.UserID)
+				assert.Equal(t, &models.Actor{Subject: "1", Email: "admin@chambeo.co"}, claims.Actor)
+				assert.Empty(t, claims.SessionID)
+				assert.Equal(t, 2, claims.TokenVersion)
+
+				record := impersonationMock.Calls[0].Arguments.Get(0).(*models.Impersonation)
+				assert.Equal(t, uint(1), record.AdminID)
+				assert.Equal(t, uint(7), record.UserID)
+				assert.Equal(t, "ticket 42", record.Reason)
+				assert.Equal(t, "10.0.0.1", record.IP)
+				assert.Equal(t, "Firefox", record.UserAgent)
+				assert.WithinDuration(t, time.Now().Add(10*time.Minute), record.ExpiresAt, time.Minute)
+			},
+		},
+		{
+			name:  "admin should not be impersonated",
+			admin: impersonationAdmin,
+			mockedBehavior: func(t *testing.T, userStoreMock, impersonationMock *mock.Mock) {
+				userStoreMock.On("Get", "7").Return(&userModels.UserRequest{Id: 7, Email: "other@chambeo.co"}, nil)
+				userStoreMock.On("GetPermissions", "7").Return(&userModels.UserPermissions{Id: 7, Role: models.RoleAdmin}, nil)
+			},
+			asserts: func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock) {
+				assert.ErrorIs(t, err, models.ErrImpersonationNotAllowed)
+				impersonationMock.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:  "user with privileged permission should not be impersonated",
+			admin: impersonationAdmin,
+			mockedBehavior: func(t *testing.T, userStoreMock, impersonationMock *mock.Mock) {
+				userStoreMock.On("Get", "7").Return(&userModels.UserRequest{Id: 7, Email: "meze@gmail.com"}, nil)
+				userStoreMock.On("GetPermissions", "7").Return(&userModels.UserPermissions{Id: 7, Role: models.RoleUser,
+					Permissions: []string{models.PermissionUsersRead, models.PermissionUsersManage}}, nil)
+			},
+			asserts: func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock) {
+				assert.ErrorIs(t, err, models.ErrImpersonationNotAllowed)
+			},
+		},
+		{
+			name:  "impersonation token should not start another impersonation",
+			admin: &models.CustomClaims{UserID: "1", Role: models.RoleAdmin, Actor: &models.Actor{Subject: "2"}},
+			mockedBehavior: func(t *testing.T, userStoreMock, impersonationMock *mock.Mock) {
+			},
+			asserts: func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock) {
+				assert.ErrorIs(t, err, models.ErrImpersonationNotAllowed)
+			},
+		},
+		{
+			name:  "unknown user should return not found",
+			admin: impersonationAdmin,
+			mockedBehavior: func(t *testing.T, userStoreMock, impersonationMock *mock.Mock) {
+				userStoreMock.On("Get", "7").Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock) {
+				assert.ErrorIs(t, err, models.ErrImpersonationNotFound)
+			},
+		},
+		{
+			name:  "audit failure should not hand out the token",
+			admin: impersonationAdmin,
+			mockedBehavior: func(t *testing.T, userStoreMock, impersonationMock *mock.Mock) {
+				userStoreMock.On("Get", "7").Return(&userModels.UserRequest{Id: 7, Email: "meze@gmail.com"}, nil)
+				userStoreMock.On("GetPermissions", "7").Return(&userModels.UserPermissions{Id: 7, Role: models.RoleUser}, nil)
+				impersonationMock.On("Create", mock.Anything).Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, token *models.TokenResponse, err error, authService *AuthService, impersonationMock *mock.Mock) {
+				assert.Error(t, err)
+				assert.Nil(t, token)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &MockAPIKeyUserStore{}
+			impersonationRepository := &MockImpersonationRepository{}
+			tt.mockedBehavior(t, &userStore.Mock, &impersonationRepository.Mock)
+			authService := NewJWTService(testKeyRing(t), &MockRefreshTokenRepository{}, repository.NewMemoryDenylist(), testTokenVersionStore(2),
+				testSessionRepository(), 0)
+
+			impersonationService := NewImpersonationService(impersonationRepository, userStore, &authService)
+
+			token, err := impersonationService.Impersonate(tt.admin, &models.ImpersonationRequest{UserID: 7, Reason: "ticket 42"}, "10.0.0.1", "Firefox")
+
+			tt.asserts(t, token, err, &authService, &impersonationRepository.Mock)
+		})
+	}
+}
+
+func TestImpersonationService_ImpersonateSelf(t *testing.T) {
+	authService := NewJWTService(testKeyRing(t), &MockRefreshTokenRepository{}, repository.NewMemoryDenylist(), testTokenVersionStore(0),
+		testSessionRepository(), 0)
+	impersonationService := NewImpersonationService(&MockImpersonationRepository{}, &MockAPIKeyUserStore{}, &authService)
+
+	_, err := impersonationService.Impersonate(impersonationAdmin, &models.ImpersonationRequest{UserID: 1, Reason: "ticket 42"}, "", "")
+
+	assert.ErrorIs(t, err, models.ErrImpersonationNotAllowed)
+}
+
+type MockImpersonationRepository struct {
+	mock.Mock
+}
+
+func (m *MockImpersonationRepository) Create(impersonation *models.Impersonation) (*models.Impersonation, error) {
+	args := m.Called(impersonation)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return impersonation, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type IntrospectionServiceInterface interface {
+	Introspect(tokenString string) (*jwt.Token, error)
+}
+
+type AnyAudienceTokenParser interface {
+	ParseTokenAnyAudience(tokenString string) (*jwt.Token, error)
+}
+
+// IntrospectionService validates the tokens presented to the introspection
+// endpoint. User tokens must be meant for this API and client tokens for
+// one of the audiences registered for their client, which must not be
+// revoked.
+type IntrospectionService struct {
+	tokenParser      AnyAudienceTokenParser
+	clientRepository repository.OAuthClientRepositoryInterface
+}
+
+func NewIntrospectionService(tokenParser AnyAudienceTokenParser, clientRepository repository.OAuthClientRepositoryInterface) IntrospectionServiceInterface {
+	return &IntrospectionService{tokenParser: tokenParser, clientRepository: clientRepository}
+}
+
+func (i *IntrospectionService) Introspect(tokenString string) (*jwt.Token, error) {
+	token, err := i.tokenParser.ParseTokenAnyAudience(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*models.CustomClaims)
+	if !ok {
+		return nil, models.ErrInvalidTokenAudience
+	}
+
+	accepted := []string{models.ClientAudience}
+	if claims.IsClient() {
+		client, err := i.clientRepository.GetByClientID(claims.ClientID)
+		if err != nil {
+			return nil, models.ErrInvalidTokenAudience
+		}
+		if client.RevokedAt != nil {
+			return nil, models.ErrTokenRevoked
+		}
+		accepted = client.AudienceList()
+	}
+	for _, audience := range claims.Audience {
+		if containsToken(accepted, audience) {
+			return token, nil
+		}
+	}
+	return nil, models.ErrInvalidTokenAudience
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestIntrospectionService_Introspect(t *testing.T) {
+	revokedAt := time.Now()
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	userToken, err := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
+	assert.NoError(t, err)
+	billingToken, err := authService.GenerateClientToken(models.ClientTokenSubject{ClientID: "billing", Scopes: []string{"read:users"}, Audience: "chambeo-billing"})
+	assert.NoError(t, err)
+	reportsToken, err := authService.GenerateClientToken(models.ClientTokenSubject{ClientID: "reports", Scopes: []string{"read:users"}, Audience: "chambeo-reports"})
+	assert.NoError(t, err)
+	revokedToken, err := authService.GenerateClientToken(models.ClientTokenSubject{ClientID: "revoked", Scopes: []string{"read:users"}, Audience: "chambeo-billing"})
+	assert.NoError(t, err)
+	orphanToken, err := authService.GenerateClientToken(models.ClientTokenSubject{ClientID: "deleted", Scopes: []string{"read:users"}, Audience: "chambeo-billing"})
+	assert.NoError(t, err)
+
+	clientRepository := &MockOAuthClientRepository{}
+	clientRepository.On("GetByClientID", "billing").Return(&models.OAuthClient{ClientID: "billing", Audiences: "chambeo-billing chambeo-fe"}, nil)
+	// The reports client no longer lists the audience its token was issued for.
+	clientRepository.On("GetByClientID", "reports").Return(&models.OAuthClient{ClientID: "reports", Audiences: "chambeo-fe"}, nil)
+	clientRepository.On("GetByClientID", "revoked").Return(&models.OAuthClient{ClientID: "revoked", Audiences: "chambeo-billing", RevokedAt: &revokedAt}, nil)
+	clientRepository.On("GetByClientID", "deleted").Return(nil, errors.New("not found"))
+
+	introspectionService := NewIntrospectionService(&authService, clientRepository)
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{name: "user token for this API", token: userToken.AccessToken},
+		{name: "client token for a registered audience", token: billingToken.AccessToken},
+		{name: "client token for an unregistered audience", token: reportsToken.AccessToken, expectedErr: models.ErrInvalidTokenAudience},
+		{name: "client token of a revoked client", token: revokedToken.AccessToken, expectedErr: models.ErrTokenRevoked},
+		{name: "client token of an unknown client", token: orphanToken.AccessToken, expectedErr: models.ErrInvalidTokenAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := introspectionService.Introspect(tt.token)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, token)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+		})
+	}
+}
@@ -1,67 +1,437 @@
 package service
 
 import (
+	"chambeo-api-core/internal/auth/keys"
 	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"chambeo-api-core/pkg/secureToken"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
-type AuthService struct{}
+const (
+	accessTokenDuration = 15 * time.Minute
+	// impersonationTokenDuration is shorter than a normal access token and
+	// can not be refreshed.
+	impersonationTokenDuration = 10 * time.Minute
+	refreshTokenDuration       = 30 * 24 * time.Hour
+	refreshTokenSize           = 32
+	sessionIDSize              = 16
+	tokenIDSize                = 16
+	tokenType                  = "Bearer"
+	userAgentMaxLength         = 512
+	// sessionActivityInterval limits how often the last activity of a
+	// session is written while its access tokens are being used.
+	sessionActivityInterval = time.Minute
+)
+
+// TokenVersionStore keeps the per user token version. Tokens issued with a
+// version lower than the current one are considered revoked.
+type TokenVersionStore interface {
+	GetTokenVersion(id string) (int, error)
+	IncrementTokenVersion(id string) error
+}
 
-func NewJWTService() AuthService {
-	return AuthService{}
+type AuthService struct {
+	keyRing                *keys.KeyRing
+	refreshTokenRepository repository.RefreshTokenRepositoryInterface
+	denylistRepository     repository.DenylistRepositoryInterface
+	tokenVersionStore      TokenVersionStore
+	sessionRepository      repository.SessionRepositoryInterface
+	// maxSessions caps the active sessions per user, the oldest ones are
+	// revoked when a new one starts. Zero means no limit.
+	maxSessions int
 }
 
-func (a *AuthService) GenerateToken(email string, userId string) (*string, error) {
+func NewJWTService(keyRing *keys.KeyRing, refreshTokenRepository repository.RefreshTokenRepositoryInterface,
+	denylistRepository repository.DenylistRepositoryInterface, tokenVersionStore TokenVersionStore,
+	sessionRepository repository.SessionRepositoryInterface, maxSessions int) AuthService {
+	return AuthService{
+		keyRing:                keyRing,
+		refreshTokenRepository: refreshTokenRepository,
+		denylistRepository:     denylistRepository,
+		tokenVersionStore:      tokenVersionStore,
+		sessionRepository:      sessionRepository,
+		maxSessions:            maxSessions,
+	}
+}
 
-	mySigningKey := []byte("secretPassword")
+// GenerateToken issues an access token and a refresh token for subject. A
+// new session (token family) is started when subject has no SessionID.
+func (a *AuthService) GenerateToken(subject models.TokenSubject) (*models.TokenResponse, error) {
 
-	claims := a.generateClaims(email, userId)
+	if subject.SessionID == "" {
+		sessionID, err := secureToken.Generate(sessionIDSize)
+		if err != nil {
+			log.Println("error trying to generate session id")
+			return nil, fmt.Errorf("error al intentar generar el token: %w", err)
+		}
+		subject.SessionID = sessionID
+		if err := a.startSession(subject); err != nil {
+			return nil, err
+		}
+	} else if err := a.sessionRepository.Touch(subject.SessionID, time.Now(), subject.IP); err != nil {
+		log.Println("error trying to update session activity: ", err.Error())
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString(mySigningKey)
+	tokenID, err := secureToken.Generate(tokenIDSize)
+	if err != nil {
+		log.Println("error trying to generate token id")
+		return nil, fmt.Errorf("error al intentar generar el token: %w", err)
+	}
+
+	ss, err := a.keyRing.Sign(a.generateClaims(subject, tokenID))
 	if err != nil {
 		log.Println("error trying to generate token")
-		return nil, errors.New("error al intentar generar el token")
+		return nil, fmt.Errorf("error al intentar generar el token: %w", err)
 	}
 
-	return &ss, err
+	refreshToken, err := a.issueRefreshToken(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  ss,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenDuration.Seconds()),
+		TokenType:    tokenType,
+	}, nil
 
 }
 
-func (a *AuthService) ParseToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &models.CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte("secretPassword"), nil
+// GenerateClientToken issues an access token for a client of the
+// client_credentials grant. There is no refresh token, the client asks for a
+// new access token with its credentials.
+func (a *AuthService) GenerateClientToken(subject models.ClientTokenSubject) (*models.TokenResponse, error) {
+	tokenID, err := secureToken.Generate(tokenIDSize)
+	if err != nil {
+		log.Println("error trying to generate token id")
+		return nil, fmt.Errorf("error al intentar generar el token: %w", err)
+	}
+
+	scope := strings.Join(subject.Scopes, " ")
+	now := time.Now()
+	ss, err := a.keyRing.Sign(models.CustomClaims{
+		Permissions: subject.Scopes,
+		Scope:       scope,
+		ClientID:    subject.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    models.Issuer,
+			Subject:   subject.ClientID,
+			ID:        tokenID,
+			Audience:  []string{subject.Audience},
+		},
 	})
+	if err != nil {
+		log.Println("error trying to generate client token")
+		return nil, fmt.Errorf("error al intentar generar el token: %w", err)
+	}
+
+	return &models.TokenResponse{
+		AccessToken: ss,
+		ExpiresIn:   int64(accessTokenDuration.Seconds()),
+		TokenType:   tokenType,
+		Scope:       scope,
+	}, nil
+}
+
+// GenerateImpersonationToken issues an access token for the user of subject
+// whose act claim identifies the admin in ActorID. It has no session nor
+// refresh token.
+func (a *AuthService) GenerateImpersonationToken(subject models.TokenSubject) (*models.TokenResponse, error) {
+	if subject.ActorID == "" {
+		return nil, errors.New("impersonation token requires an actor")
+	}
+	subject.SessionID = ""
+
+	tokenID, err := secureToken.Generate(tokenIDSize)
+	if err != nil {
+		log.Println("error trying to generate token id")
+		return nil, fmt.Errorf("error al intentar generar el token: %w", err)
+	}
+
+	claims := a.generateClaims(subject, tokenID)
+	claims.ExpiresAt = jwt.NewNumericDate(claims.IssuedAt.Add(impersonationTokenDuration))
+	ss, err := a.keyRing.Sign(claims)
+	if err != nil {
+		log.Println("error trying to generate impersonation token")
+		return nil, fmt.Errorf("error al intentar generar el token: %w", err)
+	}
+
+	return &models.TokenResponse{
+		AccessToken: ss,
+		ExpiresIn:   int64(impersonationTokenDuration.Seconds()),
+		TokenType:   tokenType,
+	}, nil
+}
+
+// ConsumeRefreshToken validates and rotates a refresh token. Presenting a
+// token that was already used revokes its whole session.
+func (a *AuthService) ConsumeRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	stored, err := a.refreshTokenRepository.GetByHash(secureToken.Hash(refreshToken))
+	if err != nil {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		return nil, a.revokeReusedSession(stored)
+	}
+
+	marked, err := a.refreshTokenRepository.MarkUsed(stored.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !marked {
+		return nil, a.revokeReusedSession(stored)
+	}
+
+	return stored, nil
+}
+
+// ParseToken validates a token meant for this API.
+func (a *AuthService) ParseToken(tokenString string) (*jwt.Token, error) {
+	return a.parseToken(tokenString, jwt.WithAudience(models.ClientAudience))
+}
+
+// ParseTokenAnyAudience validates a token like ParseToken except for its
+// audience, which the caller has to check. Introspection uses it for the
+// client tokens issued for other audiences.
+func (a *AuthService) ParseTokenAnyAudience(tokenString string) (*jwt.Token, error) {
+	return a.parseToken(tokenString)
+}
+
+func (a *AuthService) parseToken(tokenString string, options ...jwt.ParserOption) (*jwt.Token, error) {
+	options = append(options, jwt.WithValidMethods(a.keyRing.Algorithms()), jwt.WithIssuer(models.Issuer))
+	token, err := jwt.ParseWithClaims(tokenString, &models.CustomClaims{}, a.keyRing.Keyfunc, options...)
 	if err != nil {
 		log.Println("ocurrio un error al intentar parsear el token")
 		return nil, err
 	}
-	if claims, ok := token.Claims.(*models.CustomClaims); ok {
-		log.Println(fmt.Sprintf("Retrieved userID claim is %s", claims.UserID))
-		return token, nil
-	} else {
+	claims, ok := token.Claims.(*models.CustomClaims)
+	if !ok {
 		log.Println("ocurrio un error al intentar parsear los claims del token")
 		return nil, errors.New("unknown error occurred trying to parse token claims")
 	}
 
+	if err := a.checkRevocation(claims); err != nil {
+		return nil, err
+	}
+	return token, nil
 }
 
-func (a *AuthService) generateClaims(email, userId string) models.CustomClaims {
+// RevokeToken denylists the access token until it expires and revokes the
+// refresh tokens of its session.
+func (a *AuthService) RevokeToken(claims *models.CustomClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return errors.New("token can not be revoked")
+	}
+	if err := a.denylistRepository.Add(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+	userID, err := strconv.ParseUint(claims.UserID, 10, 64)
+	if err != nil {
+		log.Println(fmt.Sprintf("invalid user id %s for session revocation", claims.UserID))
+		return fmt.Errorf("error al intentar revocar la sesion: %w", err)
+	}
+	return a.endSession(claims.SessionID, uint(userID))
+}
+
+// RevokeAllTokens invalidates every access and refresh token issued to the
+// user by bumping its token version.
+func (a *AuthService) RevokeAllTokens(userID string) error {
+	id, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		log.Println(fmt.Sprintf("invalid user id %s for token revocation", userID))
+		return fmt.Errorf("error al intentar revocar los tokens: %w", err)
+	}
+	if err := a.tokenVersionStore.IncrementTokenVersion(userID); err != nil {
+		return err
+	}
+	if err := a.sessionRepository.RevokeUser(uint(id), time.Now()); err != nil {
+		return err
+	}
+	return a.refreshTokenRepository.RevokeUser(uint(id))
+}
+
+func (a *AuthService) checkRevocation(claims *models.CustomClaims) error {
+	revoked, err := a.denylistRepository.Contains(claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		log.Println(fmt.Sprintf("token %s has been revoked", claims.ID))
+		return models.ErrTokenRevoked
+	}
+	if claims.IsClient() {
+		return nil
+	}
+
+	currentVersion, err := a.tokenVersionStore.GetTokenVersion(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if claims.TokenVersion < currentVersion {
+		log.Println(fmt.Sprintf("token %s has an outdated version for user %s", claims.ID, claims.UserID))
+		return models.ErrTokenRevoked
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	// Sessions started before they were persisted have no row, their
+	// tokens stay valid until they expire.
+	session, err := a.sessionRepository.Get(claims.SessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return nil
+	}
+	if session.RevokedAt != nil {
+		log.Println(fmt.Sprintf("token %s belongs to the revoked session %s", claims.ID, claims.SessionID))
+		return models.ErrTokenRevoked
+	}
+	if time.Since(session.LastSeenAt) >= sessionActivityInterval {
+		if err := a.sessionRepository.Touch(session.ID, time.Now(), ""); err != nil {
+			log.Println("error trying to update session activity: ", err.Error())
+		}
+	}
+	return nil
+}
+
+// startSession stores the session of a new login and revokes the oldest
+// sessions of the user over the configured limit.
+func (a *AuthService) startSession(subject models.TokenSubject) error {
+	userID, err := strconv.ParseUint(subject.UserID, 10, 64)
+	if err != nil {
+		log.Println(fmt.Sprintf("invalid user id %s for session", subject.UserID))
+		return fmt.Errorf("error al intentar iniciar la sesion: %w", err)
+	}
+
+	userAgent := subject.UserAgent
+	if len(userAgent) > userAgentMaxLength {
+		userAgent = userAgent[:userAgentMaxLength]
+	}
+	now := time.Now()
+	_, err = a.sessionRepository.Create(&models.Session{
+		ID:         subject.SessionID,
+		UserID:     uint(userID),
+		UserAgent:  userAgent,
+		IP:         subject.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.maxSessions <= 0 {
+		return nil
+	}
+	sessions, err := a.sessionRepository.ListActive(uint(userID), now.Add(-refreshTokenDuration))
+	if err != nil {
+		log.Println("error trying to list sessions to enforce the limit: ", err.Error())
+		return nil
+	}
+	for i := 0; i < len(sessions)-a.maxSessions; i++ {
+		if sessions[i].ID == subject.SessionID {
+			continue
+		}
+		log.Println(fmt.Sprintf("evicting session %s of user %d over the limit", sessions[i].ID, userID))
+		if err := revokeSession(a.sessionRepository, a.refreshTokenRepository, sessions[i].ID, uint(userID)); err != nil {
+			log.Println("error trying to evict session: ", err.Error())
+		}
+	}
+	return nil
+}
+
+// endSession revokes the session of a token. Sessions started before they
+// were persisted have no row, only their refresh tokens are revoked.
+func (a *AuthService) endSession(sessionID string, userID uint) error {
+	err := revokeSession(a.sessionRepository, a.refreshTokenRepository, sessionID, userID)
+	if errors.Is(err, models.ErrSessionNotFound) {
+		return a.refreshTokenRepository.RevokeSession(sessionID)
+	}
+	return err
+}
+
+func (a *AuthService) issueRefreshToken(subject models.TokenSubject) (string, error) {
+	userID, err := strconv.ParseUint(subject.UserID, 10, 64)
+	if err != nil {
+		log.Println(fmt.Sprintf("invalid user id %s for refresh token", subject.UserID))
+		return "", fmt.Errorf("error al intentar generar el refresh token: %w", err)
+	}
+
+	refreshToken, err := secureToken.Generate(refreshTokenSize)
+	if err != nil {
+		log.Println("error trying to generate refresh token")
+		return "", fmt.Errorf("error al intentar generar el refresh token: %w", err)
+	}
+
+	_, err = a.refreshTokenRepository.Create(&models.RefreshToken{
+		UserID:    uint(userID),
+		SessionID: subject.SessionID,
+		TokenHash: secureToken.Hash(refreshToken),
+		ExpiresAt: time.Now().Add(refreshTokenDuration),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+func (a *AuthService) revokeReusedSession(stored *models.RefreshToken) error {
+	log.Println(fmt.Sprintf("refresh token reuse detected for session %s, revoking it", stored.SessionID))
+	if err := a.endSession(stored.SessionID, stored.UserID); err != nil {
+		return err
+	}
+	return models.ErrRefreshTokenReused
+}
+
+func (a *AuthService) generateClaims(subject models.TokenSubject, tokenID string) models.CustomClaims {
+	clientID := subject.ClientID
+	if clientID == "" {
+		clientID = models.ClientAudience
+	}
+	var actor *models.Actor
+	if subject.ActorID != "" {
+		actor = &models.Actor{Subject: subject.ActorID, Email: subject.ActorEmail}
+	}
 	return models.CustomClaims{
-		UserID: userId,
-		Email:  email,
+		UserID:       subject.UserID,
+		Email:        subject.Email,
+		Role:         subject.Role,
+		Permissions:  subject.Permissions,
+		SessionID:    subject.SessionID,
+		TokenVersion: subject.TokenVersion,
+		Scope:        subject.Scope,
+		ClientID:     clientID,
+		Actor:        actor,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "chambeo-co",
-			Subject:   "chambeo-be",
-			ID:        userId,
-			Audience:  []string{"chambeo-fe"},
+			Issuer:    models.Issuer,
+			Subject:   subject.UserID,
+			ID:        tokenID,
+			Audience:  []string{models.ClientAudience},
 		},
 	}
 }
@@ -1,35 +1,117 @@
 package service
 
 import (
+	"chambeo-api-core/internal/auth/keys"
 	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"chambeo-api-core/pkg/secureToken"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func TestGenerateToken(t *testing.T) {
 
-	authService := NewJWTService()
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
 
-	result, err := authService.GenerateToken("meze@gmail.com", "1")
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	result, err := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
 	assert.NotNil(t, result)
 	assert.NoError(t, err)
+	assert.NotEmpty(t, result.AccessToken)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.Equal(t, "Bearer", result.TokenType)
+	assert.Equal(t, int64(900), result.ExpiresIn)
+
+	stored := refreshTokenRepository.Calls[0].Arguments.Get(0).(*models.RefreshToken)
+	assert.Equal(t, uint(1), stored.UserID)
+	assert.Equal(t, secureToken.Hash(result.RefreshToken), stored.TokenHash)
+	assert.NotEmpty(t, stored.SessionID)
+}
+
+func TestGenerateTokenKeepsSession(t *testing.T) {
+
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	result, err := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1", SessionID: "session"})
+	assert.NoError(t, err)
+
+	parsedToken, err := authService.ParseToken(result.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "session", parsedToken.Claims.(*models.CustomClaims).SessionID)
+	assert.Equal(t, "session", refreshTokenRepository.Calls[0].Arguments.Get(0).(*models.RefreshToken).SessionID)
+}
+
+func TestGenerateTokenWithRepositoryError(t *testing.T) {
+
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(nil, errors.New("error from repository"))
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	result, err := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
+	assert.Nil(t, result)
+	assert.Error(t, err)
 }
 
 func TestParseToken(t *testing.T) {
-	authService := NewJWTService()
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
 
 	email := "email@email.com"
 	userID := "1"
 
-	token, err := authService.GenerateToken(email, userID)
-	parsedToken, _ := authService.ParseToken(*token)
+	token, err := authService.GenerateToken(models.TokenSubject{Email: email, UserID: userID, Role: models.RoleUser, Permissions: []string{models.PermissionUsersRead}})
+	parsedToken, _ := authService.ParseToken(token.AccessToken)
 
 	assert.Equal(t, email, parsedToken.Claims.(*models.CustomClaims).Email)
+	assert.Equal(t, models.RoleUser, parsedToken.Claims.(*models.CustomClaims).Role)
+	assert.Equal(t, []string{models.PermissionUsersRead}, parsedToken.Claims.(*models.CustomClaims).Permissions)
+	assert.Equal(t, userID, parsedToken.Claims.(*models.CustomClaims).Subject)
+	assert.Equal(t, models.ClientAudience, parsedToken.Claims.(*models.CustomClaims).ClientID)
+	assert.NoError(t, err)
+}
+
+func TestGenerateClientToken(t *testing.T) {
+	// The token version store is not expected to be called for client tokens.
+	authService := NewJWTService(testKeyRing(t), &MockRefreshTokenRepository{}, repository.NewMemoryDenylist(), &MockTokenVersionStore{}, testSessionRepository(), 0)
+
+	result, err := authService.GenerateClientToken(models.ClientTokenSubject{
+		ClientID: "worker",
+		Scopes:   []string{models.PermissionUsersRead},
+		Audience: models.ClientAudience,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.RefreshToken)
+	assert.Equal(t, "users:read", result.Scope)
+
+	parsedToken, err := authService.ParseToken(result.AccessToken)
+	assert.NoError(t, err)
+	claims := parsedToken.Claims.(*models.CustomClaims)
+	assert.True(t, claims.IsClient())
+	assert.Equal(t, "worker", claims.Subject)
+	assert.Equal(t, []string{models.PermissionUsersRead}, claims.Permissions)
+
+	otherAudience, err := authService.GenerateClientToken(models.ClientTokenSubject{ClientID: "worker", Audience: "notifications"})
 	assert.NoError(t, err)
+	_, err = authService.ParseToken(otherAudience.AccessToken)
+	assert.Error(t, err, "tokens for other audiences must not be accepted by this API")
 }
 
 func TestParseTokenWithInvalidToken(t *testing.T) {
-	authService := NewJWTService()
+	authService := NewJWTService(testKeyRing(t), &MockRefreshTokenRepository{}, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
 
 	token := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoiMSIsImVtYWlsIjoibWV6ZUBnbWFpbC5jb20iLCJpc3MiOiJjaG" +
 		"FtYmVvLWNvIiwic3ViIjoiY2hhbWJlby1iZSIsImF1ZCI6WyJjaGFtYmVvLWZlIl0sImV4cCI6MTcwNTI3NjMyMiwibmJmIjoxNzA1MTg5OTI" +
@@ -39,3 +121,421 @@ func TestParseTokenWithInvalidToken(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, parsedToken)
 }
+
+func TestParseTokenSignedWithRetiredKey(t *testing.T) {
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+
+	oldKey, _ := keys.NewHMACKey("old", []byte("an-old-secret-that-is-long-enough!"))
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	newKey := keys.NewEd25519Key("new", privateKey)
+
+	oldRing, _ := keys.NewKeyRing("old", oldKey)
+	rotatedRing, _ := keys.NewKeyRing("new", oldKey, newKey)
+
+	oldService := NewJWTService(oldRing, refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+	rotatedService := NewJWTService(rotatedRing, refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	oldToken, _ := oldService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
+	newToken, _ := rotatedService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
+
+	parsedOldToken, err := rotatedService.ParseToken(oldToken.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", parsedOldToken.Header["kid"])
+
+	parsedNewToken, err := rotatedService.ParseToken(newToken.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", parsedNewToken.Header["kid"])
+	assert.Equal(t, jwt.SigningMethodEdDSA, parsedNewToken.Method)
+
+	_, err = oldService.ParseToken(newToken.AccessToken)
+	assert.Error(t, err)
+}
+
+func TestConsumeRefreshToken(t *testing.T) {
+
+	usedAt := time.Now().Add(-time.Minute)
+	revokedAt := time.Now().Add(-time.Minute)
+
+	validStoredToken := &models.RefreshToken{ID: 1, UserID: 1, SessionID: "session", ExpiresAt: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, repositoryMock *mock.Mock)
+		asserts        func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error)
+	}{
+		{
+			name: "valid refresh token should be consumed",
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByHash", secureToken.Hash("refresh")).Return(validStoredToken, nil)
+				repositoryMock.On("MarkUsed", uint(1), mock.Anything).Return(true, nil)
+			},
+			asserts: func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, validStoredToken, result)
+				repositoryMock.AssertNotCalled(t, "RevokeSession", mock.Anything)
+			},
+		},
+		{
+			name: "unknown refresh token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error) {
+				assert.Nil(t, result)
+				assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+			},
+		},
+		{
+			name: "expired refresh token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(&models.RefreshToken{ID: 1, SessionID: "session", ExpiresAt: time.Now().Add(-time.Hour)}, nil)
+			},
+			asserts: func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error) {
+				assert.Nil(t, result)
+				assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+			},
+		},
+		{
+			name: "revoked refresh token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(&models.RefreshToken{ID: 1, SessionID: "session", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, nil)
+			},
+			asserts: func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error) {
+				assert.Nil(t, result)
+				assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+			},
+		},
+		{
+			name: "reused refresh token should revoke the whole session",
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(&models.RefreshToken{ID: 1, UserID: 1, SessionID: "session", ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil)
+				repositoryMock.On("RevokeSession", "session").Return(nil)
+			},
+			asserts: func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error) {
+				assert.Nil(t, result)
+				assert.ErrorIs(t, err, models.ErrRefreshTokenReused)
+				repositoryMock.AssertCalled(t, "RevokeSession", "session")
+				sessionMock.AssertCalled(t, "Revoke", "session", uint(1), mock.Anything)
+			},
+		},
+		{
+			name: "concurrently used refresh token should revoke the whole session",
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(validStoredToken, nil)
+				repositoryMock.On("MarkUsed", uint(1), mock.Anything).Return(false, nil)
+				repositoryMock.On("RevokeSession", "session").Return(nil)
+			},
+			asserts: func(t *testing.T, repositoryMock, sessionMock *mock.Mock, result *models.RefreshToken, err error) {
+				assert.Nil(t, result)
+				assert.ErrorIs(t, err, models.ErrRefreshTokenReused)
+				repositoryMock.AssertCalled(t, "RevokeSession", "session")
+				sessionMock.AssertCalled(t, "Revoke", "session", uint(1), mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokenRepository := &MockRefreshTokenRepository{}
+			tt.mockedBehavior(t, &refreshTokenRepository.Mock)
+
+			sessionRepository := testSessionRepository()
+
+			authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), sessionRepository, 0)
+
+			result, err := authService.ConsumeRefreshToken("refresh")
+
+			tt.asserts(t, &refreshTokenRepository.Mock, &sessionRepository.Mock, result, err)
+		})
+	}
+}
+
+func TestParseTokenWithRevokedToken(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, denylist repository.DenylistRepositoryInterface, versionStore *MockTokenVersionStore, claims *models.CustomClaims)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "token not revoked should be valid",
+			mockedBehavior: func(t *testing.T, denylist repository.DenylistRepositoryInterface, versionStore *MockTokenVersionStore, claims *models.CustomClaims) {
+				versionStore.On("GetTokenVersion", "1").Return(0, nil)
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name: "denylisted token should be rejected",
+			mockedBehavior: func(t *testing.T, denylist repository.DenylistRepositoryInterface, versionStore *MockTokenVersionStore, claims *models.CustomClaims) {
+				versionStore.On("GetTokenVersion", "1").Return(0, nil)
+				_ = denylist.Add(claims.ID, claims.ExpiresAt.Time)
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, models.ErrTokenRevoked)
+			},
+		},
+		{
+			name: "token with an outdated version should be rejected",
+			mockedBehavior: func(t *testing.T, denylist repository.DenylistRepositoryInterface, versionStore *MockTokenVersionStore, claims *models.CustomClaims) {
+				versionStore.On("GetTokenVersion", "1").Return(1, nil)
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, models.ErrTokenRevoked)
+			},
+		},
+		{
+			name: "token version lookup error should be returned",
+			mockedBehavior: func(t *testing.T, denylist repository.DenylistRepositoryInterface, versionStore *MockTokenVersionStore, claims *models.CustomClaims) {
+				versionStore.On("GetTokenVersion", "1").Return(0, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokenRepository := &MockRefreshTokenRepository{}
+			refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+			denylist := repository.NewMemoryDenylist()
+			versionStore := &MockTokenVersionStore{}
+
+			ring := testKeyRing(t)
+			issuer := NewJWTService(ring, refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+			token, _ := issuer.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1"})
+			issued, _ := issuer.ParseToken(token.AccessToken)
+
+			tt.mockedBehavior(t, denylist, versionStore, issued.Claims.(*models.CustomClaims))
+
+			authService := NewJWTService(ring, refreshTokenRepository, denylist, versionStore, testSessionRepository(), 0)
+			_, err := authService.ParseToken(token.AccessToken)
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func TestGenerateTokenUsesUniqueTokenID(t *testing.T) {
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	first, _ := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1", TokenVersion: 2})
+	second, _ := authService.GenerateToken(models.TokenSubject{Email: "meze@gmail.com", UserID: "1", TokenVersion: 2})
+
+	firstClaims := parseUnverified(t, first.AccessToken)
+	secondClaims := parseUnverified(t, second.AccessToken)
+
+	assert.NotEmpty(t, firstClaims.ID)
+	assert.NotEqual(t, "1", firstClaims.ID)
+	assert.NotEqual(t, firstClaims.ID, secondClaims.ID)
+	assert.Equal(t, 2, firstClaims.TokenVersion)
+}
+
+func TestRevokeToken(t *testing.T) {
+	expiresAt := jwt.NewNumericDate(time.Now().Add(time.Minute))
+
+	tests := []struct {
+		name           string
+		claims         *models.CustomClaims
+		mockedBehavior func(t *testing.T, repositoryMock *mock.Mock)
+		asserts        func(t *testing.T, repositoryMock *mock.Mock, denylist repository.DenylistRepositoryInterface, err error)
+	}{
+		{
+			name:   "token with session should be denylisted and its session revoked",
+			claims: &models.CustomClaims{UserID: "1", SessionID: "session", RegisteredClaims: jwt.RegisteredClaims{ID: "jti", ExpiresAt: expiresAt}},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("RevokeSession", "session").Return(nil)
+			},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, denylist repository.DenylistRepositoryInterface, err error) {
+				assert.NoError(t, err)
+				revoked, _ := denylist.Contains("jti")
+				assert.True(t, revoked)
+				repositoryMock.AssertCalled(t, "RevokeSession", "session")
+			},
+		},
+		{
+			name:   "token of a session without row should revoke its refresh tokens",
+			claims: &models.CustomClaims{UserID: "1", SessionID: "legacy", RegisteredClaims: jwt.RegisteredClaims{ID: "jti", ExpiresAt: expiresAt}},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {
+				repositoryMock.On("RevokeSession", "legacy").Return(nil)
+			},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, denylist repository.DenylistRepositoryInterface, err error) {
+				assert.NoError(t, err)
+				repositoryMock.AssertCalled(t, "RevokeSession", "legacy")
+			},
+		},
+		{
+			name:           "token without session should only be denylisted",
+			claims:         &models.CustomClaims{RegisteredClaims: jwt.RegisteredClaims{ID: "jti", ExpiresAt: expiresAt}},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, denylist repository.DenylistRepositoryInterface, err error) {
+				assert.NoError(t, err)
+				revoked, _ := denylist.Contains("jti")
+				assert.True(t, revoked)
+				repositoryMock.AssertNotCalled(t, "RevokeSession", mock.Anything)
+			},
+		},
+		{
+			name:           "token without jti should return error",
+			claims:         &models.CustomClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expiresAt}},
+			mockedBehavior: func(t *testing.T, repositoryMock *mock.Mock) {},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, denylist repository.DenylistRepositoryInterface, err error) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokenRepository := &MockRefreshTokenRepository{}
+			tt.mockedBehavior(t, &refreshTokenRepository.Mock)
+			denylist := repository.NewMemoryDenylist()
+			sessionRepository := &MockSessionRepository{}
+			sessionRepository.On("Revoke", "legacy", mock.Anything, mock.Anything).Return(false, nil)
+			sessionRepository.On("Revoke", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+			authService := NewJWTService(testKeyRing(t), refreshTokenRepository, denylist, testTokenVersionStore(0), sessionRepository, 0)
+
+			err := authService.RevokeToken(tt.claims)
+
+			tt.asserts(t, &refreshTokenRepository.Mock, denylist, err)
+		})
+	}
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		userID         string
+		mockedBehavior func(t *testing.T, repositoryMock, versionStoreMock *mock.Mock)
+		asserts        func(t *testing.T, repositoryMock *mock.Mock, err error)
+	}{
+		{
+			name:   "revoke all should bump the token version and revoke refresh tokens",
+			userID: "1",
+			mockedBehavior: func(t *testing.T, repositoryMock, versionStoreMock *mock.Mock) {
+				versionStoreMock.On("IncrementTokenVersion", "1").Return(nil)
+				repositoryMock.On("RevokeUser", uint(1)).Return(nil)
+			},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				repositoryMock.AssertCalled(t, "RevokeUser", uint(1))
+			},
+		},
+		{
+			name:   "token version error should not revoke refresh tokens",
+			userID: "1",
+			mockedBehavior: func(t *testing.T, repositoryMock, versionStoreMock *mock.Mock) {
+				versionStoreMock.On("IncrementTokenVersion", "1").Return(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, err error) {
+				assert.Error(t, err)
+				repositoryMock.AssertNotCalled(t, "RevokeUser", mock.Anything)
+			},
+		},
+		{
+			name:           "invalid user id should return error",
+			userID:         "invalid",
+			mockedBehavior: func(t *testing.T, repositoryMock, versionStoreMock *mock.Mock) {},
+			asserts: func(t *testing.T, repositoryMock *mock.Mock, err error) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokenRepository := &MockRefreshTokenRepository{}
+			versionStore := &MockTokenVersionStore{}
+			tt.mockedBehavior(t, &refreshTokenRepository.Mock, &versionStore.Mock)
+
+			authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), versionStore, testSessionRepository(), 0)
+
+			err := authService.RevokeAllTokens(tt.userID)
+
+			tt.asserts(t, &refreshTokenRepository.Mock, err)
+		})
+	}
+}
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) Create(token *models.RefreshToken) (*models.RefreshToken, error) {
+	args := m.Called(token)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	args := m.Called(id, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeSession(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUser(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type MockTokenVersionStore struct {
+	mock.Mock
+}
+
+func (m *MockTokenVersionStore) GetTokenVersion(id string) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTokenVersionStore) IncrementTokenVersion(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func testTokenVersionStore(version int) *MockTokenVersionStore {
+	versionStore := &MockTokenVersionStore{}
+	versionStore.On("GetTokenVersion", mock.Anything).Return(version, nil)
+	return versionStore
+}
+
+func parseUnverified(t *testing.T, tokenString string) *models.CustomClaims {
+	claims := &models.CustomClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+func testKeyRing(t *testing.T) *keys.KeyRing {
+	key, err := keys.NewHMACKey("test", []byte("a-test-secret-that-is-long-enough"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := keys.NewKeyRing("test", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
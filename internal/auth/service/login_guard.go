@@ -0,0 +1,139 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	accountKeyPrefix = "account:"
+	ipKeyPrefix      = "ip:"
+)
+
+// LoginPolicy describes how failed logins are throttled. The first
+// FreeAttempts failures have no delay, after that every failure doubles the
+// wait starting at BaseDelay up to MaxDelay. Reaching LockoutThreshold locks
+// the subject for LockoutDuration. Failures older than Window are forgotten.
+type LoginPolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+var (
+	DefaultAccountLoginPolicy = LoginPolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+	// DefaultIPLoginPolicy is more permissive since many users can share an
+	// address behind a NAT.
+	DefaultIPLoginPolicy = LoginPolicy{
+		FreeAttempts:     10,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 50,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+)
+
+type LoginGuardInterface interface {
+	// Check returns how long the caller has to wait before trying again, zero
+	// when the login can be attempted.
+	Check(email string, ip string) (time.Duration, error)
+	RegisterFailure(email string, ip string) error
+	RegisterSuccess(email string) error
+}
+
+type LoginGuard struct {
+	attemptRepository repository.LoginAttemptRepositoryInterface
+	accountPolicy     LoginPolicy
+	ipPolicy          LoginPolicy
+	now               func() time.Time
+}
+
+func NewLoginGuard(attemptRepository repository.LoginAttemptRepositoryInterface, accountPolicy LoginPolicy,
+	ipPolicy LoginPolicy) LoginGuardInterface {
+	return &LoginGuard{
+		attemptRepository: attemptRepository,
+		accountPolicy:     accountPolicy,
+		ipPolicy:          ipPolicy,
+		now:               time.Now,
+	}
+}
+
+func (l *LoginGuard) Check(email string, ip string) (time.Duration, error) {
+	accountAttempt, err := l.attemptRepository.Get(accountKey(email))
+	if err != nil {
+		return 0, err
+	}
+	ipAttempt, err := l.attemptRepository.Get(ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	now := l.now()
+	accountWait := l.accountPolicy.retryAfter(accountAttempt, now)
+	ipWait := l.ipPolicy.retryAfter(ipAttempt, now)
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+	return accountWait, nil
+}
+
+func (l *LoginGuard) RegisterFailure(email string, ip string) error {
+	now := l.now()
+	if _, err := l.attemptRepository.RegisterFailure(accountKey(email), now, now.Add(-l.accountPolicy.Window)); err != nil {
+		return err
+	}
+	_, err := l.attemptRepository.RegisterFailure(ipKey(ip), now, now.Add(-l.ipPolicy.Window))
+	return err
+}
+
+// RegisterSuccess only clears the account counter, a valid login must not
+// reset the counter of an address that may be guessing other accounts.
+func (l *LoginGuard) RegisterSuccess(email string) error {
+	return l.attemptRepository.Reset(accountKey(email))
+}
+
+func (p LoginPolicy) retryAfter(attempt *models.LoginAttempt, now time.Time) time.Duration {
+	if attempt == nil || now.Sub(attempt.LastFailureAt) > p.Window {
+		return 0
+	}
+
+	var wait time.Duration
+	switch {
+	case attempt.Failures >= p.LockoutThreshold:
+		wait = p.LockoutDuration
+		log.Println("login locked for ", attempt.Key)
+	case attempt.Failures > p.FreeAttempts:
+		wait = p.BaseDelay << (attempt.Failures - p.FreeAttempts - 1)
+		if wait > p.MaxDelay || wait <= 0 {
+			wait = p.MaxDelay
+		}
+	}
+
+	remaining := attempt.LastFailureAt.Add(wait).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func accountKey(email string) string {
+	return accountKeyPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return ipKeyPrefix + ip
+}
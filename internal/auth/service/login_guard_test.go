@@ -0,0 +1,94 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/repository"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLoginGuard_AccountBackoffAndLockout(t *testing.T) {
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	guard := NewLoginGuard(repository.NewMemoryLoginAttempt(), DefaultAccountLoginPolicy, DefaultIPLoginPolicy).(*LoginGuard)
+	guard.now = func() time.Time { return now }
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 3, expected: 0},
+		{failures: 4, expected: time.Second},
+		{failures: 5, expected: 2 * time.Second},
+		{failures: 6, expected: 4 * time.Second},
+		{failures: 9, expected: 32 * time.Second},
+		{failures: 10, expected: 15 * time.Minute},
+	}
+
+	registered := 0
+	for _, tt := range tests {
+		for ; registered < tt.failures; registered++ {
+			// A different address for every attempt, only the account counter grows.
+			assert.NoError(t, guard.RegisterFailure("Meze@gmail.com", fmt.Sprintf("10.0.0.%d", registered)))
+		}
+
+		wait, err := guard.Check("meze@gmail.com", "10.0.0.200")
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, wait, "after %d failures", tt.failures)
+	}
+
+	now = now.Add(15 * time.Minute)
+	wait, _ := guard.Check("meze@gmail.com", "10.0.0.200")
+	assert.Equal(t, time.Duration(0), wait, "lockout should expire")
+}
+
+func TestLoginGuard_IPCounter(t *testing.T) {
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	guard := NewLoginGuard(repository.NewMemoryLoginAttempt(), DefaultAccountLoginPolicy, DefaultIPLoginPolicy).(*LoginGuard)
+	guard.now = func() time.Time { return now }
+
+	for i := 0; i < DefaultIPLoginPolicy.LockoutThreshold; i++ {
+		assert.NoError(t, guard.RegisterFailure(fmt.Sprintf("user%d@gmail.com", i), "10.0.0.1"))
+	}
+
+	wait, err := guard.Check("other@gmail.com", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, wait)
+
+	wait, err = guard.Check("other@gmail.com", "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+}
+
+func TestLoginGuard_SuccessResetsAccountOnly(t *testing.T) {
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	policy := LoginPolicy{FreeAttempts: 0, BaseDelay: time.Second, MaxDelay: time.Minute, LockoutThreshold: 10, LockoutDuration: time.Hour, Window: time.Hour}
+	guard := NewLoginGuard(repository.NewMemoryLoginAttempt(), policy, policy).(*LoginGuard)
+	guard.now = func() time.Time { return now }
+
+	assert.NoError(t, guard.RegisterFailure("meze@gmail.com", "10.0.0.1"))
+	assert.NoError(t, guard.RegisterSuccess("meze@gmail.com"))
+
+	wait, _ := guard.Check("meze@gmail.com", "10.0.0.2")
+	assert.Equal(t, time.Duration(0), wait)
+
+	wait, _ = guard.Check("meze@gmail.com", "10.0.0.1")
+	assert.Equal(t, time.Second, wait)
+}
+
+func TestLoginGuard_OldFailuresAreForgotten(t *testing.T) {
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	guard := NewLoginGuard(repository.NewMemoryLoginAttempt(), DefaultAccountLoginPolicy, DefaultIPLoginPolicy).(*LoginGuard)
+	guard.now = func() time.Time { return now }
+
+	for i := 0; i < 9; i++ {
+		assert.NoError(t, guard.RegisterFailure("meze@gmail.com", "10.0.0.1"))
+	}
+
+	now = now.Add(2 * time.Hour)
+	assert.NoError(t, guard.RegisterFailure("meze@gmail.com", "10.0.0.1"))
+
+	wait, _ := guard.Check("meze@gmail.com", "10.0.0.1")
+	assert.Equal(t, time.Duration(0), wait, "a failure after the window should start a new count")
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/keys"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/mailer"
+	"chambeo-api-core/pkg/secureToken"
+	"chambeo-api-core/pkg/throttle"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const magicLinkDuration = 15 * time.Minute
+
+type MagicLinkServiceInterface interface {
+	Send(email string) error
+	Consume(token string) (*userModels.UserRequest, error)
+}
+
+type MagicLinkService struct {
+	keyRing   *keys.KeyRing
+	userStore VerificationUserStore
+	usedLinks repository.DenylistRepositoryInterface
+	mailer    mailer.MailerInterface
+	throttle  throttle.ThrottleInterface
+	loginURL  string
+}
+
+func NewMagicLinkService(keyRing *keys.KeyRing, userStore VerificationUserStore, usedLinks repository.DenylistRepositoryInterface,
+	mailer mailer.MailerInterface, throttle throttle.ThrottleInterface, loginURL string) MagicLinkServiceInterface {
+	return &MagicLinkService{
+		keyRing:   keyRing,
+		userStore: userStore,
+		usedLinks: usedLinks,
+		mailer:    mailer,
+		throttle:  throttle,
+		loginURL:  loginURL,
+	}
+}
+
+// Send mails a login link to the user. It is throttled per email address and
+// unknown emails are ignored without error, so the endpoint does not reveal
+// which accounts exist.
+func (m *MagicLinkService) Send(email string) error {
+	allowed, err := m.throttle.Allow(strings.ToLower(email))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return models.ErrMagicLinkThrottled
+	}
+
+	user, err := m.userStore.GetByEmail(email)
+	if err != nil || user == nil {
+		log.Println("magic link requested for an unknown email")
+		return nil
+	}
+
+	tokenID, err := secureToken.Generate(tokenIDSize)
+	if err != nil {
+		log.Println("error trying to generate magic link id")
+		return fmt.Errorf("error al intentar generar el enlace de acceso: %w", err)
+	}
+
+	now := time.Now()
+	token, err := m.keyRing.Sign(models.VerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    models.Issuer,
+			Subject:   strconv.Itoa(user.Id),
+			Audience:  []string{models.MagicLinkAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(magicLinkDuration)),
+		},
+	})
+	if err != nil {
+		log.Println("error trying to sign magic link token")
+		return fmt.Errorf("error al intentar generar el enlace de acceso: %w", err)
+	}
+
+	return m.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Ingresa a Chambeo",
+		Body: fmt.Sprintf("Para ingresar a tu cuenta ingresa a %s?token=%s\r\n\r\nEl enlace vence en %d minutos y sirve una sola vez. Si no lo pediste, ignora este mensaje.",
+			m.loginURL, url.QueryEscape(token), int(magicLinkDuration.Minutes())),
+	})
+}
+
+// Consume spends the link and returns its user. The link is bound to the
+// email it was sent to, and opening it proves the user owns that address.
+func (m *MagicLinkService) Consume(token string) (*userModels.UserRequest, error) {
+	claims := &models.VerificationClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, m.keyRing.Keyfunc,
+		jwt.WithValidMethods(m.keyRing.Algorithms()),
+		jwt.WithIssuer(models.Issuer),
+		jwt.WithAudience(models.MagicLinkAudience),
+		jwt.WithExpirationRequired())
+	if err != nil || claims.ID == "" {
+		log.Println("invalid magic link token")
+		return nil, models.ErrInvalidMagicLink
+	}
+
+	user, err := m.userStore.Get(claims.Subject)
+	if err != nil || user == nil || !strings.EqualFold(user.Email, claims.Email) {
+		return nil, models.ErrInvalidMagicLink
+	}
+
+	consumed, err := m.usedLinks.Consume(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, models.ErrInvalidMagicLink
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := m.userStore.MarkEmailVerified(claims.Subject); err != nil {
+			return nil, err
+		}
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return user, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/mailer"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestMagicLinkService_SendAndConsume(t *testing.T) {
+
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, userStoreMock *mock.Mock)
+		asserts        func(t *testing.T, userStoreMock *mock.Mock, user *userModels.UserRequest, err error)
+	}{
+		{
+			name: "valid link should log the user in and verify the email",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				userStoreMock.On("MarkEmailVerified", "1").Return(nil)
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, user.Id)
+				assert.NotNil(t, user.EmailVerifiedAt)
+				userStoreMock.AssertCalled(t, "MarkEmailVerified", "1")
+			},
+		},
+		{
+			name: "verified user should not be verified again",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com", EmailVerifiedAt: &verifiedAt}, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.NoError(t, err)
+				userStoreMock.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+			},
+		},
+		{
+			name: "link for a previous email should be rejected",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(&userModels.UserRequest{Id: 1, Email: "other@gmail.com"}, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidMagicLink)
+				assert.Nil(t, user)
+			},
+		},
+		{
+			name: "deleted user should be rejected",
+			mockedBehavior: func(t *testing.T, userStoreMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidMagicLink)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &MockVerificationUserStore{}
+			userStore.On("GetByEmail", "meze@gmail.com").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+			tt.mockedBehavior(t, &userStore.Mock)
+			mockedThrottle := &MockThrottle{}
+			mockedThrottle.On("Allow", "meze@gmail.com").Return(true, nil)
+			mockedMailer := &MockMailer{}
+			mockedMailer.On("Send", mock.Anything).Return(nil)
+
+			magicLinkService := NewMagicLinkService(testKeyRing(t), userStore, repository.NewMemoryDenylist(), mockedMailer, mockedThrottle, "https://chambeo.co/magic-link")
+
+			assert.NoError(t, magicLinkService.Send("meze@gmail.com"))
+			message := mockedMailer.Calls[0].Arguments.Get(0).(mailer.Message)
+			assert.Equal(t, "meze@gmail.com", message.To)
+
+			user, err := magicLinkService.Consume(verificationToken(t, message))
+
+			tt.asserts(t, &userStore.Mock, user, err)
+		})
+	}
+}
+
+func TestMagicLinkService_ConsumeIsSingleUse(t *testing.T) {
+	userStore := &MockVerificationUserStore{}
+	userStore.On("GetByEmail", "meze@gmail.com").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com", EmailVerifiedAt: &time.Time{}}, nil)
+	userStore.On("Get", "1").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com", EmailVerifiedAt: &time.Time{}}, nil)
+	mockedThrottle := &MockThrottle{}
+	mockedThrottle.On("Allow", "meze@gmail.com").Return(true, nil)
+	mockedMailer := &MockMailer{}
+	mockedMailer.On("Send", mock.Anything).Return(nil)
+
+	magicLinkService := NewMagicLinkService(testKeyRing(t), userStore, repository.NewMemoryDenylist(), mockedMailer, mockedThrottle, "https://chambeo.co/magic-link")
+
+	assert.NoError(t, magicLinkService.Send("meze@gmail.com"))
+	token := verificationToken(t, mockedMailer.Calls[0].Arguments.Get(0).(mailer.Message))
+
+	_, err := magicLinkService.Consume(token)
+	assert.NoError(t, err)
+
+	_, err = magicLinkService.Consume(token)
+	assert.ErrorIs(t, err, models.ErrInvalidMagicLink)
+}
+
+func TestMagicLinkService_ConsumeRejectsOtherTokens(t *testing.T) {
+	ring := testKeyRing(t)
+	verificationToken, _ := ring.Sign(models.VerificationClaims{
+		Email: "meze@gmail.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			Issuer:    models.Issuer,
+			Subject:   "1",
+			Audience:  []string{models.EmailVerificationAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	expiredLink, _ := ring.Sign(models.VerificationClaims{
+		Email: "meze@gmail.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			Issuer:    models.Issuer,
+			Subject:   "1",
+			Audience:  []string{models.MagicLinkAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	})
+
+	magicLinkService := NewMagicLinkService(ring, &MockVerificationUserStore{}, repository.NewMemoryDenylist(), &MockMailer{}, &MockThrottle{}, "https://chambeo.co/magic-link")
+
+	for _, token := range []string{verificationToken, expiredLink, "not-a-token"} {
+		_, err := magicLinkService.Consume(token)
+		assert.ErrorIs(t, err, models.ErrInvalidMagicLink)
+	}
+}
+
+func TestMagicLinkService_Send(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock)
+		asserts        func(t *testing.T, mailerMock *mock.Mock, err error)
+	}{
+		{
+			name: "throttled email should be rejected",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(false, nil)
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrMagicLinkThrottled)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "unknown email should not receive anything",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(true, nil)
+				userStoreMock.On("GetByEmail", "Meze@gmail.com").Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "mailer error should be returned",
+			mockedBehavior: func(t *testing.T, userStoreMock, throttleMock, mailerMock *mock.Mock) {
+				throttleMock.On("Allow", "meze@gmail.com").Return(true, nil)
+				userStoreMock.On("GetByEmail", "Meze@gmail.com").Return(&userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}, nil)
+				mailerMock.On("Send", mock.Anything).Return(errors.New("smtp down"))
+			},
+			asserts: func(t *testing.T, mailerMock *mock.Mock, err error) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &MockVerificationUserStore{}
+			mockedThrottle := &MockThrottle{}
+			mockedMailer := &MockMailer{}
+			tt.mockedBehavior(t, &userStore.Mock, &mockedThrottle.Mock, &mockedMailer.Mock)
+
+			magicLinkService := NewMagicLinkService(testKeyRing(t), userStore, repository.NewMemoryDenylist(), mockedMailer, mockedThrottle, "https://chambeo.co/magic-link")
+
+			err := magicLinkService.Send("Meze@gmail.com")
+
+			tt.asserts(t, &mockedMailer.Mock, err)
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/keys"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"chambeo-api-core/pkg/secretBox"
+	"chambeo-api-core/pkg/secureToken"
+	"chambeo-api-core/pkg/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mfaIssuerName          = "Chambeo"
+	mfaPendingDuration     = 5 * time.Minute
+	mfaMaxAttempts         = 5
+	mfaAttemptKeyPrefix    = "mfa:"
+	recoveryCodeCount      = 10
+	recoveryCodeSize       = 5
+	recoveryCodeGroupWidth = 4
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAServiceInterface interface {
+	Enroll(userID string, account string) (*models.MFAEnrollmentResponse, error)
+	Confirm(userID string, code string) ([]string, error)
+	Disable(userID string, code string) error
+	IsEnabled(userID string) (bool, error)
+	Verify(userID string, code string) error
+	IssuePendingToken(userID string) (*models.MFAPendingResponse, error)
+	ParsePendingToken(token string) (string, error)
+	ConsumePendingToken(token string) error
+}
+
+type MFAService struct {
+	keyRing       *keys.KeyRing
+	mfaRepository repository.MFARepositoryInterface
+	// usedTokens keeps the jti of the mfa_pending tokens already exchanged
+	// for a session.
+	usedTokens repository.DenylistRepositoryInterface
+	// attemptRepository counts the codes tried per mfa_pending token, so a
+	// stolen password can not be paired with a brute forced code. It is the
+	// login attempts store, shared by every instance.
+	attemptRepository repository.LoginAttemptRepositoryInterface
+	// secretBox encrypts the TOTP secrets before they are stored.
+	secretBox secretBox.BoxInterface
+}
+
+func NewMFAService(keyRing *keys.KeyRing, mfaRepository repository.MFARepositoryInterface,
+	usedTokens repository.DenylistRepositoryInterface, attemptRepository repository.LoginAttemptRepositoryInterface,
+	secretBox secretBox.BoxInterface) MFAServiceInterface {
+	return &MFAService{
+		keyRing:           keyRing,
+		mfaRepository:     mfaRepository,
+		usedTokens:        usedTokens,
+		attemptRepository: attemptRepository,
+		secretBox:         secretBox,
+	}
+}
+
+// Enroll starts a new enrollment, replacing any unconfirmed one. It is not
+// enforced on login until Confirm succeeds.
+func (m *MFAService) Enroll(userID string, account string) (*models.MFAEnrollmentResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("id de usuario invalido: %w", err)
+	}
+
+	enrollment, err := m.mfaRepository.Get(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if enrollment != nil && enrollment.ConfirmedAt != nil {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Println("error trying to generate totp secret")
+		return nil, fmt.Errorf("error al intentar generar el secreto de doble factor: %w", err)
+	}
+
+	sealedSecret, err := m.secretBox.Seal(secret)
+	if err != nil {
+		log.Println("error trying to encrypt totp secret")
+		return nil, fmt.Errorf("error al intentar cifrar el secreto de doble factor: %w", err)
+	}
+
+	err = m.mfaRepository.Enroll(&models.MFAEnrollment{UserID: uint(id), Secret: sealedSecret})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollmentResponse{
+		Secret:     secret,
+		OtpauthURI: totp.URI(mfaIssuerName, account, secret, totp.DefaultOptions),
+	}, nil
+}
+
+// Confirm enables the pending enrollment once the user sends a valid code
+// and returns the recovery codes, which are only shown this time.
+func (m *MFAService) Confirm(userID string, code string) ([]string, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("id de usuario invalido: %w", err)
+	}
+
+	enrollment, err := m.mfaRepository.Get(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, models.ErrMFANotEnrolled
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+	if err := m.verifyTOTP(enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			log.Println("error trying to generate recovery code")
+			return nil, fmt.Errorf("error al intentar generar los codigos de recuperacion: %w", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, secureToken.Hash(normalizeRecoveryCode(code)))
+	}
+
+	if err := m.mfaRepository.Confirm(uint(id), time.Now(), hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes the enrollment, it requires a valid TOTP or recovery code.
+func (m *MFAService) Disable(userID string, code string) error {
+	if err := m.Verify(userID, code); err != nil {
+		return err
+	}
+	id, _ := strconv.Atoi(userID)
+	return m.mfaRepository.Delete(uint(id))
+}
+
+func (m *MFAService) IsEnabled(userID string) (bool, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return false, fmt.Errorf("id de usuario invalido: %w", err)
+	}
+	enrollment, err := m.mfaRepository.Get(uint(id))
+	if err != nil {
+		return false, err
+	}
+	return enrollment != nil && enrollment.ConfirmedAt != nil, nil
+}
+
+// Verify accepts a TOTP code or one of the unused recovery codes of the
+// user. Both are single use.
+func (m *MFAService) Verify(userID string, code string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return models.ErrInvalidMFACode
+	}
+
+	enrollment, err := m.mfaRepository.Get(uint(id))
+	if err != nil {
+		return err
+	}
+	if enrollment == nil || enrollment.ConfirmedAt == nil {
+		return models.ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.DefaultOptions.Digits {
+		return m.verifyTOTP(enrollment, code)
+	}
+
+	used, err := m.mfaRepository.UseRecoveryCode(uint(id), secureToken.Hash(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return models.ErrInvalidMFACode
+	}
+	log.Println("recovery code used by user ", userID)
+	return nil
+}
+
+func (m *MFAService) IssuePendingToken(userID string) (*models.MFAPendingResponse, error) {
+	tokenID, err := secureToken.Generate(tokenIDSize)
+	if err != nil {
+		log.Println("error trying to generate mfa token id")
+		return nil, fmt.Errorf("error al intentar generar el token de doble factor: %w", err)
+	}
+
+	token, err := m.keyRing.Sign(jwt.RegisteredClaims{
+		Issuer:    models.Issuer,
+		Subject:   userID,
+		Audience:  []string{models.MFAAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaPendingDuration)),
+		ID:        tokenID,
+	})
+	if err != nil {
+		log.Println("error trying to sign mfa pending token")
+		return nil, fmt.Errorf("error al intentar generar el token de doble factor: %w", err)
+	}
+
+	return &models.MFAPendingResponse{
+		MFAToken:  token,
+		ExpiresIn: int64(mfaPendingDuration.Seconds()),
+		TokenType: models.MFAPendingTokenType,
+	}, nil
+}
+
+// ParsePendingToken returns the user id of a valid mfa_pending token. Each
+// call counts as an attempt, after mfaMaxAttempts the token is rejected and
+// the user has to log in again.
+func (m *MFAService) ParsePendingToken(token string) (string, error) {
+	claims, err := m.parsePendingClaims(token)
+	if err != nil {
+		return "", err
+	}
+
+	used, err := m.usedTokens.Contains(claims.ID)
+	if err != nil {
+		return "", err
+	}
+	if used {
+		return "", models.ErrInvalidMFAToken
+	}
+
+	// A zero resetBefore never resets the counter of the token, its row is
+	// purged with the old login failures.
+	attempt, err := m.attemptRepository.RegisterFailure(mfaAttemptKeyPrefix+claims.ID, time.Now(), time.Time{})
+	if err != nil {
+		return "", err
+	}
+	if attempt.Failures > mfaMaxAttempts {
+		return "", models.ErrInvalidMFAToken
+	}
+	return claims.Subject, nil
+}
+
+// ConsumePendingToken spends the mfa_pending token once its code was
+// accepted, so it can not be exchanged for another session.
+func (m *MFAService) ConsumePendingToken(token string) error {
+	claims, err := m.parsePendingClaims(token)
+	if err != nil {
+		return err
+	}
+
+	consumed, err := m.usedTokens.Consume(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return models.ErrInvalidMFAToken
+	}
+	return nil
+}
+
+func (m *MFAService) parsePendingClaims(token string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, m.keyRing.Keyfunc,
+		jwt.WithValidMethods(m.keyRing.Algorithms()),
+		jwt.WithIssuer(models.Issuer),
+		jwt.WithAudience(models.MFAAudience),
+		jwt.WithExpirationRequired())
+	if err != nil || claims.ID == "" {
+		return nil, models.ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+func (m *MFAService) verifyTOTP(enrollment *models.MFAEnrollment, code string) error {
+	secret, err := m.secretBox.Open(enrollment.Secret)
+	if err != nil {
+		log.Println("totp secret stored for user ", enrollment.UserID, " can not be decrypted")
+		return fmt.Errorf("secreto de doble factor invalido: %w", err)
+	}
+	key, err := totp.DecodeSecret(secret)
+	if err != nil {
+		log.Println("invalid totp secret stored for user ", enrollment.UserID)
+		return fmt.Errorf("secreto de doble factor invalido: %w", err)
+	}
+
+	step, ok := totp.Validate(key, code, time.Now(), totp.DefaultOptions)
+	if !ok {
+		return models.ErrInvalidMFACode
+	}
+	used, err := m.mfaRepository.UseStep(enrollment.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return models.ErrInvalidMFACode
+	}
+	if !secretBox.IsSealed(enrollment.Secret) {
+		m.sealStoredSecret(enrollment.UserID, secret)
+	}
+	return nil
+}
+
+// sealStoredSecret encrypts a secret stored before encryption was enabled,
+// an error only leaves it as it was.
+func (m *MFAService) sealStoredSecret(userID uint, secret string) {
+	sealedSecret, err := m.secretBox.Seal(secret)
+	if err == nil {
+		err = m.mfaRepository.UpdateSecret(userID, sealedSecret)
+	}
+	if err != nil {
+		log.Println("error trying to encrypt the stored totp secret of user ", userID)
+	}
+}
+
+// generateRecoveryCode returns a code like "abcd-efgh", easy to type from a
+// printed copy.
+func generateRecoveryCode() (string, error) {
+	buffer := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buffer))
+	return code[:recoveryCodeGroupWidth] + "-" + code[recoveryCodeGroupWidth:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
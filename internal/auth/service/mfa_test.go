@@ -0,0 +1,370 @@
+package service
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"chambeo-api-core/pkg/secretBox"
+	"chambeo-api-core/pkg/secureToken"
+	"chambeo-api-core/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMFASecret = "JBSWY3DPEHPK3PXP"
+
+func TestMFAService_Enroll(t *testing.T) {
+	confirmedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mfaMock *mock.Mock)
+		asserts        func(t *testing.T, response *models.MFAEnrollmentResponse, err error)
+	}{
+		{
+			name: "new enrollment should return secret and uri",
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(nil, nil)
+				mfaMock.On("Enroll", mock.MatchedBy(func(enrollment *models.MFAEnrollment) bool {
+					return secretBox.IsSealed(enrollment.Secret)
+				})).Return(nil)
+			},
+			asserts: func(t *testing.T, response *models.MFAEnrollmentResponse, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, response.Secret, 32)
+				assert.True(t, strings.HasPrefix(response.OtpauthURI, "otpauth://totp/Chambeo:meze@gmail.com?"))
+				assert.Contains(t, response.OtpauthURI, "secret="+response.Secret)
+			},
+		},
+		{
+			name: "confirmed enrollment should not be replaced",
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: testMFASecret, ConfirmedAt: &confirmedAt}, nil)
+			},
+			asserts: func(t *testing.T, response *models.MFAEnrollmentResponse, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrMFAAlreadyEnabled)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfaRepository := &MockMFARepository{}
+			tt.mockedBehavior(t, &mfaRepository.Mock)
+
+			mfaService := NewMFAService(testKeyRing(t), mfaRepository, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t))
+
+			response, err := mfaService.Enroll("1", "meze@gmail.com")
+
+			tt.asserts(t, response, err)
+		})
+	}
+}
+
+func TestMFAService_Confirm(t *testing.T) {
+	validCode := currentCode(t)
+	sealedSecret := sealedTestSecret(t)
+
+	tests := []struct {
+		name           string
+		code           string
+		mockedBehavior func(t *testing.T, mfaMock *mock.Mock)
+		asserts        func(t *testing.T, mfaMock *mock.Mock, codes []string, err error)
+	}{
+		{
+			name: "valid code should enable mfa and return recovery codes",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: sealedSecret}, nil)
+				mfaMock.On("UseStep", uint(1), mock.Anything).Return(true, nil)
+				mfaMock.On("Confirm", uint(1), mock.Anything, mock.Anything).Return(nil)
+			},
+			asserts: func(t *testing.T, mfaMock *mock.Mock, codes []string, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, codes, recoveryCodeCount)
+				hashes := mfaMock.Calls[2].Arguments.Get(2).([]string)
+				assert.Equal(t, secureToken.Hash(normalizeRecoveryCode(codes[0])), hashes[0])
+				assert.Regexp(t, "^[a-z2-7]{4}-[a-z2-7]{4}$", codes[0])
+			},
+		},
+		{
+			name: "wrong code should be rejected",
+			code: "000000",
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: testMFASecret}, nil)
+			},
+			asserts: func(t *testing.T, mfaMock *mock.Mock, codes []string, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidMFACode)
+				mfaMock.AssertNotCalled(t, "Confirm", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "missing enrollment should be rejected",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(nil, nil)
+			},
+			asserts: func(t *testing.T, mfaMock *mock.Mock, codes []string, err error) {
+				assert.ErrorIs(t, err, models.ErrMFANotEnrolled)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfaRepository := &MockMFARepository{}
+			tt.mockedBehavior(t, &mfaRepository.Mock)
+
+			mfaService := NewMFAService(testKeyRing(t), mfaRepository, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t))
+
+			codes, err := mfaService.Confirm("1", tt.code)
+
+			tt.asserts(t, &mfaRepository.Mock, codes, err)
+		})
+	}
+}
+
+func TestMFAService_Verify(t *testing.T) {
+	confirmedAt := time.Now()
+	enrollment := &models.MFAEnrollment{UserID: 1, Secret: sealedTestSecret(t), ConfirmedAt: &confirmedAt}
+	validCode := currentCode(t)
+
+	tests := []struct {
+		name           string
+		code           string
+		mockedBehavior func(t *testing.T, mfaMock *mock.Mock)
+		expectedError  error
+	}{
+		{
+			name: "valid totp code should be accepted",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(enrollment, nil)
+				mfaMock.On("UseStep", uint(1), mock.Anything).Return(true, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "secret stored before encryption should be accepted and encrypted",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: testMFASecret, ConfirmedAt: &confirmedAt}, nil)
+				mfaMock.On("UseStep", uint(1), mock.Anything).Return(true, nil)
+				mfaMock.On("UpdateSecret", uint(1), mock.MatchedBy(secretBox.IsSealed)).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "secret sealed with another key should return error",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				otherBox, _ := secretBox.NewBox(bytes.Repeat([]byte{2}, secretBox.KeySize))
+				otherSecret, _ := otherBox.Seal(testMFASecret)
+				mfaMock.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: otherSecret, ConfirmedAt: &confirmedAt}, nil)
+			},
+			expectedError: secretBox.ErrInvalidSealed,
+		},
+		{
+			name: "replayed totp code should be rejected",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(enrollment, nil)
+				mfaMock.On("UseStep", uint(1), mock.Anything).Return(false, nil)
+			},
+			expectedError: models.ErrInvalidMFACode,
+		},
+		{
+			name: "unused recovery code should be accepted",
+			code: "ABCD-EFGH",
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(enrollment, nil)
+				mfaMock.On("UseRecoveryCode", uint(1), secureToken.Hash("abcdefgh"), mock.Anything).Return(true, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "used recovery code should be rejected",
+			code: "abcd-efgh",
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(enrollment, nil)
+				mfaMock.On("UseRecoveryCode", uint(1), secureToken.Hash("abcdefgh"), mock.Anything).Return(false, nil)
+			},
+			expectedError: models.ErrInvalidMFACode,
+		},
+		{
+			name: "user without mfa should be rejected",
+			code: validCode,
+			mockedBehavior: func(t *testing.T, mfaMock *mock.Mock) {
+				mfaMock.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: testMFASecret}, nil)
+			},
+			expectedError: models.ErrMFANotEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfaRepository := &MockMFARepository{}
+			tt.mockedBehavior(t, &mfaRepository.Mock)
+
+			mfaService := NewMFAService(testKeyRing(t), mfaRepository, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t))
+
+			err := mfaService.Verify("1", tt.code)
+
+			if tt.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestMFAService_Disable(t *testing.T) {
+	confirmedAt := time.Now()
+	mfaRepository := &MockMFARepository{}
+	mfaRepository.On("Get", uint(1)).Return(&models.MFAEnrollment{UserID: 1, Secret: sealedTestSecret(t), ConfirmedAt: &confirmedAt}, nil)
+	mfaRepository.On("UseStep", uint(1), mock.Anything).Return(true, nil)
+	mfaRepository.On("Delete", uint(1)).Return(nil)
+
+	mfaService := NewMFAService(testKeyRing(t), mfaRepository, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t))
+
+	assert.NoError(t, mfaService.Disable("1", currentCode(t)))
+	mfaRepository.AssertCalled(t, "Delete", uint(1))
+}
+
+func TestMFAService_PendingToken(t *testing.T) {
+	ring := testKeyRing(t)
+	mfaService := NewMFAService(ring, &MockMFARepository{}, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t))
+
+	pending, err := mfaService.IssuePendingToken("1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MFAPendingTokenType, pending.TokenType)
+	assert.Equal(t, int64(300), pending.ExpiresIn)
+
+	for i := 0; i < mfaMaxAttempts; i++ {
+		userID, err := mfaService.ParsePendingToken(pending.MFAToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", userID)
+	}
+
+	_, err = mfaService.ParsePendingToken(pending.MFAToken)
+	assert.ErrorIs(t, err, models.ErrInvalidMFAToken, "token should be rejected after too many attempts")
+
+	_, err = mfaService.ParsePendingToken("not-a-token")
+	assert.ErrorIs(t, err, models.ErrInvalidMFAToken)
+}
+
+func TestMFAService_PendingTokenAttemptsAreShared(t *testing.T) {
+	ring := testKeyRing(t)
+	attemptRepository := repository.NewMemoryLoginAttempt()
+	firstInstance := NewMFAService(ring, &MockMFARepository{}, repository.NewMemoryDenylist(), attemptRepository, testSecretBox(t))
+	secondInstance := NewMFAService(ring, &MockMFARepository{}, repository.NewMemoryDenylist(), attemptRepository, testSecretBox(t))
+
+	pending, err := firstInstance.IssuePendingToken("1")
+	assert.NoError(t, err)
+
+	for i := 0; i < mfaMaxAttempts; i++ {
+		instance := firstInstance
+		if i%2 == 1 {
+			instance = secondInstance
+		}
+		_, err := instance.ParsePendingToken(pending.MFAToken)
+		assert.NoError(t, err)
+	}
+
+	_, err = secondInstance.ParsePendingToken(pending.MFAToken)
+	assert.ErrorIs(t, err, models.ErrInvalidMFAToken, "attempts made on another instance should count")
+}
+
+func TestMFAService_ConsumePendingToken(t *testing.T) {
+	mfaService := NewMFAService(testKeyRing(t), &MockMFARepository{}, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t))
+
+	pending, err := mfaService.IssuePendingToken("1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, mfaService.ConsumePendingToken(pending.MFAToken))
+	assert.ErrorIs(t, mfaService.ConsumePendingToken(pending.MFAToken), models.ErrInvalidMFAToken, "token should be spent once")
+
+	_, err = mfaService.ParsePendingToken(pending.MFAToken)
+	assert.ErrorIs(t, err, models.ErrInvalidMFAToken, "spent token should not be accepted again")
+}
+
+func TestMFAService_PendingTokenIsNotAnAccessToken(t *testing.T) {
+	ring := testKeyRing(t)
+	pending, _ := NewMFAService(ring, &MockMFARepository{}, repository.NewMemoryDenylist(), repository.NewMemoryLoginAttempt(), testSecretBox(t)).IssuePendingToken("1")
+
+	authService := NewJWTService(ring, &MockRefreshTokenRepository{}, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+
+	_, err := authService.ParseToken(pending.MFAToken)
+	assert.Error(t, err)
+}
+
+func testSecretBox(t *testing.T) secretBox.BoxInterface {
+	box, err := secretBox.NewBox(bytes.Repeat([]byte{1}, secretBox.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func sealedTestSecret(t *testing.T) string {
+	sealed, err := testSecretBox(t).Seal(testMFASecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func currentCode(t *testing.T) string {
+	key, err := totp.DecodeSecret(testMFASecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totp.GenerateCode(key, time.Now(), totp.DefaultOptions)
+}
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) Get(userID uint) (*models.MFAEnrollment, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockMFARepository) Enroll(enrollment *models.MFAEnrollment) error {
+	args := m.Called(enrollment)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) Confirm(userID uint, confirmedAt time.Time, codeHashes []string) error {
+	args := m.Called(userID, confirmedAt, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseStep(userID uint, step int64) (bool, error) {
+	args := m.Called(userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) UpdateSecret(userID uint, secret string) error {
+	args := m.Called(userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	args := m.Called(userID, codeHash, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) Delete(userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"chambeo-api-core/pkg/secureToken"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	oauthClientIDSize     = 16
+	oauthClientSecretSize = 32
+)
+
+type OAuthClientServiceInterface interface {
+	Register(request *models.CreateOAuthClientRequest) (*models.CreatedOAuthClientResponse, error)
+	List() ([]models.OAuthClientResponse, error)
+	Revoke(clientID string) error
+	// IssueToken authenticates the client and issues an access token for
+	// the requested scopes and audience, every allowed scope and the first
+	// allowed audience when they are empty.
+	IssueToken(clientID string, clientSecret string, scope string, audience string) (*models.TokenResponse, error)
+}
+
+type ClientTokenIssuer interface {
+	GenerateClientToken(subject models.ClientTokenSubject) (*models.TokenResponse, error)
+}
+
+type OAuthClientService struct {
+	clientRepository repository.OAuthClientRepositoryInterface
+	tokenIssuer      ClientTokenIssuer
+}
+
+func NewOAuthClientService(clientRepository repository.OAuthClientRepositoryInterface, tokenIssuer ClientTokenIssuer) OAuthClientServiceInterface {
+	return &OAuthClientService{
+		clientRepository: clientRepository,
+		tokenIssuer:      tokenIssuer,
+	}
+}
+
+// Register stores a new client, the secret is only returned here.
+func (o *OAuthClientService) Register(request *models.CreateOAuthClientRequest) (*models.CreatedOAuthClientResponse, error) {
+	scopes, ok := uniqueTokens(request.Scopes)
+	if !ok || len(scopes) == 0 {
+		return nil, models.ErrInvalidClientScope
+	}
+	for _, scope := range scopes {
+		if !models.IsClientScope(scope) {
+			return nil, models.ErrInvalidClientScope
+		}
+	}
+	audiences, ok := uniqueTokens(request.Audiences)
+	if !ok {
+		return nil, models.ErrInvalidClientAudience
+	}
+	if len(audiences) == 0 {
+		audiences = []string{models.ClientAudience}
+	}
+
+	clientID, errID := secureToken.Generate(oauthClientIDSize)
+	clientSecret, errSecret := secureToken.Generate(oauthClientSecretSize)
+	if errID != nil || errSecret != nil {
+		log.Println("error trying to generate oauth client credentials")
+		return nil, fmt.Errorf("error al intentar generar las credenciales del cliente: %w", errors.Join(errID, errSecret))
+	}
+
+	client, err := o.clientRepository.Create(&models.OAuthClient{
+		ClientID:   clientID,
+		Name:       request.Name,
+		SecretHash: secureToken.Hash(clientSecret),
+		Scopes:     strings.Join(scopes, " "),
+		Audiences:  strings.Join(audiences, " "),
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedOAuthClientResponse{OAuthClientResponse: oauthClientResponse(client), ClientSecret: clientSecret}, nil
+}
+
+func (o *OAuthClientService) List() ([]models.OAuthClientResponse, error) {
+	clients, err := o.clientRepository.List()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.OAuthClientResponse, 0, len(clients))
+	for i := range clients {
+		responses = append(responses, oauthClientResponse(&clients[i]))
+	}
+	return responses, nil
+}
+
+// Revoke stops the client from getting new tokens, the ones already issued
+// are valid until they expire.
+func (o *OAuthClientService) Revoke(clientID string) error {
+	revoked, err := o.clientRepository.Revoke(clientID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return models.ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func (o *OAuthClientService) IssueToken(clientID string, clientSecret string, scope string, audience string) (*models.TokenResponse, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, models.ErrInvalidClient
+	}
+
+	client, err := o.clientRepository.GetByClientID(clientID)
+	if err != nil {
+		return nil, models.ErrInvalidClient
+	}
+	if !validClientSecret(client, clientSecret) {
+		return nil, models.ErrInvalidClient
+	}
+
+	scopes := clientScopes(client)
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		if scopes, err = grantedScopes(requested, scopes); err != nil {
+			return nil, models.ErrInvalidClientScope
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, models.ErrInvalidClientScope
+	}
+
+	audiences := client.AudienceList()
+	if audience == "" && len(audiences) > 0 {
+		audience = audiences[0]
+	}
+	if !containsToken(audiences, audience) {
+		return nil, models.ErrInvalidClientAudience
+	}
+
+	return o.tokenIssuer.GenerateClientToken(models.ClientTokenSubject{
+		ClientID: client.ClientID,
+		Scopes:   scopes,
+		Audience: audience,
+	})
+}
+
+// clientScopes drops the stored scopes a client can no longer be granted,
+// so clients registered before the restriction do not keep them.
+func clientScopes(client *models.OAuthClient) []string {
+	var scopes []string
+	for _, scope := range client.ScopeList() {
+		if models.IsClientScope(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// validClientSecret compares the secret in constant time and rejects
+// revoked clients.
+func validClientSecret(client *models.OAuthClient, clientSecret string) bool {
+	validSecret := subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(secureToken.Hash(clientSecret))) == 1
+	return validSecret && client.RevokedAt == nil
+}
+
+// uniqueTokens removes duplicated values and fails when one of them is
+// empty or contains spaces, since they are stored separated by spaces.
+func uniqueTokens(values []string) ([]string, bool) {
+	var tokens []string
+	for _, value := range values {
+		if len(strings.Fields(value)) != 1 || strings.TrimSpace(value) != value {
+			return nil, false
+		}
+		if !containsToken(tokens, value) {
+			tokens = append(tokens, value)
+		}
+	}
+	return tokens, true
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, candidate := range tokens {
+		if candidate == token {
+			return true
+		}
+	}
+	return false
+}
+
+func oauthClientResponse(client *models.OAuthClient) models.OAuthClientResponse {
+	return models.OAuthClientResponse{
+		ClientID:  client.ClientID,
+		Name:      client.Name,
+		Scopes:    client.ScopeList(),
+		Audiences: client.AudienceList(),
+		CreatedAt: client.CreatedAt,
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/pkg/secureToken"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestOAuthClientService_Register(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		request        *models.CreateOAuthClientRequest
+		mockedBehavior func(repository *MockOAuthClientRepository)
+		asserts        func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository)
+	}{
+		{
+			name:    "client should be stored with hashed secret and default audience",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read", "users:read", "jobs:publish"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+				repository.On("Create", mock.Anything).Return(func(client *models.OAuthClient) *models.OAuthClient {
+					return client
+				}, nil)
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, response.ClientID)
+				assert.NotEmpty(t, response.ClientSecret)
+				assert.Equal(t, []string{"users:read", "jobs:publish"}, response.Scopes)
+				assert.Equal(t, []string{models.ClientAudience}, response.Audiences)
+
+				stored := repository.Calls[0].Arguments.Get(0).(*models.OAuthClient)
+				assert.Equal(t, secureToken.Hash(response.ClientSecret), stored.SecretHash)
+				assert.Equal(t, "users:read jobs:publish", stored.Scopes)
+			},
+		},
+		{
+			name:    "scope with spaces should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read jobs:publish"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+			},
+		},
+		{
+			name:    "unknown scope should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read", "everything"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+				repository.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:    "privileged scope users:manage should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read", "users:manage"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+				repository.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:    "privileged scope clients:manage should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read", "clients:manage"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+				repository.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:    "privileged scope users:impersonate should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read", "users:impersonate"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+				repository.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:    "privileged scope audit:read should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read", "audit:read"}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+				repository.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:    "empty audience should be rejected",
+			request: &models.CreateOAuthClientRequest{Name: "Worker", Scopes: []string{"users:read"}, Audiences: []string{""}},
+			mockedBehavior: func(repository *MockOAuthClientRepository) {
+			},
+			asserts: func(t *testing.T, response *models.CreatedOAuthClientResponse, err error, repository *MockOAuthClientRepository) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidClientAudience)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &MockOAuthClientRepository{}
+			tt.mockedBehavior(repository)
+
+			clientService := NewOAuthClientService(repository, &MockClientTokenIssuer{})
+
+			response, err := clientService.Register(tt.request)
+
+			tt.asserts(t, response, err, repository)
+		})
+	}
+}
+
+func TestOAuthClientService_IssueToken(t *testing.T) {
+	revokedAt := time.Now()
+	client := &models.OAuthClient{ClientID: "worker", SecretHash: secureToken.Hash("secret"),
+		Scopes: "users:read jobs:publish", Audiences: "chambeo-fe notifications"}
+	revokedClient := &models.OAuthClient{ClientID: "worker", SecretHash: secureToken.Hash("secret"),
+		Scopes: "users:read", Audiences: "chambeo-fe", RevokedAt: &revokedAt}
+	privilegedClient := &models.OAuthClient{ClientID: "worker", SecretHash: secureToken.Hash("secret"),
+		Scopes: "users:read users:manage", Audiences: "chambeo-fe"}
+	tokenResponse := &models.TokenResponse{AccessToken: "token", TokenType: "Bearer"}
+
+	tests := []struct {
+		name           string
+		secret         string
+		scope          string
+		audience       string
+		mockedBehavior func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer)
+		asserts        func(t *testing.T, response *models.TokenResponse, err error)
+	}{
+		{
+			name:   "valid credentials should get every allowed scope and the first audience",
+			secret: "secret",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(client, nil)
+				issuer.On("GenerateClientToken", models.ClientTokenSubject{ClientID: "worker",
+					Scopes: []string{"users:read", "jobs:publish"}, Audience: "chambeo-fe"}).Return(tokenResponse, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, tokenResponse, response)
+			},
+		},
+		{
+			name:     "requested scope and audience should be narrowed",
+			secret:   "secret",
+			scope:    "jobs:publish",
+			audience: "notifications",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(client, nil)
+				issuer.On("GenerateClientToken", models.ClientTokenSubject{ClientID: "worker",
+					Scopes: []string{"jobs:publish"}, Audience: "notifications"}).Return(tokenResponse, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name:   "scope not allowed should be rejected",
+			secret: "secret",
+			scope:  "users:manage",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(client, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidClientScope)
+			},
+		},
+		{
+			name:   "privileged scope stored before the restriction should not be granted",
+			secret: "secret",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(privilegedClient, nil)
+				issuer.On("GenerateClientToken", models.ClientTokenSubject{ClientID: "worker",
+					Scopes: []string{"users:read"}, Audience: "chambeo-fe"}).Return(tokenResponse, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name:     "audience not allowed should be rejected",
+			secret:   "secret",
+			audience: "payments",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(client, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidClientAudience)
+			},
+		},
+		{
+			name:   "wrong secret should be rejected",
+			secret: "other",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(client, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidClient)
+			},
+		},
+		{
+			name:   "revoked client should be rejected",
+			secret: "secret",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(revokedClient, nil)
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidClient)
+			},
+		},
+		{
+			name:   "unknown client should be rejected",
+			secret: "secret",
+			mockedBehavior: func(repository *MockOAuthClientRepository, issuer *MockClientTokenIssuer) {
+				repository.On("GetByClientID", "worker").Return(nil, errors.New("error retrieving oauth client from DB"))
+			},
+			asserts: func(t *testing.T, response *models.TokenResponse, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidClient)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &MockOAuthClientRepository{}
+			issuer := &MockClientTokenIssuer{}
+			tt.mockedBehavior(repository, issuer)
+
+			clientService := NewOAuthClientService(repository, issuer)
+
+			response, err := clientService.IssueToken("worker", tt.secret, tt.scope, tt.audience)
+
+			tt.asserts(t, response, err)
+		})
+	}
+}
+
+func TestOAuthClientService_Revoke(t *testing.T) {
+	repository := &MockOAuthClientRepository{}
+	repository.On("Revoke", "worker", mock.Anything).Return(true, nil)
+	repository.On("Revoke", "unknown", mock.Anything).Return(false, nil)
+	clientService := NewOAuthClientService(repository, &MockClientTokenIssuer{})
+
+	assert.NoError(t, clientService.Revoke("worker"))
+	assert.ErrorIs(t, clientService.Revoke("unknown"), models.ErrOAuthClientNotFound)
+}
+
+type MockOAuthClientRepository struct {
+	mock.Mock
+}
+
+func (m *MockOAuthClientRepository) Create(client *models.OAuthClient) (*models.OAuthClient, error) {
+	args := m.Called(client)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	if fn, ok := args.Get(0).(func(*models.OAuthClient) *models.OAuthClient); ok {
+		return fn(client), nil
+	}
+	return args.Get(0).(*models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	args := m.Called(clientID)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) List() ([]models.OAuthClient, error) {
+	args := m.Called()
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthClientRepository) Revoke(clientID string, revokedAt time.Time) (bool, error) {
+	args := m.Called(clientID, revokedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockClientTokenIssuer struct {
+	mock.Mock
+}
+
+func (m *MockClientTokenIssuer) GenerateClientToken(subject models.ClientTokenSubject) (*models.TokenResponse, error) {
+	args := m.Called(subject)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TokenResponse), args.Error(1)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/keys"
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/oidc"
+	"chambeo-api-core/pkg/secureToken"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	oidcStateDuration = 10 * time.Minute
+	oidcValueSize     = 32
+)
+
+type OIDCServiceInterface interface {
+	// BeginLogin returns the authorization URL of the provider and the state
+	// token the callback has to present.
+	BeginLogin(provider string) (*models.OIDCAuthorization, error)
+	// CompleteLogin exchanges the authorization code and returns the user
+	// linked to the external identity, linking or creating it if needed.
+	CompleteLogin(provider string, code string, state string, stateToken string) (*userModels.UserRequest, error)
+}
+
+type ExternalUserStore interface {
+	Get(id string) (*userModels.UserRequest, error)
+	GetByEmail(email string) (*userModels.UserRequest, error)
+	CreateExternal(user *userModels.UserRequest) (*userModels.UserRequest, error)
+}
+
+type OIDCService struct {
+	keyRing            *keys.KeyRing
+	providers          map[string]oidc.ProviderInterface
+	identityRepository repository.ExternalIdentityRepositoryInterface
+	userStore          ExternalUserStore
+}
+
+func NewOIDCService(keyRing *keys.KeyRing, providers []oidc.ProviderInterface,
+	identityRepository repository.ExternalIdentityRepositoryInterface, userStore ExternalUserStore) OIDCServiceInterface {
+	byName := map[string]oidc.ProviderInterface{}
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &OIDCService{
+		keyRing:            keyRing,
+		providers:          byName,
+		identityRepository: identityRepository,
+		userStore:          userStore,
+	}
+}
+
+func (o *OIDCService) BeginLogin(providerName string) (*models.OIDCAuthorization, error) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return nil, models.ErrUnknownOIDCProvider
+	}
+
+	state, errState := secureToken.Generate(oidcValueSize)
+	nonce, errNonce := secureToken.Generate(oidcValueSize)
+	codeVerifier, errVerifier := secureToken.Generate(oidcValueSize)
+	if errState != nil || errNonce != nil || errVerifier != nil {
+		log.Println("error trying to generate social login state")
+		return nil, fmt.Errorf("error al intentar iniciar el login social: %w", errors.Join(errState, errNonce, errVerifier))
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		log.Println("error trying to build authorization url: ", err.Error())
+		return nil, fmt.Errorf("error al intentar iniciar el login social: %w", err)
+	}
+
+	stateToken, err := o.keyRing.Sign(models.OIDCStateClaims{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    models.Issuer,
+			Audience:  []string{models.OIDCStateAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateDuration)),
+			ID:        state,
+		},
+	})
+	if err != nil {
+		log.Println("error trying to sign social login state")
+		return nil, fmt.Errorf("error al intentar iniciar el login social: %w", err)
+	}
+
+	return &models.OIDCAuthorization{
+		URL:        authURL,
+		StateToken: stateToken,
+		ExpiresIn:  int(oidcStateDuration.Seconds()),
+	}, nil
+}
+
+func (o *OIDCService) CompleteLogin(providerName string, code string, state string, stateToken string) (*userModels.UserRequest, error) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return nil, models.ErrUnknownOIDCProvider
+	}
+
+	claims := &models.OIDCStateClaims{}
+	_, err := jwt.ParseWithClaims(stateToken, claims, o.keyRing.Keyfunc,
+		jwt.WithValidMethods(o.keyRing.Algorithms()),
+		jwt.WithIssuer(models.Issuer),
+		jwt.WithAudience(models.OIDCStateAudience))
+	if err != nil || claims.Provider != providerName || claims.ID == "" ||
+		subtle.ConstantTimeCompare([]byte(claims.ID), []byte(state)) != 1 {
+		return nil, models.ErrInvalidOIDCState
+	}
+
+	tokens, err := provider.Exchange(code, claims.CodeVerifier)
+	if err != nil {
+		log.Println("error trying to exchange authorization code: ", err.Error())
+		return nil, models.ErrInvalidOIDCLogin
+	}
+	idClaims, err := provider.VerifyIDToken(tokens.IDToken, claims.Nonce)
+	if err != nil {
+		log.Println("invalid id token: ", err.Error())
+		return nil, models.ErrInvalidOIDCLogin
+	}
+
+	identity, err := o.identityRepository.Get(providerName, idClaims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := o.userStore.Get(strconv.Itoa(int(identity.UserID)))
+		if err != nil || user == nil {
+			return nil, fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", err)
+		}
+		return user, nil
+	}
+
+	return o.link(providerName, idClaims)
+}
+
+// link attaches a new external identity to the account with the same email,
+// or to a new account. Only emails verified on both sides are trusted, so a
+// provider account can not take over an account someone else registered.
+func (o *OIDCService) link(providerName string, idClaims *oidc.IDTokenClaims) (*userModels.UserRequest, error) {
+	email := strings.TrimSpace(idClaims.Email)
+	if email == "" || !idClaims.EmailVerified {
+		return nil, models.ErrOIDCEmailNotVerified
+	}
+
+	user, err := o.userStore.GetByEmail(email)
+	if err != nil && !errors.Is(err, userModels.ErrUserNotFound) {
+		return nil, err
+	}
+	if user != nil && user.EmailVerifiedAt == nil {
+		return nil, models.ErrOIDCAccountNotLinkable
+	}
+	if user == nil {
+		user, err = o.userStore.CreateExternal(&userModels.UserRequest{
+			FirstName: idClaims.GivenName,
+			LastName:  idClaims.FamilyName,
+			Email:     email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = o.identityRepository.Create(&models.ExternalIdentity{
+		Provider:  providerName,
+		Subject:   idClaims.Subject,
+		UserID:    uint(user.Id),
+		Email:     email,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/oidc"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestOIDCService_BeginLogin(t *testing.T) {
+	keyRing := testKeyRing(t)
+	provider := &MockOIDCProvider{name: "google"}
+	provider.On("AuthCodeURL", mock.Anything, mock.Anything, mock.Anything).Return("https://idp.example.com/authorize?x=1", nil)
+	oidcService := NewOIDCService(keyRing, []oidc.ProviderInterface{provider}, &MockExternalIdentityRepository{}, &MockExternalUserStore{})
+
+	authorization, err := oidcService.BeginLogin("google")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://idp.example.com/authorize?x=1", authorization.URL)
+	assert.Equal(t, 600, authorization.ExpiresIn)
+
+	claims := &models.OIDCStateClaims{}
+	_, err = jwt.ParseWithClaims(authorization.StateToken, claims, keyRing.Keyfunc, jwt.WithAudience(models.OIDCStateAudience))
+	assert.NoError(t, err)
+	assert.Equal(t, "google", claims.Provider)
+
+	arguments := provider.Calls[0].Arguments
+	assert.Equal(t, claims.ID, arguments.String(0))
+	assert.Equal(t, claims.Nonce, arguments.String(1))
+	assert.Equal(t, oidc.CodeChallenge(claims.CodeVerifier), arguments.String(2))
+
+	_, err = oidcService.BeginLogin("unknown")
+	assert.ErrorIs(t, err, models.ErrUnknownOIDCProvider)
+}
+
+func TestOIDCService_CompleteLogin(t *testing.T) {
+	keyRing := testKeyRing(t)
+	verifiedAt := time.Now()
+	verifiedUser := &userModels.UserRequest{Id: 7, Email: "meze@gmail.com", EmailVerifiedAt: &verifiedAt}
+	idClaims := &oidc.IDTokenClaims{
+		Email:            "meze@gmail.com",
+		EmailVerified:    true,
+		GivenName:        "Meze",
+		FamilyName:       "Lawyer",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "1234567890"},
+	}
+
+	stateToken := func(provider string, state string) string {
+		token, _ := keyRing.Sign(models.OIDCStateClaims{
+			Provider:     provider,
+			Nonce:        "nonce",
+			CodeVerifier: "verifier",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    models.Issuer,
+				Audience:  []string{models.OIDCStateAudience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				ID:        state,
+			},
+		})
+		return token
+	}
+	exchangeSucceeds := func(providerMock *mock.Mock, claims *oidc.IDTokenClaims) {
+		providerMock.On("Exchange", "code", "verifier").Return(&oidc.Tokens{IDToken: "id-token"}, nil)
+		providerMock.On("VerifyIDToken", "id-token", "nonce").Return(claims, nil)
+	}
+
+	tests := []struct {
+		name           string
+		provider       string
+		stateToken     string
+		mockedBehavior func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock)
+		asserts        func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error)
+	}{
+		{
+			name:       "linked identity should return its user",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				exchangeSucceeds(providerMock, idClaims)
+				identityMock.On("Get", "google", "1234567890").Return(&models.ExternalIdentity{UserID: 7}, nil)
+				userStoreMock.On("Get", "7").Return(verifiedUser, nil)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 7, user.Id)
+				identityMock.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:       "new identity should be linked to the verified account with the same email",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				exchangeSucceeds(providerMock, idClaims)
+				identityMock.On("Get", "google", "1234567890").Return(nil, nil)
+				userStoreMock.On("GetByEmail", "meze@gmail.com").Return(verifiedUser, nil)
+				identityMock.On("Create", mock.MatchedBy(func(identity *models.ExternalIdentity) bool {
+					return identity.UserID == 7 && identity.Provider == "google" && identity.Subject == "1234567890"
+				})).Return(&models.ExternalIdentity{}, nil)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 7, user.Id)
+				userStoreMock.AssertNotCalled(t, "CreateExternal", mock.Anything)
+			},
+		},
+		{
+			name:       "new identity without account should create one",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				exchangeSucceeds(providerMock, idClaims)
+				identityMock.On("Get", "google", "1234567890").Return(nil, nil)
+				userStoreMock.On("GetByEmail", "meze@gmail.com").Return(nil, userModels.ErrUserNotFound)
+				userStoreMock.On("CreateExternal", mock.MatchedBy(func(user *userModels.UserRequest) bool {
+					return user.FirstName == "Meze" && user.LastName == "Lawyer" && user.Email == "meze@gmail.com"
+				})).Return(&userModels.UserRequest{Id: 8, Email: "meze@gmail.com"}, nil)
+				identityMock.On("Create", mock.Anything).Return(&models.ExternalIdentity{}, nil)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 8, user.Id)
+			},
+		},
+		{
+			name:       "account with unverified email should not be linked",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				exchangeSucceeds(providerMock, idClaims)
+				identityMock.On("Get", "google", "1234567890").Return(nil, nil)
+				userStoreMock.On("GetByEmail", "meze@gmail.com").Return(&userModels.UserRequest{Id: 7, Email: "meze@gmail.com"}, nil)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrOIDCAccountNotLinkable)
+				identityMock.AssertNotCalled(t, "Create", mock.Anything)
+			},
+		},
+		{
+			name:       "email not verified by the provider should be rejected",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				exchangeSucceeds(providerMock, &oidc.IDTokenClaims{Email: "meze@gmail.com", RegisteredClaims: jwt.RegisteredClaims{Subject: "1234567890"}})
+				identityMock.On("Get", "google", "1234567890").Return(nil, nil)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrOIDCEmailNotVerified)
+				userStoreMock.AssertNotCalled(t, "GetByEmail", mock.Anything)
+			},
+		},
+		{
+			name:           "unknown provider should be rejected",
+			provider:       "facebook",
+			stateToken:     stateToken("facebook", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrUnknownOIDCProvider)
+			},
+		},
+		{
+			name:           "state of another login should be rejected",
+			provider:       "google",
+			stateToken:     stateToken("google", "other-state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidOIDCState)
+			},
+		},
+		{
+			name:           "state of another provider should be rejected",
+			provider:       "google",
+			stateToken:     stateToken("github", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidOIDCState)
+			},
+		},
+		{
+			name:           "missing state cookie should be rejected",
+			provider:       "google",
+			stateToken:     "",
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidOIDCState)
+			},
+		},
+		{
+			name:       "failed code exchange should be rejected",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				providerMock.On("Exchange", "code", "verifier").Return(nil, oidc.ErrExchange)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidOIDCLogin)
+			},
+		},
+		{
+			name:       "invalid id token should be rejected",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				providerMock.On("Exchange", "code", "verifier").Return(&oidc.Tokens{IDToken: "id-token"}, nil)
+				providerMock.On("VerifyIDToken", "id-token", "nonce").Return(nil, oidc.ErrInvalidIDToken)
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidOIDCLogin)
+				identityMock.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:       "repository error should be returned",
+			provider:   "google",
+			stateToken: stateToken("google", "state"),
+			mockedBehavior: func(t *testing.T, providerMock, identityMock, userStoreMock *mock.Mock) {
+				exchangeSucceeds(providerMock, idClaims)
+				identityMock.On("Get", "google", "1234567890").Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, identityMock, userStoreMock *mock.Mock, user *userModels.UserRequest, err error) {
+				assert.Error(t, err)
+				assert.Nil(t, user)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &MockOIDCProvider{name: "google"}
+			identityRepository := &MockExternalIdentityRepository{}
+			userStore := &MockExternalUserStore{}
+			tt.mockedBehavior(t, &provider.Mock, &identityRepository.Mock, &userStore.Mock)
+
+			oidcService := NewOIDCService(keyRing, []oidc.ProviderInterface{provider}, identityRepository, userStore)
+
+			user, err := oidcService.CompleteLogin(tt.provider, "code", "state", tt.stateToken)
+
+			tt.asserts(t, &identityRepository.Mock, &userStore.Mock, user, err)
+		})
+	}
+}
+
+type MockOIDCProvider struct {
+	mock.Mock
+	name string
+}
+
+func (m *MockOIDCProvider) Name() string {
+	return m.name
+}
+
+func (m *MockOIDCProvider) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+	args := m.Called(state, nonce, codeChallenge)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOIDCProvider) Exchange(code string, codeVerifier string) (*oidc.Tokens, error) {
+	args := m.Called(code, codeVerifier)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*oidc.Tokens), args.Error(1)
+}
+
+func (m *MockOIDCProvider) VerifyIDToken(rawIDToken string, nonce string) (*oidc.IDTokenClaims, error) {
+	args := m.Called(rawIDToken, nonce)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*oidc.IDTokenClaims), args.Error(1)
+}
+
+type MockExternalIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockExternalIdentityRepository) Get(provider string, subject string) (*models.ExternalIdentity, error) {
+	args := m.Called(provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExternalIdentity), args.Error(1)
+}
+
+func (m *MockExternalIdentityRepository) Create(identity *models.ExternalIdentity) (*models.ExternalIdentity, error) {
+	args := m.Called(identity)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExternalIdentity), args.Error(1)
+}
+
+type MockExternalUserStore struct {
+	mock.Mock
+}
+
+func (m *MockExternalUserStore) Get(id string) (*userModels.UserRequest, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockExternalUserStore) GetByEmail(email string) (*userModels.UserRequest, error) {
+	args := m.Called(email)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockExternalUserStore) CreateExternal(user *userModels.UserRequest) (*userModels.UserRequest, error) {
+	args := m.Called(user)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/mailer"
+	"chambeo-api-core/pkg/password"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"chambeo-api-core/pkg/secureToken"
+	"chambeo-api-core/pkg/throttle"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	passwordResetTokenDuration = time.Hour
+	passwordResetTokenSize     = 32
+)
+
+type PasswordServiceInterface interface {
+	RequestReset(email string) error
+	ResetPassword(token string, password string) error
+	ChangePassword(userID string, currentPassword string, newPassword string) error
+}
+
+type PasswordUserStore interface {
+	Get(id string) (*userModels.UserRequest, error)
+	GetByEmail(email string) (*userModels.UserRequest, error)
+	UpdatePassword(id string, password string) error
+}
+
+type TokenRevoker interface {
+	RevokeAllTokens(userID string) error
+}
+
+type PasswordService struct {
+	resetTokenRepository repository.PasswordResetTokenRepositoryInterface
+	userStore            PasswordUserStore
+	tokenRevoker         TokenRevoker
+	mailer               mailer.MailerInterface
+	throttle             throttle.ThrottleInterface
+	passwordHasher       password.HasherInterface
+	passwordPolicy       passwordPolicy.PolicyInterface
+	resetURL             string
+}
+
+func NewPasswordService(resetTokenRepository repository.PasswordResetTokenRepositoryInterface, userStore PasswordUserStore,
+	tokenRevoker TokenRevoker, mailer mailer.MailerInterface, throttle throttle.ThrottleInterface,
+	passwordHasher password.HasherInterface, passwordPolicy passwordPolicy.PolicyInterface, resetURL string) PasswordServiceInterface {
+	return &PasswordService{
+		resetTokenRepository: resetTokenRepository,
+		userStore:            userStore,
+		tokenRevoker:         tokenRevoker,
+		mailer:               mailer,
+		throttle:             throttle,
+		passwordHasher:       passwordHasher,
+		passwordPolicy:       passwordPolicy,
+		resetURL:             resetURL,
+	}
+}
+
+// RequestReset mails a reset link to the user. It is throttled per email
+// address and unknown emails are ignored without error so the endpoint does
+// not reveal which accounts exist.
+func (p *PasswordService) RequestReset(email string) error {
+	allowed, err := p.throttle.Allow(strings.ToLower(email))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return models.ErrPasswordResetThrottled
+	}
+
+	user, err := p.userStore.GetByEmail(email)
+	if err != nil || user == nil {
+		log.Println("password reset requested for an unknown email")
+		return nil
+	}
+
+	token, err := secureToken.Generate(passwordResetTokenSize)
+	if err != nil {
+		log.Println("error trying to generate password reset token")
+		return fmt.Errorf("error al intentar generar el token de recuperacion: %w", err)
+	}
+
+	_, err = p.resetTokenRepository.Create(&models.PasswordResetToken{
+		UserID:    uint(user.Id),
+		TokenHash: secureToken.Hash(token),
+		ExpiresAt: time.Now().Add(passwordResetTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Recupera tu contrasena de Chambeo",
+		Body: fmt.Sprintf("Para elegir una nueva contrasena ingresa a %s?token=%s\r\n\r\nEl enlace vence en %d minutos. Si no lo pediste, ignora este mensaje.",
+			p.resetURL, url.QueryEscape(token), int(passwordResetTokenDuration.Minutes())),
+	})
+}
+
+// ResetPassword consumes the reset token, stores the new password and
+// revokes every session of the user. A password refused by the policy
+// leaves the token unused so the user can try again.
+func (p *PasswordService) ResetPassword(token string, password string) error {
+	stored, err := p.resetTokenRepository.GetByHash(secureToken.Hash(token))
+	if err != nil {
+		return models.ErrInvalidResetToken
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return models.ErrInvalidResetToken
+	}
+
+	user, err := p.userStore.Get(strconv.Itoa(int(stored.UserID)))
+	if err != nil || user == nil {
+		return models.ErrInvalidResetToken
+	}
+	if err := p.passwordPolicy.Validate(password, userPersonalInfo(user)); err != nil {
+		return err
+	}
+
+	marked, err := p.resetTokenRepository.MarkUsed(stored.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return models.ErrInvalidResetToken
+	}
+
+	userID := strconv.Itoa(int(stored.UserID))
+	if err := p.userStore.UpdatePassword(userID, password); err != nil {
+		return err
+	}
+	return p.tokenRevoker.RevokeAllTokens(userID)
+}
+
+// ChangePassword replaces the password of an authenticated user after
+// checking the current one, then revokes every session of the user.
+func (p *PasswordService) ChangePassword(userID string, currentPassword string, newPassword string) error {
+	user, err := p.userStore.Get(userID)
+	if err != nil || user == nil {
+		return fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", err)
+	}
+
+	valid, err := p.passwordHasher.Verify(currentPassword, user.Password)
+	if err != nil || !valid {
+		return models.ErrInvalidCurrentPassword
+	}
+	if err := p.passwordPolicy.Validate(newPassword, userPersonalInfo(user)); err != nil {
+		return err
+	}
+
+	if err := p.userStore.UpdatePassword(userID, newPassword); err != nil {
+		return err
+	}
+	return p.tokenRevoker.RevokeAllTokens(userID)
+}
+
+func userPersonalInfo(user *userModels.UserRequest) passwordPolicy.PersonalInfo {
+	return passwordPolicy.PersonalInfo{FirstName: user.FirstName, LastName: user.LastName, Email: user.Email}
+}
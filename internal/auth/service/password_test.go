@@ -0,0 +1,376 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	userModels "chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/mailer"
+	"chambeo-api-core/pkg/password"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"chambeo-api-core/pkg/secureToken"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordService_RequestReset(t *testing.T) {
+
+	user := &userModels.UserRequest{Id: 1, Email: "meze@gmail.com"}
+
+	tests := []struct {
+		name           string
+		throttled      bool
+		mockedBehavior func(t *testing.T, repositoryMock, userStoreMock, mailerMock *mock.Mock)
+		asserts        func(t *testing.T, repositoryMock, mailerMock *mock.Mock, err error)
+	}{
+		{
+			name: "known email should receive a reset link",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, mailerMock *mock.Mock) {
+				userStoreMock.On("GetByEmail", "meze@gmail.com").Return(user, nil)
+				repositoryMock.On("Create", mock.Anything).Return(&models.PasswordResetToken{}, nil)
+				mailerMock.On("Send", mock.Anything).Return(nil)
+			},
+			asserts: func(t *testing.T, repositoryMock, mailerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+
+				stored := repositoryMock.Calls[0].Arguments.Get(0).(*models.PasswordResetToken)
+				assert.Equal(t, uint(1), stored.UserID)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+
+				message := mailerMock.Calls[0].Arguments.Get(0).(mailer.Message)
+				assert.Equal(t, "meze@gmail.com", message.To)
+				token := strings.Fields(strings.SplitN(message.Body, "?token=", 2)[1])[0]
+				assert.Equal(t, stored.TokenHash, secureToken.Hash(token))
+				assert.Contains(t, message.Body, "https://chambeo.co/reset-password?token=")
+			},
+		},
+		{
+			name: "unknown email should not send anything",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, mailerMock *mock.Mock) {
+				userStoreMock.On("GetByEmail", "meze@gmail.com").Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, repositoryMock, mailerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				repositoryMock.AssertNotCalled(t, "Create", mock.Anything)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name:           "throttled email should not be looked up",
+			throttled:      true,
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, mailerMock *mock.Mock) {},
+			asserts: func(t *testing.T, repositoryMock, mailerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrPasswordResetThrottled)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+		{
+			name: "repository error should be returned",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, mailerMock *mock.Mock) {
+				userStoreMock.On("GetByEmail", "meze@gmail.com").Return(user, nil)
+				repositoryMock.On("Create", mock.Anything).Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, repositoryMock, mailerMock *mock.Mock, err error) {
+				assert.Error(t, err)
+				mailerMock.AssertNotCalled(t, "Send", mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetTokenRepository := &MockPasswordResetTokenRepository{}
+			userStore := &MockPasswordUserStore{}
+			mockedMailer := &MockMailer{}
+			mockedThrottle := &MockThrottle{}
+			mockedThrottle.On("Allow", "meze@gmail.com").Return(!tt.throttled, nil)
+			tt.mockedBehavior(t, &resetTokenRepository.Mock, &userStore.Mock, &mockedMailer.Mock)
+
+			passwordService := NewPasswordService(resetTokenRepository, userStore, &MockTokenRevoker{}, mockedMailer, mockedThrottle,
+				testHasher(t), testPolicy(), "https://chambeo.co/reset-password")
+
+			err := passwordService.RequestReset("meze@gmail.com")
+
+			tt.asserts(t, &resetTokenRepository.Mock, &mockedMailer.Mock, err)
+		})
+	}
+}
+
+func TestPasswordService_ResetPassword(t *testing.T) {
+
+	usedAt := time.Now().Add(-time.Minute)
+	validStoredToken := &models.PasswordResetToken{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	user := &userModels.UserRequest{Id: 1, FirstName: "Meze", Email: "meze@gmail.com"}
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock)
+		asserts        func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error)
+	}{
+		{
+			name: "valid token should update the password and revoke sessions",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock) {
+				repositoryMock.On("GetByHash", secureToken.Hash("reset")).Return(validStoredToken, nil)
+				userStoreMock.On("Get", "1").Return(user, nil)
+				repositoryMock.On("MarkUsed", uint(1), mock.Anything).Return(true, nil)
+				userStoreMock.On("UpdatePassword", "1", "new-password").Return(nil)
+				revokerMock.On("RevokeAllTokens", "1").Return(nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				revokerMock.AssertCalled(t, "RevokeAllTokens", "1")
+			},
+		},
+		{
+			name: "unknown token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(nil, errors.New("not found"))
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidResetToken)
+			},
+		},
+		{
+			name: "expired token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(&models.PasswordResetToken{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidResetToken)
+				userStoreMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "used token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(&models.PasswordResetToken{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidResetToken)
+			},
+		},
+		{
+			name: "concurrently used token should be rejected",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(validStoredToken, nil)
+				userStoreMock.On("Get", "1").Return(user, nil)
+				repositoryMock.On("MarkUsed", uint(1), mock.Anything).Return(false, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidResetToken)
+				userStoreMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "password update error should not revoke sessions",
+			mockedBehavior: func(t *testing.T, repositoryMock, userStoreMock, revokerMock *mock.Mock) {
+				repositoryMock.On("GetByHash", mock.Anything).Return(validStoredToken, nil)
+				userStoreMock.On("Get", "1").Return(user, nil)
+				repositoryMock.On("MarkUsed", uint(1), mock.Anything).Return(true, nil)
+				userStoreMock.On("UpdatePassword", "1", "new-password").Return(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.Error(t, err)
+				revokerMock.AssertNotCalled(t, "RevokeAllTokens", mock.Anything)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetTokenRepository := &MockPasswordResetTokenRepository{}
+			userStore := &MockPasswordUserStore{}
+			tokenRevoker := &MockTokenRevoker{}
+			tt.mockedBehavior(t, &resetTokenRepository.Mock, &userStore.Mock, &tokenRevoker.Mock)
+
+			passwordService := NewPasswordService(resetTokenRepository, userStore, tokenRevoker, &MockMailer{}, &MockThrottle{}, testHasher(t),
+				testPolicy(), "https://chambeo.co/reset-password")
+
+			err := passwordService.ResetPassword("reset", "new-password")
+
+			tt.asserts(t, &userStore.Mock, &tokenRevoker.Mock, err)
+		})
+	}
+}
+
+func TestPasswordService_ResetPasswordWithWeakPassword(t *testing.T) {
+	resetTokenRepository := &MockPasswordResetTokenRepository{}
+	resetTokenRepository.On("GetByHash", mock.Anything).Return(&models.PasswordResetToken{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	userStore := &MockPasswordUserStore{}
+	userStore.On("Get", "1").Return(&userModels.UserRequest{Id: 1, FirstName: "Meze", Email: "meze@gmail.com"}, nil)
+
+	passwordService := NewPasswordService(resetTokenRepository, userStore, &MockTokenRevoker{}, &MockMailer{}, &MockThrottle{}, testHasher(t),
+		testPolicy(), "https://chambeo.co/reset-password")
+
+	err := passwordService.ResetPassword("reset", "Meze-2024")
+
+	var violationError *passwordPolicy.ViolationError
+	assert.True(t, errors.As(err, &violationError))
+	resetTokenRepository.AssertNotCalled(t, "MarkUsed", mock.Anything, mock.Anything)
+	userStore.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+
+	hasher := testHasher(t)
+	currentHash, _ := hasher.Hash("current-password")
+	user := &userModels.UserRequest{Id: 1, FirstName: "Meze", Email: "meze@gmail.com", Password: currentHash}
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		newPassword     string
+		mockedBehavior  func(t *testing.T, userStoreMock, revokerMock *mock.Mock)
+		asserts         func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error)
+	}{
+		{
+			name:            "valid passwords should update the password and revoke sessions",
+			currentPassword: "current-password",
+			newPassword:     "new-password",
+			mockedBehavior: func(t *testing.T, userStoreMock, revokerMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(user, nil)
+				userStoreMock.On("UpdatePassword", "1", "new-password").Return(nil)
+				revokerMock.On("RevokeAllTokens", "1").Return(nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.NoError(t, err)
+				revokerMock.AssertCalled(t, "RevokeAllTokens", "1")
+			},
+		},
+		{
+			name:            "wrong current password should be rejected",
+			currentPassword: "wrong-password",
+			newPassword:     "new-password",
+			mockedBehavior: func(t *testing.T, userStoreMock, revokerMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(user, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidCurrentPassword)
+				userStoreMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:            "weak new password should be rejected",
+			currentPassword: "current-password",
+			newPassword:     "meze@gmail.com",
+			mockedBehavior: func(t *testing.T, userStoreMock, revokerMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(user, nil)
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				var violationError *passwordPolicy.ViolationError
+				assert.True(t, errors.As(err, &violationError))
+				userStoreMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:            "user store error should be returned",
+			currentPassword: "current-password",
+			newPassword:     "new-password",
+			mockedBehavior: func(t *testing.T, userStoreMock, revokerMock *mock.Mock) {
+				userStoreMock.On("Get", "1").Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, userStoreMock, revokerMock *mock.Mock, err error) {
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, models.ErrInvalidCurrentPassword)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStore := &MockPasswordUserStore{}
+			tokenRevoker := &MockTokenRevoker{}
+			tt.mockedBehavior(t, &userStore.Mock, &tokenRevoker.Mock)
+
+			passwordService := NewPasswordService(&MockPasswordResetTokenRepository{}, userStore, tokenRevoker, &MockMailer{}, &MockThrottle{}, hasher,
+				testPolicy(), "https://chambeo.co/reset-password")
+
+			err := passwordService.ChangePassword("1", tt.currentPassword, tt.newPassword)
+
+			tt.asserts(t, &userStore.Mock, &tokenRevoker.Mock, err)
+		})
+	}
+}
+
+type MockPasswordResetTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetTokenRepository) Create(token *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	args := m.Called(token)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockPasswordResetTokenRepository) GetByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockPasswordResetTokenRepository) MarkUsed(id uint, usedAt time.Time) (bool, error) {
+	args := m.Called(id, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockPasswordUserStore struct {
+	mock.Mock
+}
+
+func (m *MockPasswordUserStore) Get(id string) (*userModels.UserRequest, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockPasswordUserStore) GetByEmail(email string) (*userModels.UserRequest, error) {
+	args := m.Called(email)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userModels.UserRequest), args.Error(1)
+}
+
+func (m *MockPasswordUserStore) UpdatePassword(id string, password string) error {
+	args := m.Called(id, password)
+	return args.Error(0)
+}
+
+type MockTokenRevoker struct {
+	mock.Mock
+}
+
+func (m *MockTokenRevoker) RevokeAllTokens(userID string) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(message mailer.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func testHasher(t *testing.T) password.HasherInterface {
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+func testPolicy() passwordPolicy.PolicyInterface {
+	return passwordPolicy.NewPolicy(passwordPolicy.DefaultConfig, nil)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type SessionServiceInterface interface {
+	// List returns the active sessions of the user, flagging the one with
+	// currentSessionID.
+	List(userID string, currentSessionID string) ([]models.SessionResponse, error)
+	Revoke(userID string, sessionID string) error
+}
+
+type SessionService struct {
+	sessionRepository      repository.SessionRepositoryInterface
+	refreshTokenRepository repository.RefreshTokenRepositoryInterface
+}
+
+func NewSessionService(sessionRepository repository.SessionRepositoryInterface,
+	refreshTokenRepository repository.RefreshTokenRepositoryInterface) SessionServiceInterface {
+	return &SessionService{
+		sessionRepository:      sessionRepository,
+		refreshTokenRepository: refreshTokenRepository,
+	}
+}
+
+func (s *SessionService) List(userID string, currentSessionID string) ([]models.SessionResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("id de usuario invalido: %w", err)
+	}
+
+	sessions, err := s.sessionRepository.ListActive(uint(id), time.Now().Add(-refreshTokenDuration))
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, models.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return responses, nil
+}
+
+// Revoke ends the session and its refresh tokens, its access tokens are
+// rejected from then on.
+func (s *SessionService) Revoke(userID string, sessionID string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("id de usuario invalido: %w", err)
+	}
+
+	return revokeSession(s.sessionRepository, s.refreshTokenRepository, sessionID, uint(id))
+}
+
+// revokeSession revokes the session and the refresh tokens of its family,
+// sessions of another user or already revoked answer ErrSessionNotFound.
+func revokeSession(sessionRepository repository.SessionRepositoryInterface,
+	refreshTokenRepository repository.RefreshTokenRepositoryInterface, sessionID string, userID uint) error {
+	revoked, err := sessionRepository.Revoke(sessionID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return models.ErrSessionNotFound
+	}
+	return refreshTokenRepository.RevokeSession(sessionID)
+}
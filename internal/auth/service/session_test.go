@@ -0,0 +1,264 @@
+package service
+
+import (
+	"chambeo-api-core/internal/auth/models"
+	"chambeo-api-core/internal/auth/repository"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestGenerateTokenStartsSession(t *testing.T) {
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+	sessionRepository := &MockSessionRepository{}
+	sessionRepository.On("Create", mock.Anything).Return(&models.Session{}, nil)
+	sessionRepository.On("Touch", mock.Anything, mock.Anything, "10.0.0.2").Return(nil)
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), sessionRepository, 0)
+
+	_, err := authService.GenerateToken(models.TokenSubject{UserID: "7", UserAgent: "Firefox", IP: "10.0.0.1"})
+	assert.NoError(t, err)
+
+	session := sessionRepository.Calls[0].Arguments.Get(0).(*models.Session)
+	assert.Equal(t, uint(7), session.UserID)
+	assert.Equal(t, "Firefox", session.UserAgent)
+	assert.Equal(t, "10.0.0.1", session.IP)
+	assert.Equal(t, refreshTokenRepository.Calls[0].Arguments.Get(0).(*models.RefreshToken).SessionID, session.ID)
+	sessionRepository.AssertNotCalled(t, "ListActive", mock.Anything, mock.Anything)
+
+	_, err = authService.GenerateToken(models.TokenSubject{UserID: "7", SessionID: session.ID, IP: "10.0.0.2"})
+	assert.NoError(t, err)
+	sessionRepository.AssertCalled(t, "Touch", session.ID, mock.Anything, "10.0.0.2")
+	sessionRepository.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestGenerateTokenEvictsOldestSessions(t *testing.T) {
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+	refreshTokenRepository.On("RevokeSession", "oldest").Return(nil)
+	sessionRepository := &MockSessionRepository{}
+	sessionRepository.On("Create", mock.Anything).Return(&models.Session{}, nil)
+	sessionRepository.On("ListActive", uint(7), mock.Anything).Return([]models.Session{{ID: "oldest"}, {ID: "older"}, {ID: "new"}}, nil)
+	sessionRepository.On("Revoke", "oldest", uint(7), mock.Anything).Return(true, nil)
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), sessionRepository, 2)
+
+	_, err := authService.GenerateToken(models.TokenSubject{UserID: "7"})
+
+	assert.NoError(t, err)
+	sessionRepository.AssertNumberOfCalls(t, "Revoke", 1)
+	refreshTokenRepository.AssertCalled(t, "RevokeSession", "oldest")
+}
+
+func TestGenerateTokenSkipsAlreadyRevokedSessions(t *testing.T) {
+	refreshTokenRepository := &MockRefreshTokenRepository{}
+	refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+	sessionRepository := &MockSessionRepository{}
+	sessionRepository.On("Create", mock.Anything).Return(&models.Session{}, nil)
+	sessionRepository.On("ListActive", uint(7), mock.Anything).Return([]models.Session{{ID: "oldest"}, {ID: "new"}}, nil)
+	sessionRepository.On("Revoke", "oldest", uint(7), mock.Anything).Return(false, nil)
+
+	authService := NewJWTService(testKeyRing(t), refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), sessionRepository, 1)
+
+	_, err := authService.GenerateToken(models.TokenSubject{UserID: "7"})
+
+	assert.NoError(t, err)
+	refreshTokenRepository.AssertNotCalled(t, "RevokeSession", mock.Anything)
+}
+
+func TestParseTokenWithRevokedSession(t *testing.T) {
+	revokedAt := time.Now()
+	recently := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(sessionRepository *MockSessionRepository)
+		asserts        func(t *testing.T, err error, sessionRepository *MockSessionRepository)
+	}{
+		{
+			name: "active session should be valid",
+			mockedBehavior: func(sessionRepository *MockSessionRepository) {
+				sessionRepository.On("Get", "session").Return(&models.Session{ID: "session", LastSeenAt: recently}, nil)
+			},
+			asserts: func(t *testing.T, err error, sessionRepository *MockSessionRepository) {
+				assert.NoError(t, err)
+				sessionRepository.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "idle session should record the activity",
+			mockedBehavior: func(sessionRepository *MockSessionRepository) {
+				sessionRepository.On("Get", "session").Return(&models.Session{ID: "session", LastSeenAt: recently.Add(-time.Hour)}, nil)
+				sessionRepository.On("Touch", "session", mock.Anything, "").Return(nil)
+			},
+			asserts: func(t *testing.T, err error, sessionRepository *MockSessionRepository) {
+				assert.NoError(t, err)
+				sessionRepository.AssertCalled(t, "Touch", "session", mock.Anything, "")
+			},
+		},
+		{
+			name: "revoked session should be rejected",
+			mockedBehavior: func(sessionRepository *MockSessionRepository) {
+				sessionRepository.On("Get", "session").Return(&models.Session{ID: "session", RevokedAt: &revokedAt}, nil)
+			},
+			asserts: func(t *testing.T, err error, sessionRepository *MockSessionRepository) {
+				assert.ErrorIs(t, err, models.ErrTokenRevoked)
+			},
+		},
+		{
+			name: "session without row should be valid",
+			mockedBehavior: func(sessionRepository *MockSessionRepository) {
+				sessionRepository.On("Get", "session").Return(nil, nil)
+			},
+			asserts: func(t *testing.T, err error, sessionRepository *MockSessionRepository) {
+				assert.NoError(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshTokenRepository := &MockRefreshTokenRepository{}
+			refreshTokenRepository.On("Create", mock.Anything).Return(&models.RefreshToken{}, nil)
+			ring := testKeyRing(t)
+			issuer := NewJWTService(ring, refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), testSessionRepository(), 0)
+			token, _ := issuer.GenerateToken(models.TokenSubject{UserID: "1", SessionID: "session"})
+
+			sessionRepository := &MockSessionRepository{}
+			tt.mockedBehavior(sessionRepository)
+
+			authService := NewJWTService(ring, refreshTokenRepository, repository.NewMemoryDenylist(), testTokenVersionStore(0), sessionRepository, 0)
+			_, err := authService.ParseToken(token.AccessToken)
+
+			tt.asserts(t, err, sessionRepository)
+		})
+	}
+}
+
+func TestSessionService_List(t *testing.T) {
+	createdAt := time.Now()
+	sessionRepository := &MockSessionRepository{}
+	sessionRepository.On("ListActive", uint(7), mock.Anything).Return([]models.Session{
+		{ID: "laptop", UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: createdAt, LastSeenAt: createdAt},
+		{ID: "phone", UserAgent: "Safari", IP: "10.0.0.2", CreatedAt: createdAt, LastSeenAt: createdAt},
+	}, nil)
+
+	sessions, err := NewSessionService(sessionRepository, &MockRefreshTokenRepository{}).List("7", "phone")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.SessionResponse{
+		{ID: "laptop", UserAgent: "Firefox", IP: "10.0.0.1", CreatedAt: createdAt, LastSeenAt: createdAt, Current: false},
+		{ID: "phone", UserAgent: "Safari", IP: "10.0.0.2", CreatedAt: createdAt, LastSeenAt: createdAt, Current: true},
+	}, sessions)
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(sessionRepository *MockSessionRepository, refreshTokenRepository *MockRefreshTokenRepository)
+		asserts        func(t *testing.T, err error, refreshTokenRepository *MockRefreshTokenRepository)
+	}{
+		{
+			name: "own session should be revoked with its refresh tokens",
+			mockedBehavior: func(sessionRepository *MockSessionRepository, refreshTokenRepository *MockRefreshTokenRepository) {
+				sessionRepository.On("Revoke", "laptop", uint(7), mock.Anything).Return(true, nil)
+				refreshTokenRepository.On("RevokeSession", "laptop").Return(nil)
+			},
+			asserts: func(t *testing.T, err error, refreshTokenRepository *MockRefreshTokenRepository) {
+				assert.NoError(t, err)
+				refreshTokenRepository.AssertCalled(t, "RevokeSession", "laptop")
+			},
+		},
+		{
+			name: "unknown session should return not found",
+			mockedBehavior: func(sessionRepository *MockSessionRepository, refreshTokenRepository *MockRefreshTokenRepository) {
+				sessionRepository.On("Revoke", "laptop", uint(7), mock.Anything).Return(false, nil)
+			},
+			asserts: func(t *testing.T, err error, refreshTokenRepository *MockRefreshTokenRepository) {
+				assert.ErrorIs(t, err, models.ErrSessionNotFound)
+				refreshTokenRepository.AssertNotCalled(t, "RevokeSession", mock.Anything)
+			},
+		},
+		{
+			name: "repository error should be returned",
+			mockedBehavior: func(sessionRepository *MockSessionRepository, refreshTokenRepository *MockRefreshTokenRepository) {
+				sessionRepository.On("Revoke", "laptop", uint(7), mock.Anything).Return(false, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, err error, refreshTokenRepository *MockRefreshTokenRepository) {
+				assert.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionRepository := &MockSessionRepository{}
+			refreshTokenRepository := &MockRefreshTokenRepository{}
+			tt.mockedBehavior(sessionRepository, refreshTokenRepository)
+
+			err := NewSessionService(sessionRepository, refreshTokenRepository).Revoke("7", "laptop")
+
+			tt.asserts(t, err, refreshTokenRepository)
+		})
+	}
+}
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(session *models.Session) (*models.Session, error) {
+	args := m.Called(session)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return session, nil
+}
+
+func (m *MockSessionRepository) Get(id string) (*models.Session, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListActive(userID uint, seenAfter time.Time) ([]models.Session, error) {
+	args := m.Called(userID, seenAfter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Touch(id string, seenAt time.Time, ip string) error {
+	args := m.Called(id, seenAt, ip)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) Revoke(id string, userID uint, revokedAt time.Time) (bool, error) {
+	args := m.Called(id, userID, revokedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeUser(userID uint, revokedAt time.Time) error {
+	args := m.Called(userID, revokedAt)
+	return args.Error(0)
+}
+
+// testSessionRepository accepts every call, for the tests that do not look
+// at sessions.
+func testSessionRepository() *MockSessionRepository {
+	sessionRepository := &MockSessionRepository{}
+	sessionRepository.On("Create", mock.Anything).Return(&models.Session{}, nil).Maybe()
+	sessionRepository.On("Get", mock.Anything).Return(nil, nil).Maybe()
+	sessionRepository.On("ListActive", mock.Anything, mock.Anything).Return([]models.Session{}, nil).Maybe()
+	sessionRepository.On("Touch", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	sessionRepository.On("Revoke", mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	sessionRepository.On("RevokeUser", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return sessionRepository
+}
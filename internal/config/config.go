@@ -0,0 +1,192 @@
+package config
+
+import (
+	"chambeo-api-core/pkg/oidc"
+	"chambeo-api-core/pkg/password"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	// BaseURL is the public URL of the API, used to build absolute links.
+	BaseURL string
+	// FrontendURL is the public URL of the web client, used in the links
+	// sent by email.
+	FrontendURL string
+	// TrustedProxies are the addresses allowed to set X-Forwarded-For, the
+	// client IP is taken from the connection when it is empty.
+	TrustedProxies []string
+	Auth           AuthConfig
+	Mail           MailConfig
+	Audit          AuditConfig
+	Password       password.Config
+	PasswordPolicy passwordPolicy.Config
+	// BreachedPasswordsPath is a file with SHA-1 hashes, or prefixes of them,
+	// of breached passwords. The check is skipped when it is empty.
+	BreachedPasswordsPath string
+}
+
+type MailConfig struct {
+	// OutboxDir is where the local mailer writes messages, they are printed
+	// to the log when it is empty.
+	OutboxDir string
+}
+
+type AuditConfig struct {
+	// RetentionDays is how long audit events are kept, zero keeps them
+	// forever.
+	RetentionDays int
+}
+
+type AuthConfig struct {
+	// KeysDir holds PEM encoded signing keys, the file name (without
+	// extension) is used as the key id.
+	KeysDir string
+	// ActiveKeyID is the kid used to sign new tokens. Every other key is
+	// kept only to verify tokens issued before a rotation.
+	ActiveKeyID string
+	HMACSecret  string
+	HMACKeyID   string
+	// DenylistStore selects where revoked tokens are kept, "postgres" or
+	// "memory".
+	DenylistStore string
+	// LoginAttemptStore selects where failed login counters are kept,
+	// "postgres" or "memory".
+	LoginAttemptStore string
+	// ThrottleStore selects where the last password reset, magic link and
+	// verification emails are kept, "postgres" or "memory".
+	ThrottleStore string
+	// IntrospectionClients maps the client id to the secret of the clients
+	// allowed to call the introspection endpoint.
+	IntrospectionClients map[string]string
+	// RequireVerifiedEmail makes login refuse accounts whose email has not
+	// been confirmed yet.
+	RequireVerifiedEmail bool
+	// OIDCProviders are the identity providers enabled for social login.
+	OIDCProviders []oidc.Config
+	// MFAEncryptionKey is the base64 encoded 32 byte key used to encrypt
+	// the TOTP secrets before they are stored.
+	MFAEncryptionKey string
+	// MaxSessions caps the active sessions of an account, the oldest one is
+	// revoked when a new login goes over it. Zero means no limit.
+	MaxSessions int
+}
+
+func Load() Config {
+	baseURL := getEnv("PUBLIC_BASE_URL", "http://localhost:8080")
+	return Config{
+		BaseURL:        baseURL,
+		FrontendURL:    getEnv("PUBLIC_FRONTEND_URL", "http://localhost:3000"),
+		TrustedProxies: parseList(os.Getenv("HTTP_TRUSTED_PROXIES")),
+		Password: password.Config{
+			Algorithm:  getEnv("PASSWORD_HASH_ALGORITHM", password.DefaultConfig.Algorithm),
+			BcryptCost: getInt("PASSWORD_BCRYPT_COST", password.DefaultConfig.BcryptCost),
+			Argon2: password.Argon2Params{
+				Memory:      uint32(getInt("PASSWORD_ARGON2_MEMORY_KIB", int(password.DefaultConfig.Argon2.Memory))),
+				Iterations:  uint32(getInt("PASSWORD_ARGON2_ITERATIONS", int(password.DefaultConfig.Argon2.Iterations))),
+				Parallelism: uint8(getInt("PASSWORD_ARGON2_PARALLELISM", int(password.DefaultConfig.Argon2.Parallelism))),
+				SaltLength:  password.DefaultConfig.Argon2.SaltLength,
+				KeyLength:   password.DefaultConfig.Argon2.KeyLength,
+			},
+		},
+		PasswordPolicy: passwordPolicy.Config{
+			MinLength:           getInt("PASSWORD_MIN_LENGTH", passwordPolicy.DefaultConfig.MinLength),
+			MaxLength:           getInt("PASSWORD_MAX_LENGTH", passwordPolicy.DefaultConfig.MaxLength),
+			MinCharacterClasses: getInt("PASSWORD_MIN_CHARACTER_CLASSES", passwordPolicy.DefaultConfig.MinCharacterClasses),
+			RejectPersonalInfo:  getBool("PASSWORD_REJECT_PERSONAL_INFO", passwordPolicy.DefaultConfig.RejectPersonalInfo),
+		},
+		BreachedPasswordsPath: os.Getenv("PASSWORD_BREACHED_LIST_PATH"),
+		Mail: MailConfig{
+			OutboxDir: os.Getenv("MAIL_OUTBOX_DIR"),
+		},
+		Audit: AuditConfig{
+			RetentionDays: getInt("AUDIT_RETENTION_DAYS", 365),
+		},
+		Auth: AuthConfig{
+			KeysDir:              os.Getenv("AUTH_KEYS_DIR"),
+			ActiveKeyID:          os.Getenv("AUTH_ACTIVE_KID"),
+			HMACSecret:           os.Getenv("AUTH_HMAC_SECRET"),
+			HMACKeyID:            getEnv("AUTH_HMAC_KID", "hs256"),
+			DenylistStore:        getEnv("AUTH_DENYLIST_STORE", "postgres"),
+			LoginAttemptStore:    getEnv("AUTH_LOGIN_ATTEMPT_STORE", "postgres"),
+			ThrottleStore:        getEnv("AUTH_THROTTLE_STORE", "postgres"),
+			IntrospectionClients: parseClients(os.Getenv("AUTH_INTROSPECTION_CLIENTS")),
+			RequireVerifiedEmail: getBool("AUTH_REQUIRE_VERIFIED_EMAIL", false),
+			OIDCProviders:        parseOIDCProviders(os.Getenv("AUTH_OIDC_PROVIDERS"), baseURL),
+			MFAEncryptionKey:     os.Getenv("AUTH_MFA_ENCRYPTION_KEY"),
+			MaxSessions:          getInt("AUTH_MAX_SESSIONS", 0),
+		},
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseClients reads a comma separated list of "client_id:secret" pairs.
+func parseClients(value string) map[string]string {
+	clients := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		clientID, secret, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || clientID == "" || secret == "" {
+			continue
+		}
+		clients[clientID] = secret
+	}
+	return clients
+}
+
+// parseOIDCProviders reads the settings of every provider listed in value
+// from AUTH_OIDC_<NAME>_* variables, providers without issuer or client id
+// are skipped.
+func parseOIDCProviders(value string, baseURL string) []oidc.Config {
+	var providers []oidc.Config
+	for _, name := range parseList(value) {
+		name = strings.ToLower(name)
+		prefix := "AUTH_OIDC_" + strings.ToUpper(name) + "_"
+		provider := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  strings.TrimSuffix(baseURL, "/") + "/api/v1/auth/oidc/" + name + "/callback",
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"chambeo-api-core/internal/auth/middleware"
+	authModels "chambeo-api-core/internal/auth/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes mounts the users endpoints on the group. The ones that can
+// hand the account to someone else, changing its profile, deleting it or
+// changing its role, are not available to impersonation tokens. Changing a
+// role also needs a login session, not an API key or a client token.
+func RegisterRoutes(usersRouting *gin.RouterGroup, usrHandler UserHandlerInterface, authenticate gin.HandlerFunc) {
+	usersRouting.POST("/", usrHandler.Create)
+	usersRouting.GET("", authenticate,
+		middleware.RequirePermission(authModels.PermissionUsersManage), usrHandler.List)
+	usersRouting.GET("/search", authenticate,
+		middleware.RequirePermission(authModels.PermissionUsersManage), usrHandler.Search)
+	usersRouting.GET("/:id", authenticate,
+		middleware.RequirePermission(authModels.PermissionUsersRead), usrHandler.Get)
+	usersRouting.GET("/email/:email", authenticate,
+		middleware.RequirePermission(authModels.PermissionUsersRead), usrHandler.GetByEmail)
+	usersRouting.PUT("/", authenticate, middleware.DenyImpersonation(),
+		middleware.RequirePermission(authModels.PermissionUsersWrite), usrHandler.Update)
+	usersRouting.PATCH("/:id", authenticate, middleware.DenyImpersonation(),
+		middleware.RequirePermission(authModels.PermissionUsersWrite), usrHandler.Patch)
+	usersRouting.DELETE("/:id", authenticate, middleware.DenyImpersonation(),
+		middleware.RequirePermission(authModels.PermissionUsersWrite), usrHandler.Delete)
+	usersRouting.GET("/:id/permissions", authenticate,
+		middleware.RequirePermission(authModels.PermissionUsersRead), usrHandler.GetPermissions)
+	usersRouting.PUT("/:id/permissions", authenticate, middleware.RequireSession(),
+		middleware.RequirePermission(authModels.PermissionUsersManage), usrHandler.UpdatePermissions)
+}
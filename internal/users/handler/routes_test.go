@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"bytes"
+	"chambeo-api-core/internal/auth/middleware"
+	authModels "chambeo-api-core/internal/auth/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegisterRoutes_DenyImpersonation(t *testing.T) {
+	impersonationClaims := &authModels.CustomClaims{
+		UserID:      "1",
+		Role:        authModels.RoleUser,
+		Permissions: authModels.DefaultPermissions(authModels.RoleUser),
+		Actor:       &authModels.Actor{Subject: "99", Email: "admin@email.com"},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "Update", method: "PUT", path: "/api/v1/users/", body: `{"id":1,"first_name":"Meze"}`},
+		{name: "Patch", method: "PATCH", path: "/api/v1/users/1", body: `{"first_name":"Meze"}`},
+		{name: "Delete", method: "DELETE", path: "/api/v1/users/1"},
+		{name: "UpdatePermissions", method: "PUT", path: "/api/v1/users/1/permissions", body: `{"role":"admin"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" with an impersonation token should return 403", func(t *testing.T) {
+			mockedService := &MockUserService{}
+
+			router := gin.New()
+			RegisterRoutes(router.Group("/api/v1/users"), NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()),
+				func(c *gin.Context) { c.Set(middleware.ClaimsKey, impersonationClaims) })
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Empty(t, mockedService.Calls)
+		})
+	}
+}
+
+func TestRegisterRoutes_UpdatePermissionsRequiresSession(t *testing.T) {
+	adminPermissions := authModels.DefaultPermissions(authModels.RoleAdmin)
+
+	tests := []struct {
+		name   string
+		claims *authModels.CustomClaims
+	}{
+		{name: "API key", claims: &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin, Permissions: adminPermissions, APIKeyID: "7"}},
+		{name: "client token", claims: &authModels.CustomClaims{ClientID: "billing", Permissions: []string{authModels.PermissionUsersManage}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" should return 403", func(t *testing.T) {
+			mockedService := &MockUserService{}
+
+			router := gin.New()
+			RegisterRoutes(router.Group("/api/v1/users"), NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()),
+				func(c *gin.Context) { c.Set(middleware.ClaimsKey, tt.claims) })
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/api/v1/users/1/permissions", bytes.NewReader([]byte(`{"role":"worker"}`)))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Empty(t, mockedService.Calls)
+		})
+	}
+}
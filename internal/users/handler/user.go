@@ -1,50 +1,82 @@
 package handler
 
 import (
+	auditModels "chambeo-api-core/internal/audit/models"
+	auditService "chambeo-api-core/internal/audit/service"
+	"chambeo-api-core/internal/auth/middleware"
 	"chambeo-api-core/internal/users/models"
 	"chambeo-api-core/internal/users/service"
 	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"log"
 	"net/http"
+	"strconv"
 )
 
 type UserHandlerInterface interface {
 	Create(c *gin.Context)
 	Get(c *gin.Context)
+	List(c *gin.Context)
+	Search(c *gin.Context)
 	GetByEmail(c *gin.Context)
 	Update(c *gin.Context)
+	Patch(c *gin.Context)
 	Delete(c *gin.Context)
+	GetPermissions(c *gin.Context)
+	UpdatePermissions(c *gin.Context)
 }
 
+const mergePatchContentType = "application/merge-patch+json"
+
+type VerificationSender interface {
+	SendVerification(user *models.UserRequest) error
+}
+
+type Auditor interface {
+	Record(event *auditModels.AuditEvent)
+}
+
+// UserHandler reports every error with c.Error, they are answered by
+// customError.ErrorHandler.
 type UserHandler struct {
-	userService service.UserServiceInterface
+	userService        service.UserServiceInterface
+	verificationSender VerificationSender
+	auditor            Auditor
 }
 
-func NewUserHandler(userService service.UserServiceInterface) UserHandlerInterface {
-	return &UserHandler{userService}
+func NewUserHandler(userService service.UserServiceInterface, verificationSender VerificationSender, auditor Auditor) UserHandlerInterface {
+	return &UserHandler{userService, verificationSender, auditor}
 }
 func (u *UserHandler) Create(c *gin.Context) {
 
-	var userDto models.UserRequest
+	var userDto models.CreateUserRequest
 	err := c.ShouldBindJSON(&userDto)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.InvalidBody,
-			Message: "Invalid request body",
-		})
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
 		return
 	}
 
 	user, err := u.userService.Create(&userDto)
+	var violationError *passwordPolicy.ViolationError
+	if errors.As(err, &violationError) {
+		c.Error(customError.NewValidation("Password does not satisfy the password policy", violationError.FieldErrors("password"), err))
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "An error occurred when tyring to create user",
-		})
+		c.Error(err).SetMeta("An error occurred when tyring to create user")
 		return
 	}
-	c.JSON(http.StatusCreated, user)
+
+	// The account is already created, a failed email can be retried through
+	// the resend endpoint.
+	if err := u.verificationSender.SendVerification(user); err != nil {
+		log.Println("error trying to send verification email: ", err.Error())
+	}
+	c.JSON(http.StatusCreated, user.Self())
 	return
 }
 
@@ -52,76 +84,143 @@ func (u *UserHandler) Get(c *gin.Context) {
 	userId := c.Param("id")
 
 	if userId == "" {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.MissingParameter,
-			Message: "Missing or mismatch userId",
-		})
+		c.Error(customError.NewValidation("Missing or mismatch userId", nil, nil).WithCode(customError.MissingParameter))
 		return
 	}
 
 	user, err := u.userService.Get(userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: fmt.Sprintf("An error occurred when trying to retrieve user with id %s", userId),
-		})
+		c.Error(err).SetMeta(fmt.Sprintf("An error occurred when trying to retrieve user with id %s", userId))
+		return
+	}
+
+	c.JSON(http.StatusOK, userView(c, user))
+	return
+}
+
+// List is meant for the backoffice, it pages through every account with the
+// filters and sort of the query string.
+func (u *UserHandler) List(c *gin.Context) {
+	var filter models.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid query parameters", err))
 		return
 	}
 
-	if user == nil {
-		c.JSON(http.StatusNotFound, customError.Error{
-			Code:    customError.NotFound,
-			Message: "User not found",
-		})
+	page, err := u.userService.List(filter)
+	if err != nil {
+		c.Error(err).SetMeta("An error occurred when trying to list users")
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
-	return
+	c.JSON(http.StatusOK, page)
+}
+
+// Search looks up accounts by name or email for support agents, the best
+// matches come first.
+func (u *UserHandler) Search(c *gin.Context) {
+	var search models.UserSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		c.Error(customError.NewInvalidBody("Invalid query parameters", err))
+		return
+	}
+
+	page, err := u.userService.Search(search)
+	if err != nil {
+		c.Error(err).SetMeta("An error occurred when trying to search users")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 func (u *UserHandler) Update(c *gin.Context) {
-	var userDto models.UserRequest
+	var userDto models.UpdateUserRequest
 	err := c.ShouldBindJSON(&userDto)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.InvalidBody,
-			Message: "Invalid request body",
-		})
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
 		return
 	}
+
+	if !middleware.CanAccessUser(c, strconv.Itoa(userDto.Id)) {
+		c.Error(customError.NewForbidden("Not allowed to update this user", nil))
+		return
+	}
+
 	user, err := u.userService.Update(&userDto)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: "An error occurred when tyring to update user",
-		})
+		c.Error(err).SetMeta("An error occurred when tyring to update user")
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, userView(c, user))
 	return
 }
 
+// Patch applies an RFC 7396 merge patch to the user, only the fields listed
+// in the patch change and null clears them.
+func (u *UserHandler) Patch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(customError.NewValidation("Invalid user id", nil, err))
+		return
+	}
+	userId := strconv.Itoa(id)
+
+	if !middleware.CanAccessUser(c, userId) {
+		c.Error(customError.NewForbidden("Not allowed to update this user", nil))
+		return
+	}
+
+	if contentType := c.ContentType(); contentType != mergePatchContentType && contentType != binding.MIMEJSON {
+		c.Error(customError.NewUnsupportedMediaType("Content type must be "+mergePatchContentType, nil))
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	changes, err := models.ParseUserPatch(body)
+	var patchError *models.PatchError
+	if errors.As(err, &patchError) {
+		c.Error(customError.NewValidation("Merge patch can not be applied", patchError.Fields, err))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := u.userService.Patch(userId, changes)
+	if err != nil {
+		c.Error(err).SetMeta(fmt.Sprintf("An error occurred when trying to update user with id %s", userId))
+		return
+	}
+
+	c.JSON(http.StatusOK, userView(c, user))
+}
+
 func (u *UserHandler) Delete(c *gin.Context) {
 	userId := c.Param("id")
 
 	if userId == "" {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.MissingParameter,
-			Message: "Missing or mismatch userId",
-		})
+		c.Error(customError.NewValidation("Missing or mismatch userId", nil, nil).WithCode(customError.MissingParameter))
+		return
+	}
+
+	if !middleware.CanAccessUser(c, userId) {
+		c.Error(customError.NewForbidden("Not allowed to delete this user", nil))
 		return
 	}
 
-	user, err := u.userService.Delete(userId)
+	_, err := u.userService.Delete(userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: fmt.Sprintf("An error occurred when trying to delete user with id %s", userId),
-		})
+		c.Error(err).SetMeta(fmt.Sprintf("An error occurred when trying to delete user with id %s", userId))
 		return
 	}
-	c.JSON(http.StatusNoContent, user)
+	c.Status(http.StatusNoContent)
 	return
 }
 
@@ -129,30 +228,78 @@ func (u *UserHandler) GetByEmail(c *gin.Context) {
 	email := c.Param("email")
 
 	if email == "" {
-		c.JSON(http.StatusBadRequest, customError.Error{
-			Code:    customError.MissingParameter,
-			Message: "Missing or mismatch email",
-		})
+		c.Error(customError.NewValidation("Missing or mismatch email", nil, nil).WithCode(customError.MissingParameter))
 		return
 	}
 
 	user, err := u.userService.GetByEmail(email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, customError.Error{
-			Code:    customError.ApplicationError,
-			Message: fmt.Sprintf("An error occurred when trying to retrieve user with email %s", email),
-		})
+		c.Error(err).SetMeta(fmt.Sprintf("An error occurred when trying to retrieve user with email %s", email))
 		return
 	}
 
-	if user == nil {
-		c.JSON(http.StatusNotFound, customError.Error{
-			Code:    customError.NotFound,
-			Message: "User not found",
-		})
+	c.JSON(http.StatusOK, userView(c, user))
+	return
+}
+
+func (u *UserHandler) GetPermissions(c *gin.Context) {
+	userId := c.Param("id")
+
+	if !middleware.CanAccessUser(c, userId) {
+		c.Error(customError.NewForbidden("Not allowed to read permissions of this user", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
-	return
+	permissions, err := u.userService.GetPermissions(userId)
+	if err != nil {
+		c.Error(err).SetMeta(fmt.Sprintf("An error occurred when trying to retrieve permissions of user with id %s", userId))
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// UpdatePermissions replaces the role and the granted permissions of a user.
+// The route is expected to be restricted to callers that can manage users.
+func (u *UserHandler) UpdatePermissions(c *gin.Context) {
+	userId := c.Param("id")
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(customError.NewUnauthorized("Invalid or expired token", nil))
+		return
+	}
+
+	var permissionsDto models.UserPermissions
+	err := c.ShouldBindJSON(&permissionsDto)
+	if err != nil {
+		c.Error(customError.NewInvalidBody("Invalid request body", err))
+		return
+	}
+
+	permissions, err := u.userService.UpdatePermissions(userId, &permissionsDto, claims)
+	if err != nil {
+		c.Error(err).SetMeta(fmt.Sprintf("An error occurred when trying to update permissions of user with id %s", userId))
+		return
+	}
+
+	// The reason holds the role granted by the change.
+	event := auditService.NewEvent(c, auditModels.EventRoleChange, auditModels.OutcomeSuccess)
+	event.TargetID = userId
+	event.Email = ""
+	event.Reason = permissions.Role
+	u.auditor.Record(event)
+	c.JSON(http.StatusOK, permissions)
+}
+
+// userView picks what the caller may see of the account: elevated callers
+// get the backoffice view, the owner its own view and anyone else the
+// public one.
+func userView(c *gin.Context, user *models.UserRequest) interface{} {
+	claims, ok := middleware.GetClaims(c)
+	if ok && claims.IsElevated() {
+		return user.Admin()
+	}
+	if ok && claims.UserID != "" && claims.UserID == strconv.Itoa(user.Id) {
+		return user.Self()
+	}
+	return user.Public()
 }
@@ -2,7 +2,12 @@ package handler
 
 import (
 	"bytes"
+	auditModels "chambeo-api-core/internal/audit/models"
+	"chambeo-api-core/internal/auth/middleware"
+	authModels "chambeo-api-core/internal/auth/models"
 	"chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/passwordPolicy"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -37,7 +42,7 @@ func TestUserHandler_Create(t *testing.T) {
 					  "password": "password"
 					}
 					`,
-			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","password":"password","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
+			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
 			expectedHttpStatusResponse: http.StatusCreated,
 			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
 				mockedService.On("Create", mock.Anything).Return(&models.UserRequest{
@@ -66,6 +71,46 @@ func TestUserHandler_Create(t *testing.T) {
 				assert.Equal(t, expectedHttpStatusResponse, response.Code)
 			},
 		},
+		{
+			name: "Test with weak password should return 400 with field errors",
+			requestBody: `{
+					  "first_name": "Meze",
+					  "email": "meze@email.com",
+					  "password": "meze"
+					}
+					`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Password does not satisfy the password policy","fields":[{"field":"password","code":"TOO_SHORT","message":"Must be at least 8 characters long"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Create", mock.Anything).Return(nil, &passwordPolicy.ViolationError{
+					Violations: []passwordPolicy.Violation{{Code: passwordPolicy.TooShort, Message: "Must be at least 8 characters long"}},
+				})
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
+		{
+			name: "Test with a registered email should return 409",
+			requestBody: `{
+					  "id": 1,
+					  "first_name": "Meze",
+					  "last_name": "Lawyer",
+					  "email": "meze@email.com",
+					  "password": "password"
+					}
+					`,
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"Email already registered"}`,
+			expectedHttpStatusResponse: http.StatusConflict,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Create", mock.Anything).Return(nil, models.ErrEmailTaken.Wrap(errors.New("duplicated key")))
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
 		{
 			name: "Test with valid data should return 500 due service error",
 			requestBody: `{
@@ -92,12 +137,14 @@ func TestUserHandler_Create(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			mockedService := &MockUserService{}
+			mockedSender := &MockVerificationSender{}
+			mockedSender.On("SendVerification", mock.Anything).Return(nil)
 
 			tt.mockedBehavior(t, &mockedService.Mock)
 
-			userHandler := NewUserHandler(mockedService)
+			userHandler := NewUserHandler(mockedService, mockedSender, testAuditor())
 
-			router := setupMockedRouter(userHandler)
+			router := setupMockedRouter(userHandler, ownerClaims)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("POST", "/api/v1/users/", bytes.NewReader([]byte(tt.requestBody)))
@@ -110,12 +157,51 @@ func TestUserHandler_Create(t *testing.T) {
 	}
 }
 
+func TestUserHandler_CreateSendsVerification(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		sendError error
+	}{
+		{
+			name:      "created user should receive a verification link",
+			sendError: nil,
+		},
+		{
+			name:      "failed verification email should not fail the signup",
+			sendError: errors.New("error from mailer"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdUser := &models.UserRequest{Id: 1, Email: "meze@email.com"}
+
+			mockedService := &MockUserService{}
+			mockedService.On("Create", mock.Anything).Return(createdUser, nil)
+			mockedSender := &MockVerificationSender{}
+			mockedSender.On("SendVerification", createdUser).Return(tt.sendError)
+
+			router := setupMockedRouter(NewUserHandler(mockedService, mockedSender, testAuditor()), ownerClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/users/", bytes.NewReader([]byte(`{"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","password":"password"}`)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			mockedSender.AssertCalled(t, "SendVerification", createdUser)
+		})
+	}
+}
+
 func TestUserHandler_Update(t *testing.T) {
 	createdAt, _ := time.Parse(time.RFC3339, "2024-01-05T23:01:41.9180793-03:00")
 	updatedAt, _ := time.Parse(time.RFC3339, "2024-01-05T23:01:41.9180793-03:00")
 
 	tests := []struct {
 		name                       string
+		claims                     *authModels.CustomClaims
 		requestBody                string
 		expectedBodyResponse       string
 		expectedHttpStatusResponse int
@@ -132,7 +218,7 @@ func TestUserHandler_Update(t *testing.T) {
 					  "password": "password"
 					}
 					`,
-			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","password":"password","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
+			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
 			expectedHttpStatusResponse: http.StatusOK,
 			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
 				mockedService.On("Update", mock.Anything).Return(&models.UserRequest{
@@ -182,6 +268,39 @@ func TestUserHandler_Update(t *testing.T) {
 				assert.Equal(t, expectedBody, response.Body.String())
 			},
 		},
+		{
+			name: "Test updating another user should return 403",
+			requestBody: `{
+					  "id": 2,
+					  "first_name": "Meze"
+					}
+					`,
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"Not allowed to update this user"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
+		{
+			name:   "Test updating another user as admin should return 200",
+			claims: &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin},
+			requestBody: `{
+					  "id": 2,
+					  "first_name": "Meze"
+					}
+					`,
+			expectedBodyResponse:       `{"id":2,"first_name":"Meze","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Update", mock.Anything).Return(&models.UserRequest{Id: 2, FirstName: "Meze"}, nil)
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,9 +310,13 @@ func TestUserHandler_Update(t *testing.T) {
 
 			tt.mockedBehavior(t, &mockedService.Mock)
 
-			userHandler := NewUserHandler(mockedService)
+			userHandler := NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor())
 
-			router := setupMockedRouter(userHandler)
+			claims := tt.claims
+			if claims == nil {
+				claims = ownerClaims
+			}
+			router := setupMockedRouter(userHandler, claims)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("PUT", "/api/v1/users/", bytes.NewReader([]byte(tt.requestBody)))
@@ -221,7 +344,7 @@ func TestUserHandler_Get(t *testing.T) {
 		{
 			name:                       "Valid request should return 200 status code",
 			id:                         "1",
-			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","password":"password","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
+			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
 			expectedHttpStatusResponse: http.StatusOK,
 			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
 				mockedService.On("Get", mock.Anything).Return(&models.UserRequest{
@@ -245,7 +368,7 @@ func TestUserHandler_Get(t *testing.T) {
 			id:                   "1",
 			expectedBodyResponse: `{"code":"NOT_FOUND","message":"User not found"}`,
 			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
-				mockedService.On("Get", mock.Anything).Return(nil, nil)
+				mockedService.On("Get", mock.Anything).Return(nil, models.ErrUserNotFound)
 			},
 			expectedHttpStatusResponse: http.StatusNotFound,
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
@@ -286,9 +409,9 @@ func TestUserHandler_Get(t *testing.T) {
 
 			tt.mockedBehavior(t, &mockedService.Mock)
 
-			userHandler := NewUserHandler(mockedService)
+			userHandler := NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor())
 
-			router := setupMockedRouter(userHandler)
+			router := setupMockedRouter(userHandler, ownerClaims)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/users/%s", tt.id), nil)
@@ -305,6 +428,7 @@ func TestUserHandler_Delete(t *testing.T) {
 
 	tests := []struct {
 		name                       string
+		claims                     *authModels.CustomClaims
 		id                         string
 		expectedBodyResponse       string
 		expectedHttpStatusResponse int
@@ -335,6 +459,19 @@ func TestUserHandler_Delete(t *testing.T) {
 				assert.Equal(t, expectedBody, response.Body.String())
 			},
 		},
+		{
+			name:                       "Test with unknown id should return 404",
+			id:                         "1",
+			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"User not found"}`,
+			expectedHttpStatusResponse: http.StatusNotFound,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Delete", mock.Anything).Return(nil, models.ErrUserNotFound)
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
 		{
 			name:                       "Test with valid data should return 500 due service error",
 			id:                         "1",
@@ -348,6 +485,31 @@ func TestUserHandler_Delete(t *testing.T) {
 				assert.Equal(t, expectedBody, response.Body.String())
 			},
 		},
+		{
+			name:                       "Test deleting another user should return 403",
+			id:                         "2",
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"Not allowed to delete this user"}`,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+			expectedHttpStatusResponse: http.StatusForbidden,
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
+		{
+			name:                       "Test deleting another user as admin should return 204",
+			claims:                     &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin},
+			id:                         "2",
+			expectedBodyResponse:       "",
+			expectedHttpStatusResponse: http.StatusNoContent,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Delete", "2").Return(&models.UserRequest{}, nil)
+			},
+			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
+				assert.Equal(t, expectedHttpStatusResponse, response.Code)
+				assert.Equal(t, expectedBody, response.Body.String())
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -357,9 +519,13 @@ func TestUserHandler_Delete(t *testing.T) {
 
 			tt.mockedBehavior(t, &mockedService.Mock)
 
-			userHandler := NewUserHandler(mockedService)
+			userHandler := NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor())
 
-			router := setupMockedRouter(userHandler)
+			claims := tt.claims
+			if claims == nil {
+				claims = ownerClaims
+			}
+			router := setupMockedRouter(userHandler, claims)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/users/%s", tt.id), nil)
@@ -387,7 +553,7 @@ func TestUserHandler_GetByEmail(t *testing.T) {
 		{
 			name:                       "Valid request should return 200 status code",
 			email:                      "meze@email.com",
-			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","password":"password","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
+			expectedBodyResponse:       `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","created_at":"2024-01-05T23:01:41.9180793-03:00","updated_at":"2024-01-05T23:01:41.9180793-03:00"}`,
 			expectedHttpStatusResponse: http.StatusOK,
 			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
 				mockedService.On("GetByEmail", mock.Anything).Return(&models.UserRequest{
@@ -411,7 +577,7 @@ func TestUserHandler_GetByEmail(t *testing.T) {
 			email:                "meze@email.com",
 			expectedBodyResponse: `{"code":"NOT_FOUND","message":"User not found"}`,
 			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
-				mockedService.On("GetByEmail", mock.Anything).Return(nil, nil)
+				mockedService.On("GetByEmail", mock.Anything).Return(nil, models.ErrUserNotFound)
 			},
 			expectedHttpStatusResponse: http.StatusNotFound,
 			asserts: func(t *testing.T, response *httptest.ResponseRecorder, expectedHttpStatusResponse int, expectedBody string) {
@@ -452,9 +618,9 @@ func TestUserHandler_GetByEmail(t *testing.T) {
 
 			tt.mockedBehavior(t, &mockedService.Mock)
 
-			userHandler := NewUserHandler(mockedService)
+			userHandler := NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor())
 
-			router := setupMockedRouter(userHandler)
+			router := setupMockedRouter(userHandler, ownerClaims)
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/users/email/%s", tt.email), nil)
@@ -467,24 +633,542 @@ func TestUserHandler_GetByEmail(t *testing.T) {
 	}
 }
 
-func setupMockedRouter(userHandler UserHandlerInterface) *gin.Engine {
+func TestUserHandler_GetPermissions(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		id                         string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mock *mock.Mock)
+	}{
+		{
+			name:                       "Valid request should return permissions",
+			id:                         "1",
+			expectedBodyResponse:       `{"id":1,"role":"worker","permissions":["users:read","jobs:apply"]}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("GetPermissions", "1").Return(&models.UserPermissions{Id: 1, Role: "worker", Permissions: []string{"users:read", "jobs:apply"}}, nil)
+			},
+		},
+		{
+			name:                       "Test reading permissions of another user should return 403",
+			id:                         "2",
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"Not allowed to read permissions of this user"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Test with service error should return 500",
+			id:                         "1",
+			expectedBodyResponse:       `{"code":"ERROR","message":"An error occurred when trying to retrieve permissions of user with id 1"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("GetPermissions", "1").Return(nil, errors.New("error from service"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockedService := &MockUserService{}
+
+			tt.mockedBehavior(t, &mockedService.Mock)
+
+			router := setupMockedRouter(NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()), ownerClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/users/%s/permissions", tt.id), nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_UpdatePermissions(t *testing.T) {
+
+	adminClaims := &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin}
+
+	tests := []struct {
+		name                       string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mock *mock.Mock)
+	}{
+		{
+			name:                       "Valid request should return updated permissions",
+			requestBody:                `{"role":"employer","permissions":["jobs:apply"]}`,
+			expectedBodyResponse:       `{"id":2,"role":"employer","permissions":["users:read","users:write","jobs:publish","jobs:apply"]}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("UpdatePermissions", "2", &models.UserPermissions{Role: "employer", Permissions: []string{"jobs:apply"}}, adminClaims).
+					Return(&models.UserPermissions{Id: 2, Role: "employer", Permissions: []string{"users:read", "users:write", "jobs:publish", "jobs:apply"}}, nil)
+			},
+		},
+		{
+			name:                       "Test without role should return 400",
+			requestBody:                `{"permissions":["jobs:apply"]}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Test with unknown role should return 400",
+			requestBody:                `{"role":"owner","permissions":[]}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Unknown role"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("UpdatePermissions", "2", mock.Anything, adminClaims).Return(nil, models.ErrInvalidRole)
+			},
+		},
+		{
+			name:                       "Test with unknown permission should return 400",
+			requestBody:                `{"role":"employer","permissions":["everything"]}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Unknown permission"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("UpdatePermissions", "2", mock.Anything, adminClaims).Return(nil, models.ErrInvalidPermission)
+			},
+		},
+		{
+			name:                       "Test granting a permission not held should return 403",
+			requestBody:                `{"role":"employer","permissions":["audit:read"]}`,
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"Cannot grant a role or permission you do not hold"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("UpdatePermissions", "2", mock.Anything, adminClaims).Return(nil, models.ErrPermissionNotHeld)
+			},
+		},
+		{
+			name:                       "Test with service error should return 500",
+			requestBody:                `{"role":"employer"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"An error occurred when trying to update permissions of user with id 2"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("UpdatePermissions", "2", mock.Anything, adminClaims).Return(nil, errors.New("error from service"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockedService := &MockUserService{}
+
+			tt.mockedBehavior(t, &mockedService.Mock)
+
+			mockedAuditor := testAuditor()
+			router := setupMockedRouter(NewUserHandler(mockedService, &MockVerificationSender{}, mockedAuditor), adminClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/api/v1/users/2/permissions", bytes.NewReader([]byte(tt.requestBody)))
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+			if tt.expectedHttpStatusResponse == http.StatusOK {
+				event := mockedAuditor.Calls[0].Arguments.Get(0).(*auditModels.AuditEvent)
+				assert.Equal(t, auditModels.EventRoleChange, event.EventType)
+				assert.Equal(t, adminClaims.UserID, event.ActorID)
+				assert.Equal(t, "2", event.TargetID)
+				assert.Equal(t, "employer", event.Reason)
+			} else {
+				mockedAuditor.AssertNotCalled(t, "Record", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestUserHandler_List(t *testing.T) {
+	adminClaims := &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin}
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	total := int64(8)
+
+	tests := []struct {
+		name                       string
+		query                      string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mockedService *mock.Mock)
+	}{
+		{
+			name:                       "Valid query should return a page of users",
+			query:                      "?name=meze&email_domain=gmail.com&created_from=2024-05-01T00:00:00Z&status=verified&sort=-email&limit=1&include_total=true",
+			expectedBodyResponse:       `{"users":[{"id":2,"first_name":"Meze","email":"meze@gmail.com","created_at":"2024-05-01T10:00:00Z","updated_at":"0001-01-01T00:00:00Z"}],"next_cursor":"next","total":8}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("List", models.UserFilter{
+					Name:         "meze",
+					EmailDomain:  "gmail.com",
+					CreatedFrom:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+					Status:       models.UserStatusVerified,
+					Sort:         "-email",
+					Limit:        1,
+					IncludeTotal: true,
+				}).Return(&models.UserPage{
+					Users:      []models.AdminUser{{SelfUser: models.SelfUser{Id: 2, FirstName: "Meze", Email: "meze@gmail.com", CreatedAt: createdAt}}},
+					NextCursor: "next",
+					Total:      &total,
+				}, nil)
+			},
+		},
+		{
+			name:                       "Unknown status should return 400",
+			query:                      "?status=banned",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid query parameters"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Unknown sort should return 400",
+			query:                      "?sort=password",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Unknown sort field"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("List", mock.Anything).Return(nil, models.ErrInvalidSort)
+			},
+		},
+		{
+			name:                       "Invalid cursor should return 400",
+			query:                      "?cursor=abc",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid cursor"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("List", mock.Anything).Return(nil, models.ErrInvalidCursor)
+			},
+		},
+		{
+			name:                       "Service error should return 500",
+			expectedBodyResponse:       `{"code":"ERROR","message":"An error occurred when trying to list users"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("List", mock.Anything).Return(nil, errors.New("error from service"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockedService := &MockUserService{}
+
+			tt.mockedBehavior(t, &mockedService.Mock)
+
+			router := setupMockedRouter(NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()), adminClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/users"+tt.query, nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_Search(t *testing.T) {
+
+	tests := []struct {
+		name                       string
+		query                      string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mockedService *mock.Mock)
+	}{
+		{
+			name:                       "Valid query should return matching users",
+			query:                      "?q=jose%20gonzales&limit=5",
+			expectedBodyResponse:       `{"users":[{"id":7,"first_name":"José","last_name":"González","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}]}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Search", models.UserSearch{Query: "jose gonzales", Limit: 5}).
+					Return(&models.UserPage{Users: []models.AdminUser{{SelfUser: models.SelfUser{Id: 7, FirstName: "José", LastName: "González"}}}}, nil)
+			},
+		},
+		{
+			name:                       "Missing query should return 400",
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid query parameters"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Service error should return 500",
+			query:                      "?q=meze",
+			expectedBodyResponse:       `{"code":"ERROR","message":"An error occurred when trying to search users"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Search", mock.Anything).Return(nil, errors.New("error from service"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockedService := &MockUserService{}
+
+			tt.mockedBehavior(t, &mockedService.Mock)
+
+			router := setupMockedRouter(NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()), nil)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/users/search"+tt.query, nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_Patch(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                       string
+		userId                     string
+		contentType                string
+		requestBody                string
+		expectedBodyResponse       string
+		expectedHttpStatusResponse int
+		mockedBehavior             func(t *testing.T, mockedService *mock.Mock)
+	}{
+		{
+			name:                       "Valid patch should return the updated user",
+			userId:                     "1",
+			contentType:                "application/merge-patch+json",
+			requestBody:                `{"first_name":"Meze","last_name":null}`,
+			expectedBodyResponse:       `{"id":1,"first_name":"Meze","email":"meze@email.com","created_at":"0001-01-01T00:00:00Z","updated_at":"2024-05-01T10:00:00Z"}`,
+			expectedHttpStatusResponse: http.StatusOK,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Patch", "1", map[string]interface{}{"first_name": "Meze", "last_name": ""}).
+					Return(&models.UserRequest{Id: 1, FirstName: "Meze", Email: "meze@email.com", UpdatedAt: updatedAt}, nil)
+			},
+		},
+		{
+			name:                       "Immutable and unknown fields should return 400",
+			userId:                     "1",
+			contentType:                "application/json",
+			requestBody:                `{"email":"other@email.com","password":"secret","nickname":"meze","first_name":7}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Merge patch can not be applied","fields":[{"field":"email","code":"IMMUTABLE_FIELD","message":"Field can not be changed through this endpoint"},{"field":"first_name","code":"INVALID_VALUE","message":"Must be a string of up to 100 characters or null"},{"field":"nickname","code":"UNKNOWN_FIELD","message":"Field does not exist"},{"field":"password","code":"IMMUTABLE_FIELD","message":"Field can not be changed through this endpoint"}]}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Patch that is not an object should return 400",
+			userId:                     "1",
+			contentType:                "application/merge-patch+json",
+			requestBody:                `["first_name"]`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Other content type should return 415",
+			userId:                     "1",
+			contentType:                "text/plain",
+			requestBody:                `{"first_name":"Meze"}`,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Content type must be application/merge-patch+json"}`,
+			expectedHttpStatusResponse: http.StatusUnsupportedMediaType,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Patch of another user should return 403",
+			userId:                     "2",
+			contentType:                "application/merge-patch+json",
+			requestBody:                `{"first_name":"Meze"}`,
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"Not allowed to update this user"}`,
+			expectedHttpStatusResponse: http.StatusForbidden,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Id that is not a number should return 400",
+			userId:                     "1%20OR%201%3D1",
+			contentType:                "application/merge-patch+json",
+			requestBody:                `{"first_name":"Meze"}`,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Invalid user id"}`,
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			mockedBehavior:             func(t *testing.T, mockedService *mock.Mock) {},
+		},
+		{
+			name:                       "Unknown user should return 404",
+			userId:                     "1",
+			contentType:                "application/merge-patch+json",
+			requestBody:                `{}`,
+			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"User not found"}`,
+			expectedHttpStatusResponse: http.StatusNotFound,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Patch", "1", map[string]interface{}{}).Return(nil, models.ErrUserNotFound)
+			},
+		},
+		{
+			name:                       "Service error should return 500",
+			userId:                     "1",
+			contentType:                "application/merge-patch+json",
+			requestBody:                `{"first_name":"Meze"}`,
+			expectedBodyResponse:       `{"code":"ERROR","message":"An error occurred when trying to update user with id 1"}`,
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			mockedBehavior: func(t *testing.T, mockedService *mock.Mock) {
+				mockedService.On("Patch", "1", mock.Anything).Return(nil, errors.New("error from service"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			mockedService := &MockUserService{}
+
+			tt.mockedBehavior(t, &mockedService.Mock)
+
+			router := setupMockedRouter(NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()), ownerClaims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", "/api/v1/users/"+tt.userId, bytes.NewReader([]byte(tt.requestBody)))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_GetViews(t *testing.T) {
+	deletedAt := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	storedUser := &models.UserRequest{
+		Id:           1,
+		FirstName:    "Meze",
+		LastName:     "Lawyer",
+		Email:        "meze@email.com",
+		Password:     "$2a$10$hash",
+		Role:         authModels.RoleWorker,
+		TokenVersion: 3,
+		DeletedAt:    &deletedAt,
+	}
+
+	tests := []struct {
+		name                 string
+		claims               *authModels.CustomClaims
+		expectedBodyResponse string
+	}{
+		{
+			name:                 "Owner should see its own account",
+			claims:               ownerClaims,
+			expectedBodyResponse: `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","role":"worker","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:                 "Another user should only see the public fields",
+			claims:               &authModels.CustomClaims{UserID: "2", Role: authModels.RoleUser},
+			expectedBodyResponse: `{"id":1,"first_name":"Meze","last_name":"Lawyer"}`,
+		},
+		{
+			name:                 "Admin should see the backoffice fields",
+			claims:               &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin},
+			expectedBodyResponse: `{"id":1,"first_name":"Meze","last_name":"Lawyer","email":"meze@email.com","role":"worker","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","deleted_at":"2024-06-01T10:00:00Z"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockedService := &MockUserService{}
+			mockedService.On("Get", "1").Return(storedUser, nil)
+
+			router := setupMockedRouter(NewUserHandler(mockedService, &MockVerificationSender{}, testAuditor()), tt.claims)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/users/1", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
+
+func TestUserHandler_ResponsesNeverContainSecrets(t *testing.T) {
+	storedUser := &models.UserRequest{Id: 1, Email: "meze@email.com", Password: "$2a$10$hash", TokenVersion: 3}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "Create", method: "POST", path: "/api/v1/users/", body: `{"email":"meze@email.com","password":"password"}`},
+		{name: "Get", method: "GET", path: "/api/v1/users/1"},
+		{name: "GetByEmail", method: "GET", path: "/api/v1/users/email/meze@email.com"},
+		{name: "Update", method: "PUT", path: "/api/v1/users/", body: `{"id":1,"first_name":"Meze"}`},
+		{name: "Patch", method: "PATCH", path: "/api/v1/users/1", body: `{"first_name":"Meze"}`},
+	}
+
+	for _, claims := range []*authModels.CustomClaims{ownerClaims, {UserID: "99", Role: authModels.RoleAdmin}} {
+		for _, tt := range tests {
+			t.Run(tt.name+" as "+claims.Role, func(t *testing.T) {
+				mockedService := &MockUserService{}
+				mockedService.On(tt.name, mock.Anything).Return(storedUser, nil)
+				mockedService.On(tt.name, mock.Anything, mock.Anything).Return(storedUser, nil)
+				mockedSender := &MockVerificationSender{}
+				mockedSender.On("SendVerification", mock.Anything).Return(nil)
+
+				router := setupMockedRouter(NewUserHandler(mockedService, mockedSender, testAuditor()), claims)
+
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(tt.method, tt.path, bytes.NewReader([]byte(tt.body)))
+				req.Header.Set("Content-Type", "application/json")
+
+				router.ServeHTTP(w, req)
+
+				assert.Less(t, w.Code, http.StatusBadRequest)
+				assert.NotContains(t, w.Body.String(), "$2a$10$hash")
+				assert.NotContains(t, w.Body.String(), "password")
+				assert.NotContains(t, w.Body.String(), "token_version")
+			})
+		}
+	}
+}
+
+var ownerClaims = &authModels.CustomClaims{UserID: "1", Email: "meze@email.com", Role: authModels.RoleUser}
+
+func setupMockedRouter(userHandler UserHandlerInterface, claims *authModels.CustomClaims) *gin.Engine {
 	r := gin.Default()
+	r.Use(customError.ErrorHandler())
 	r.GET("/ping", func(c *gin.Context) {
 		c.String(200, "pong")
 	})
 
 	v1 := r.Group("/api/v1")
+	v1.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(middleware.ClaimsKey, claims)
+		}
+	})
 	{
 		users := v1.Group("/users")
 		{
 			users.POST("/", userHandler.Create)
+			users.GET("", userHandler.List)
+			users.GET("/search", userHandler.Search)
 			users.GET("/:id", userHandler.Get)
 			users.GET("/", userHandler.Get)
 			users.GET("/email/:email", userHandler.GetByEmail)
 			users.GET("/email/", userHandler.GetByEmail)
 			users.PUT("/", userHandler.Update)
+			users.PATCH("/:id", userHandler.Patch)
 			users.DELETE("/:id", userHandler.Delete)
 			users.DELETE("/", userHandler.Delete)
+			users.GET("/:id/permissions", userHandler.GetPermissions)
+			users.PUT("/:id/permissions", userHandler.UpdatePermissions)
 		}
 
 	}
@@ -504,7 +1188,7 @@ func (m *MockUserService) Get(id string) (*models.UserRequest, error) {
 	return args.Get(0).(*models.UserRequest), args.Error(1)
 }
 
-func (m *MockUserService) Update(user *models.UserRequest) (*models.UserRequest, error) {
+func (m *MockUserService) Update(user *models.UpdateUserRequest) (*models.UserRequest, error) {
 	args := m.Called(user)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
@@ -520,7 +1204,7 @@ func (m *MockUserService) Delete(id string) (*models.UserRequest, error) {
 	return args.Get(0).(*models.UserRequest), args.Error(1)
 }
 
-func (m *MockUserService) Create(user *models.UserRequest) (*models.UserRequest, error) {
+func (m *MockUserService) Create(user *models.CreateUserRequest) (*models.UserRequest, error) {
 	args := m.Called(user)
 	if args.Get(1) != nil {
 		return nil, args.Error(1)
@@ -536,3 +1220,94 @@ func (m *MockUserService) GetByEmail(email string) (*models.UserRequest, error)
 	}
 	return args.Get(0).(*models.UserRequest), args.Error(1)
 }
+
+func (m *MockUserService) GetTokenVersion(id string) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) IncrementTokenVersion(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetPermissions(id string) (*models.UserPermissions, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPermissions), args.Error(1)
+}
+
+func (m *MockUserService) UpdatePermissions(id string, permissions *models.UserPermissions, caller *authModels.CustomClaims) (*models.UserPermissions, error) {
+	args := m.Called(id, permissions, caller)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPermissions), args.Error(1)
+}
+
+func (m *MockUserService) UpdatePassword(id string, password string) error {
+	args := m.Called(id, password)
+	return args.Error(0)
+}
+
+func (m *MockUserService) MarkEmailVerified(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) CreateExternal(user *models.UserRequest) (*models.UserRequest, error) {
+	args := m.Called(user)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserRequest), args.Error(1)
+}
+
+func (m *MockUserService) List(filter models.UserFilter) (*models.UserPage, error) {
+	args := m.Called(filter)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPage), args.Error(1)
+}
+
+func (m *MockUserService) Search(search models.UserSearch) (*models.UserPage, error) {
+	args := m.Called(search)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserPage), args.Error(1)
+}
+
+func (m *MockUserService) Patch(id string, changes map[string]interface{}) (*models.UserRequest, error) {
+	args := m.Called(id, changes)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserRequest), args.Error(1)
+}
+
+type MockVerificationSender struct {
+	mock.Mock
+}
+
+func (m *MockVerificationSender) SendVerification(user *models.UserRequest) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+type MockAuditor struct {
+	mock.Mock
+}
+
+func (m *MockAuditor) Record(event *auditModels.AuditEvent) {
+	m.Called(event)
+}
+
+func testAuditor() *MockAuditor {
+	auditor := &MockAuditor{}
+	auditor.On("Record", mock.Anything)
+	return auditor
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"chambeo-api-core/pkg/customError"
+)
+
+var (
+	ErrInvalidRole       = customError.NewInvalidBody("Unknown role", nil)
+	ErrInvalidPermission = customError.NewInvalidBody("Unknown permission", nil)
+	ErrInvalidCursor     = customError.NewInvalidBody("Invalid cursor", nil)
+	ErrInvalidSort       = customError.NewInvalidBody("Unknown sort field", nil)
+	ErrInvalidPatch      = customError.NewInvalidBody("Invalid request body", nil)
+
+	ErrUserNotFound = customError.NewNotFound("User not found", nil)
+	ErrEmailTaken   = customError.NewConflict("Email already registered", nil)
+
+	ErrOwnPermissions    = customError.NewForbidden("Cannot change your own permissions", nil)
+	ErrPermissionNotHeld = customError.NewForbidden("Cannot grant a role or permission you do not hold", nil)
+)
@@ -2,12 +2,16 @@ package models
 
 import (
 	"gorm.io/gorm"
+	"time"
 )
 
 type User struct {
 	gorm.Model
-	FirstName string
-	LastName  string
-	Email     string
-	Password  string
+	FirstName       string
+	LastName        string
+	Email           string
+	Password        string
+	Role            string
+	TokenVersion    int
+	EmailVerifiedAt *time.Time
 }
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+	// DefaultUserSort lists the newest accounts first.
+	DefaultUserSort = "-created_at"
+)
+
+// Statuses of an account. Deleted accounts are only listed when asked for,
+// every other status excludes them.
+const (
+	UserStatusVerified   = "verified"
+	UserStatusUnverified = "unverified"
+	UserStatusDeleted    = "deleted"
+)
+
+// userSortColumns are the fields users can be sorted by.
+var userSortColumns = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"email":      true,
+	"first_name": true,
+	"last_name":  true,
+}
+
+// UserFilter selects the users of a listing, zero values are ignored. Sort
+// is a field name, prefixed with "-" for descending order, and Cursor is the
+// next_cursor of the previous page.
+type UserFilter struct {
+	Name         string    `form:"name"`
+	EmailDomain  string    `form:"email_domain"`
+	CreatedFrom  time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo    time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Status       string    `form:"status" binding:"omitempty,oneof=verified unverified deleted"`
+	Sort         string    `form:"sort"`
+	Cursor       string    `form:"cursor"`
+	Limit        int       `form:"limit" binding:"omitempty,min=1,max=200"`
+	IncludeTotal bool      `form:"include_total"`
+}
+
+// UserCursor is the position of the last user of a page. Value is the sort
+// field of that user, the id breaks ties between equal values.
+type UserCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+type UserPage struct {
+	Users      []AdminUser `json:"users"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      *int64      `json:"total,omitempty"`
+}
+
+// ParseUserSort returns the column and direction of a sort, ok is false when
+// the field can not be sorted by.
+func ParseUserSort(sort string) (column string, descending bool, ok bool) {
+	if sort == "" {
+		sort = DefaultUserSort
+	}
+	column = strings.TrimPrefix(sort, "-")
+	return column, column != sort, userSortColumns[column]
+}
+
+// SortValue returns Value with the type of the sort column.
+func (c UserCursor) SortValue() (interface{}, error) {
+	column, _, _ := ParseUserSort(c.Sort)
+	switch column {
+	case "id":
+		return c.ID, nil
+	case "created_at":
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return createdAt, nil
+	default:
+		return c.Value, nil
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"bytes"
+	"chambeo-api-core/pkg/customError"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codes of the members of a merge patch that are refused.
+const (
+	PatchImmutableField = "IMMUTABLE_FIELD"
+	PatchUnknownField   = "UNKNOWN_FIELD"
+	PatchInvalidValue   = "INVALID_VALUE"
+)
+
+const userNameMaxLength = 100
+
+// userPatchFields are the members a merge patch can change, mapped to their
+// column.
+var userPatchFields = map[string]string{
+	"first_name": "first_name",
+	"last_name":  "last_name",
+}
+
+// immutableUserFields change only through their own endpoints, or never.
+var immutableUserFields = map[string]bool{
+	"id":                true,
+	"email":             true,
+	"password":          true,
+	"role":              true,
+	"email_verified_at": true,
+	"created_at":        true,
+	"updated_at":        true,
+	"deleted_at":        true,
+}
+
+// PatchError lists every member of a merge patch that can not be applied,
+// handlers turn it into field level validation errors.
+type PatchError struct {
+	Fields []customError.FieldError
+}
+
+func (p *PatchError) Error() string {
+	fields := make([]string, 0, len(p.Fields))
+	for _, field := range p.Fields {
+		fields = append(fields, field.Field)
+	}
+	return "merge patch can not be applied to: " + strings.Join(fields, ", ")
+}
+
+// ParseUserPatch turns an RFC 7396 merge patch into the column updates it
+// makes. A null member clears the field, the columns are NOT NULL so it is
+// stored as an empty string.
+func ParseUserPatch(body []byte) (map[string]interface{}, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return nil, ErrInvalidPatch
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := map[string]interface{}{}
+	patchError := &PatchError{}
+	for _, name := range names {
+		if immutableUserFields[name] {
+			patchError.Fields = append(patchError.Fields, customError.FieldError{
+				Field:   name,
+				Code:    PatchImmutableField,
+				Message: "Field can not be changed through this endpoint",
+			})
+			continue
+		}
+		column, ok := userPatchFields[name]
+		if !ok {
+			patchError.Fields = append(patchError.Fields, customError.FieldError{
+				Field:   name,
+				Code:    PatchUnknownField,
+				Message: "Field does not exist",
+			})
+			continue
+		}
+
+		var value string
+		raw := members[name]
+		if !bytes.Equal(raw, []byte("null")) {
+			if err := json.Unmarshal(raw, &value); err != nil || utf8.RuneCountInString(value) > userNameMaxLength {
+				patchError.Fields = append(patchError.Fields, customError.FieldError{
+					Field:   name,
+					Code:    PatchInvalidValue,
+					Message: "Must be a string of up to 100 characters or null",
+				})
+				continue
+			}
+		}
+		changes[column] = value
+	}
+
+	if len(patchError.Fields) > 0 {
+		return nil, patchError
+	}
+	return changes, nil
+}
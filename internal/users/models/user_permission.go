@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UserPermission is a permission granted to a user on top of the ones of its
+// role.
+type UserPermission struct {
+	ID         uint `gorm:"primarykey"`
+	UserID     uint
+	Permission string
+	CreatedAt  time.Time
+}
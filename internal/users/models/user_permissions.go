@@ -0,0 +1,7 @@
+package models
+
+type UserPermissions struct {
+	Id          int      `json:"id,omitempty"`
+	Role        string   `json:"role" binding:"required"`
+	Permissions []string `json:"permissions"`
+}
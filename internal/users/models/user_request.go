@@ -2,13 +2,38 @@ package models
 
 import "time"
 
+// UserRequest is the user as the services exchange it. It carries the
+// password hash, so it is never written to a response: handlers answer with
+// one of the views in user_response.go.
 type UserRequest struct {
-	Id        int        `json:"id,omitempty"`
-	FirstName string     `json:"first_name,omitempty"`
-	LastName  string     `json:"last_name,omitempty"`
-	Email     string     `json:"email,omitempty"`
-	Password  string     `json:"password,omitempty"`
-	CreatedAt time.Time  `json:"created_at,omitempty"`
-	UpdatedAt time.Time  `json:"updated_at,omitempty"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Id              int        `json:"id,omitempty"`
+	FirstName       string     `json:"first_name,omitempty"`
+	LastName        string     `json:"last_name,omitempty"`
+	Email           string     `json:"email,omitempty"`
+	Password        string     `json:"-"`
+	Role            string     `json:"role,omitempty"`
+	TokenVersion    int        `json:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CreateUserRequest is the body of a sign up, the only request that takes a
+// plain text password.
+type CreateUserRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Role      string `json:"role"`
+}
+
+// UpdateUserRequest is the body of a full update. The email, the password
+// and the role have their own endpoints, the email in particular has to be
+// verified again before it changes.
+type UpdateUserRequest struct {
+	Id        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
 }
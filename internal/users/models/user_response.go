@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// PublicUser is what any authenticated caller may see of another account.
+type PublicUser struct {
+	Id        int    `json:"id,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+}
+
+// SelfUser is the view of the owner of the account.
+type SelfUser struct {
+	Id              int        `json:"id,omitempty"`
+	FirstName       string     `json:"first_name,omitempty"`
+	LastName        string     `json:"last_name,omitempty"`
+	Email           string     `json:"email,omitempty"`
+	Role            string     `json:"role,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at,omitempty"`
+}
+
+// AdminUser is the view of the backoffice, it also shows deleted accounts.
+type AdminUser struct {
+	SelfUser
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (u *UserRequest) Public() PublicUser {
+	return PublicUser{Id: u.Id, FirstName: u.FirstName, LastName: u.LastName}
+}
+
+func (u *UserRequest) Self() SelfUser {
+	return SelfUser{
+		Id:              u.Id,
+		FirstName:       u.FirstName,
+		LastName:        u.LastName,
+		Email:           u.Email,
+		Role:            u.Role,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+}
+
+func (u *UserRequest) Admin() AdminUser {
+	return AdminUser{SelfUser: u.Self(), DeletedAt: u.DeletedAt}
+}
@@ -0,0 +1,8 @@
+package models
+
+const DefaultSearchSize = 20
+
+type UserSearch struct {
+	Query string `form:"q" binding:"required,min=2,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type UserRepositoryInterface interface {
@@ -14,8 +18,22 @@ type UserRepositoryInterface interface {
 	GetByEmail(id string) (*models.User, error)
 	Update(user *models.User) (*models.User, error)
 	Delete(id string) (*models.User, error)
+	GetTokenVersion(id string) (int, error)
+	IncrementTokenVersion(id string) error
+	GetPermissions(id string) ([]string, error)
+	UpdatePermissions(id string, role string, permissions []string) error
+	UpdatePassword(id string, password string) error
+	MarkEmailVerified(id string, verifiedAt time.Time) error
+	List(filter models.UserFilter, after *models.UserCursor, limit int) ([]models.User, error)
+	Count(filter models.UserFilter) (int64, error)
+	Search(text string, limit int) ([]models.User, error)
+	Patch(id string, changes map[string]interface{}) error
 }
 
+// searchDocument is the text indexed by scripts/14_users_search.sql, it has
+// to match the indexed expression for Postgres to use the indexes.
+const searchDocument = "immutable_unaccent(lower(first_name || ' ' || last_name || ' ' || email))"
+
 type UserRepository struct {
 	DB gorm.DB
 }
@@ -27,6 +45,9 @@ func NewUser(db gorm.DB) UserRepositoryInterface {
 func (u *UserRepository) Create(user *models.User) (*models.User, error) {
 	result := u.DB.Create(&user) // pass pointer of data to Create
 
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, models.ErrEmailTaken.Wrap(result.Error)
+	}
 	if result.Error != nil {
 		log.Println("Error on insert user: ", result.Error.Error()) // TODO
 		return nil, errors.New("error al insertar el usuario en DB")
@@ -37,7 +58,11 @@ func (u *UserRepository) Create(user *models.User) (*models.User, error) {
 
 func (u *UserRepository) Get(id string) (*models.User, error) {
 	var user *models.User
-	if tx := u.DB.First(&user, id); tx.Error != nil {
+	tx := u.DB.Where("id = ?", id).First(&user)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrUserNotFound.Wrap(tx.Error)
+	}
+	if tx.Error != nil {
 		log.Println(fmt.Sprintf("error retrieving user with id %s %s", id, tx.Error.Error()))
 		return nil, errors.New("error al recuperar el usuario en DB")
 	}
@@ -46,7 +71,11 @@ func (u *UserRepository) Get(id string) (*models.User, error) {
 
 func (u *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user *models.User
-	if tx := u.DB.Where("email = ?", email).First(&user); tx.Error != nil {
+	tx := u.DB.Where("email = ?", email).First(&user)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, models.ErrUserNotFound.Wrap(tx.Error)
+	}
+	if tx.Error != nil {
 		log.Println(fmt.Sprintf("error retrieving user with email %s %s", email, tx.Error.Error()))
 		return nil, errors.New("error al recuperar el usuario en DB")
 	}
@@ -54,17 +83,259 @@ func (u *UserRepository) GetByEmail(email string) (*models.User, error) {
 }
 
 func (u *UserRepository) Update(user *models.User) (*models.User, error) {
-	if tx := u.DB.Updates(user); tx.Error != nil {
+	tx := u.DB.Updates(user)
+	if tx.Error != nil {
 		log.Println(fmt.Sprintf("Error trying to update user with id %d", user.ID))
 		return nil, errors.New("error al actualizar el usuario en DB")
 	}
+	if tx.RowsAffected == 0 {
+		return nil, models.ErrUserNotFound
+	}
 	return user, nil
 }
 
 func (u *UserRepository) Delete(id string) (*models.User, error) {
-	if tx := u.DB.Where("id = ?", id).Delete(&models.User{}); tx.Error != nil {
+	tx := u.DB.Where("id = ?", id).Delete(&models.User{})
+	if tx.Error != nil {
 		log.Println(fmt.Sprintf("Error trying to delete user with id %s", id))
 		return nil, errors.New("error al intentar eliminar el usuario")
 	}
+	if tx.RowsAffected == 0 {
+		return nil, models.ErrUserNotFound
+	}
 	return &models.User{}, nil // TODO
 }
+
+// Patch updates only the given columns, unlike Update zero values are
+// written too.
+func (u *UserRepository) Patch(id string, changes map[string]interface{}) error {
+	tx := u.DB.Model(&models.User{}).Where("id = ?", id).Updates(changes)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("Error trying to patch user with id %s %s", id, tx.Error.Error()))
+		return errors.New("error al actualizar el usuario en DB")
+	}
+	if tx.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+func (u *UserRepository) GetTokenVersion(id string) (int, error) {
+	var user models.User
+	tx := u.DB.Select("token_version").Where("id = ?", id).First(&user)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return 0, models.ErrUserNotFound.Wrap(tx.Error)
+	}
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving token version of user with id %s %s", id, tx.Error.Error()))
+		return 0, errors.New("error al recuperar el usuario en DB")
+	}
+	return user.TokenVersion, nil
+}
+
+func (u *UserRepository) IncrementTokenVersion(id string) error {
+	tx := u.DB.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("token_version", gorm.Expr("token_version + 1"))
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("Error trying to increment token version of user with id %s", id))
+		return errors.New("error al actualizar el usuario en DB")
+	}
+	return nil
+}
+
+func (u *UserRepository) GetPermissions(id string) ([]string, error) {
+	var permissions []string
+	tx := u.DB.Model(&models.UserPermission{}).
+		Where("user_id = ?", id).
+		Order("id").
+		Pluck("permission", &permissions)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("error retrieving permissions of user with id %s %s", id, tx.Error.Error()))
+		return nil, errors.New("error al recuperar los permisos del usuario en DB")
+	}
+	return permissions, nil
+}
+
+// UpdatePermissions replaces the role and the granted permissions of the user
+// in a single transaction.
+func (u *UserRepository) UpdatePermissions(id string, role string, permissions []string) error {
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return errors.New("error al actualizar los permisos del usuario en DB")
+	}
+
+	err = u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("role", role).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserPermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissions) == 0 {
+			return nil
+		}
+		grants := make([]models.UserPermission, 0, len(permissions))
+		for _, permission := range permissions {
+			grants = append(grants, models.UserPermission{UserID: uint(userID), Permission: permission})
+		}
+		return tx.Create(&grants).Error
+	})
+	if err != nil {
+		log.Println(fmt.Sprintf("Error trying to update permissions of user with id %s %s", id, err.Error()))
+		return errors.New("error al actualizar los permisos del usuario en DB")
+	}
+	return nil
+}
+
+func (u *UserRepository) UpdatePassword(id string, password string) error {
+	tx := u.DB.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("password", password)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("Error trying to update password of user with id %s", id))
+		return errors.New("error al actualizar el usuario en DB")
+	}
+	return nil
+}
+
+func (u *UserRepository) MarkEmailVerified(id string, verifiedAt time.Time) error {
+	tx := u.DB.Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", verifiedAt)
+	if tx.Error != nil {
+		log.Println(fmt.Sprintf("Error trying to verify email of user with id %s", id))
+		return errors.New("error al actualizar el usuario en DB")
+	}
+	return nil
+}
+
+// List returns up to limit users matching the filter in the order of
+// filter.Sort, starting after the cursor when there is one. Ties are broken
+// by id so pages stay stable while users are inserted.
+func (u *UserRepository) List(filter models.UserFilter, after *models.UserCursor, limit int) ([]models.User, error) {
+	column, descending, ok := models.ParseUserSort(filter.Sort)
+	if !ok {
+		return nil, models.ErrInvalidSort
+	}
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := u.filterUsers(filter)
+	if after != nil {
+		value, err := after.SortValue()
+		if err != nil {
+			return nil, err
+		}
+		if column == "id" {
+			query = query.Where(fmt.Sprintf("id %s ?", comparison), after.ID)
+		} else {
+			query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison), value, after.ID)
+		}
+	}
+	order := fmt.Sprintf("%s %s", column, direction)
+	if column != "id" {
+		order += fmt.Sprintf(", id %s", direction)
+	}
+
+	var users []models.User
+	if tx := query.Order(order).Limit(limit).Find(&users); tx.Error != nil {
+		log.Println(fmt.Sprintf("error listing users %s", tx.Error.Error()))
+		return nil, errors.New("error al recuperar los usuarios en DB")
+	}
+	return users, nil
+}
+
+func (u *UserRepository) Count(filter models.UserFilter) (int64, error) {
+	var total int64
+	if tx := u.filterUsers(filter).Count(&total); tx.Error != nil {
+		log.Println(fmt.Sprintf("error counting users %s", tx.Error.Error()))
+		return 0, errors.New("error al contar los usuarios en DB")
+	}
+	return total, nil
+}
+
+func (u *UserRepository) filterUsers(filter models.UserFilter) *gorm.DB {
+	query := u.DB.Model(&models.User{})
+	switch filter.Status {
+	case models.UserStatusDeleted:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	case models.UserStatusVerified:
+		query = query.Where("email_verified_at IS NOT NULL")
+	case models.UserStatusUnverified:
+		query = query.Where("email_verified_at IS NULL")
+	}
+	if filter.Name != "" {
+		name := "%" + escapeLike(strings.ToLower(filter.Name)) + "%"
+		query = query.Where("LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?", name, name)
+	}
+	if filter.EmailDomain != "" {
+		domain := strings.TrimPrefix(strings.ToLower(filter.EmailDomain), "@")
+		query = query.Where("LOWER(email) LIKE ?", "%@"+escapeLike(domain))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", filter.CreatedTo)
+	}
+	return query
+}
+
+// Search returns up to limit users ranked by relevance to the text. On
+// Postgres it combines full-text and trigram matching, ignoring accents and
+// tolerating typos. Other databases only match the words of the text.
+func (u *UserRepository) Search(text string, limit int) ([]models.User, error) {
+	query := u.plainSearch(text)
+	if u.DB.Dialector.Name() == "postgres" {
+		query = u.fullTextSearch(text)
+	}
+
+	var users []models.User
+	if tx := query.Limit(limit).Find(&users); tx.Error != nil {
+		log.Println(fmt.Sprintf("error searching users %s", tx.Error.Error()))
+		return nil, errors.New("error al buscar los usuarios en DB")
+	}
+	return users, nil
+}
+
+// fullTextSearch matches the whole words of the text or, through the
+// trigram word similarity, misspelled ones.
+func (u *UserRepository) fullTextSearch(text string) *gorm.DB {
+	document := fmt.Sprintf("to_tsvector('simple', %s)", searchDocument)
+	tsQuery := "plainto_tsquery('simple', immutable_unaccent(lower(?)))"
+	return u.DB.Model(&models.User{}).
+		Where(fmt.Sprintf("%s @@ %s OR immutable_unaccent(lower(?)) <%% %s", document, tsQuery, searchDocument), text, text).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                fmt.Sprintf("ts_rank(%s, %s) + word_similarity(immutable_unaccent(lower(?)), %s) DESC, id", document, tsQuery, searchDocument),
+			Vars:               []interface{}{text, text},
+			WithoutParentheses: true,
+		}})
+}
+
+// plainSearch requires every word of the text in a field, users with a name
+// or email starting with the first word come first.
+func (u *UserRepository) plainSearch(text string) *gorm.DB {
+	words := strings.Fields(strings.ToLower(text))
+	query := u.DB.Model(&models.User{})
+	if len(words) == 0 {
+		return query.Order("id")
+	}
+	for _, word := range words {
+		pattern := "%" + escapeLike(word) + "%"
+		query = query.Where("LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern, pattern)
+	}
+	prefix := escapeLike(words[0]) + "%"
+	return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "CASE WHEN LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ? THEN 0 ELSE 1 END, id",
+		Vars:               []interface{}{prefix, prefix, prefix},
+		WithoutParentheses: true,
+	}})
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match themselves.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
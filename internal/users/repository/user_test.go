@@ -2,10 +2,13 @@ package repository
 
 import (
 	"chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/customError"
 	"errors"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"regexp"
@@ -35,12 +38,13 @@ func TestUserRepository_Create(t *testing.T) {
 				LastName:  "Lawyer",
 				Email:     "meze@gmail.com",
 				Password:  "password",
+				Role:      "user",
 			},
 			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, validUser *models.User) {
 
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users` (`created_at`,`updated_at`,`deleted_at`,`first_name`,`last_name`,`email`,`password`,`id`) VALUES (?,?,?,?,?,?,?,?)")).
-					WithArgs(validUser.CreatedAt, validUser.UpdatedAt, nil, validUser.FirstName, validUser.LastName, validUser.Email, validUser.Password, validUser.ID).
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users` (`created_at`,`updated_at`,`deleted_at`,`first_name`,`last_name`,`email`,`password`,`role`,`token_version`,`email_verified_at`,`id`) VALUES (?,?,?,?,?,?,?,?,?,?,?)")).
+					WithArgs(validUser.CreatedAt, validUser.UpdatedAt, nil, validUser.FirstName, validUser.LastName, validUser.Email, validUser.Password, validUser.Role, validUser.TokenVersion, nil, validUser.ID).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 
@@ -61,11 +65,12 @@ func TestUserRepository_Create(t *testing.T) {
 				LastName:  "Lawyer",
 				Email:     "meze@gmail.com",
 				Password:  "password",
+				Role:      "user",
 			},
 			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, validUser *models.User) {
 				mock.ExpectBegin()
-				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users` (`created_at`,`updated_at`,`deleted_at`,`first_name`,`last_name`,`email`,`password`,`id`) VALUES (?,?,?,?,?,?,?,?)")).
-					WithArgs(validUser.CreatedAt, validUser.UpdatedAt, nil, validUser.FirstName, validUser.LastName, validUser.Email, validUser.Password, validUser.ID).
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users` (`created_at`,`updated_at`,`deleted_at`,`first_name`,`last_name`,`email`,`password`,`role`,`token_version`,`email_verified_at`,`id`) VALUES (?,?,?,?,?,?,?,?,?,?,?)")).
+					WithArgs(validUser.CreatedAt, validUser.UpdatedAt, nil, validUser.FirstName, validUser.LastName, validUser.Email, validUser.Password, validUser.Role, validUser.TokenVersion, nil, validUser.ID).
 					WillReturnError(errors.New("error from db"))
 				mock.ExpectCommit()
 			},
@@ -74,6 +79,31 @@ func TestUserRepository_Create(t *testing.T) {
 				assert.Equal(t, "error al insertar el usuario en DB", error.Error())
 			},
 		},
+		{
+			name: "create user with a registered email should return conflict",
+			userRequest: &models.User{
+				Model: gorm.Model{
+					ID:        1,
+					CreatedAt: createdAt,
+					UpdatedAt: updatedAt,
+				},
+				Email:    "meze@gmail.com",
+				Password: "password",
+				Role:     "user",
+			},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, validUser *models.User) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users`")).
+					WillReturnError(&mysqlDriver.MySQLError{Number: 1062, Message: "Duplicate entry 'meze@gmail.com' for key 'email'"})
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, usr *models.User, error error) {
+				assert.Nil(t, usr)
+				assert.ErrorIs(t, error, models.ErrEmailTaken)
+				assert.ErrorIs(t, error, customError.ErrConflict)
+				assert.ErrorIs(t, error, gorm.ErrDuplicatedKey)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -88,7 +118,8 @@ func TestUserRepository_Create(t *testing.T) {
 				Conn:                      db,
 				SkipInitializeWithVersion: true,
 			}), &gorm.Config{
-				Logger: logger.Default.LogMode(logger.Info),
+				Logger:         logger.Default.LogMode(logger.Info),
+				TranslateError: true,
 			})
 
 			if err != nil {
@@ -122,7 +153,7 @@ func TestUserRepository_Get(t *testing.T) {
 				rows := sqlmock.NewRows([]string{"id", "first_name", "last_name"}).
 					AddRow(1, "Martin", "Lawyer")
 
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE `users`.`id` = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
 					WillReturnRows(rows)
 				mock.ExpectCommit()
 			},
@@ -136,7 +167,7 @@ func TestUserRepository_Get(t *testing.T) {
 			id:   "1",
 			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, id string) {
 
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE `users`.`id` = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
 					WillReturnError(errors.New("error al recuperar el usuario en DB"))
 				mock.ExpectCommit()
 			},
@@ -150,13 +181,28 @@ func TestUserRepository_Get(t *testing.T) {
 			id:   "1",
 			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, id string) {
 				emptyRows := &sqlmock.Rows{}
-				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE `users`.`id` = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
 					WillReturnRows(emptyRows)
 				mock.ExpectCommit()
 			},
 			asserts: func(t *testing.T, user *models.User, err error) {
 				assert.Nil(t, user)
-				assert.NotNil(t, err)
+				assert.ErrorIs(t, err, models.ErrUserNotFound)
+				assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			},
+		},
+		{
+			name: "Test with a SQL fragment as id should bind it as a parameter",
+			id:   "1 OR 1=1",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, id string) {
+				emptyRows := &sqlmock.Rows{}
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
+					WillReturnRows(emptyRows)
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, user *models.User, err error) {
+				assert.Nil(t, user)
+				assert.ErrorIs(t, err, models.ErrUserNotFound)
 			},
 		},
 	}
@@ -240,7 +286,7 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 			},
 			asserts: func(t *testing.T, user *models.User, err error) {
 				assert.Nil(t, user)
-				assert.NotNil(t, err)
+				assert.ErrorIs(t, err, models.ErrUserNotFound)
 			},
 		},
 	}
@@ -311,6 +357,19 @@ func TestUserRepository_Delete(t *testing.T) {
 				assert.Equal(t, "error al intentar eliminar el usuario", err.Error())
 			},
 		},
+		{
+			name: "Test with unknown id to delete should return not found",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, id string) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `deleted_at`=? WHERE id = ? AND `users`.`deleted_at` IS NULL")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, user *models.User, err error) {
+				assert.Nil(t, user)
+				assert.ErrorIs(t, err, models.ErrUserNotFound)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -434,3 +493,742 @@ func TestUserRepository_Update(t *testing.T) {
 	}
 
 }
+
+func TestUserRepository_GetTokenVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock, id string)
+		asserts        func(t *testing.T, version int, err error)
+	}{
+		{
+			name: "Test with valid id should return token version",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, id string) {
+				rows := sqlmock.NewRows([]string{"token_version"}).AddRow(3)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT `token_version` FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT 1")).WithArgs(id).
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, version int, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 3, version)
+			},
+		},
+		{
+			name: "Test with unknown id should return error",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock, id string) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT `token_version` FROM `users`")).WithArgs(id).
+					WillReturnRows(&sqlmock.Rows{})
+			},
+			asserts: func(t *testing.T, version int, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, 0, version)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock, tt.id)
+
+			repository := NewUser(*gormDb)
+
+			result, err := repository.GetTokenVersion(tt.id)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestUserRepository_IncrementTokenVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "Test with valid id should increment token version",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `token_version`=token_version + 1,`updated_at`=? WHERE id = ? AND `users`.`deleted_at` IS NULL")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "Test with valid id should return error from db",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `token_version`=token_version + 1")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error al actualizar el usuario en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			err = repository.IncrementTokenVersion(tt.id)
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func TestUserRepository_GetPermissions(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, permissions []string, err error)
+	}{
+		{
+			name: "Test with valid id should return granted permissions",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"permission"}).AddRow("jobs:publish").AddRow("jobs:apply")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT `permission` FROM `user_permissions` WHERE user_id = ? ORDER BY id")).WithArgs("1").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, permissions []string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"jobs:publish", "jobs:apply"}, permissions)
+			},
+		},
+		{
+			name: "Test with valid id should return error from db",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT `permission` FROM `user_permissions`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, permissions []string, err error) {
+				assert.Nil(t, permissions)
+				assert.Equal(t, "error al recuperar los permisos del usuario en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			result, err := repository.GetPermissions("1")
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestUserRepository_UpdatePermissions(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		permissions    []string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name:        "Test with valid data should replace role and permissions",
+			id:          "1",
+			permissions: []string{"jobs:publish"},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `role`=?,`updated_at`=? WHERE id = ? AND `users`.`deleted_at` IS NULL")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `user_permissions` WHERE user_id = ?")).
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `user_permissions` (`user_id`,`permission`,`created_at`) VALUES (?,?,?)")).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:        "Test without permissions should only clear granted permissions",
+			id:          "1",
+			permissions: nil,
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `role`=?")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `user_permissions` WHERE user_id = ?")).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:        "Test with db error should rollback",
+			id:          "1",
+			permissions: []string{"jobs:publish"},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `role`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error al actualizar los permisos del usuario en DB", err.Error())
+			},
+		},
+		{
+			name:           "Test with invalid id should return error",
+			id:             "invalid",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error al actualizar los permisos del usuario en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			err = repository.UpdatePermissions(tt.id, "employer", tt.permissions)
+
+			tt.asserts(t, err)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepository_UpdatePassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "Test with valid id should update password",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `password`=?,`updated_at`=? WHERE id = ? AND `users`.`deleted_at` IS NULL")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "Test with valid id should return error from db",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `password`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error al actualizar el usuario en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			err = repository.UpdatePassword("1", "hash")
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func TestUserRepository_MarkEmailVerified(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "Test with unverified user should set verification date",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `email_verified_at`=?,`updated_at`=? WHERE (id = ? AND email_verified_at IS NULL) AND `users`.`deleted_at` IS NULL")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "Test with valid id should return error from db",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `email_verified_at`=?")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error al actualizar el usuario en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			err = repository.MarkEmailVerified("1", verifiedAt)
+
+			tt.asserts(t, err)
+		})
+	}
+}
+
+func TestUserRepository_List(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		filter         models.UserFilter
+		after          *models.UserCursor
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, users []models.User, err error)
+	}{
+		{
+			name:   "Test with filters should return matching users newest first",
+			filter: models.UserFilter{Name: "Mez_", EmailDomain: "@Gmail.com", CreatedFrom: createdAt, Status: models.UserStatusVerified},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "first_name", "email"}).AddRow(2, "Meze", "meze@gmail.com")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE email_verified_at IS NOT NULL AND (LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?) AND LOWER(email) LIKE ? AND created_at >= ? AND `users`.`deleted_at` IS NULL ORDER BY created_at DESC, id DESC LIMIT 3")).
+					WithArgs(`%mez\_%`, `%mez\_%`, "%@gmail.com", createdAt).
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, users, 1)
+				assert.Equal(t, "meze@gmail.com", users[0].Email)
+			},
+		},
+		{
+			name:   "Test with cursor should continue after the last user",
+			filter: models.UserFilter{Sort: "email"},
+			after:  &models.UserCursor{Sort: "email", Value: "meze@gmail.com", ID: 2},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE (email, id) > (?, ?) AND `users`.`deleted_at` IS NULL ORDER BY email ASC, id ASC LIMIT 3")).
+					WithArgs("meze@gmail.com", 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, err)
+				assert.Empty(t, users)
+			},
+		},
+		{
+			name:   "Test with deleted status should only return deleted users",
+			filter: models.UserFilter{Status: models.UserStatusDeleted, Sort: "-id"},
+			after:  &models.UserCursor{Sort: "-id", ID: 9},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE deleted_at IS NOT NULL AND id < ? ORDER BY id DESC LIMIT 3")).
+					WithArgs(9).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, users, 1)
+			},
+		},
+		{
+			name:           "Test with unknown sort should return error",
+			filter:         models.UserFilter{Sort: "password"},
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidSort)
+			},
+		},
+		{
+			name: "Test with error from db should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, users)
+				assert.Equal(t, "error al recuperar los usuarios en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			result, err := repository.List(tt.filter, tt.after, 3)
+
+			tt.asserts(t, result, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepository_Count(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, total int64, err error)
+	}{
+		{
+			name: "Test with filter should count matching users",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `users` WHERE email_verified_at IS NULL AND `users`.`deleted_at` IS NULL")).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+			},
+			asserts: func(t *testing.T, total int64, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, int64(12), total)
+			},
+		},
+		{
+			name: "Test with error from db should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `users`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, total int64, err error) {
+				assert.Equal(t, "error al contar los usuarios en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			total, err := repository.Count(models.UserFilter{Status: models.UserStatusUnverified})
+
+			tt.asserts(t, total, err)
+		})
+	}
+}
+
+func TestUserRepository_Search(t *testing.T) {
+	tests := []struct {
+		name           string
+		postgres       bool
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, users []models.User, err error)
+	}{
+		{
+			name:     "Test on postgres should rank full-text and trigram matches",
+			postgres: true,
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				document := "immutable_unaccent(lower(first_name || ' ' || last_name || ' ' || email))"
+				rows := sqlmock.NewRows([]string{"id", "first_name", "last_name"}).AddRow(7, "José", "González")
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE (to_tsvector('simple', `+document+`) @@ plainto_tsquery('simple', immutable_unaccent(lower($1))) OR immutable_unaccent(lower($2)) <% `+document+`) AND "users"."deleted_at" IS NULL `+
+					`ORDER BY ts_rank(to_tsvector('simple', `+document+`), plainto_tsquery('simple', immutable_unaccent(lower($3)))) + word_similarity(immutable_unaccent(lower($4)), `+document+`) DESC, id LIMIT 20`)).
+					WithArgs("Jose Gonzales", "Jose Gonzales", "Jose Gonzales", "Jose Gonzales").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "José", users[0].FirstName)
+			},
+		},
+		{
+			name: "Test on other databases should match every word",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "first_name", "last_name"}).AddRow(7, "Jose", "Gonzales")
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE (LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ?) AND (LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ?) AND `users`.`deleted_at` IS NULL "+
+					"ORDER BY CASE WHEN LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ? THEN 0 ELSE 1 END, id LIMIT 20")).
+					WithArgs("%jose%", "%jose%", "%jose%", "%gonzales%", "%gonzales%", "%gonzales%", "jose%", "jose%", "jose%").
+					WillReturnRows(rows)
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, users, 1)
+			},
+		},
+		{
+			name: "Test with error from db should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users`")).
+					WillReturnError(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, users []models.User, err error) {
+				assert.Nil(t, users)
+				assert.Equal(t, "error al buscar los usuarios en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			dialector := mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			})
+			if tt.postgres {
+				dialector = postgres.New(postgres.Config{Conn: db})
+			}
+			gormDb, err := gorm.Open(dialector, &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			result, err := repository.Search("Jose Gonzales", 20)
+
+			tt.asserts(t, result, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepository_Patch(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mock sqlmock.Sqlmock)
+		asserts        func(t *testing.T, err error)
+	}{
+		{
+			name: "Test with changes should write zero values too",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET `first_name`=?,`last_name`=?,`updated_at`=? WHERE id = ? AND `users`.`deleted_at` IS NULL")).
+					WithArgs("Meze", "", sqlmock.AnyArg(), "1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "Test with unknown id should return not found",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET")).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.ErrorIs(t, err, models.ErrUserNotFound)
+			},
+		},
+		{
+			name: "Test with error from db should return error",
+			mockedBehavior: func(t *testing.T, mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE `users` SET")).
+					WillReturnError(errors.New("error from db"))
+				mock.ExpectRollback()
+			},
+			asserts: func(t *testing.T, err error) {
+				assert.Equal(t, "error al actualizar el usuario en DB", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			gormDb, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+
+			if err != nil {
+				t.Error(err.Error())
+			}
+
+			tt.mockedBehavior(t, mock)
+
+			repository := NewUser(*gormDb)
+
+			err = repository.Patch("1", map[string]interface{}{"first_name": "Meze", "last_name": ""})
+
+			tt.asserts(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,42 +1,76 @@
 package service
 
 import (
+	authModels "chambeo-api-core/internal/auth/models"
 	"chambeo-api-core/internal/users/models"
 	"chambeo-api-core/internal/users/repository"
+	"chambeo-api-core/pkg/customError"
+	"chambeo-api-core/pkg/password"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"log"
+	"strings"
+	"time"
 )
 
 type UserServiceInterface interface {
-	Create(user *models.UserRequest) (*models.UserRequest, error)
+	Create(user *models.CreateUserRequest) (*models.UserRequest, error)
+	CreateExternal(user *models.UserRequest) (*models.UserRequest, error)
 	Get(id string) (*models.UserRequest, error)
 	GetByEmail(id string) (*models.UserRequest, error)
-	Update(user *models.UserRequest) (*models.UserRequest, error)
+	Update(user *models.UpdateUserRequest) (*models.UserRequest, error)
+	Patch(id string, changes map[string]interface{}) (*models.UserRequest, error)
 	Delete(id string) (*models.UserRequest, error)
+	GetTokenVersion(id string) (int, error)
+	IncrementTokenVersion(id string) error
+	GetPermissions(id string) (*models.UserPermissions, error)
+	UpdatePermissions(id string, permissions *models.UserPermissions, caller *authModels.CustomClaims) (*models.UserPermissions, error)
+	UpdatePassword(id string, password string) error
+	MarkEmailVerified(id string) error
+	List(filter models.UserFilter) (*models.UserPage, error)
+	Search(search models.UserSearch) (*models.UserPage, error)
 }
 
 type UserService struct {
 	userRepository repository.UserRepositoryInterface
+	passwordHasher password.HasherInterface
+	passwordPolicy passwordPolicy.PolicyInterface
 }
 
-func NewUser(userRepository repository.UserRepositoryInterface) UserServiceInterface {
-	return &UserService{userRepository: userRepository}
+func NewUser(userRepository repository.UserRepositoryInterface, passwordHasher password.HasherInterface,
+	passwordPolicy passwordPolicy.PolicyInterface) UserServiceInterface {
+	return &UserService{userRepository: userRepository, passwordHasher: passwordHasher, passwordPolicy: passwordPolicy}
 }
 
-func (u *UserService) Create(user *models.UserRequest) (*models.UserRequest, error) {
+func (u *UserService) Create(user *models.CreateUserRequest) (*models.UserRequest, error) {
 
-	encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), 16)
+	if err := u.passwordPolicy.Validate(user.Password, personalInfo(user)); err != nil {
+		return nil, err
+	}
+
+	encryptedPassword, err := u.passwordHasher.Hash(user.Password)
 
 	if err != nil {
 		log.Println("error when trying to encrypt password")
-		return nil, errors.New("error al generar la contrasena para la cuenta")
+		return nil, fmt.Errorf("error al generar la contrasena para la cuenta: %w", err)
 	}
-	user.Password = string(encryptedPassword)
 
-	create, err := u.userRepository.Create(mapUserDtoToUserDb(*user))
+	userDb := &models.User{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Password:  encryptedPassword,
+		Role:      authModels.RoleUser,
+	}
+	if authModels.IsSelfAssignableRole(user.Role) {
+		userDb.Role = user.Role
+	}
+
+	create, err := u.userRepository.Create(userDb)
 
 	if err != nil {
 		return nil, err
@@ -44,42 +78,317 @@ func (u *UserService) Create(user *models.UserRequest) (*models.UserRequest, err
 	return mapUserDbToDto(*create), nil
 }
 
+// CreateExternal creates a user that signs in through an identity provider.
+// It has no password until one is set with the reset flow, and its email
+// was already verified by the provider.
+func (u *UserService) CreateExternal(user *models.UserRequest) (*models.UserRequest, error) {
+	userDb := mapUserDtoToUserDb(*user)
+	userDb.Password = ""
+	userDb.Role = authModels.RoleUser
+	verifiedAt := time.Now()
+	userDb.EmailVerifiedAt = &verifiedAt
+
+	create, err := u.userRepository.Create(userDb)
+	if err != nil {
+		return nil, err
+	}
+	return mapUserDbToDto(*create), nil
+}
+
 func (u *UserService) Get(id string) (*models.UserRequest, error) {
 	user, err := u.userRepository.Get(id)
+	if isDomainError(err) {
+		return nil, err
+	}
 	if err != nil {
 		log.Println(fmt.Sprintf("error occurred trying to retrieve user with id %s", id))
-		return nil, errors.New("ocurrio un error al intentar recuperar el usuario")
+		return nil, fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", err)
 	}
 	return mapUserDbToDto(*user), nil
 }
 
-func (u *UserService) Update(user *models.UserRequest) (*models.UserRequest, error) {
-	updatedUser, err := u.userRepository.Update(mapUserDtoToUserDb(*user))
+// Update never changes the email nor the password, the password can only be
+// changed through the password endpoints that hash it and enforce the
+// password policy.
+func (u *UserService) Update(user *models.UpdateUserRequest) (*models.UserRequest, error) {
+	userDb := &models.User{
+		Model:     gorm.Model{ID: uint(user.Id)},
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+	updatedUser, err := u.userRepository.Update(userDb)
+	if isDomainError(err) {
+		return nil, err
+	}
 	if err != nil {
 		log.Println(fmt.Sprintf("An error occurred trying to update user with id %v", user.Id))
-		return nil, errors.New("ocurrio un error al intentar actualizar el usuario")
+		return nil, fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", err)
 	}
 	return mapUserDbToDto(*updatedUser), nil
 }
 
+// Patch applies the column updates of a merge patch, see
+// models.ParseUserPatch, and returns the updated user.
+func (u *UserService) Patch(id string, changes map[string]interface{}) (*models.UserRequest, error) {
+	err := u.userRepository.Patch(id, changes)
+	if isDomainError(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to patch user with id %s", id))
+		return nil, fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", err)
+	}
+
+	return u.Get(id)
+}
+
 func (u *UserService) Delete(id string) (*models.UserRequest, error) {
 	user, err := u.userRepository.Delete(id)
+	if isDomainError(err) {
+		return nil, err
+	}
 	if err != nil {
 		log.Println(fmt.Sprintf("error occurred trying to delete user with id %s", id))
-		return nil, errors.New("ocurrio un error al intentar eliminar el usuario")
+		return nil, fmt.Errorf("ocurrio un error al intentar eliminar el usuario: %w", err)
 	}
 	return mapUserDbToDto(*user), nil
 }
 
 func (u *UserService) GetByEmail(email string) (*models.UserRequest, error) {
 	user, err := u.userRepository.GetByEmail(email)
+	if isDomainError(err) {
+		return nil, err
+	}
 	if err != nil {
 		log.Println(fmt.Sprintf("error occurred trying to retrieve user with email %s", email))
-		return nil, errors.New("ocurrio un error al intentar recuperar el usuario")
+		return nil, fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", err)
 	}
 	return mapUserDbToDto(*user), nil
 }
 
+func (u *UserService) GetTokenVersion(id string) (int, error) {
+	return u.userRepository.GetTokenVersion(id)
+}
+
+func (u *UserService) IncrementTokenVersion(id string) error {
+	if err := u.userRepository.IncrementTokenVersion(id); err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to increment token version of user with id %s", id))
+		return fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", err)
+	}
+	return nil
+}
+
+func (u *UserService) UpdatePassword(id string, newPassword string) error {
+	encryptedPassword, err := u.passwordHasher.Hash(newPassword)
+	if err != nil {
+		log.Println("error when trying to encrypt password")
+		return fmt.Errorf("error al generar la contrasena para la cuenta: %w", err)
+	}
+
+	if err := u.userRepository.UpdatePassword(id, encryptedPassword); err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to update password of user with id %s", id))
+		return fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", err)
+	}
+	return nil
+}
+
+func (u *UserService) MarkEmailVerified(id string) error {
+	if err := u.userRepository.MarkEmailVerified(id, time.Now()); err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to verify email of user with id %s", id))
+		return fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", err)
+	}
+	return nil
+}
+
+// GetPermissions returns the role of the user together with its effective
+// permissions, the ones of the role plus the ones granted to the user.
+func (u *UserService) GetPermissions(id string) (*models.UserPermissions, error) {
+	user, err := u.userRepository.Get(id)
+	if isDomainError(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to retrieve user with id %s", id))
+		return nil, fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", err)
+	}
+
+	granted, err := u.userRepository.GetPermissions(id)
+	if err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to retrieve permissions of user with id %s", id))
+		return nil, fmt.Errorf("ocurrio un error al intentar recuperar los permisos del usuario: %w", err)
+	}
+
+	return &models.UserPermissions{
+		Id:          int(user.ID),
+		Role:        user.Role,
+		Permissions: mergePermissions(authModels.DefaultPermissions(user.Role), granted),
+	}, nil
+}
+
+// UpdatePermissions replaces the role and the granted permissions of the user.
+// Tokens already issued keep the previous permissions until they are refreshed.
+// The caller can not change its own account, grant the admin role or grant
+// a permission it does not hold, directly or through the role.
+func (u *UserService) UpdatePermissions(id string, permissions *models.UserPermissions, caller *authModels.CustomClaims) (*models.UserPermissions, error) {
+	if !authModels.IsValidRole(permissions.Role) {
+		return nil, models.ErrInvalidRole
+	}
+	for _, permission := range permissions.Permissions {
+		if !authModels.IsValidPermission(permission) {
+			return nil, models.ErrInvalidPermission
+		}
+	}
+	if caller.UserID == id {
+		return nil, models.ErrOwnPermissions
+	}
+	if permissions.Role == authModels.RoleAdmin {
+		return nil, models.ErrPermissionNotHeld
+	}
+	for _, permission := range mergePermissions(authModels.DefaultPermissions(permissions.Role), permissions.Permissions) {
+		if !caller.HasPermission(permission) {
+			return nil, models.ErrPermissionNotHeld
+		}
+	}
+
+	granted := mergePermissions(nil, permissions.Permissions)
+	if err := u.userRepository.UpdatePermissions(id, permissions.Role, granted); err != nil {
+		log.Println(fmt.Sprintf("error occurred trying to update permissions of user with id %s", id))
+		return nil, fmt.Errorf("ocurrio un error al intentar actualizar los permisos del usuario: %w", err)
+	}
+
+	return u.GetPermissions(id)
+}
+
+// List returns a page of users, without their password. The page size is
+// capped by MaxPageSize and the total is only counted when asked for.
+func (u *UserService) List(filter models.UserFilter) (*models.UserPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = models.DefaultUserSort
+	}
+	column, _, ok := models.ParseUserSort(filter.Sort)
+	if !ok {
+		return nil, models.ErrInvalidSort
+	}
+	if filter.Limit <= 0 || filter.Limit > models.MaxPageSize {
+		filter.Limit = models.DefaultPageSize
+	}
+
+	var after *models.UserCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeUserCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor
+	}
+
+	// One more user than the page size tells whether there is a next page.
+	users, err := u.userRepository.List(filter, after, filter.Limit+1)
+	if err != nil {
+		log.Println("error occurred trying to list users: ", err.Error())
+		return nil, fmt.Errorf("ocurrio un error al intentar listar los usuarios: %w", err)
+	}
+
+	page := &models.UserPage{Users: make([]models.AdminUser, 0, len(users))}
+	if len(users) > filter.Limit {
+		users = users[:filter.Limit]
+		page.NextCursor = encodeUserCursor(users[len(users)-1], column, filter.Sort)
+	}
+	for _, user := range users {
+		page.Users = append(page.Users, mapUserDbToDto(user).Admin())
+	}
+
+	if filter.IncludeTotal {
+		total, err := u.userRepository.Count(filter)
+		if err != nil {
+			log.Println("error occurred trying to count users: ", err.Error())
+			return nil, fmt.Errorf("ocurrio un error al intentar listar los usuarios: %w", err)
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// Search returns the users that best match the query, most relevant first.
+func (u *UserService) Search(search models.UserSearch) (*models.UserPage, error) {
+	if search.Limit <= 0 {
+		search.Limit = models.DefaultSearchSize
+	}
+	page := &models.UserPage{Users: []models.AdminUser{}}
+	query := strings.Join(strings.Fields(search.Query), " ")
+	if query == "" {
+		return page, nil
+	}
+
+	users, err := u.userRepository.Search(query, search.Limit)
+	if err != nil {
+		log.Println("error occurred trying to search users: ", err.Error())
+		return nil, fmt.Errorf("ocurrio un error al intentar buscar los usuarios: %w", err)
+	}
+	for _, user := range users {
+		page.Users = append(page.Users, mapUserDbToDto(user).Admin())
+	}
+	return page, nil
+}
+
+func encodeUserCursor(user models.User, column string, sort string) string {
+	cursor := models.UserCursor{Sort: sort, ID: user.ID}
+	switch column {
+	case "created_at":
+		cursor.Value = user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "email":
+		cursor.Value = user.Email
+	case "first_name":
+		cursor.Value = user.FirstName
+	case "last_name":
+		cursor.Value = user.LastName
+	}
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeUserCursor rejects cursors issued for another sort, their position
+// means nothing in the requested order.
+func decodeUserCursor(value string, sort string) (*models.UserCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	var cursor models.UserCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.Sort != sort {
+		return nil, models.ErrInvalidCursor
+	}
+	if _, err := cursor.SortValue(); err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// isDomainError reports whether err is meant for the client, see
+// customError.DomainError. Any other error is replaced by a generic one.
+func isDomainError(err error) bool {
+	var domainError *customError.DomainError
+	return errors.As(err, &domainError)
+}
+
+// personalInfo is the data of the user a password must not contain.
+func personalInfo(user *models.CreateUserRequest) passwordPolicy.PersonalInfo {
+	return passwordPolicy.PersonalInfo{FirstName: user.FirstName, LastName: user.LastName, Email: user.Email}
+}
+
+func mergePermissions(base []string, extra []string) []string {
+	merged := make([]string, 0, len(base)+len(extra))
+	seen := map[string]bool{}
+	for _, permission := range append(base, extra...) {
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		merged = append(merged, permission)
+	}
+	return merged
+}
+
 func mapUserDtoToUserDb(user models.UserRequest) *models.User {
 	return &models.User{
 		Model: gorm.Model{
@@ -95,14 +404,20 @@ func mapUserDtoToUserDb(user models.UserRequest) *models.User {
 }
 
 func mapUserDbToDto(user models.User) *models.UserRequest {
-	return &models.UserRequest{
-		Id:        int(user.Model.ID),
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		Password:  user.Password,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		DeletedAt: &user.DeletedAt.Time,
+	dto := &models.UserRequest{
+		Id:              int(user.Model.ID),
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		Password:        user.Password,
+		Role:            user.Role,
+		TokenVersion:    user.TokenVersion,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+	if user.DeletedAt.Valid {
+		dto.DeletedAt = &user.DeletedAt.Time
 	}
+	return dto
 }
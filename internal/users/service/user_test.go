@@ -1,31 +1,41 @@
 package service
 
 import (
+	authModels "chambeo-api-core/internal/auth/models"
 	"chambeo-api-core/internal/users/models"
+	"chambeo-api-core/pkg/password"
+	"chambeo-api-core/pkg/passwordPolicy"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"testing"
 	"time"
 )
 
 var (
-	validUserRequest = &models.UserRequest{
-		Id:        1,
+	validCreateRequest = &models.CreateUserRequest{
 		FirstName: "Meze",
 		LastName:  "Lawyer",
 		Email:     "meze@gmail.com",
 		Password:  "password",
 	}
 
+	validUpdateRequest = &models.UpdateUserRequest{
+		Id:        1,
+		FirstName: "Meze",
+		LastName:  "Lawyer",
+	}
+
 	validUserResponse = &models.UserRequest{
 		Id:        1,
 		FirstName: "Meze",
 		LastName:  "Lawyer",
 		Email:     "meze@gmail.com",
 		Password:  "$2a$16$xYdZVlrHaga5BC6Hw80a/u9QRl5BMJXew8bBKlzA3mpVPgVwk.WMi",
-		DeletedAt: &time.Time{},
 	}
 
 	validUserModel = &models.User{
@@ -46,7 +56,7 @@ func TestUserService_Create(t *testing.T) {
 		name           string
 		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
 		asserts        func(t *testing.T, response *models.UserRequest, errorResult error, expectedError error)
-		request        *models.UserRequest
+		request        *models.CreateUserRequest
 		response       *models.UserRequest
 		error          error
 	}{
@@ -60,7 +70,7 @@ func TestUserService_Create(t *testing.T) {
 				assert.Nil(t, error)
 				assert.Equal(t, validUserResponse, response)
 			},
-			request:  validUserRequest,
+			request:  validCreateRequest,
 			response: validUserResponse,
 			error:    nil,
 		},
@@ -74,10 +84,38 @@ func TestUserService_Create(t *testing.T) {
 				assert.NotNil(t, error)
 				assert.Equal(t, error.Error(), expectedError.Error())
 			},
-			request:  validUserRequest,
+			request:  validCreateRequest,
 			response: nil,
 			error:    errors.New("error from repo"),
 		},
+		{
+			name: "With self assignable role should keep it",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Create", mock.MatchedBy(func(user *models.User) bool {
+					return user.Role == "employer"
+				})).Return(validUserModel, nil)
+			},
+			asserts: func(t *testing.T, response *models.UserRequest, error error, expectedError error) {
+				assert.Nil(t, error)
+			},
+			request:  &models.CreateUserRequest{Email: "meze@gmail.com", Password: "password", Role: "employer"},
+			response: validUserResponse,
+			error:    nil,
+		},
+		{
+			name: "With admin role should fallback to user role",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Create", mock.MatchedBy(func(user *models.User) bool {
+					return user.Role == "user"
+				})).Return(validUserModel, nil)
+			},
+			asserts: func(t *testing.T, response *models.UserRequest, error error, expectedError error) {
+				assert.Nil(t, error)
+			},
+			request:  &models.CreateUserRequest{Email: "meze@gmail.com", Password: "password", Role: "admin"},
+			response: validUserResponse,
+			error:    nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -87,7 +125,7 @@ func TestUserService_Create(t *testing.T) {
 
 			tt.mockedBehavior(t, &userRepository.Mock)
 
-			userService := NewUser(userRepository)
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
 
 			result, err := userService.Create(tt.request)
 
@@ -98,6 +136,58 @@ func TestUserService_Create(t *testing.T) {
 
 }
 
+func TestUserService_CreateWithWeakPassword(t *testing.T) {
+	userRepository := &MockUserRepository{}
+	userService := NewUser(userRepository, testPasswordHasher(t), passwordPolicy.NewPolicy(passwordPolicy.DefaultConfig, nil))
+
+	result, err := userService.Create(&models.CreateUserRequest{FirstName: "Meze", Email: "meze@gmail.com", Password: "meze1234"})
+
+	var violationError *passwordPolicy.ViolationError
+	assert.Nil(t, result)
+	assert.True(t, errors.As(err, &violationError))
+	assert.Equal(t, passwordPolicy.ContainsPersonalInfo, violationError.Violations[0].Code)
+	userRepository.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestUserService_UpdateIgnoresPassword(t *testing.T) {
+	userRepository := &MockUserRepository{}
+	userRepository.On("Update", mock.MatchedBy(func(user *models.User) bool {
+		return user.Password == "" && user.Email == ""
+	})).Return(validUserModel, nil)
+	userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+	_, err := userService.Update(&models.UpdateUserRequest{Id: 1, FirstName: "Meze"})
+
+	assert.Nil(t, err)
+	userRepository.AssertExpectations(t)
+}
+
+func TestUserService_GetDeletedUser(t *testing.T) {
+	deletedAt := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	userRepository := &MockUserRepository{}
+	userRepository.On("Get", "1").Return(&models.User{Model: gorm.Model{ID: 1, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}}, nil)
+	userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+	result, err := userService.Get("1")
+
+	assert.Nil(t, err)
+	assert.Equal(t, &deletedAt, result.DeletedAt)
+}
+
+func TestUserService_CreateExternal(t *testing.T) {
+	userRepository := &MockUserRepository{}
+	userRepository.On("Create", mock.MatchedBy(func(user *models.User) bool {
+		return user.Password == "" && user.Role == "user" && user.EmailVerifiedAt != nil
+	})).Return(validUserModel, nil)
+	userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+	result, err := userService.CreateExternal(&models.UserRequest{FirstName: "Meze", Email: "meze@gmail.com", Password: "ignored", Role: "admin"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, validUserResponse, result)
+	userRepository.AssertExpectations(t)
+}
+
 func TestUserService_Get(t *testing.T) {
 
 	tests := []struct {
@@ -133,7 +223,19 @@ func TestUserService_Get(t *testing.T) {
 				assert.Equal(t, errorResult.Error(), expectedError.Error())
 			},
 			response: nil,
-			error:    errors.New("ocurrio un error al intentar recuperar el usuario"),
+			error:    fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", errors.New("error")),
+		},
+		{
+			name: "Get by unknown id should return not found",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Get", mock.Anything).Return(nil, models.ErrUserNotFound.Wrap(gorm.ErrRecordNotFound))
+			},
+			asserts: func(t *testing.T, response *models.UserRequest, errorResult error, expectedError error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, errorResult, models.ErrUserNotFound)
+				assert.ErrorIs(t, errorResult, gorm.ErrRecordNotFound)
+			},
 		},
 	}
 
@@ -143,7 +245,7 @@ func TestUserService_Get(t *testing.T) {
 
 			tt.mockedBehavior(t, &userRepository.Mock)
 
-			userService := NewUser(userRepository)
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
 
 			result, err := userService.Get(tt.id)
 
@@ -187,7 +289,20 @@ func TestUserService_GetByEmail(t *testing.T) {
 				assert.Equal(t, errorResult.Error(), expectedError.Error())
 			},
 			response: nil,
-			error:    errors.New("ocurrio un error al intentar recuperar el usuario"),
+			error:    fmt.Errorf("ocurrio un error al intentar recuperar el usuario: %w", errors.New("error")),
+		},
+		{
+			name:  "Get by unknown email should return not found",
+			email: "meze@email.com",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("GetByEmail", mock.Anything).Return(nil, models.ErrUserNotFound)
+			},
+			asserts: func(t *testing.T, response *models.UserRequest, errorResult error, expectedError error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, errorResult, expectedError)
+			},
+			response: nil,
+			error:    models.ErrUserNotFound,
 		},
 	}
 
@@ -197,7 +312,7 @@ func TestUserService_GetByEmail(t *testing.T) {
 
 			tt.mockedBehavior(t, &userRepository.Mock)
 
-			userService := NewUser(userRepository)
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
 
 			result, err := userService.GetByEmail(tt.email)
 
@@ -213,7 +328,7 @@ func TestUserService_Update(t *testing.T) {
 		name           string
 		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
 		asserts        func(t *testing.T, response *models.UserRequest, errorResult error, expectedError error)
-		request        *models.UserRequest
+		request        *models.UpdateUserRequest
 		response       *models.UserRequest
 		error          error
 	}{
@@ -227,7 +342,7 @@ func TestUserService_Update(t *testing.T) {
 				assert.Nil(t, error)
 				assert.Equal(t, validUserResponse, response)
 			},
-			request:  validUserRequest,
+			request:  validUpdateRequest,
 			response: validUserResponse,
 			error:    nil,
 		},
@@ -241,9 +356,9 @@ func TestUserService_Update(t *testing.T) {
 				assert.NotNil(t, error)
 				assert.Equal(t, error.Error(), expectedError.Error())
 			},
-			request:  validUserRequest,
+			request:  validUpdateRequest,
 			response: nil,
-			error:    errors.New("ocurrio un error al intentar actualizar el usuario"),
+			error:    fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", errors.New("error from repo")),
 		},
 	}
 
@@ -254,7 +369,7 @@ func TestUserService_Update(t *testing.T) {
 
 			tt.mockedBehavior(t, &userRepository.Mock)
 
-			userService := NewUser(userRepository)
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
 
 			result, err := userService.Update(tt.request)
 
@@ -300,7 +415,7 @@ func TestUserService_Delete(t *testing.T) {
 				assert.Equal(t, errorResult.Error(), expectedError.Error())
 			},
 			response: nil,
-			error:    errors.New("ocurrio un error al intentar eliminar el usuario"),
+			error:    fmt.Errorf("ocurrio un error al intentar eliminar el usuario: %w", errors.New("error")),
 		},
 	}
 
@@ -310,7 +425,7 @@ func TestUserService_Delete(t *testing.T) {
 
 			tt.mockedBehavior(t, &userRepository.Mock)
 
-			userService := NewUser(userRepository)
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
 
 			result, err := userService.Delete(tt.id)
 
@@ -320,6 +435,545 @@ func TestUserService_Delete(t *testing.T) {
 
 }
 
+func TestUserService_IncrementTokenVersion(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		id             string
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		error          error
+	}{
+		{
+			name: "Increment token version should be successful",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("IncrementTokenVersion", "1").Return(nil)
+			},
+			error: nil,
+		},
+		{
+			name: "Increment token version should return error",
+			id:   "1",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("IncrementTokenVersion", "1").Return(errors.New("error"))
+			},
+			error: fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			err := userService.IncrementTokenVersion(tt.id)
+
+			assert.Equal(t, tt.error, err)
+		})
+	}
+
+}
+
+func TestUserService_GetPermissions(t *testing.T) {
+
+	employerModel := &models.User{Model: gorm.Model{ID: 1}, Role: "employer"}
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		asserts        func(t *testing.T, response *models.UserPermissions, err error)
+	}{
+		{
+			name: "Get permissions should merge role and granted permissions",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Get", "1").Return(employerModel, nil)
+				mockedRepository.On("GetPermissions", "1").Return([]string{"jobs:apply", "users:read"}, nil)
+			},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, &models.UserPermissions{
+					Id:          1,
+					Role:        "employer",
+					Permissions: []string{"users:read", "users:write", "jobs:publish", "jobs:apply"},
+				}, response)
+			},
+		},
+		{
+			name: "Get permissions should return error retrieving user",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Get", "1").Return(nil, errors.New("error"))
+			},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.Equal(t, "ocurrio un error al intentar recuperar el usuario: error", err.Error())
+			},
+		},
+		{
+			name: "Get permissions should return error retrieving permissions",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Get", "1").Return(employerModel, nil)
+				mockedRepository.On("GetPermissions", "1").Return(nil, errors.New("error"))
+			},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.Equal(t, "ocurrio un error al intentar recuperar los permisos del usuario: error", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			result, err := userService.GetPermissions("1")
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestUserService_UpdatePermissions(t *testing.T) {
+
+	workerModel := &models.User{Model: gorm.Model{ID: 1}, Role: "worker"}
+	adminCaller := &authModels.CustomClaims{UserID: "99", Role: authModels.RoleAdmin,
+		Permissions: authModels.DefaultPermissions(authModels.RoleAdmin)}
+	managerCaller := &authModels.CustomClaims{UserID: "98", Role: authModels.RoleWorker,
+		Permissions: append(authModels.DefaultPermissions(authModels.RoleWorker), authModels.PermissionUsersManage)}
+
+	tests := []struct {
+		name           string
+		request        *models.UserPermissions
+		caller         *authModels.CustomClaims
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		asserts        func(t *testing.T, response *models.UserPermissions, err error)
+	}{
+		{
+			name:    "Update permissions should persist role and deduplicated grants",
+			request: &models.UserPermissions{Role: "worker", Permissions: []string{"jobs:publish", "jobs:publish"}},
+			caller:  adminCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("UpdatePermissions", "1", "worker", []string{"jobs:publish"}).Return(nil)
+				mockedRepository.On("Get", "1").Return(workerModel, nil)
+				mockedRepository.On("GetPermissions", "1").Return([]string{"jobs:publish"}, nil)
+			},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"users:read", "users:write", "jobs:apply", "jobs:publish"}, response.Permissions)
+			},
+		},
+		{
+			name:           "Update permissions with unknown role should fail",
+			request:        &models.UserPermissions{Role: "superuser"},
+			caller:         adminCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidRole)
+			},
+		},
+		{
+			name:           "Update permissions with unknown permission should fail",
+			request:        &models.UserPermissions{Role: "worker", Permissions: []string{"everything"}},
+			caller:         adminCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrInvalidPermission)
+			},
+		},
+		{
+			name:           "Update own permissions should be forbidden",
+			request:        &models.UserPermissions{Role: "worker"},
+			caller:         &authModels.CustomClaims{UserID: "1", Role: authModels.RoleAdmin, Permissions: authModels.DefaultPermissions(authModels.RoleAdmin)},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrOwnPermissions)
+			},
+		},
+		{
+			name:           "Granting the admin role should be forbidden",
+			request:        &models.UserPermissions{Role: authModels.RoleAdmin},
+			caller:         adminCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrPermissionNotHeld)
+			},
+		},
+		{
+			name:           "Granting a permission the caller does not hold should be forbidden",
+			request:        &models.UserPermissions{Role: "worker", Permissions: []string{authModels.PermissionClientsManage}},
+			caller:         managerCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrPermissionNotHeld)
+			},
+		},
+		{
+			name:           "Granting a role with permissions the caller does not hold should be forbidden",
+			request:        &models.UserPermissions{Role: "employer"},
+			caller:         managerCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.ErrorIs(t, err, models.ErrPermissionNotHeld)
+			},
+		},
+		{
+			name:    "Manager should grant the permissions it holds",
+			request: &models.UserPermissions{Role: "worker", Permissions: []string{authModels.PermissionUsersManage}},
+			caller:  managerCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("UpdatePermissions", "1", "worker", []string{"users:manage"}).Return(nil)
+				mockedRepository.On("Get", "1").Return(workerModel, nil)
+				mockedRepository.On("GetPermissions", "1").Return([]string{"users:manage"}, nil)
+			},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []string{"users:read", "users:write", "jobs:apply", "users:manage"}, response.Permissions)
+			},
+		},
+		{
+			name:    "Update permissions should return repository error",
+			request: &models.UserPermissions{Role: "worker"},
+			caller:  adminCaller,
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("UpdatePermissions", "1", "worker", []string{}).Return(errors.New("error"))
+			},
+			asserts: func(t *testing.T, response *models.UserPermissions, err error) {
+				assert.Nil(t, response)
+				assert.Equal(t, "ocurrio un error al intentar actualizar los permisos del usuario: error", err.Error())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			result, err := userService.UpdatePermissions("1", tt.request, tt.caller)
+
+			tt.asserts(t, result, err)
+		})
+	}
+}
+
+func TestUserService_UpdatePassword(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		error          error
+	}{
+		{
+			name: "Update password should store the hashed password",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("UpdatePassword", "1", mock.MatchedBy(func(hash string) bool {
+					return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+				})).Return(nil)
+			},
+			error: nil,
+		},
+		{
+			name: "Update password should return repository error",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("UpdatePassword", "1", mock.Anything).Return(errors.New("error"))
+			},
+			error: fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			err := userService.UpdatePassword("1", "new-password")
+
+			assert.Equal(t, tt.error, err)
+		})
+	}
+}
+
+func TestUserService_MarkEmailVerified(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		error          error
+	}{
+		{
+			name: "Mark email verified should be successful",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("MarkEmailVerified", "1", mock.Anything).Return(nil)
+			},
+			error: nil,
+		},
+		{
+			name: "Mark email verified should return error",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("MarkEmailVerified", "1", mock.Anything).Return(errors.New("error"))
+			},
+			error: fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", errors.New("error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			err := userService.MarkEmailVerified("1")
+
+			assert.Equal(t, tt.error, err)
+		})
+	}
+}
+
+func TestUserService_List(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 123456000, time.UTC)
+	listedUsers := []models.User{
+		{Model: gorm.Model{ID: 3, CreatedAt: createdAt}, Email: "c@gmail.com", Password: "hash"},
+		{Model: gorm.Model{ID: 2, CreatedAt: createdAt}, Email: "b@gmail.com", Password: "hash"},
+		{Model: gorm.Model{ID: 1, CreatedAt: createdAt}, Email: "a@gmail.com", Password: "hash"},
+	}
+
+	tests := []struct {
+		name           string
+		filter         models.UserFilter
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		asserts        func(t *testing.T, page *models.UserPage, err error)
+	}{
+		{
+			name:   "Full page should return a cursor to the next one",
+			filter: models.UserFilter{Limit: 2},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("List", models.UserFilter{Sort: "-created_at", Limit: 2}, (*models.UserCursor)(nil), 3).Return(listedUsers, nil)
+			},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, page.Users, 2)
+				assert.NotContains(t, marshal(t, page), "hash")
+				assert.Nil(t, page.Total)
+
+				cursor, err := decodeUserCursor(page.NextCursor, "-created_at")
+				assert.NoError(t, err)
+				assert.Equal(t, &models.UserCursor{Sort: "-created_at", Value: "2024-05-01T10:00:00.123456Z", ID: 2}, cursor)
+			},
+		},
+		{
+			name:   "Last page should not return a cursor and count when asked",
+			filter: models.UserFilter{Sort: "email", IncludeTotal: true},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				filter := models.UserFilter{Sort: "email", IncludeTotal: true, Limit: models.DefaultPageSize}
+				mockedRepository.On("List", filter, (*models.UserCursor)(nil), models.DefaultPageSize+1).Return(listedUsers, nil)
+				mockedRepository.On("Count", filter).Return(int64(3), nil)
+			},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, page.Users, 3)
+				assert.Empty(t, page.NextCursor)
+				assert.Equal(t, int64(3), *page.Total)
+			},
+		},
+		{
+			name:   "Cursor should be passed to the repository",
+			filter: models.UserFilter{Sort: "email", Cursor: encodeUserCursor(listedUsers[1], "email", "email")},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("List", mock.Anything, &models.UserCursor{Sort: "email", Value: "b@gmail.com", ID: 2}, models.DefaultPageSize+1).
+					Return([]models.User{}, nil)
+			},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, []models.AdminUser{}, page.Users)
+			},
+		},
+		{
+			name:           "Cursor of another sort should be rejected",
+			filter:         models.UserFilter{Sort: "-email", Cursor: encodeUserCursor(listedUsers[1], "email", "email")},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidCursor)
+			},
+		},
+		{
+			name:           "Malformed cursor should be rejected",
+			filter:         models.UserFilter{Cursor: "not-a-cursor"},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidCursor)
+			},
+		},
+		{
+			name:           "Unknown sort should be rejected",
+			filter:         models.UserFilter{Sort: "password"},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.ErrorIs(t, err, models.ErrInvalidSort)
+			},
+		},
+		{
+			name:   "Repository error should be returned",
+			filter: models.UserFilter{},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("List", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.Nil(t, page)
+				assert.Equal(t, fmt.Errorf("ocurrio un error al intentar listar los usuarios: %w", errors.New("error from db")), err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			page, err := userService.List(tt.filter)
+
+			tt.asserts(t, page, err)
+		})
+	}
+}
+
+func TestUserService_Search(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		search         models.UserSearch
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		asserts        func(t *testing.T, page *models.UserPage, err error)
+	}{
+		{
+			name:   "Search should return ranked users without password",
+			search: models.UserSearch{Query: "  José   González "},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Search", "José González", models.DefaultSearchSize).
+					Return([]models.User{{Model: gorm.Model{ID: 7}, FirstName: "José", Password: "hash"}, {Model: gorm.Model{ID: 3}, FirstName: "Josefina"}}, nil)
+			},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, 7, page.Users[0].Id)
+				assert.Equal(t, 3, page.Users[1].Id)
+				assert.NotContains(t, marshal(t, page), "hash")
+			},
+		},
+		{
+			name:           "Blank query should not search",
+			search:         models.UserSearch{Query: "   ", Limit: 5},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.NoError(t, err)
+				assert.Empty(t, page.Users)
+			},
+		},
+		{
+			name:   "Repository error should be returned",
+			search: models.UserSearch{Query: "meze", Limit: 5},
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Search", "meze", 5).Return(nil, errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, page *models.UserPage, err error) {
+				assert.Nil(t, page)
+				assert.Equal(t, fmt.Errorf("ocurrio un error al intentar buscar los usuarios: %w", errors.New("error from db")), err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			page, err := userService.Search(tt.search)
+
+			tt.asserts(t, page, err)
+		})
+	}
+}
+
+func TestUserService_Patch(t *testing.T) {
+	changes := map[string]interface{}{"first_name": "Meze", "last_name": ""}
+
+	tests := []struct {
+		name           string
+		mockedBehavior func(t *testing.T, mockedRepository *mock.Mock)
+		asserts        func(t *testing.T, user *models.UserRequest, err error)
+	}{
+		{
+			name: "Patch should return the updated user",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Patch", "1", changes).Return(nil)
+				mockedRepository.On("Get", "1").Return(&models.User{Model: gorm.Model{ID: 1}, FirstName: "Meze", Password: "hash"}, nil)
+			},
+			asserts: func(t *testing.T, user *models.UserRequest, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "Meze", user.FirstName)
+				assert.Empty(t, user.LastName)
+			},
+		},
+		{
+			name: "Unknown user should return not found",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Patch", "1", changes).Return(models.ErrUserNotFound)
+			},
+			asserts: func(t *testing.T, user *models.UserRequest, err error) {
+				assert.ErrorIs(t, err, models.ErrUserNotFound)
+			},
+		},
+		{
+			name: "Repository error should be returned",
+			mockedBehavior: func(t *testing.T, mockedRepository *mock.Mock) {
+				mockedRepository.On("Patch", "1", changes).Return(errors.New("error from db"))
+			},
+			asserts: func(t *testing.T, user *models.UserRequest, err error) {
+				assert.Nil(t, user)
+				assert.Equal(t, fmt.Errorf("ocurrio un error al intentar actualizar el usuario: %w", errors.New("error from db")), err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := &MockUserRepository{}
+
+			tt.mockedBehavior(t, &userRepository.Mock)
+
+			userService := NewUser(userRepository, testPasswordHasher(t), testPasswordPolicy())
+
+			user, err := userService.Patch("1", changes)
+
+			tt.asserts(t, user, err)
+		})
+	}
+}
+
 type MockUserRepository struct {
 	mock.Mock
 }
@@ -363,3 +1017,83 @@ func (m *MockUserRepository) GetByEmail(email string) (*models.User, error) {
 	}
 	return args.Get(0).(*models.User), args.Error(1)
 }
+
+func (m *MockUserRepository) GetTokenVersion(id string) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) IncrementTokenVersion(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetPermissions(id string) ([]string, error) {
+	args := m.Called(id)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockUserRepository) List(filter models.UserFilter, after *models.UserCursor, limit int) ([]models.User, error) {
+	args := m.Called(filter, after, limit)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Count(filter models.UserFilter) (int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) Search(text string, limit int) ([]models.User, error) {
+	args := m.Called(text, limit)
+	if args.Get(1) != nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Patch(id string, changes map[string]interface{}) error {
+	args := m.Called(id, changes)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePermissions(id string, role string, permissions []string) error {
+	args := m.Called(id, role, permissions)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(id string, password string) error {
+	args := m.Called(id, password)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(id string, verifiedAt time.Time) error {
+	args := m.Called(id, verifiedAt)
+	return args.Error(0)
+}
+
+func testPasswordHasher(t *testing.T) password.HasherInterface {
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+// testPasswordPolicy accepts any password.
+func testPasswordPolicy() passwordPolicy.PolicyInterface {
+	return passwordPolicy.NewPolicy(passwordPolicy.Config{}, nil)
+}
+
+func marshal(t *testing.T, value interface{}) string {
+	body, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
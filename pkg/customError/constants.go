@@ -1,8 +1,17 @@
 package customError
 
 const (
-	InvalidBody      = "INVALID_BODY"
-	ApplicationError = "ERROR"
-	MissingParameter = "MISSING_PARAMETER"
-	NotFound         = "NOT_FOUND"
+	InvalidBody        = "INVALID_BODY"
+	ApplicationError   = "ERROR"
+	MissingParameter   = "MISSING_PARAMETER"
+	NotFound           = "NOT_FOUND"
+	Unauthorized       = "UNAUTHORIZED"
+	Forbidden          = "FORBIDDEN"
+	InvalidToken       = "INVALID_TOKEN"
+	TooManyRequests    = "TOO_MANY_REQUESTS"
+	EmailNotVerified   = "EMAIL_NOT_VERIFIED"
+	InvalidMFACode     = "INVALID_MFA_CODE"
+	InvalidCredentials = "INVALID_CREDENTIALS"
+	Conflict           = "CONFLICT"
+	ValidationError    = "VALIDATION_ERROR"
 )
@@ -0,0 +1,99 @@
+package customError
+
+import "errors"
+
+// The kinds of domain errors, a DomainError matches its kind with errors.Is.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrValidation           = errors.New("validation failed")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrTooManyRequests      = errors.New("too many requests")
+)
+
+// DomainError is an error the API answers with a status other than 500, see
+// ErrorHandler. Message is written to the response, Cause only to the log.
+// Code replaces the code of the kind when set, e.g. INVALID_BODY.
+type DomainError struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []FieldError
+	Cause   error
+}
+
+func NewNotFound(message string, cause error) *DomainError {
+	return &DomainError{Kind: ErrNotFound, Message: message, Cause: cause}
+}
+
+func NewConflict(message string, cause error) *DomainError {
+	return &DomainError{Kind: ErrConflict, Message: message, Cause: cause}
+}
+
+func NewValidation(message string, fields []FieldError, cause error) *DomainError {
+	return &DomainError{Kind: ErrValidation, Message: message, Fields: fields, Cause: cause}
+}
+
+func NewUnauthorized(message string, cause error) *DomainError {
+	return &DomainError{Kind: ErrUnauthorized, Message: message, Cause: cause}
+}
+
+func NewForbidden(message string, cause error) *DomainError {
+	return &DomainError{Kind: ErrForbidden, Message: message, Cause: cause}
+}
+
+// NewInvalidBody is the validation error of a request body or query string
+// that could not be bound.
+func NewInvalidBody(message string, cause error) *DomainError {
+	return NewValidation(message, nil, cause).WithCode(InvalidBody)
+}
+
+// NewUnsupportedMediaType is answered with 415 when the body is not in a
+// content type the endpoint accepts.
+func NewUnsupportedMediaType(message string, cause error) *DomainError {
+	return &DomainError{Kind: ErrUnsupportedMediaType, Code: InvalidBody, Message: message, Cause: cause}
+}
+
+// NewTooManyRequests is answered with 429, the handler sets Retry-After
+// when it knows the wait.
+func NewTooManyRequests(message string, cause error) *DomainError {
+	return &DomainError{Kind: ErrTooManyRequests, Message: message, Cause: cause}
+}
+
+func (e *DomainError) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *DomainError) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Kind, e.Cause}
+	}
+	return []error{e.Kind}
+}
+
+// Is matches another DomainError of the same kind and message, so sentinel
+// errors such as a "user not found" still match once Wrap added a cause.
+func (e *DomainError) Is(target error) bool {
+	other, ok := target.(*DomainError)
+	return ok && other.Kind == e.Kind && other.Message == e.Message
+}
+
+// WithCode returns a copy of the error answered with code instead of the
+// code of its kind.
+func (e *DomainError) WithCode(code string) *DomainError {
+	coded := *e
+	coded.Code = code
+	return &coded
+}
+
+// Wrap returns a copy of the error carrying cause.
+func (e *DomainError) Wrap(cause error) *DomainError {
+	wrapped := *e
+	wrapped.Cause = cause
+	return &wrapped
+}
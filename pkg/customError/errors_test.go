@@ -0,0 +1,26 @@
+package customError
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDomainError_Is(t *testing.T) {
+	errUserNotFound := NewNotFound("User not found", nil)
+	cause := errors.New("record not found")
+
+	wrapped := fmt.Errorf("retrieving user: %w", errUserNotFound.Wrap(cause))
+
+	assert.ErrorIs(t, wrapped, errUserNotFound)
+	assert.ErrorIs(t, wrapped, ErrNotFound)
+	assert.ErrorIs(t, wrapped, cause)
+	assert.NotErrorIs(t, wrapped, ErrConflict)
+	assert.NotErrorIs(t, wrapped, NewNotFound("Client not found", nil))
+	assert.Nil(t, errUserNotFound.Cause)
+
+	var domainError *DomainError
+	assert.True(t, errors.As(wrapped, &domainError))
+	assert.Equal(t, "User not found: record not found", domainError.Error())
+}
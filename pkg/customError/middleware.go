@@ -0,0 +1,70 @@
+package customError
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+var statusByKind = map[error]int{
+	ErrNotFound:             http.StatusNotFound,
+	ErrConflict:             http.StatusConflict,
+	ErrValidation:           http.StatusBadRequest,
+	ErrUnauthorized:         http.StatusUnauthorized,
+	ErrForbidden:            http.StatusForbidden,
+	ErrUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	ErrTooManyRequests:      http.StatusTooManyRequests,
+}
+
+var codeByKind = map[error]string{
+	ErrNotFound:             NotFound,
+	ErrConflict:             Conflict,
+	ErrValidation:           ValidationError,
+	ErrUnauthorized:         Unauthorized,
+	ErrForbidden:            Forbidden,
+	ErrUnsupportedMediaType: InvalidBody,
+	ErrTooManyRequests:      TooManyRequests,
+}
+
+// ErrorHandler answers the last error a handler added with c.Error when the
+// handler did not write a response itself. DomainErrors get the status of
+// their kind, anything else is a 500 whose message is the error meta when
+// it is a string, so the cause never reaches the client.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		ginError := c.Errors.Last()
+
+		var domainError *DomainError
+		if errors.As(ginError.Err, &domainError) {
+			if domainError.Cause != nil {
+				log.Println("request failed: ", domainError.Error())
+			}
+			code := domainError.Code
+			if code == "" {
+				code = codeByKind[domainError.Kind]
+			}
+			c.JSON(statusByKind[domainError.Kind], Error{
+				Code:    code,
+				Message: domainError.Message,
+				Fields:  domainError.Fields,
+			})
+			return
+		}
+
+		log.Println("request failed: ", ginError.Err.Error())
+		message, ok := ginError.Meta.(string)
+		if !ok {
+			message = "An unexpected error occurred"
+		}
+		c.JSON(http.StatusInternalServerError, Error{
+			Code:    ApplicationError,
+			Message: message,
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package customError
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name                       string
+		handler                    gin.HandlerFunc
+		expectedHttpStatusResponse int
+		expectedBodyResponse       string
+	}{
+		{
+			name: "Not found should return 404",
+			handler: func(c *gin.Context) {
+				c.Error(NewNotFound("User not found", errors.New("record not found")))
+			},
+			expectedHttpStatusResponse: http.StatusNotFound,
+			expectedBodyResponse:       `{"code":"NOT_FOUND","message":"User not found"}`,
+		},
+		{
+			name: "Conflict should return 409",
+			handler: func(c *gin.Context) {
+				c.Error(NewConflict("Email already registered", nil))
+			},
+			expectedHttpStatusResponse: http.StatusConflict,
+			expectedBodyResponse:       `{"code":"CONFLICT","message":"Email already registered"}`,
+		},
+		{
+			name: "Validation should return 400 with the fields",
+			handler: func(c *gin.Context) {
+				c.Error(NewValidation("Invalid user", []FieldError{{Field: "email", Code: "REQUIRED", Message: "Email is required"}}, nil))
+			},
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			expectedBodyResponse:       `{"code":"VALIDATION_ERROR","message":"Invalid user","fields":[{"field":"email","code":"REQUIRED","message":"Email is required"}]}`,
+		},
+		{
+			name: "Unauthorized should return 401",
+			handler: func(c *gin.Context) {
+				c.Error(NewUnauthorized("Invalid credentials", nil))
+			},
+			expectedHttpStatusResponse: http.StatusUnauthorized,
+			expectedBodyResponse:       `{"code":"UNAUTHORIZED","message":"Invalid credentials"}`,
+		},
+		{
+			name: "Forbidden should return 403",
+			handler: func(c *gin.Context) {
+				c.Error(NewForbidden("Not allowed", nil))
+			},
+			expectedHttpStatusResponse: http.StatusForbidden,
+			expectedBodyResponse:       `{"code":"FORBIDDEN","message":"Not allowed"}`,
+		},
+		{
+			name: "Code should replace the code of the kind",
+			handler: func(c *gin.Context) {
+				c.Error(NewInvalidBody("Invalid request body", errors.New("EOF")))
+			},
+			expectedHttpStatusResponse: http.StatusBadRequest,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Invalid request body"}`,
+		},
+		{
+			name: "Unsupported media type should return 415",
+			handler: func(c *gin.Context) {
+				c.Error(NewUnsupportedMediaType("Content type must be application/json", nil))
+			},
+			expectedHttpStatusResponse: http.StatusUnsupportedMediaType,
+			expectedBodyResponse:       `{"code":"INVALID_BODY","message":"Content type must be application/json"}`,
+		},
+		{
+			name: "Too many requests should return 429",
+			handler: func(c *gin.Context) {
+				c.Error(NewTooManyRequests("Try again later", nil))
+			},
+			expectedHttpStatusResponse: http.StatusTooManyRequests,
+			expectedBodyResponse:       `{"code":"TOO_MANY_REQUESTS","message":"Try again later"}`,
+		},
+		{
+			name: "Other errors should return 500 with the meta message",
+			handler: func(c *gin.Context) {
+				c.Error(errors.New("connection refused")).SetMeta("An error occurred when trying to retrieve user")
+			},
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			expectedBodyResponse:       `{"code":"ERROR","message":"An error occurred when trying to retrieve user"}`,
+		},
+		{
+			name: "Other errors without meta should not expose the cause",
+			handler: func(c *gin.Context) {
+				c.Error(errors.New("connection refused"))
+			},
+			expectedHttpStatusResponse: http.StatusInternalServerError,
+			expectedBodyResponse:       `{"code":"ERROR","message":"An unexpected error occurred"}`,
+		},
+		{
+			name: "Written responses should be kept",
+			handler: func(c *gin.Context) {
+				c.Error(errors.New("connection refused"))
+				c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+			},
+			expectedHttpStatusResponse: http.StatusAccepted,
+			expectedBodyResponse:       `{"status":"queued"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", tt.handler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/", nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedHttpStatusResponse, w.Code)
+			assert.Equal(t, tt.expectedBodyResponse, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type MailerInterface interface {
+	Send(message Message) error
+}
+
+// LocalMailer is meant for development and tests, messages are written as
+// files into Dir or printed to the log when Dir is empty.
+type LocalMailer struct {
+	Dir string
+	now func() time.Time
+}
+
+func NewLocalMailer(dir string) MailerInterface {
+	return &LocalMailer{Dir: dir, now: time.Now}
+}
+
+func (l *LocalMailer) Send(message Message) error {
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", message.To, message.Subject, message.Body)
+
+	if l.Dir == "" {
+		log.Println(fmt.Sprintf("sending mail\n%s", content))
+		return nil
+	}
+
+	if err := os.MkdirAll(l.Dir, 0700); err != nil {
+		log.Println("error creating mail directory: ", err.Error())
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", l.now().UnixNano(), sanitize(message.To))
+	if err := os.WriteFile(filepath.Join(l.Dir, name), []byte(content), 0600); err != nil {
+		log.Println("error writing mail: ", err.Error())
+		return err
+	}
+	return nil
+}
+
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, address)
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalMailer_SendWritesFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+
+	err := NewLocalMailer(dir).Send(Message{To: "meze@gmail.com", Subject: "Hola", Body: "Cuerpo"})
+	assert.NoError(t, err)
+
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 1)
+	assert.Contains(t, files[0].Name(), "meze@gmail.com")
+
+	content, _ := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.Equal(t, "To: meze@gmail.com\r\nSubject: Hola\r\n\r\nCuerpo\r\n", string(content))
+}
+
+func TestLocalMailer_SendWithoutDir(t *testing.T) {
+	err := NewLocalMailer("").Send(Message{To: "meze@gmail.com", Subject: "Hola", Body: "Cuerpo"})
+	assert.NoError(t, err)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "a_b@c.com", sanitize("a/b@c.com"))
+}
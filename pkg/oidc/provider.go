@@ -0,0 +1,261 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// jwksRefreshInterval limits how often an unknown kid forces the keys of
+	// the provider to be downloaded again.
+	jwksRefreshInterval = time.Minute
+	clockSkew           = time.Minute
+)
+
+var (
+	ErrDiscovery      = errors.New("oidc: provider discovery failed")
+	ErrExchange       = errors.New("oidc: authorization code exchange failed")
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+)
+
+// signingMethods are the algorithms accepted for ID tokens, symmetric ones
+// are excluded because the client secret is not meant to verify signatures.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type Config struct {
+	// Name identifies the provider in the routes, e.g. "google".
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type IDTokenClaims struct {
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	GivenName       string `json:"given_name"`
+	FamilyName      string `json:"family_name"`
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+type ProviderInterface interface {
+	Name() string
+	// AuthCodeURL returns the authorization endpoint URL for the
+	// authorization code flow with a S256 PKCE challenge.
+	AuthCodeURL(state string, nonce string, codeChallenge string) (string, error)
+	Exchange(code string, codeVerifier string) (*Tokens, error)
+	// VerifyIDToken checks the signature, issuer, audience, expiration and
+	// nonce of an ID token.
+	VerifyIDToken(rawIDToken string, nonce string) (*IDTokenClaims, error)
+}
+
+// Provider discovers the endpoints of the issuer on first use, so the API
+// can start while an identity provider is unreachable.
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	metadata      *Metadata
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewProvider(config Config, httpClient *http.Client) ProviderInterface {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{config: config, httpClient: httpClient}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint", ErrDiscovery)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+func (p *Provider) Exchange(code string, codeVerifier string) (*Tokens, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", p.config.ClientID)
+
+	request, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrExchange, err.Error())
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	tokens := &Tokens{}
+	if err := p.do(request, tokens); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrExchange, err.Error())
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: response has no id_token", ErrExchange)
+	}
+	return tokens, nil
+}
+
+func (p *Provider) VerifyIDToken(rawIDToken string, nonce string) (*IDTokenClaims, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, p.keyfunc,
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err.Error())
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: token was issued to another party", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+func (p *Provider) discover() (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDiscovery, err.Error())
+	}
+	metadata := &Metadata{}
+	if err := p.do(request, metadata); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDiscovery, err.Error())
+	}
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", ErrDiscovery)
+	}
+
+	p.metadata = metadata
+	return metadata, nil
+}
+
+func (p *Provider) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, p.metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	set := jwkSet{}
+	if err := p.do(request, &set); err != nil {
+		return nil, err
+	}
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (p *Provider) do(request *http.Request, target interface{}) error {
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded %d: %s", request.URL.Path, response.StatusCode, body)
+	}
+	return json.Unmarshal(body, target)
+}
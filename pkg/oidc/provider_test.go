@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"chambeo-api-core/pkg/oidc/oidctest"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testRedirectURL = "https://api.chambeo.co/api/v1/auth/oidc/test/callback"
+
+func newTestProvider(idp *oidctest.IdP) ProviderInterface {
+	return NewProvider(Config{
+		Name:         "test",
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  testRedirectURL,
+	}, nil)
+}
+
+// authorize follows the authorization URL and returns the code the IdP
+// sends back to the redirect URL.
+func authorize(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	response, err := client.Get(authURL)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusFound, response.StatusCode)
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	assert.NoError(t, err)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewIdP("chambeo", "secret")
+	defer idp.Close()
+	provider := newTestProvider(idp)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	authURL, err := provider.AuthCodeURL("the-state", "the-nonce", CodeChallenge(verifier))
+	assert.NoError(t, err)
+
+	parsed, _ := url.Parse(authURL)
+	assert.Equal(t, "openid email profile", parsed.Query().Get("scope"))
+	assert.Equal(t, testRedirectURL, parsed.Query().Get("redirect_uri"))
+
+	code, state := authorize(t, authURL)
+	assert.Equal(t, "the-state", state)
+
+	tokens, err := provider.Exchange(code, verifier)
+	assert.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(tokens.IDToken, "the-nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567890", claims.Subject)
+	assert.Equal(t, "meze@gmail.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "Meze", claims.GivenName)
+}
+
+func TestProvider_ExchangeErrors(t *testing.T) {
+	idp := oidctest.NewIdP("chambeo", "secret")
+	defer idp.Close()
+
+	tests := []struct {
+		name         string
+		clientSecret string
+		verifier     string
+		reuseCode    bool
+	}{
+		{name: "wrong code verifier", clientSecret: "secret", verifier: "another-verifier-that-does-not-match-the-challenge"},
+		{name: "wrong client secret", clientSecret: "other", verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"},
+		{name: "code used twice", clientSecret: "secret", verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", reuseCode: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewProvider(Config{
+				Name: "test", Issuer: idp.Issuer(), ClientID: "chambeo", ClientSecret: tt.clientSecret, RedirectURL: testRedirectURL,
+			}, nil)
+
+			authURL, _ := provider.AuthCodeURL("state", "nonce", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+			code, _ := authorize(t, authURL)
+			if tt.reuseCode {
+				_, err := provider.Exchange(code, tt.verifier)
+				assert.NoError(t, err)
+			}
+
+			_, err := provider.Exchange(code, tt.verifier)
+			assert.True(t, errors.Is(err, ErrExchange), err)
+		})
+	}
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	idp := oidctest.NewIdP("chambeo", "secret")
+	defer idp.Close()
+	provider := newTestProvider(idp)
+	now := time.Now()
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   idp.Issuer(),
+			"sub":   "1234567890",
+			"aud":   "chambeo",
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		token  func() string
+		nonce  string
+		failed bool
+	}{
+		{name: "valid token", token: func() string { return idp.SignIDToken(validClaims()) }, nonce: "nonce"},
+		{name: "wrong nonce", token: func() string { return idp.SignIDToken(validClaims()) }, nonce: "other", failed: true},
+		{
+			name: "other audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "another-client"
+				return idp.SignIDToken(claims)
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+		{
+			name: "several audiences without azp",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"chambeo", "another-client"}
+				return idp.SignIDToken(claims)
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+		{
+			name: "other issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://evil.example.com"
+				return idp.SignIDToken(claims)
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = now.Add(-time.Hour).Unix()
+				return idp.SignIDToken(claims)
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+		{
+			name: "without expiration",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return idp.SignIDToken(claims)
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+		{
+			name: "without subject",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "sub")
+				return idp.SignIDToken(claims)
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+		{
+			name: "signed by another provider",
+			token: func() string {
+				other := oidctest.NewIdP("chambeo", "secret")
+				defer other.Close()
+				return other.SignIDToken(validClaims())
+			},
+			nonce:  "nonce",
+			failed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(tt.token(), tt.nonce)
+			if tt.failed {
+				assert.True(t, errors.Is(err, ErrInvalidIDToken), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "1234567890", claims.Subject)
+		})
+	}
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewIdP("chambeo", "secret")
+	defer idp.Close()
+
+	provider := NewProvider(Config{Name: "test", Issuer: idp.Issuer() + "/", ClientID: "chambeo", RedirectURL: testRedirectURL}, nil)
+
+	_, err := provider.AuthCodeURL("state", "nonce", "challenge")
+	assert.True(t, errors.Is(err, ErrDiscovery), err)
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B.
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
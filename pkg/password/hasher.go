@@ -0,0 +1,177 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("password hash format is not supported")
+
+// Argon2Params are encoded in every argon2id hash, so changing them only
+// affects new hashes.
+type Argon2Params struct {
+	// Memory is expressed in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type Config struct {
+	// Algorithm is used for new hashes, hashes of the other algorithm are
+	// still verified and reported by NeedsRehash.
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// DefaultConfig follows the OWASP recommendation for argon2id.
+var DefaultConfig = Config{
+	Algorithm:  AlgorithmArgon2id,
+	BcryptCost: 12,
+	Argon2: Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	},
+}
+
+type HasherInterface interface {
+	Hash(password string) (string, error)
+	// Verify reads the algorithm and parameters from encoded.
+	Verify(password string, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded was produced with another
+	// algorithm or other parameters than the configured ones.
+	NeedsRehash(encoded string) bool
+}
+
+type Hasher struct {
+	config Config
+}
+
+func NewHasher(config Config) (HasherInterface, error) {
+	switch config.Algorithm {
+	case AlgorithmBcrypt:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		params := config.Argon2
+		if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 || params.SaltLength == 0 || params.KeyLength == 0 {
+			return nil, errors.New("argon2id parameters must be greater than zero")
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", config.Algorithm)
+	}
+	return &Hasher{config: config}, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	params := h.config.Argon2
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return encodeArgon2(params, salt, key), nil
+}
+
+func (h *Hasher) Verify(password string, encoded string) (bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, key, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if isBcrypt(encoded) {
+		if h.config.Algorithm != AlgorithmBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.config.BcryptCost
+	}
+
+	params, salt, key, err := decodeArgon2(encoded)
+	if err != nil || h.config.Algorithm != AlgorithmArgon2id {
+		return true
+	}
+	expected := h.config.Argon2
+	return params.Memory != expected.Memory ||
+		params.Iterations != expected.Iterations ||
+		params.Parallelism != expected.Parallelism ||
+		uint32(len(salt)) != expected.SaltLength ||
+		uint32(len(key)) != expected.KeyLength
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// encodeArgon2 uses the PHC string format also produced by the reference
+// implementation: $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+func encodeArgon2(params Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Iterations,
+		params.Parallelism, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2(encoded string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return Argon2Params{}, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrUnknownHash
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, ErrUnknownHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, ErrUnknownHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+	"strings"
+	"testing"
+)
+
+var fastArgon2 = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		prefix string
+	}{
+		{name: "argon2id", config: Config{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2}, prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+		{name: "bcrypt", config: Config{Algorithm: AlgorithmBcrypt, BcryptCost: 4}, prefix: "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewHasher(tt.config)
+			assert.NoError(t, err)
+
+			hash, err := hasher.Hash("password")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, tt.prefix), hash)
+
+			valid, err := hasher.Verify("password", hash)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+
+			valid, err = hasher.Verify("other-password", hash)
+			assert.NoError(t, err)
+			assert.False(t, valid)
+
+			assert.False(t, hasher.NeedsRehash(hash))
+		})
+	}
+}
+
+func TestHasher_VerifyStoredHashes(t *testing.T) {
+	hasher, _ := NewHasher(Config{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2})
+
+	salt := []byte("somesalt")
+	key := argon2.IDKey([]byte("password"), salt, 2, 128, 2, 24)
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{
+			name: "argon2id with other parameters",
+			encoded: "$argon2id$v=19$m=128,t=2,p=2$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+				base64.RawStdEncoding.EncodeToString(key),
+		},
+		{name: "legacy bcrypt", encoded: "$2a$04$J2ssqgfNj0aOCjbBaj8guehhYBxaUp/qX9RtG4ZINN6F20kZfuh8O"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := hasher.Verify("password", tt.encoded)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+			assert.True(t, hasher.NeedsRehash(tt.encoded))
+		})
+	}
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	argon2Hasher, _ := NewHasher(Config{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2})
+	bcryptHasher, _ := NewHasher(Config{Algorithm: AlgorithmBcrypt, BcryptCost: 4})
+	strongerArgon2 := fastArgon2
+	strongerArgon2.Iterations = 2
+	strongerHasher, _ := NewHasher(Config{Algorithm: AlgorithmArgon2id, Argon2: strongerArgon2})
+
+	argon2Hash, _ := argon2Hasher.Hash("password")
+	bcryptHash, _ := bcryptHasher.Hash("password")
+
+	assert.True(t, argon2Hasher.NeedsRehash(bcryptHash), "bcrypt hash should be migrated to argon2id")
+	assert.True(t, bcryptHasher.NeedsRehash(argon2Hash), "argon2id hash should be migrated to bcrypt")
+	assert.True(t, strongerHasher.NeedsRehash(argon2Hash), "outdated parameters should be rehashed")
+	assert.True(t, argon2Hasher.NeedsRehash("plain-text"))
+	assert.False(t, argon2Hasher.NeedsRehash(argon2Hash))
+}
+
+func TestHasher_VerifyUnknownFormat(t *testing.T) {
+	hasher, _ := NewHasher(DefaultConfig)
+
+	valid, err := hasher.Verify("password", "$argon2i$v=19$m=64,t=1,p=1$c29tZXNhbHQ$aGFzaA")
+	assert.False(t, valid)
+	assert.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestNewHasher_InvalidConfig(t *testing.T) {
+	_, err := NewHasher(Config{Algorithm: "md5"})
+	assert.Error(t, err)
+
+	_, err = NewHasher(Config{Algorithm: AlgorithmBcrypt, BcryptCost: 40})
+	assert.Error(t, err)
+
+	_, err = NewHasher(Config{Algorithm: AlgorithmArgon2id})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package passwordPolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// minPrefixLength keeps a truncated corpus from matching most passwords.
+const minPrefixLength = 10
+
+type BreachedListInterface interface {
+	Contains(password string) bool
+}
+
+// BreachedList holds SHA-1 hashes of breached passwords, or prefixes of
+// them to keep the file small at the cost of some false positives.
+type BreachedList struct {
+	// prefixes is indexed by prefix length.
+	prefixes map[int]map[string]struct{}
+}
+
+// LoadBreachedList reads one upper or lower case hex SHA-1 hash or prefix
+// per line. An optional ":count" suffix, as in the Have I Been Pwned
+// downloads, is ignored. Empty lines and lines starting with # are skipped.
+func LoadBreachedList(path string) (BreachedListInterface, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{prefixes: map[int]map[string]struct{}{}}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		entry, _, _ = strings.Cut(entry, ":")
+		entry = strings.ToUpper(entry)
+		if len(entry) < minPrefixLength || len(entry) > sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: prefix must have between %d and %d hex characters", path, line, minPrefixLength, sha1.Size*2)
+		}
+		if _, err := hex.DecodeString(padHex(entry)); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid hex", path, line)
+		}
+		if list.prefixes[len(entry)] == nil {
+			list.prefixes[len(entry)] = map[string]struct{}{}
+		}
+		list.prefixes[len(entry)][entry] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (b *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	for length, prefixes := range b.prefixes {
+		if _, ok := prefixes[hash[:length]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func padHex(value string) string {
+	if len(value)%2 == 1 {
+		return value + "0"
+	}
+	return value
+}
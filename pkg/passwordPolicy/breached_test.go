@@ -0,0 +1,71 @@
+package passwordPolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeList(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadBreachedList(t *testing.T) {
+	content := strings.Join([]string{
+		"# breached passwords",
+		"",
+		strings.ToUpper(sha1Hex("Password123")) + ":2048",
+		sha1Hex("letmein!")[:12],
+	}, "\n")
+
+	list, err := LoadBreachedList(writeList(t, content))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		expected bool
+	}{
+		{name: "full hash with count", password: "Password123", expected: true},
+		{name: "lower case prefix", password: "letmein!", expected: true},
+		{name: "not listed", password: "correct-horse-battery", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, list.Contains(tt.password))
+		})
+	}
+}
+
+func TestLoadBreachedList_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "prefix too short", content: "ABCDE"},
+		{name: "not hex", content: "ZZZZZZZZZZZZ"},
+		{name: "longer than a hash", content: strings.Repeat("A", 41)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadBreachedList(writeList(t, tt.content))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadBreachedList(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package passwordPolicy
+
+import (
+	"chambeo-api-core/pkg/customError"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	TooShort                = "TOO_SHORT"
+	TooLong                 = "TOO_LONG"
+	MissingCharacterClasses = "MISSING_CHARACTER_CLASSES"
+	ContainsPersonalInfo    = "CONTAINS_PERSONAL_INFO"
+	Breached                = "BREACHED"
+)
+
+// minPersonalInfoLength avoids rejecting passwords because they contain a
+// very short name such as "Al".
+const minPersonalInfoLength = 3
+
+type Config struct {
+	MinLength int
+	MaxLength int
+	// MinCharacterClasses is how many of lowercase, uppercase, digits and
+	// symbols the password has to mix.
+	MinCharacterClasses int
+	RejectPersonalInfo  bool
+}
+
+var DefaultConfig = Config{
+	MinLength:           8,
+	MaxLength:           128,
+	MinCharacterClasses: 2,
+	RejectPersonalInfo:  true,
+}
+
+type Violation struct {
+	Code    string
+	Message string
+}
+
+// ViolationError is returned by the services when a password is refused,
+// handlers turn it into field level validation errors.
+type ViolationError struct {
+	Violations []Violation
+}
+
+func (v *ViolationError) Error() string {
+	codes := make([]string, 0, len(v.Violations))
+	for _, violation := range v.Violations {
+		codes = append(codes, violation.Code)
+	}
+	return "password does not satisfy the policy: " + strings.Join(codes, ", ")
+}
+
+// FieldErrors maps every violation to a validation error of the given
+// request field.
+func (v *ViolationError) FieldErrors(field string) []customError.FieldError {
+	fieldErrors := make([]customError.FieldError, 0, len(v.Violations))
+	for _, violation := range v.Violations {
+		fieldErrors = append(fieldErrors, customError.FieldError{
+			Field:   field,
+			Code:    violation.Code,
+			Message: violation.Message,
+		})
+	}
+	return fieldErrors
+}
+
+// PersonalInfo is the data of the user the password must not contain.
+type PersonalInfo struct {
+	FirstName string
+	LastName  string
+	Email     string
+}
+
+type PolicyInterface interface {
+	// Validate returns nil or a *ViolationError with every rule the
+	// password breaks.
+	Validate(password string, info PersonalInfo) error
+}
+
+type Policy struct {
+	config   Config
+	breached BreachedListInterface
+}
+
+func NewPolicy(config Config, breached BreachedListInterface) PolicyInterface {
+	return &Policy{config: config, breached: breached}
+}
+
+func (p *Policy) Validate(password string, info PersonalInfo) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		violations = append(violations, Violation{
+			Code:    TooShort,
+			Message: fmt.Sprintf("Must be at least %d characters long", p.config.MinLength),
+		})
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		violations = append(violations, Violation{
+			Code:    TooLong,
+			Message: fmt.Sprintf("Must be at most %d characters long", p.config.MaxLength),
+		})
+	}
+	if characterClasses(password) < p.config.MinCharacterClasses {
+		violations = append(violations, Violation{
+			Code:    MissingCharacterClasses,
+			Message: fmt.Sprintf("Must mix at least %d of lowercase, uppercase, digits and symbols", p.config.MinCharacterClasses),
+		})
+	}
+	if p.config.RejectPersonalInfo && containsPersonalInfo(password, info) {
+		violations = append(violations, Violation{
+			Code:    ContainsPersonalInfo,
+			Message: "Must not contain your name or email",
+		})
+	}
+	if p.breached != nil && p.breached.Contains(password) {
+		violations = append(violations, Violation{
+			Code:    Breached,
+			Message: "Appears in a list of breached passwords",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &ViolationError{Violations: violations}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+func containsPersonalInfo(password string, info PersonalInfo) bool {
+	lowered := strings.ToLower(password)
+	localPart, _, _ := strings.Cut(info.Email, "@")
+	for _, value := range []string{info.FirstName, info.LastName, info.Email, localPart} {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if utf8.RuneCountInString(value) >= minPersonalInfoLength && strings.Contains(lowered, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package passwordPolicy
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type fakeBreachedList map[string]bool
+
+func (f fakeBreachedList) Contains(password string) bool {
+	return f[password]
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	info := PersonalInfo{FirstName: "Juan", LastName: "Al", Email: "jperez@mail.com"}
+	policy := NewPolicy(DefaultConfig, fakeBreachedList{"Qwerty123": true})
+
+	tests := []struct {
+		name     string
+		password string
+		codes    []string
+	}{
+		{name: "valid", password: "correct-horse-battery"},
+		{name: "short name is ignored", password: "Albatross-42"},
+		{name: "multibyte characters count once", password: "ñandú-ñandú"},
+		{name: "too short", password: "ab1", codes: []string{TooShort}},
+		{name: "too long", password: strings.Repeat("a1", 65), codes: []string{TooLong}},
+		{name: "single character class", password: "abcdefghijk", codes: []string{MissingCharacterClasses}},
+		{name: "contains first name", password: "soyJUAN2024", codes: []string{ContainsPersonalInfo}},
+		{name: "contains email local part", password: "jperez-rocks", codes: []string{ContainsPersonalInfo}},
+		{name: "breached", password: "Qwerty123", codes: []string{Breached}},
+		{name: "several violations", password: "juan", codes: []string{TooShort, MissingCharacterClasses, ContainsPersonalInfo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, info)
+			if tt.codes == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var violationError *ViolationError
+			assert.True(t, errors.As(err, &violationError))
+			codes := make([]string, 0, len(violationError.Violations))
+			for _, violation := range violationError.Violations {
+				codes = append(codes, violation.Code)
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+}
+
+func TestViolationError_FieldErrors(t *testing.T) {
+	err := &ViolationError{Violations: []Violation{{Code: TooShort, Message: "Must be at least 8 characters long"}}}
+
+	fieldErrors := err.FieldErrors("password")
+
+	assert.Len(t, fieldErrors, 1)
+	assert.Equal(t, "password", fieldErrors[0].Field)
+	assert.Equal(t, TooShort, fieldErrors[0].Code)
+}
@@ -0,0 +1,97 @@
+package secretBox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const (
+	KeySize = 32
+	// sealedPrefix marks the values sealed by a Box, values without it were
+	// stored before encryption was enabled.
+	sealedPrefix = "v1:"
+)
+
+var (
+	ErrInvalidKey    = errors.New("secret box key must be 32 bytes")
+	ErrInvalidSealed = errors.New("sealed value is malformed or was sealed with another key")
+)
+
+// BoxInterface encrypts short secrets, like TOTP seeds, before they are
+// stored.
+type BoxInterface interface {
+	Seal(plaintext string) (string, error)
+	// Open returns the plaintext of a sealed value. Values stored before
+	// encryption was enabled are returned as they are.
+	Open(value string) (string, error)
+}
+
+// Box uses AES-256-GCM with a random nonce per value.
+type Box struct {
+	aead cipher.AEAD
+}
+
+func NewBox(key []byte) (BoxInterface, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// ParseKey decodes a base64 encoded key, standard or URL-safe, with or
+// without padding.
+func ParseKey(value string) ([]byte, error) {
+	value = strings.TrimRight(strings.TrimSpace(value), "=")
+	key, err := base64.RawStdEncoding.DecodeString(value)
+	if err != nil {
+		key, err = base64.RawURLEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// IsSealed reports whether value was sealed by a Box, values stored before
+// encryption was enabled are not.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *Box) Open(value string) (string, error) {
+	encoded, found := strings.CutPrefix(value, sealedPrefix)
+	if !found {
+		return value, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrInvalidSealed
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealed
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,66 @@
+package secretBox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBox_SealOpen(t *testing.T) {
+	box, err := NewBox(bytes.Repeat([]byte{1}, KeySize))
+	assert.NoError(t, err)
+
+	first, err := box.Seal("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	second, err := box.Seal("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+
+	assert.True(t, IsSealed(first))
+	assert.NotContains(t, first, "JBSWY3DPEHPK3PXP")
+	assert.NotEqual(t, first, second, "every value should use its own nonce")
+
+	plaintext, err := box.Open(first)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+}
+
+func TestBox_Open(t *testing.T) {
+	box, _ := NewBox(bytes.Repeat([]byte{1}, KeySize))
+	otherBox, _ := NewBox(bytes.Repeat([]byte{2}, KeySize))
+	sealed, _ := otherBox.Seal("JBSWY3DPEHPK3PXP")
+
+	assert.False(t, IsSealed("JBSWY3DPEHPK3PXP"))
+	plaintext, err := box.Open("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err, "values stored before encryption should be returned as they are")
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	_, err = box.Open(sealed)
+	assert.ErrorIs(t, err, ErrInvalidSealed)
+
+	_, err = box.Open(sealedPrefix + "not base64!")
+	assert.ErrorIs(t, err, ErrInvalidSealed)
+}
+
+func TestNewBoxWithInvalidKey(t *testing.T) {
+	_, err := NewBox([]byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestParseKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0xfb}, KeySize)
+
+	parsed, err := ParseKey(base64.StdEncoding.EncodeToString(key))
+	assert.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	parsed, err = ParseKey(base64.RawURLEncoding.EncodeToString(key))
+	assert.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString(key[:16]))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParseKey("")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
@@ -0,0 +1,22 @@
+package secureToken
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate(32)
+	assert.NoError(t, err)
+	second, err := Generate(32)
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 43)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Hash("test"))
+	assert.Equal(t, Hash("token"), Hash("token"))
+	assert.NotEqual(t, Hash("token"), Hash("other"))
+}
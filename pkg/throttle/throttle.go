@@ -0,0 +1,43 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+type ThrottleInterface interface {
+	// Allow reports whether an action for key may run now, and records it
+	// when it does.
+	Allow(key string) (bool, error)
+}
+
+// MemoryThrottle lets through at most one action per key every interval. It
+// keeps its state in process memory, so each instance counts on its own.
+type MemoryThrottle struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	last     map[string]time.Time
+	now      func() time.Time
+}
+
+func NewMemoryThrottle(interval time.Duration) ThrottleInterface {
+	return &MemoryThrottle{interval: interval, last: map[string]time.Time{}, now: time.Now}
+}
+
+func (m *MemoryThrottle) Allow(key string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	for stored, at := range m.last {
+		if now.Sub(at) >= m.interval {
+			delete(m.last, stored)
+		}
+	}
+
+	if _, throttled := m.last[key]; throttled {
+		return false, nil
+	}
+	m.last[key] = now
+	return true, nil
+}
@@ -0,0 +1,25 @@
+package throttle
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryThrottle_Allow(t *testing.T) {
+	now := time.Now()
+	throttle := &MemoryThrottle{interval: time.Minute, last: map[string]time.Time{}, now: func() time.Time { return now }}
+	allow := func(key string) bool {
+		allowed, err := throttle.Allow(key)
+		assert.NoError(t, err)
+		return allowed
+	}
+
+	assert.True(t, allow("meze@gmail.com"))
+	assert.False(t, allow("meze@gmail.com"))
+	assert.True(t, allow("other@gmail.com"))
+
+	now = now.Add(time.Minute)
+	assert.True(t, allow("meze@gmail.com"))
+	assert.Len(t, throttle.last, 1)
+}
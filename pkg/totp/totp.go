@@ -0,0 +1,141 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+const secretSize = 20
+
+// Options follow RFC 6238. The zero value is completed with the defaults
+// understood by every authenticator app: SHA1, 6 digits and 30 seconds.
+type Options struct {
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+	// Skew is the number of periods accepted before and after the current
+	// one to tolerate clock drift.
+	Skew int
+}
+
+var DefaultOptions = Options{Algorithm: SHA1, Digits: 6, Period: 30 * time.Second, Skew: 1}
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random secret encoded in base32 without padding,
+// the format expected in otpauth:// URIs.
+func GenerateSecret() (string, error) {
+	buffer := make([]byte, secretSize)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(buffer), nil
+}
+
+func DecodeSecret(secret string) ([]byte, error) {
+	return encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+}
+
+// Step returns the time step t belongs to.
+func Step(t time.Time, opts Options) int64 {
+	opts = opts.withDefaults()
+	return t.Unix() / int64(opts.Period/time.Second)
+}
+
+// GenerateCode returns the code for the time step of t.
+func GenerateCode(key []byte, t time.Time, opts Options) string {
+	opts = opts.withDefaults()
+	return hotp(key, Step(t, opts), opts)
+}
+
+// Validate checks code against the steps inside the skew window and returns
+// the matching step, so callers can refuse a code that was already used.
+func Validate(key []byte, code string, t time.Time, opts Options) (int64, bool) {
+	opts = opts.withDefaults()
+	if len(code) != opts.Digits {
+		return 0, false
+	}
+	current := Step(t, opts)
+	for offset := -opts.Skew; offset <= opts.Skew; offset++ {
+		step := current + int64(offset)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step, opts)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI builds the otpauth:// URI rendered as a QR code by authenticator apps.
+func URI(issuer, account, secret string, opts Options) string {
+	opts = opts.withDefaults()
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", string(opts.Algorithm))
+	query.Set("digits", fmt.Sprint(opts.Digits))
+	query.Set("period", fmt.Sprint(int(opts.Period/time.Second)))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// hotp is the HOTP algorithm of RFC 4226 with the hash selected by opts.
+func hotp(key []byte, counter int64, opts Options) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(opts.hash(), key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < opts.Digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", opts.Digits, value%modulo)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Algorithm == "" {
+		o.Algorithm = DefaultOptions.Algorithm
+	}
+	if o.Digits == 0 {
+		o.Digits = DefaultOptions.Digits
+	}
+	if o.Period == 0 {
+		o.Period = DefaultOptions.Period
+	}
+	return o
+}
+
+func (o Options) hash() func() hash.Hash {
+	switch o.Algorithm {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
@@ -0,0 +1,93 @@
+package totp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 Appendix B.
+func TestGenerateCode_RFC6238(t *testing.T) {
+	seeds := map[Algorithm][]byte{
+		SHA1:   []byte("12345678901234567890"),
+		SHA256: []byte("12345678901234567890123456789012"),
+		SHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	tests := []struct {
+		unix      int64
+		algorithm Algorithm
+		expected  string
+	}{
+		{59, SHA1, "94287082"},
+		{59, SHA256, "46119246"},
+		{59, SHA512, "90693936"},
+		{1111111109, SHA1, "07081804"},
+		{1111111109, SHA256, "68084774"},
+		{1111111109, SHA512, "25091201"},
+		{1111111111, SHA1, "14050471"},
+		{1111111111, SHA256, "67062674"},
+		{1111111111, SHA512, "99943326"},
+		{1234567890, SHA1, "89005924"},
+		{1234567890, SHA256, "91819424"},
+		{1234567890, SHA512, "93441116"},
+		{2000000000, SHA1, "69279037"},
+		{2000000000, SHA256, "90698825"},
+		{2000000000, SHA512, "38618901"},
+		{20000000000, SHA1, "65353130"},
+		{20000000000, SHA256, "77737706"},
+		{20000000000, SHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm)+"_"+time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			opts := Options{Algorithm: tt.algorithm, Digits: 8, Period: 30 * time.Second}
+			assert.Equal(t, tt.expected, GenerateCode(seeds[tt.algorithm], time.Unix(tt.unix, 0), opts))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	code := GenerateCode(key, now, DefaultOptions)
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		expected bool
+	}{
+		{name: "current code should be valid", code: code, at: now, expected: true},
+		{name: "code from the previous period should be valid", code: code, at: now.Add(30 * time.Second), expected: true},
+		{name: "code from two periods ago should be rejected", code: code, at: now.Add(60 * time.Second), expected: false},
+		{name: "wrong code should be rejected", code: "000000", at: now, expected: false},
+		{name: "code with wrong length should be rejected", code: code[:5], at: now, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(key, tt.code, tt.at, DefaultOptions)
+			assert.Equal(t, tt.expected, ok)
+			if ok {
+				assert.Equal(t, Step(now, DefaultOptions), step)
+			}
+		})
+	}
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	key, err := DecodeSecret(strings.ToLower(secret))
+	assert.NoError(t, err)
+	assert.Len(t, key, secretSize)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Chambeo", "meze@gmail.com", "JBSWY3DPEHPK3PXP", DefaultOptions)
+	assert.Equal(t, "otpauth://totp/Chambeo:meze@gmail.com?algorithm=SHA1&digits=6&issuer=Chambeo&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}
@@ -0,0 +1,12 @@
+CREATE TABLE refresh_tokens (
+                       id SERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       session_id VARCHAR(64) NOT NULL,
+                       token_hash VARCHAR(64) UNIQUE NOT NULL,
+                       expires_at TIMESTAMP NOT NULL,
+                       used_at TIMESTAMP NULL,
+                       revoked_at TIMESTAMP NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id);
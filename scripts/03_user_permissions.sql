@@ -0,0 +1,7 @@
+CREATE TABLE user_permissions (
+                       id SERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       permission VARCHAR(50) NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       UNIQUE (user_id, permission)
+);
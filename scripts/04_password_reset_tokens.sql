@@ -0,0 +1,8 @@
+CREATE TABLE password_reset_tokens (
+                       id SERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       token_hash VARCHAR(64) UNIQUE NOT NULL,
+                       expires_at TIMESTAMP NOT NULL,
+                       used_at TIMESTAMP NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
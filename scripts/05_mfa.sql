@@ -0,0 +1,17 @@
+CREATE TABLE mfa_enrollments (
+                       user_id INTEGER PRIMARY KEY REFERENCES users (id),
+                       secret VARCHAR(64) NOT NULL,
+                       confirmed_at TIMESTAMP NULL,
+                       last_used_step BIGINT NOT NULL DEFAULT 0,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE mfa_recovery_codes (
+                       id SERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       code_hash VARCHAR(64) NOT NULL,
+                       used_at TIMESTAMP NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
@@ -0,0 +1,7 @@
+CREATE TABLE login_attempts (
+                       key VARCHAR(320) PRIMARY KEY,
+                       failures INTEGER NOT NULL DEFAULT 0,
+                       last_failure_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_attempts_last_failure_at_idx ON login_attempts (last_failure_at);
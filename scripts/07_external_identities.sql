@@ -0,0 +1,11 @@
+CREATE TABLE external_identities (
+                       id SERIAL PRIMARY KEY,
+                       provider VARCHAR(50) NOT NULL,
+                       subject VARCHAR(255) NOT NULL,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       email VARCHAR(320) NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       UNIQUE (provider, subject)
+);
+
+CREATE INDEX external_identities_user_id_idx ON external_identities (user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+                       id SERIAL PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       name VARCHAR(100) NOT NULL,
+                       prefix VARCHAR(20) NOT NULL,
+                       key_hash VARCHAR(64) UNIQUE NOT NULL,
+                       scopes VARCHAR(500) NOT NULL,
+                       expires_at TIMESTAMP NULL,
+                       last_used_at TIMESTAMP NULL,
+                       revoked_at TIMESTAMP NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE oauth_clients (
+                       id SERIAL PRIMARY KEY,
+                       client_id VARCHAR(64) UNIQUE NOT NULL,
+                       name VARCHAR(100) NOT NULL,
+                       secret_hash VARCHAR(64) NOT NULL,
+                       scopes VARCHAR(500) NOT NULL,
+                       audiences VARCHAR(500) NOT NULL,
+                       revoked_at TIMESTAMP NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
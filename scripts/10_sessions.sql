@@ -0,0 +1,11 @@
+CREATE TABLE sessions (
+                       id VARCHAR(64) PRIMARY KEY,
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       user_agent VARCHAR(512) NOT NULL DEFAULT '',
+                       ip VARCHAR(64) NOT NULL DEFAULT '',
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                       last_seen_at TIMESTAMP NOT NULL,
+                       revoked_at TIMESTAMP NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE impersonations (
+                       id SERIAL PRIMARY KEY,
+                       admin_id INTEGER NOT NULL REFERENCES users (id),
+                       user_id INTEGER NOT NULL REFERENCES users (id),
+                       reason VARCHAR(500) NOT NULL,
+                       ip VARCHAR(64) NOT NULL DEFAULT '',
+                       user_agent VARCHAR(512) NOT NULL DEFAULT '',
+                       expires_at TIMESTAMP NOT NULL,
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX impersonations_user_id_idx ON impersonations (user_id);
+CREATE INDEX impersonations_admin_id_idx ON impersonations (admin_id);
@@ -0,0 +1,27 @@
+CREATE TABLE audit_events (
+                       id BIGSERIAL PRIMARY KEY,
+                       event_type VARCHAR(50) NOT NULL,
+                       outcome VARCHAR(20) NOT NULL,
+                       reason VARCHAR(100) NOT NULL DEFAULT '',
+                       actor_id VARCHAR(64) NOT NULL DEFAULT '',
+                       target_id VARCHAR(64) NOT NULL DEFAULT '',
+                       email VARCHAR(255) NOT NULL DEFAULT '',
+                       ip VARCHAR(64) NOT NULL DEFAULT '',
+                       user_agent VARCHAR(512) NOT NULL DEFAULT '',
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id);
+CREATE INDEX audit_events_target_id_idx ON audit_events (target_id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- The log is append only: entries can not be modified, and are deleted only
+-- by the retention purge.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
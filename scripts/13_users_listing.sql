@@ -0,0 +1,6 @@
+-- Keyset pagination of GET /api/v1/users, every sort field is paired with
+-- the id that breaks ties.
+CREATE INDEX users_created_at_id_idx ON users (created_at, id);
+CREATE INDEX users_email_id_idx ON users (email, id);
+CREATE INDEX users_first_name_id_idx ON users (first_name, id);
+CREATE INDEX users_last_name_id_idx ON users (last_name, id);
@@ -0,0 +1,15 @@
+-- Accent insensitive and typo tolerant search of GET /api/v1/users/search.
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- unaccent is only STABLE, indexes need an IMMUTABLE function. The
+-- dictionary is fixed so the result can not change between calls.
+CREATE OR REPLACE FUNCTION immutable_unaccent(text) RETURNS text AS $$
+    SELECT public.unaccent('public.unaccent', $1)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+-- Both expressions must match searchDocument in internal/users/repository.
+CREATE INDEX users_search_fts_idx ON users
+    USING gin (to_tsvector('simple', immutable_unaccent(lower(first_name || ' ' || last_name || ' ' || email))));
+CREATE INDEX users_search_trgm_idx ON users
+    USING gin (immutable_unaccent(lower(first_name || ' ' || last_name || ' ' || email)) gin_trgm_ops);
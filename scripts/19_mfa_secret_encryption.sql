@@ -0,0 +1 @@
+ALTER TABLE mfa_enrollments ALTER COLUMN secret TYPE VARCHAR(255);
@@ -0,0 +1,6 @@
+CREATE TABLE throttled_actions (
+                       key VARCHAR(320) PRIMARY KEY,
+                       last_action_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX throttled_actions_last_action_at_idx ON throttled_actions (last_action_at);